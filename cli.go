@@ -0,0 +1,397 @@
+package main
+
+// 命令行子命令解析：
+//   node [--p2p-port N] [--api-port N] [--peers a,b] 启动节点（兼容旧的 <p2p_port> [api_port] [bootstrap_peers] 位置参数）
+//   wallet new                                   生成新的密钥，输出地址和十六进制私钥
+//   wallet balance [--api url] <address>         查询地址的可花费余额
+//   tx send [--api url] [--fee N] <to> <amount>  用 MINICHAIN_WALLET_KEY 签名转账并提交到节点
+//   signmsg <message>                            使用 MINICHAIN_WALLET_KEY（十六进制私钥）签名
+//   verifymsg <address> <message> <signature>    验证签名
+//   sync [api_url]                               查询运行中节点的同步进度（默认 http://localhost:8080）
+//   demo                                         在内存链上完成一次注资、转账、挖矿并输出余额
+// 除node外的命令都是一次性命令，执行后直接退出
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini_chain/gossip/core"
+	"mini_chain/internal/blockchain"
+	"mini_chain/internal/wallet"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// defaultAPIURL 查询类命令默认访问的节点API地址
+const defaultAPIURL = "http://localhost:8080"
+
+// defaultAPIPort node命令未指定API端口时使用的端口
+const defaultAPIPort = 8080
+
+// usage 顶层帮助信息
+const usage = `Usage: mini_chain <command> [arguments]
+
+Commands:
+  node [--p2p-port N] [--api-port N] [--peers a,b]  run a node (legacy: <p2p_port> [api_port] [bootstrap_peers])
+  wallet new                                        generate a key and print its address and hex private key
+  wallet balance [--api url] <address>              print the spendable balance of an address
+  tx send [--api url] [--fee N] <to> <amount>       sign a transfer with MINICHAIN_WALLET_KEY and submit it
+  signmsg <message>                                 sign a message with MINICHAIN_WALLET_KEY
+  verifymsg <address> <message> <signature>         verify a signed message
+  sync [api_url]                                    print the sync progress of a running node
+  demo                                              fund, transfer and mine on an in-memory chain
+  help                                              show this help
+
+Run "mini_chain <command> --help" for the flags of a command.
+Example: mini_chain node --p2p-port 3000 --api-port 8080 --peers /ip4/127.0.0.1/tcp/3001/p2p/QmPeerId
+`
+
+// nodeOptions node命令的参数
+type nodeOptions struct {
+	P2PPort        int      // P2P监听端口
+	APIPort        int      // HTTP API端口
+	BootstrapPeers []string // 启动时连接的引导节点multiaddr
+}
+
+// command 解析后的命令行命令：Node非nil时启动节点，否则执行Run后退出
+type command struct {
+	Name string       // 命令名，如 "node"、"wallet balance"
+	Node *nodeOptions // node命令的参数
+	Run  func() error // 一次性命令
+}
+
+// parseArgs 解析命令行参数（不含程序名）；--help/help把帮助写到out并返回flag.ErrHelp，
+// 未知命令或参数错误返回说明用法的错误
+func parseArgs(args []string, out io.Writer) (command, error) {
+	if len(args) == 0 {
+		fmt.Fprint(out, usage)
+		return command{}, errors.New("no command given")
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(out, usage)
+		return command{}, flag.ErrHelp
+	case "node":
+		return parseNode(args[1:], out)
+	case "wallet":
+		return parseWallet(args[1:], out)
+	case "tx":
+		return parseTx(args[1:], out)
+	case "signmsg":
+		if len(args) < 2 {
+			return command{}, errors.New("usage: signmsg <message>")
+		}
+		return command{Name: "signmsg", Run: func() error { return signMessage(strings.Join(args[1:], " "), out) }}, nil
+	case "verifymsg":
+		if len(args) != 4 {
+			return command{}, errors.New("usage: verifymsg <address> <message> <signature>")
+		}
+		return command{Name: "verifymsg", Run: func() error { return verifyMessage(args[1], args[2], args[3], out) }}, nil
+	case "sync":
+		if len(args) > 2 {
+			return command{}, errors.New("usage: sync [api_url]")
+		}
+		url := defaultAPIURL
+		if len(args) == 2 {
+			url = strings.TrimRight(args[1], "/")
+		}
+		return command{Name: "sync", Run: func() error { return printSync(url, out) }}, nil
+	case "demo":
+		if len(args) != 1 {
+			return command{}, errors.New("usage: demo")
+		}
+		return command{Name: "demo", Run: func() error { return printDemo(out) }}, nil
+	}
+	// 旧用法：第一个参数直接是P2P端口
+	if _, err := strconv.Atoi(args[0]); err == nil {
+		return parseNode(args, out)
+	}
+	return command{}, fmt.Errorf("unknown command %q; run with --help for usage", args[0])
+}
+
+// newFlagSet 创建出错时返回错误而不退出进程的FlagSet，帮助和错误信息写到out
+func newFlagSet(name, synopsis string, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintf(out, "Usage: mini_chain %s\n", synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseNode 解析node命令；标志之后的位置参数按旧用法依次作为P2P端口、API端口和引导节点
+func parseNode(args []string, out io.Writer) (command, error) {
+	fs := newFlagSet("node", "node [--p2p-port N] [--api-port N] [--peers a,b] | <p2p_port> [api_port] [bootstrap_peers]", out)
+	p2pPort := fs.Int("p2p-port", 0, "P2P listen port (required)")
+	apiPort := fs.Int("api-port", defaultAPIPort, "HTTP API port")
+	peers := fs.String("peers", "", "comma-separated bootstrap peer multiaddrs")
+	if err := fs.Parse(args); err != nil {
+		return command{}, err
+	}
+	rest := fs.Args()
+	if len(rest) > 3 {
+		return command{}, fmt.Errorf("node: unexpected arguments %v", rest[3:])
+	}
+	if len(rest) >= 1 {
+		port, err := strconv.Atoi(rest[0])
+		if err != nil {
+			return command{}, fmt.Errorf("node: invalid P2P port %q", rest[0])
+		}
+		*p2pPort = port
+	}
+	if len(rest) >= 2 {
+		port, err := strconv.Atoi(rest[1])
+		if err != nil {
+			return command{}, fmt.Errorf("node: invalid API port %q", rest[1])
+		}
+		*apiPort = port
+	}
+	if len(rest) >= 3 {
+		*peers = rest[2]
+	}
+	if *p2pPort <= 0 || *p2pPort > 65535 {
+		return command{}, errors.New("node: --p2p-port is required and must be between 1 and 65535")
+	}
+	if *apiPort <= 0 || *apiPort > 65535 {
+		return command{}, fmt.Errorf("node: invalid API port %d", *apiPort)
+	}
+	opts := &nodeOptions{P2PPort: *p2pPort, APIPort: *apiPort}
+	if *peers != "" {
+		opts.BootstrapPeers = strings.Split(*peers, ",")
+	}
+	return command{Name: "node", Node: opts}, nil
+}
+
+// parseWallet 解析wallet子命令
+func parseWallet(args []string, out io.Writer) (command, error) {
+	const synopsis = "wallet <new|balance> [arguments]"
+	if len(args) == 0 {
+		return command{}, errors.New("usage: " + synopsis)
+	}
+	switch args[0] {
+	case "-h", "-help", "--help":
+		fmt.Fprintf(out, "Usage: mini_chain %s\n", synopsis)
+		return command{}, flag.ErrHelp
+	case "new":
+		fs := newFlagSet("wallet new", "wallet new", out)
+		if err := fs.Parse(args[1:]); err != nil {
+			return command{}, err
+		}
+		if fs.NArg() != 0 {
+			return command{}, errors.New("usage: wallet new")
+		}
+		return command{Name: "wallet new", Run: func() error { return newWallet(out) }}, nil
+	case "balance":
+		fs := newFlagSet("wallet balance", "wallet balance [--api url] <address>", out)
+		api := fs.String("api", defaultAPIURL, "node API URL")
+		if err := fs.Parse(args[1:]); err != nil {
+			return command{}, err
+		}
+		if fs.NArg() != 1 {
+			return command{}, errors.New("usage: wallet balance [--api url] <address>")
+		}
+		address := fs.Arg(0)
+		if !wallet.IsValidAddress(address) {
+			return command{}, fmt.Errorf("wallet balance: invalid address %q", address)
+		}
+		url := strings.TrimRight(*api, "/")
+		return command{Name: "wallet balance", Run: func() error { return printBalance(url, address, out) }}, nil
+	}
+	return command{}, fmt.Errorf("unknown wallet command %q; usage: %s", args[0], synopsis)
+}
+
+// parseTx 解析tx子命令
+func parseTx(args []string, out io.Writer) (command, error) {
+	const synopsis = "tx send [--api url] [--fee N] <to> <amount>"
+	if len(args) == 0 {
+		return command{}, errors.New("usage: " + synopsis)
+	}
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		fmt.Fprintf(out, "Usage: mini_chain %s\n", synopsis)
+		return command{}, flag.ErrHelp
+	}
+	if args[0] != "send" {
+		return command{}, fmt.Errorf("unknown tx command %q; usage: %s", args[0], synopsis)
+	}
+	fs := newFlagSet("tx send", synopsis, out)
+	api := fs.String("api", defaultAPIURL, "node API URL")
+	fee := fs.Int("fee", blockchain.DefaultMinRelayFee, "transaction fee")
+	if err := fs.Parse(args[1:]); err != nil {
+		return command{}, err
+	}
+	if fs.NArg() != 2 {
+		return command{}, errors.New("usage: " + synopsis)
+	}
+	to := fs.Arg(0)
+	if !wallet.IsValidAddress(to) {
+		return command{}, fmt.Errorf("tx send: invalid recipient address %q", to)
+	}
+	// 与其他节点的命令行使用同一个有界解析器：非数字、不大于0或超过core.MaxAmount的金额在签名前被拒绝
+	amount, err := core.ParseAmount(fs.Arg(1))
+	if err != nil {
+		return command{}, fmt.Errorf("tx send: invalid amount %q: %w", fs.Arg(1), err)
+	}
+	if *fee < 0 {
+		return command{}, fmt.Errorf("tx send: invalid fee %d", *fee)
+	}
+	url := strings.TrimRight(*api, "/")
+	return command{Name: "tx send", Run: func() error { return sendTx(url, to, amount, *fee, out) }}, nil
+}
+
+// signMessage 用 MINICHAIN_WALLET_KEY 签名消息
+func signMessage(msg string, out io.Writer) error {
+	key, err := loadSignerKey(os.Getenv("MINICHAIN_WALLET_KEY"))
+	if err != nil {
+		return fmt.Errorf("invalid MINICHAIN_WALLET_KEY: %v", err)
+	}
+	if key == nil {
+		return errors.New("signmsg: set MINICHAIN_WALLET_KEY to the hex private key")
+	}
+	sig, err := wallet.SignMessage(key, msg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Address:  ", wallet.FromPrivate(key).Address)
+	fmt.Fprintln(out, "Signature:", sig)
+	return nil
+}
+
+// verifyMessage 验证地址对消息的签名
+func verifyMessage(address, msg, sig string, out io.Writer) error {
+	ok, err := wallet.VerifyMessage(address, msg, sig)
+	if err != nil {
+		return fmt.Errorf("verifymsg: %v", err)
+	}
+	if !ok {
+		return errors.New("signature INVALID")
+	}
+	fmt.Fprintln(out, "signature valid")
+	return nil
+}
+
+// printSync 输出节点的同步进度
+func printSync(apiURL string, out io.Writer) error {
+	p, err := fetchSyncProgress(apiURL)
+	if err != nil {
+		return fmt.Errorf("sync: %v", err)
+	}
+	state := "up to date"
+	if p.Syncing {
+		state = "syncing"
+	}
+	fmt.Fprintf(out, "Sync: %d/%d (%.1f%%) %s\n", p.CurrentHeight, p.TargetHeight, p.Percent, state)
+	return nil
+}
+
+// printDemo 执行demo并输出结果
+func printDemo(out io.Writer) error {
+	r, err := runDemo(context.Background())
+	if err != nil {
+		return fmt.Errorf("demo: %v", err)
+	}
+	fmt.Fprintf(out, "Sender:    %s (funded %d)\n", r.Sender, demoFaucet)
+	fmt.Fprintf(out, "Recipient: %s\n", r.Recipient)
+	fmt.Fprintf(out, "Sent %d in tx %s, mined in block %d (%s)\n", demoAmount, r.TxID, r.Block.Index, r.Block.Hash)
+	fmt.Fprintf(out, "Sender balance:    %d\n", r.SenderBalance)
+	fmt.Fprintf(out, "Recipient balance: %d\n", r.RecipientBalance)
+	return nil
+}
+
+// newWallet 生成新密钥，私钥以 MINICHAIN_WALLET_KEY 接受的十六进制格式输出
+func newWallet(out io.Writer) error {
+	acc, err := wallet.NewAccount()
+	if err != nil {
+		return fmt.Errorf("wallet new: %v", err)
+	}
+	fmt.Fprintln(out, "Address:    ", acc.Address)
+	fmt.Fprintln(out, "Private key:", hex.EncodeToString(crypto.FromECDSA(acc.Private)))
+	return nil
+}
+
+// printBalance 从节点的 GET /balance/{address} 读取余额
+func printBalance(apiURL, address string, out io.Writer) error {
+	var b struct {
+		Balance int `json:"balance"`
+	}
+	if err := getJSON(apiURL+"/balance/"+address, &b); err != nil {
+		return fmt.Errorf("wallet balance: %v", err)
+	}
+	fmt.Fprintf(out, "Balance: %d\n", b.Balance)
+	return nil
+}
+
+// sendTx 用 MINICHAIN_WALLET_KEY 的可花费UTXO构造转账，提交到节点的 POST /tx
+func sendTx(apiURL, to string, amount, fee int, out io.Writer) error {
+	key, err := loadSignerKey(os.Getenv("MINICHAIN_WALLET_KEY"))
+	if err != nil {
+		return fmt.Errorf("invalid MINICHAIN_WALLET_KEY: %v", err)
+	}
+	if key == nil {
+		return errors.New("tx send: set MINICHAIN_WALLET_KEY to the hex private key")
+	}
+	acc := wallet.FromPrivate(key)
+	var utxos []wallet.UTXO
+	if err := getJSON(apiURL+"/addr/"+acc.Address+"/utxos", &utxos); err != nil {
+		return fmt.Errorf("tx send: %v", err)
+	}
+	wtx, err := wallet.BuildTransaction(acc, to, amount, fee, utxos)
+	if err != nil {
+		return fmt.Errorf("tx send: %v", err)
+	}
+	tx := blockchain.TxFromWallet(wtx)
+	txid, err := blockchain.TxID(tx)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(apiURL+"/tx", "application/json", bytes.NewReader(mustMarshal(tx)))
+	if err != nil {
+		return fmt.Errorf("tx send: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("tx send: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	fmt.Fprintln(out, "From:", acc.Address)
+	fmt.Fprintln(out, "TxID:", txid)
+	return nil
+}
+
+// getJSON 发送GET请求并把200响应解码到v
+func getJSON(url string, v interface{}) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// fetchSyncProgress 从节点的 GET /status 读取同步进度
+func fetchSyncProgress(apiURL string) (blockchain.SyncProgress, error) {
+	var status struct {
+		Sync blockchain.SyncProgress `json:"sync"`
+	}
+	if err := getJSON(apiURL+"/status", &status); err != nil {
+		return blockchain.SyncProgress{}, err
+	}
+	return status.Sync, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+// demo命令：在单个进程内走通一次完整转账，用于快速验证构建是否可用
+// 创建两个钱包，通过创世分配为第一个钱包注资，向第二个钱包转账并挖出一个区块，
+// 依次经过签名、内存池校验、挖矿和区块应用
+
+import (
+	"context"
+	"fmt"
+
+	"mini_chain/internal/blockchain"
+	"mini_chain/internal/wallet"
+)
+
+// demo流程的金额参数
+const (
+	demoFaucet = 100 // 创世分配给发送方的金额
+	demoAmount = 40  // 转给接收方的金额
+)
+
+// demoResult demo流程的结果
+type demoResult struct {
+	Sender, Recipient               string // 发送方和接收方地址
+	TxID                            string // 转账交易ID
+	Block                           blockchain.Block
+	Reward                          int // 发送方兼任矿工获得的coinbase金额（区块奖励加手续费）
+	SenderBalance, RecipientBalance int // 出块后的余额
+}
+
+// runDemo 在一条新的内存链上执行demo流程
+func runDemo(ctx context.Context) (demoResult, error) {
+	sender, err := wallet.NewAccount()
+	if err != nil {
+		return demoResult{}, err
+	}
+	recipient, err := wallet.NewAccount()
+	if err != nil {
+		return demoResult{}, err
+	}
+	bc := blockchain.NewBlockchain(1, nil, blockchain.GenesisAlloc{Address: sender.Address, Amount: demoFaucet})
+
+	// 用发送方的全部UTXO构造并签名转账交易
+	var utxos []wallet.UTXO
+	for _, u := range blockchain.FindUTXOsForAddress(sender.Address) {
+		utxos = append(utxos, wallet.UTXO{Txid: u.Txid, Vout: u.Vout, Amount: u.Amount})
+	}
+	wtx, err := wallet.BuildTransaction(sender, recipient.Address, demoAmount, blockchain.DefaultMinRelayFee, utxos)
+	if err != nil {
+		return demoResult{}, fmt.Errorf("build tx: %v", err)
+	}
+	tx := blockchain.TxFromWallet(wtx)
+	if _, err := blockchain.ValidateTxForMempool(tx, blockchain.DefaultMinRelayFee); err != nil {
+		return demoResult{}, fmt.Errorf("tx rejected: %v", err)
+	}
+	txid, err := blockchain.PutTransaction(tx)
+	if err != nil {
+		return demoResult{}, err
+	}
+	blockchain.AddToMempool(txid)
+
+	// 发送方作为矿工打包内存池并应用区块，奖励按共识参数计算，手续费也归矿工
+	b, err := bc.MinePending(ctx, sender.Address)
+	if err != nil {
+		return demoResult{}, fmt.Errorf("mine: %v", err)
+	}
+	if err := bc.ValidateAndApplyBlock(b); err != nil {
+		return demoResult{}, fmt.Errorf("apply block: %v", err)
+	}
+	coinbase, err := blockchain.GetTransaction(b.Transactions[0])
+	if err != nil {
+		return demoResult{}, err
+	}
+	reward, err := blockchain.SumOutputs(coinbase)
+	if err != nil {
+		return demoResult{}, err
+	}
+	return demoResult{
+		Sender:           sender.Address,
+		Recipient:        recipient.Address,
+		TxID:             txid,
+		Block:            b,
+		Reward:           reward,
+		SenderBalance:    blockchain.GetBalance(sender.Address),
+		RecipientBalance: blockchain.GetBalance(recipient.Address),
+	}, nil
+}
@@ -0,0 +1,43 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxAmount 单笔转账允许的最大金额，防止误输入的超大金额被签名发出
+const MaxAmount = 1_000_000_000
+
+var (
+	// ErrAmountNotNumber 金额不是十进制整数
+	ErrAmountNotNumber = errors.New("amount must be a whole number")
+	// ErrAmountNotPositive 金额为零或负数
+	ErrAmountNotPositive = errors.New("amount must be greater than zero")
+	// ErrAmountTooLarge 金额超过MaxAmount
+	ErrAmountTooLarge = fmt.Errorf("amount must not exceed %d", MaxAmount)
+)
+
+// ParseAmount 解析命令行输入的转账金额
+// 非数字、零、负数以及超过MaxAmount的金额都会返回错误，调用者应在签名前拒绝
+func ParseAmount(s string) (int, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) && numErr.Err == strconv.ErrRange {
+			if strings.HasPrefix(strings.TrimSpace(s), "-") {
+				return 0, ErrAmountNotPositive
+			}
+			return 0, ErrAmountTooLarge
+		}
+		return 0, ErrAmountNotNumber
+	}
+	if n <= 0 {
+		return 0, ErrAmountNotPositive
+	}
+	if n > MaxAmount {
+		return 0, ErrAmountTooLarge
+	}
+	return int(n), nil
+}
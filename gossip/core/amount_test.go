@@ -0,0 +1,29 @@
+package core
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int
+		err  error
+	}{
+		{"42", 42, nil},
+		{" 7 ", 7, nil},
+		{"1000000000", MaxAmount, nil},
+		{"abc", 0, ErrAmountNotNumber},
+		{"", 0, ErrAmountNotNumber},
+		{"1.5", 0, ErrAmountNotNumber},
+		{"0", 0, ErrAmountNotPositive},
+		{"-5", 0, ErrAmountNotPositive},
+		{"-99999999999999999999", 0, ErrAmountNotPositive},
+		{"1000000001", 0, ErrAmountTooLarge},
+		{"99999999999999999999", 0, ErrAmountTooLarge},
+	}
+	for _, c := range cases {
+		got, err := ParseAmount(c.in)
+		if got != c.want || err != c.err {
+			t.Errorf("ParseAmount(%q) = %d, %v; want %d, %v", c.in, got, err, c.want, c.err)
+		}
+	}
+}
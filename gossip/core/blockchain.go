@@ -1,28 +1,62 @@
 package core
 
 import (
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"runtime"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Difficulty 挖矿难度，表示哈希值需要以多少个0开头
+// 与internal/blockchain保持一致：难度按每个十六进制0对应4个比特换算为big.Int目标值
 const Difficulty = 3
 
+// GenesisTimestamp 创世区块的固定时间戳
+// 各节点独立创建的创世区块必须完全相同，ReplaceChain才会接受对方的链
+const GenesisTimestamp int64 = 1700000000
+
+// DefaultChainID 默认的链ID
+// 链ID参与交易签名哈希，使签名只在特定网络上有效，防止跨网络重放
+const DefaultChainID = "mini-chain-1"
+
+// 哈希域分隔标签的默认值
+const (
+	DefaultTxHashDomain    = "minichain-tx-v1"
+	DefaultBlockHashDomain = "minichain-block-v1"
+)
+
+// 哈希域分隔标签：交易签名哈希和区块哈希在哈希前分别加上不同的标签，
+// 使两类结构的哈希输入不会相同，签名也只对交易签名哈希有效。
+// 修改标签会使已有的签名和区块哈希失效，同一网络的节点必须使用相同的标签
+var (
+	TxHashDomain    = DefaultTxHashDomain
+	BlockHashDomain = DefaultBlockHashDomain
+)
+
 // Transaction 交易结构体，表示一笔转账交易
 type Transaction struct {
-	From      string `json:"from"`      // 发送方地址
-	To        string `json:"to"`        // 接收方地址
-	Amount    int    `json:"amount"`    // 转账金额
-	Signature string `json:"signature"` // 交易签名，用于验证交易有效性
+	From         string `json:"from"`                    // 发送方地址
+	To           string `json:"to"`                      // 接收方地址
+	Amount       int    `json:"amount"`                  // 转账金额
+	ExpiryHeight int    `json:"expiry_height,omitempty"` // 过期高度：只能被打包进高度不超过该值的区块，0表示永不过期
+	Signature    string `json:"signature"`               // 交易签名，用于验证交易有效性
+}
+
+// Expired 判断交易在指定高度的区块中是否已过期
+// height: 待打包区块的高度
+func (tx Transaction) Expired(height int) bool {
+	return tx.ExpiryHeight > 0 && height > tx.ExpiryHeight
 }
 
 // Block 区块结构体，包含区块的所有信息
@@ -33,6 +67,7 @@ type Block struct {
 	PrevHash     string        `json:"prev_hash"`     // 前一个区块的哈希值
 	Nonce        int64         `json:"nonce"`         // 工作量证明的随机数
 	Hash         string        `json:"hash"`          // 当前区块的哈希值
+	UTXOTxs      []UTXOTx      `json:"utxo_txs,omitempty"` // UTXO模式下的交易列表
 }
 
 // Blockchain 区块链结构体
@@ -40,6 +75,13 @@ type Blockchain struct {
 	chain       []Block
 	transaction []Transaction
 	mutex       sync.Mutex
+	chainID     string // 链ID，只接受为该链签名的交易
+	// 已打包交易的TxID到所在区块高度，用于拒绝重复提交已确认的交易
+	confirmed map[string]int
+	// UTXO模式：UTXO集合和UTXO交易池
+	utxoMode bool
+	utxos    map[UTXOKey]UTXOEntry
+	utxoPool []UTXOTx
 }
 
 // NewKeyPair 生成新的椭圆曲线密钥对，用于创建钱包地址
@@ -49,16 +91,73 @@ func NewKeyPair() (*ecdsa.PrivateKey, string) {
 	return priv, hex.EncodeToString(pubBytes)
 }
 
-// HashTransaction 计算交易的哈希值，用于签名和验证
+// AddressHexLen 地址（未压缩P256公钥的十六进制编码）的长度
+const AddressHexLen = 2 * 65
+
+// ErrMalformedPubKey 公钥不是P256曲线上点的未压缩编码
+var ErrMalformedPubKey = errors.New("malformed public key")
+
+// IsValidAddress 检查地址格式：长度为AddressHexLen的十六进制串，且能解析为P256曲线上的未压缩公钥
+// 格式错误的地址没有对应私钥，转给它的币将无法再被花费
+func IsValidAddress(addr string) bool {
+	_, err := ParsePublicKey(addr)
+	return err == nil
+}
+
+// ParsePublicKey 把地址（未压缩P256公钥的十六进制编码）解析为ECDSA公钥
+// 先检查长度和0x04前缀，再由crypto/ecdh确认是曲线上的点（不是无穷远点）；
+// 任何格式错误都返回ErrMalformedPubKey，不会panic
+func ParsePublicKey(addr string) (*ecdsa.PublicKey, error) {
+	if len(addr) != AddressHexLen {
+		return nil, fmt.Errorf("%w: length %d, want %d hex chars", ErrMalformedPubKey, len(addr), AddressHexLen)
+	}
+	pubBytes, err := hex.DecodeString(addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedPubKey, err)
+	}
+	if pubBytes[0] != 0x04 {
+		return nil, fmt.Errorf("%w: not an uncompressed point", ErrMalformedPubKey)
+	}
+	if _, err := ecdh.P256().NewPublicKey(pubBytes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedPubKey, err)
+	}
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(pubBytes[1:33]),
+		Y:     new(big.Int).SetBytes(pubBytes[33:]),
+	}, nil
+}
+
+// HashTransaction 计算默认链上交易的哈希值，用于签名和验证
 func HashTransaction(tx Transaction) []byte {
-	data := tx.From + "|" + tx.To + "|" + strconv.Itoa(tx.Amount)
+	return HashTransactionForChain(tx, DefaultChainID)
+}
+
+// HashTransactionForChain 计算指定链上交易的哈希值
+// 链ID和过期高度都参与哈希，防止签名被重放到其他网络或签名后被篡改；哈希输入以TxHashDomain开头
+func HashTransactionForChain(tx Transaction, chainID string) []byte {
+	data := TxHashDomain + "|" + chainID + "|" + tx.From + "|" + tx.To + "|" + strconv.Itoa(tx.Amount) + "|" + strconv.Itoa(tx.ExpiryHeight)
 	h := sha256.Sum256([]byte(data))
 	return h[:]
 }
 
-// SignTransaction 使用私钥对交易进行签名
+// TxID 返回账户模型交易的唯一标识：签名内容和签名一起的SHA256（十六进制）
+// HashTransaction不包含签名，金额和收发方相同的两笔转账得到同一个签名哈希；
+// 签名带有随机数，TxID因此能区分这样的两笔交易。交易池去重、已确认索引和按ID查询都使用TxID
+func TxID(tx Transaction) string {
+	data := TxHashDomain + "|id|" + tx.From + "|" + tx.To + "|" + strconv.Itoa(tx.Amount) + "|" + strconv.Itoa(tx.ExpiryHeight) + "|" + tx.Signature
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
+
+// SignTransaction 使用私钥为默认链对交易进行签名
 func SignTransaction(priv *ecdsa.PrivateKey, tx Transaction) (string, error) {
-	h := HashTransaction(tx)
+	return SignTransactionForChain(priv, tx, DefaultChainID)
+}
+
+// SignTransactionForChain 使用私钥为指定链对交易进行签名
+func SignTransactionForChain(priv *ecdsa.PrivateKey, tx Transaction, chainID string) (string, error) {
+	h := HashTransactionForChain(tx, chainID)
 	sig, err := ecdsa.SignASN1(rand.Reader, priv, h)
 	if err != nil {
 		return "", err
@@ -66,35 +165,123 @@ func SignTransaction(priv *ecdsa.PrivateKey, tx Transaction) (string, error) {
 	return hex.EncodeToString(sig), nil
 }
 
-// VerifyTransaction 验证交易签名的有效性
+// VerifyTransaction 验证交易在默认链上的签名有效性
 func VerifyTransaction(tx Transaction) bool {
-	pubBytes, err := hex.DecodeString(tx.From)
+	return VerifyTransactionForChain(tx, DefaultChainID)
+}
+
+// VerifyTransactionForChain 验证交易在指定链上的签名有效性
+// 为其他链签名的交易在此返回false
+func VerifyTransactionForChain(tx Transaction, chainID string) bool {
+	pub, err := ParsePublicKey(tx.From)
 	if err != nil {
 		return false
 	}
-	x, y := elliptic.Unmarshal(elliptic.P256(), pubBytes)
-	if x == nil {
-		return false
-	}
-	pub := ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
 	sigBytes, err := hex.DecodeString(tx.Signature)
 	if err != nil {
 		return false
 	}
-	h := HashTransaction(tx)
-	return ecdsa.VerifyASN1(&pub, h, sigBytes)
+	h := HashTransactionForChain(tx, chainID)
+	return ecdsa.VerifyASN1(pub, h, sigBytes)
 }
 
-// CalculateHash 计算区块的哈希值
+// SigVerifyWorkers 并行验证区块交易签名时使用的worker数量
+var SigVerifyWorkers = runtime.NumCPU()
+
+// VerifyTransactionsForChain 使用worker池并行验证一组交易在指定链上的签名
+// 任意一个签名无效即返回false，其余worker尽快停止
+func VerifyTransactionsForChain(txs []Transaction, chainID string) bool {
+	workers := SigVerifyWorkers
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	if workers <= 1 {
+		for _, tx := range txs {
+			if !VerifyTransactionForChain(tx, chainID) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var (
+		failed atomic.Bool
+		next   atomic.Int64 // 下一个待验证交易的下标
+		wg     sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= len(txs) {
+					return
+				}
+				if !VerifyTransactionForChain(txs[i], chainID) {
+					failed.Store(true)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return !failed.Load()
+}
+
+// CalculateHash 计算区块的哈希值，哈希输入以BlockHashDomain开头
 func CalculateHash(b Block) string {
-	txBytes, _ := json.Marshal(b.Transactions)
-	record := strconv.Itoa(b.Index) + strconv.FormatInt(b.Timestamp, 10) + string(txBytes) + b.PrevHash + strconv.FormatInt(b.Nonce, 10)
+	txBytes := CanonicalTxBytes(b.Transactions)
+	record := BlockHashDomain + "|" + strconv.Itoa(b.Index) + strconv.FormatInt(b.Timestamp, 10) + string(txBytes) + b.PrevHash + strconv.FormatInt(b.Nonce, 10)
+	// UTXO交易只在存在时参与哈希，账户模式区块的哈希保持不变
+	if len(b.UTXOTxs) > 0 {
+		utxoBytes, _ := json.Marshal(b.UTXOTxs)
+		record += string(utxoBytes)
+	}
 	h := sha256.Sum256([]byte(record))
 	return fmt.Sprintf("%x", h)
 }
 
+// CanonicalTxBytes 返回交易列表参与区块哈希的规范编码
+// nil与空切片都编码为"[]"，与创世区块一致，避免空区块经JSON往返后哈希不同；
+// 泛型参数使交易结构不同的独立节点（p2p、libp2p）也能复用同一编码
+func CanonicalTxBytes[T any](txs []T) []byte {
+	if len(txs) == 0 {
+		return []byte("[]")
+	}
+	b, _ := json.Marshal(txs)
+	return b
+}
+
+// Target 根据难度计算PoW目标值：2^(256 - difficulty*4)
+// 哈希值（按大端无符号整数解释）必须严格小于该目标值
+func Target(difficulty int) *big.Int {
+	target := big.NewInt(1)
+	target.Lsh(target, uint(256-difficulty*4))
+	return target
+}
+
+// HashMeetsTarget 判断十六进制哈希是否满足难度对应的数值目标
+// 非法的十六进制串或长度不是64的哈希一律视为不满足；
+// 难度小于1（目标值不小于2^256，任何哈希都满足）或超过64时同样视为不满足
+func HashMeetsTarget(hash string, difficulty int) bool {
+	if difficulty < 1 || difficulty > 2*sha256.Size {
+		return false
+	}
+	raw, err := hex.DecodeString(hash)
+	if err != nil || len(raw) != sha256.Size {
+		return false
+	}
+	return new(big.Int).SetBytes(raw).Cmp(Target(difficulty)) == -1
+}
+
 // MineBlock 挖掘新区块，通过工作量证明找到满足难度要求的哈希值
 func MineBlock(transactions []Transaction, prev Block) Block {
+	return MineUTXOBlock(transactions, nil, prev)
+}
+
+// MineUTXOBlock 挖掘同时包含账户交易和UTXO交易的新区块
+func MineUTXOBlock(transactions []Transaction, utxoTxs []UTXOTx, prev Block) Block {
 	newBlock := Block{
 		Index:        prev.Index + 1,        // 新区块索引为前一区块索引+1
 		Timestamp:    time.Now().Unix(),     // 设置当前时间戳
@@ -102,12 +289,13 @@ func MineBlock(transactions []Transaction, prev Block) Block {
 		PrevHash:     prev.Hash,             // 设置前一区块哈希
 		Nonce:        0,                     // 初始化随机数为0
 		Hash:         "",                    // 初始化哈希为空
+		UTXOTxs:      utxoTxs,               // UTXO交易（账户模式为空）
 	}
 	// 不断尝试不同的Nonce值直到找到满足难度要求的哈希
 	for {
 		newBlock.Hash = CalculateHash(newBlock)
-		// 检查哈希是否满足难度要求（以指定数量的0开头）
-		if strings.HasPrefix(newBlock.Hash, strings.Repeat("0", Difficulty)) {
+		// 检查哈希是否满足难度对应的数值目标
+		if HashMeetsTarget(newBlock.Hash, Difficulty) {
 			break
 		}
 		newBlock.Nonce++ // 增加Nonce值继续尝试
@@ -115,47 +303,117 @@ func MineBlock(transactions []Transaction, prev Block) Block {
 	return newBlock
 }
 
-// NewBlockchain 创建新的区块链实例
+// NewBlockchain 创建使用默认链ID的区块链实例
 func NewBlockchain() *Blockchain {
+	return NewBlockchainWithChainID(DefaultChainID)
+}
+
+// NewBlockchainWithChainID 创建使用指定链ID的区块链实例
+// chainID: 链ID，交易必须为该链签名才会被接受
+func NewBlockchainWithChainID(chainID string) *Blockchain {
 	bc := &Blockchain{
 		chain:       []Block{},
 		transaction: []Transaction{},
+		chainID:     chainID,
+		confirmed:   make(map[string]int),
 	}
 	bc.initGenesis()
 	return bc
 }
 
-// initGenesis 初始化创世区块
-func (bc *Blockchain) initGenesis() {
+// ChainID 返回区块链的链ID
+func (bc *Blockchain) ChainID() string {
+	return bc.chainID
+}
+
+// GenesisBlock 返回创世区块，各节点独立创建的创世区块完全相同
+func GenesisBlock() Block {
 	genesis := Block{
 		Index:        0,                     // 创世区块索引为0
-		Timestamp:    time.Now().Unix(),     // 设置当前时间戳
+		Timestamp:    GenesisTimestamp,      // 固定时间戳
 		Transactions: []Transaction{},       // 创世区块不包含交易
 		PrevHash:     "0",                   // 前一区块哈希为"0"
 		Nonce:        0,                     // 随机数初始化为0
 	}
 	genesis.Hash = CalculateHash(genesis)   // 计算创世区块哈希
-	bc.chain = []Block{genesis}             // 将创世区块加入区块链
+	return genesis
+}
+
+// initGenesis 初始化创世区块
+func (bc *Blockchain) initGenesis() {
+	bc.chain = []Block{GenesisBlock()}      // 将创世区块加入区块链
 }
 
-// AddBlock 向区块链添加新区块
+// AddBlock 向区块链添加新区块，区块中的交易同时从交易池移除
 func (bc *Blockchain) AddBlock(b Block) bool {
 	bc.mutex.Lock()                         // 加锁保护区块链数据
 	defer bc.mutex.Unlock()                 // 函数结束时解锁
 	last := bc.chain[len(bc.chain)-1]       // 获取最后一个区块
 
-	// 验证区块有效性：
-	// 1. 前一区块哈希必须匹配
-	// 2. 区块哈希必须正确
-	// 3. 区块哈希必须满足难度要求
-	if b.PrevHash != last.Hash || CalculateHash(b) != b.Hash || !strings.HasPrefix(b.Hash, strings.Repeat("0", Difficulty)) {
+	utxos, err := bc.checkBlockLocked(last, b, bc.utxos)
+	if err != nil {
 		return false
 	}
 
 	bc.chain = append(bc.chain, b)          // 将新区块添加到区块链末尾
+	bc.indexTxsLocked(b)                    // 记录已确认的交易
+	bc.removeTxsLocked(b.Transactions)      // 已打包的交易离开交易池
+	bc.pruneExpiredLocked(b.Index + 1)      // 丢弃下一个区块已无法打包的交易
+	if utxos != nil {
+		bc.utxos = utxos
+		bc.pruneUTXOPoolLocked() // 丢弃已打包或输入已被花费的UTXO交易
+	}
 	return true
 }
 
+// checkBlockLocked 校验区块b能否接在prev之后（调用者需持有锁）
+// utxos为应用b之前的UTXO集合；b携带UTXO交易时返回应用后的新集合，否则返回nil
+func (bc *Blockchain) checkBlockLocked(prev, b Block, utxos map[UTXOKey]UTXOEntry) (map[UTXOKey]UTXOEntry, error) {
+	// 验证区块有效性：
+	// 1. 前一区块哈希必须匹配
+	// 2. 区块哈希必须正确
+	// 3. 区块哈希必须满足难度对应的数值目标
+	if b.PrevHash != prev.Hash {
+		return nil, errors.New("block does not link to its predecessor")
+	}
+	if CalculateHash(b) != b.Hash {
+		return nil, errors.New("block hash mismatch")
+	}
+	if !HashMeetsTarget(b.Hash, Difficulty) {
+		return nil, errors.New("block hash does not meet the PoW target")
+	}
+	// 4. 区块中不能包含已过期的交易
+	for _, tx := range b.Transactions {
+		if tx.Expired(b.Index) {
+			return nil, errors.New("block contains an expired transaction")
+		}
+	}
+	// 5. 所有交易签名必须有效（并行验证），任一无效则整个区块被拒绝
+	if !VerifyTransactionsForChain(b.Transactions, bc.chainID) {
+		return nil, errors.New("block contains an invalid transaction signature")
+	}
+	// 6. UTXO交易只在UTXO模式下接受，且必须针对当前UTXO集合全部有效
+	if len(b.UTXOTxs) == 0 {
+		return nil, nil
+	}
+	if !bc.utxoMode {
+		return nil, errors.New("UTXO transactions are only accepted in UTXO mode")
+	}
+	return applyUTXOBlock(utxos, b.UTXOTxs, bc.chainID)
+}
+
+// pruneExpiredLocked 从交易池中移除在指定高度已过期的交易（调用者需持有锁）
+// height: 下一个待打包区块的高度
+func (bc *Blockchain) pruneExpiredLocked(height int) {
+	kept := bc.transaction[:0]
+	for _, tx := range bc.transaction {
+		if !tx.Expired(height) {
+			kept = append(kept, tx)
+		}
+	}
+	bc.transaction = kept
+}
+
 // GetBlocks 获取区块链副本
 func (bc *Blockchain) GetBlocks() []Block {
 	bc.mutex.Lock()
@@ -167,17 +425,33 @@ func (bc *Blockchain) GetBlocks() []Block {
 
 // AddTransaction 添加交易到交易池
 func (bc *Blockchain) AddTransaction(tx Transaction) bool {
-	// 首先验证交易签名的有效性
-	if !VerifyTransaction(tx) {
+	// 发送方和接收方都必须是有效地址，拒绝转给格式错误地址的交易
+	if !IsValidAddress(tx.From) || !IsValidAddress(tx.To) {
+		return false
+	}
+
+	// 首先验证交易签名的有效性（必须为本链签名）
+	if !VerifyTransactionForChain(tx, bc.chainID) {
 		return false
 	}
 
 	bc.mutex.Lock()
 	defer bc.mutex.Unlock()
 
+	// 已经无法被打包进下一个区块的交易直接拒绝
+	if tx.Expired(len(bc.chain)) {
+		return false
+	}
+
+	// 已被链上区块打包的交易不再进入交易池
+	id := TxID(tx)
+	if _, ok := bc.confirmed[id]; ok {
+		return false
+	}
+
 	// 检查交易是否已经在交易池中
 	for _, t := range bc.transaction {
-		if t.Signature == tx.Signature {
+		if TxID(t) == id {
 			return false
 		}
 	}
@@ -187,6 +461,40 @@ func (bc *Blockchain) AddTransaction(tx Transaction) bool {
 	return true
 }
 
+// TxConfirmed 判断交易是否已被链上区块打包，返回所在区块高度
+func (bc *Blockchain) TxConfirmed(tx Transaction) (int, bool) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	height, ok := bc.confirmed[TxID(tx)]
+	return height, ok
+}
+
+// FindTransaction 按TxID查找交易：在交易池中时返回高度-1，已被打包时返回所在区块高度
+func (bc *Blockchain) FindTransaction(id string) (Transaction, int, bool) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	if height, ok := bc.confirmed[id]; ok && height < len(bc.chain) {
+		for _, tx := range bc.chain[height].Transactions {
+			if TxID(tx) == id {
+				return tx, height, true
+			}
+		}
+	}
+	for _, tx := range bc.transaction {
+		if TxID(tx) == id {
+			return tx, -1, true
+		}
+	}
+	return Transaction{}, 0, false
+}
+
+// indexTxsLocked 把区块中的交易记入已确认索引（调用者需持有锁）
+func (bc *Blockchain) indexTxsLocked(b Block) {
+	for _, tx := range b.Transactions {
+		bc.confirmed[TxID(tx)] = b.Index
+	}
+}
+
 // GetTransactions 获取交易池副本
 func (bc *Blockchain) GetTransactions() []Transaction {
 	bc.mutex.Lock()
@@ -200,21 +508,23 @@ func (bc *Blockchain) GetTransactions() []Transaction {
 func (bc *Blockchain) ClearTransactions(txs []Transaction) {
 	bc.mutex.Lock()
 	defer bc.mutex.Unlock()
+	bc.removeTxsLocked(txs)
+}
 
+// removeTxsLocked 从交易池中移除与txs的TxID相同的交易（调用者需持有锁）
+func (bc *Blockchain) removeTxsLocked(txs []Transaction) {
+	if len(txs) == 0 {
+		return
+	}
+	remove := make(map[string]bool, len(txs))
+	for _, t := range txs {
+		remove[TxID(t)] = true
+	}
 	newPool := []Transaction{}              // 创建新的交易池
 
-	// 遍历当前交易池中的所有交易
+	// 保留不在要移除列表中的交易
 	for _, p := range bc.transaction {
-		found := false
-		// 检查该交易是否在要移除的交易列表中
-		for _, t := range txs {
-			if p.Signature == t.Signature {
-				found = true
-				break
-			}
-		}
-		// 如果不在要移除的列表中，则保留在新交易池中
-		if !found {
+		if !remove[TxID(p)] {
 			newPool = append(newPool, p)
 		}
 	}
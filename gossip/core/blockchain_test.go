@@ -1,9 +1,23 @@
 package core
 
 import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
 	"testing"
 )
 
+// receiver 测试交易使用的有效接收地址
+var receiver = func() string {
+	_, pub := NewKeyPair()
+	return pub
+}()
+
 // TestNewKeyPair 测试密钥对生成
 func TestNewKeyPair(t *testing.T) {
 	priv, pub := NewKeyPair()
@@ -21,7 +35,7 @@ func TestNewKeyPair(t *testing.T) {
 func TestHashTransaction(t *testing.T) {
 	tx := Transaction{
 		From:   "sender",
-		To:     "receiver",
+		To:     receiver,
 		Amount: 100,
 	}
 	
@@ -40,7 +54,7 @@ func TestSignAndVerifyTransaction(t *testing.T) {
 	// 创建交易
 	tx := Transaction{
 		From:   pub,
-		To:     "receiver",
+		To:     receiver,
 		Amount: 100,
 	}
 	
@@ -65,6 +79,82 @@ func TestSignAndVerifyTransaction(t *testing.T) {
 	}
 }
 
+// TestHashDomainSeparation 对不带域分隔标签的旧交易哈希的签名在新方案下不能通过验证
+func TestHashDomainSeparation(t *testing.T) {
+	priv, pub := NewKeyPair()
+	tx := Transaction{From: pub, To: receiver, Amount: 100}
+
+	old := sha256.Sum256([]byte(DefaultChainID + "|" + tx.From + "|" + tx.To + "|" + strconv.Itoa(tx.Amount) + "|" + strconv.Itoa(tx.ExpiryHeight)))
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, old[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Signature = hex.EncodeToString(sig)
+	if VerifyTransaction(tx) {
+		t.Error("signature over the undomained hash must not verify")
+	}
+
+	if tx.Signature, err = SignTransaction(priv, tx); err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyTransaction(tx) {
+		t.Fatal("signature over the domained hash should verify")
+	}
+
+	// 交易签名哈希和区块哈希使用不同的标签，更换交易标签后原签名失效
+	if TxHashDomain == BlockHashDomain {
+		t.Fatal("tx and block hashes must use different domains")
+	}
+	defer func(d string) { TxHashDomain = d }(TxHashDomain)
+	TxHashDomain = "other-tx-domain"
+	if VerifyTransaction(tx) {
+		t.Error("signature must not verify under a different tx domain")
+	}
+}
+
+// TestVerifyTransactionMalformedFrom 格式错误的From公钥使验证返回false而不是panic
+func TestVerifyTransactionMalformedFrom(t *testing.T) {
+	priv, pub := NewKeyPair()
+	tx := Transaction{From: pub, To: receiver, Amount: 1}
+	sig, err := SignTransaction(priv, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Signature = sig
+	if !VerifyTransaction(tx) {
+		t.Fatal("well-formed transaction should verify")
+	}
+
+	// 把Y坐标最后一个字节加1，得到不在曲线上的点
+	offCurve, _ := hex.DecodeString(pub)
+	offCurve[64]++
+	cases := map[string]string{
+		"empty":       "",
+		"truncated":   pub[:len(pub)-2],
+		"prefix only": "04",
+		"over-long":   pub + "00",
+		"double":      pub + pub,
+		"not hex":     "zz" + pub[2:],
+		"compressed":  "02" + pub[2:],
+		"off curve":   hex.EncodeToString(offCurve),
+		"zero point":  "04" + strings.Repeat("00", 64),
+		"odd length":  pub[:len(pub)-1],
+	}
+	for name, from := range cases {
+		tx := tx
+		tx.From = from
+		if VerifyTransaction(tx) {
+			t.Errorf("%s: malformed From verified", name)
+		}
+		if IsValidAddress(from) {
+			t.Errorf("%s: malformed address accepted", name)
+		}
+		if _, err := ParsePublicKey(from); !errors.Is(err, ErrMalformedPubKey) {
+			t.Errorf("%s: ParsePublicKey err = %v, want %v", name, err, ErrMalformedPubKey)
+		}
+	}
+}
+
 // TestCalculateHash 测试区块哈希计算
 func TestCalculateHash(t *testing.T) {
 	block := Block{
@@ -73,7 +163,7 @@ func TestCalculateHash(t *testing.T) {
 		Transactions: []Transaction{
 			{
 				From:      "sender",
-				To:        "receiver",
+				To:        receiver,
 				Amount:    100,
 				Signature: "signature",
 			},
@@ -109,7 +199,7 @@ func TestMineBlock(t *testing.T) {
 	transactions := []Transaction{
 		{
 			From:      "sender",
-			To:        "receiver",
+			To:        receiver,
 			Amount:    100,
 			Signature: "signature",
 		},
@@ -179,15 +269,8 @@ func TestAddValidBlock(t *testing.T) {
 	blocks = bc.GetBlocks()
 	genesis := blocks[0]
 	
-	// 创建交易
-	transactions := []Transaction{
-		{
-			From:      "sender",
-			To:        "receiver",
-			Amount:    100,
-			Signature: "signature",
-		},
-	}
+	// 创建交易（区块中的交易签名必须有效）
+	transactions := []Transaction{signedTx(t, 100, 0)}
 	
 	// 使用挖矿创建新区块
 	newBlock := MineBlock(transactions, genesis)
@@ -217,7 +300,7 @@ func TestAddInvalidBlock(t *testing.T) {
 		Transactions: []Transaction{
 			{
 				From:      "sender",
-				To:        "receiver",
+				To:        receiver,
 				Amount:    100,
 				Signature: "signature",
 			},
@@ -251,7 +334,7 @@ func TestAddTransaction(t *testing.T) {
 	// 创建并签名交易
 	tx := Transaction{
 		From:   pub,
-		To:     "receiver",
+		To:     receiver,
 		Amount: 100,
 	}
 	
@@ -286,7 +369,7 @@ func TestAddInvalidTransaction(t *testing.T) {
 	// 创建无效交易（无有效签名）
 	invalidTx := Transaction{
 		From:      "sender",
-		To:        "receiver",
+		To:        receiver,
 		Amount:    100,
 		Signature: "invalid_signature",
 	}
@@ -305,6 +388,50 @@ func TestAddInvalidTransaction(t *testing.T) {
 	}
 }
 
+// TestIsValidAddress 测试地址格式检查
+func TestIsValidAddress(t *testing.T) {
+	if !IsValidAddress(receiver) {
+		t.Fatalf("generated address %s rejected", receiver)
+	}
+
+	offCurve, _ := hex.DecodeString(receiver)
+	offCurve[len(offCurve)-1] ^= 1 // 修改Y坐标，点不再在曲线上
+	malformed := map[string]string{
+		"empty":     "",
+		"name":      "receiver",
+		"too short": receiver[:AddressHexLen-2],
+		"too long":  receiver + "00",
+		"not hex":   "zz" + receiver[2:],
+		"prefix":    "02" + receiver[2:],
+		"off curve": hex.EncodeToString(offCurve),
+	}
+	for name, addr := range malformed {
+		if IsValidAddress(addr) {
+			t.Errorf("%s: malformed address %q accepted", name, addr)
+		}
+	}
+}
+
+// TestAddTransactionMalformedRecipient 测试转给格式错误地址的交易即使签名有效也被拒绝
+func TestAddTransactionMalformedRecipient(t *testing.T) {
+	bc := NewBlockchain()
+	priv, pub := NewKeyPair()
+	for _, to := range []string{"receiver", receiver[:AddressHexLen-2], "zz" + receiver[2:], "02" + receiver[2:]} {
+		tx := Transaction{From: pub, To: to, Amount: 10}
+		sig, err := SignTransaction(priv, tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.Signature = sig
+		if bc.AddTransaction(tx) {
+			t.Errorf("transaction to %q accepted", to)
+		}
+	}
+	if n := len(bc.GetTransactions()); n != 0 {
+		t.Fatalf("expected empty pool, got %d transactions", n)
+	}
+}
+
 // TestClearTransactions 测试清除交易
 func TestClearTransactions(t *testing.T) {
 	bc := NewBlockchain()
@@ -317,7 +444,7 @@ func TestClearTransactions(t *testing.T) {
 	for i := 0; i < 3; i++ {
 		tx := Transaction{
 			From:   pub,
-			To:     "receiver",
+			To:     receiver,
 			Amount: 100 + i,
 		}
 		
@@ -350,4 +477,342 @@ func TestClearTransactions(t *testing.T) {
 	if txs[0].Amount != 102 {
 		t.Errorf("Expected amount 102 in remaining transaction, got %d", txs[0].Amount)
 	}
-}
\ No newline at end of file
+}
+// TestHashMeetsTarget 测试数值目标与字符串前缀在边界处的一致性
+func TestHashMeetsTarget(t *testing.T) {
+	cases := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		// 恰好低于目标值：满足
+		{"just below target", "000" + strings.Repeat("f", 61), true},
+		// 恰好等于目标值：不满足（必须严格小于）
+		{"equal to target", "001" + strings.Repeat("0", 61), false},
+		// 通过字符串前缀检查但不是合法十六进制：不满足
+		{"prefix but not hex", "000" + strings.Repeat("z", 61), false},
+		// 通过字符串前缀检查但长度不对：不满足
+		{"prefix but short", "000abc", false},
+		// 大写十六进制与小写数值相同
+		{"uppercase hex", "000" + strings.Repeat("F", 61), true},
+	}
+
+	for _, c := range cases {
+		if got := HashMeetsTarget(c.hash, Difficulty); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+
+	// 难度小于1或超过64时任何哈希都不满足，而不是全部放行
+	for _, d := range []int{0, -1, 65} {
+		if HashMeetsTarget(strings.Repeat("0", 64), d) {
+			t.Errorf("difficulty %d: all-zero hash accepted", d)
+		}
+	}
+}
+
+// TestAddBlockRejectsPrefixOnlyHash 测试仅满足字符串前缀的区块被AddBlock拒绝
+func TestAddBlockRejectsPrefixOnlyHash(t *testing.T) {
+	bc := NewBlockchain()
+	genesis := bc.GetBlocks()[0]
+
+	b := MineBlock([]Transaction{}, genesis)
+	// 篡改为一个以"000"开头但不是合法哈希的值
+	b.Hash = "000" + strings.Repeat("z", 61)
+	if bc.AddBlock(b) {
+		t.Error("Block with non-numeric hash should be rejected")
+	}
+
+	// 正常挖出的区块在数值目标下同样有效
+	valid := MineBlock([]Transaction{}, genesis)
+	if !HashMeetsTarget(valid.Hash, Difficulty) {
+		t.Error("Mined block should meet the numeric target")
+	}
+	if !bc.AddBlock(valid) {
+		t.Error("Mined block should be accepted")
+	}
+}
+
+// signedTx 创建并签名一笔交易
+func signedTx(t *testing.T, amount, expiry int) Transaction {
+	t.Helper()
+	priv, pub := NewKeyPair()
+	tx := Transaction{From: pub, To: receiver, Amount: amount, ExpiryHeight: expiry}
+	sig, err := SignTransaction(priv, tx)
+	if err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+	tx.Signature = sig
+	return tx
+}
+
+// TestExpiryHeightSigned 测试过期高度参与签名哈希
+func TestExpiryHeightSigned(t *testing.T) {
+	tx := signedTx(t, 10, 5)
+	tx.ExpiryHeight = 50
+	if VerifyTransaction(tx) {
+		t.Error("Changing expiry height should invalidate the signature")
+	}
+}
+
+// TestTransactionMinedBeforeExpiry 测试过期前被打包的交易被接受
+func TestTransactionMinedBeforeExpiry(t *testing.T) {
+	bc := NewBlockchain()
+	tx := signedTx(t, 10, 1)
+	if !bc.AddTransaction(tx) {
+		t.Fatal("Transaction before expiry should be accepted into the pool")
+	}
+
+	b := MineBlock(bc.GetTransactions(), bc.GetBlocks()[0])
+	if !bc.AddBlock(b) {
+		t.Fatal("Block at the expiry height should be accepted")
+	}
+}
+
+// TestExpiredTransactionRejected 测试过期交易被拒绝并从交易池中移除
+func TestExpiredTransactionRejected(t *testing.T) {
+	bc := NewBlockchain()
+	tx := signedTx(t, 10, 1)
+	if !bc.AddTransaction(tx) {
+		t.Fatal("Transaction before expiry should be accepted into the pool")
+	}
+
+	// 高度1的区块不包含该交易，之后它已无法被打包
+	genesis := bc.GetBlocks()[0]
+	b1 := MineBlock([]Transaction{}, genesis)
+	if !bc.AddBlock(b1) {
+		t.Fatal("Failed to add empty block")
+	}
+	if len(bc.GetTransactions()) != 0 {
+		t.Error("Expired transaction should be evicted from the pool")
+	}
+
+	// 包含过期交易的区块被拒绝
+	b2 := MineBlock([]Transaction{tx}, b1)
+	if bc.AddBlock(b2) {
+		t.Error("Block containing an expired transaction should be rejected")
+	}
+
+	// 已过期的交易不能再进入交易池
+	if bc.AddTransaction(tx) {
+		t.Error("Expired transaction should not be accepted into the pool")
+	}
+}
+
+// TestChainIDReplayProtection 测试为链A签名的交易在链B上被拒绝
+func TestChainIDReplayProtection(t *testing.T) {
+	chainA := NewBlockchainWithChainID("chain-a")
+	chainB := NewBlockchainWithChainID("chain-b")
+
+	priv, pub := NewKeyPair()
+	tx := Transaction{From: pub, To: receiver, Amount: 10}
+	sig, err := SignTransactionForChain(priv, tx, chainA.ChainID())
+	if err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+	tx.Signature = sig
+
+	if !chainA.AddTransaction(tx) {
+		t.Error("Transaction signed for chain A should be accepted on chain A")
+	}
+	if chainB.AddTransaction(tx) {
+		t.Error("Transaction signed for chain A should be rejected on chain B")
+	}
+	if VerifyTransactionForChain(tx, "chain-b") {
+		t.Error("Signature should not verify for a different chain ID")
+	}
+}
+
+// TestAddBlockRejectsInvalidSignature 测试区块中只要有一笔交易签名无效，整个区块就被拒绝
+func TestAddBlockRejectsInvalidSignature(t *testing.T) {
+	bc := NewBlockchain()
+	genesis := bc.GetBlocks()[0]
+
+	txs := make([]Transaction, 32)
+	for i := range txs {
+		txs[i] = signedTx(t, i+1, 0)
+	}
+	// 篡改其中一笔交易的金额，使其签名失效
+	txs[17].Amount = 9999
+
+	if bc.AddBlock(MineBlock(txs, genesis)) {
+		t.Fatal("Block containing an invalid signature should be rejected")
+	}
+	if VerifyTransactionsForChain(txs, DefaultChainID) {
+		t.Error("Batch verification should fail when any signature is invalid")
+	}
+
+	txs[17] = signedTx(t, 18, 0)
+	if !bc.AddBlock(MineBlock(txs, genesis)) {
+		t.Fatal("Block with all signatures valid should be accepted")
+	}
+}
+
+// TestCalculateHashEmptyTransactions 测试空交易列表的规范编码：nil与空切片哈希相同，且经JSON往返后不变
+func TestCalculateHashEmptyTransactions(t *testing.T) {
+	b := Block{Index: 1, Timestamp: 1700000001, PrevHash: "prev", Nonce: 7}
+	withNil := CalculateHash(b)
+	b.Transactions = []Transaction{}
+	if got := CalculateHash(b); got != withNil {
+		t.Fatalf("empty slice hash %s != nil hash %s", got, withNil)
+	}
+
+	for _, raw := range []string{`null`, `[]`} {
+		var decoded Block
+		data := `{"index":1,"timestamp":1700000001,"prev_hash":"prev","nonce":7,"transactions":` + raw + `}`
+		if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if got := CalculateHash(decoded); got != withNil {
+			t.Errorf("transactions=%s: hash %s, want %s", raw, got, withNil)
+		}
+	}
+
+	// 创世区块（空交易列表）的哈希与其规范编码一致
+	g := NewBlockchain().GetBlocks()[0]
+	if g.Hash != CalculateHash(g) {
+		t.Error("genesis hash does not match the canonical encoding")
+	}
+}
+
+// TestConfirmedTransactionResubmitted 测试已被打包的交易重新提交时被拒绝
+func TestConfirmedTransactionResubmitted(t *testing.T) {
+	bc := NewBlockchain()
+	tx := signedTx(t, 10, 0)
+	if !bc.AddTransaction(tx) {
+		t.Fatal("Transaction should be accepted into the pool")
+	}
+	b := MineBlock(bc.GetTransactions(), bc.Tip())
+	if !bc.AddBlock(b) {
+		t.Fatal("Block should be accepted")
+	}
+
+	if height, ok := bc.TxConfirmed(tx); !ok || height != 1 {
+		t.Fatalf("TxConfirmed = %d, %v; want confirmed at height 1", height, ok)
+	}
+	if bc.AddTransaction(tx) {
+		t.Fatal("Already confirmed transaction should be rejected")
+	}
+	if len(bc.Mempool()) != 0 {
+		t.Fatal("Confirmed transaction re-entered the pool")
+	}
+
+	// 替换链后按新链重建已确认索引
+	other := NewBlockchain()
+	b1 := MineBlock(nil, other.Tip())
+	if !other.AddBlock(b1) || !other.AddBlock(MineBlock(nil, b1)) {
+		t.Fatal("Failed to build the replacement chain")
+	}
+	if err := bc.ReplaceChain(other.GetBlocks()); err != nil {
+		t.Fatalf("ReplaceChain failed: %v", err)
+	}
+	if _, ok := bc.TxConfirmed(tx); ok {
+		t.Fatal("Transaction from the replaced chain still marked confirmed")
+	}
+	if !bc.AddTransaction(tx) {
+		t.Fatal("Transaction no longer on the chain should be accepted again")
+	}
+}
+
+// TestGenesisDeterministic 测试独立创建的区块链得到相同的创世区块
+func TestGenesisDeterministic(t *testing.T) {
+	a, b := NewBlockchain().GetBlocks()[0], NewBlockchain().GetBlocks()[0]
+	if a.Timestamp != GenesisTimestamp {
+		t.Fatalf("genesis timestamp %d, want %d", a.Timestamp, GenesisTimestamp)
+	}
+	if a.Hash != b.Hash {
+		t.Fatalf("genesis hashes differ: %s vs %s", a.Hash, b.Hash)
+	}
+}
+
+func TestReplaceChainWithLongerValidChain(t *testing.T) {
+	// 两个独立创建的区块链有相同的创世区块，才能接受彼此的链
+	bc := NewBlockchain()
+	if !bc.AddBlock(MineBlock(nil, bc.Tip())) {
+		t.Fatal("Failed to build the local chain")
+	}
+
+	other := NewBlockchain()
+	for i := 0; i < 3; i++ {
+		if !other.AddBlock(MineBlock(nil, other.Tip())) {
+			t.Fatal("Failed to build the replacement chain")
+		}
+	}
+	longer := other.GetBlocks()
+
+	// 无效的候选链被拒绝，本地链保持2个区块
+	brokenLink := append([]Block(nil), longer...)
+	brokenLink[2] = MineBlock(nil, Block{Index: 1, Hash: "not-the-parent"})
+	badPoW := append([]Block(nil), longer...)
+	badPoW[3].Nonce++
+	badPoW[3].Hash = CalculateHash(badPoW[3])
+	for HashMeetsTarget(badPoW[3].Hash, Difficulty) {
+		badPoW[3].Nonce++
+		badPoW[3].Hash = CalculateHash(badPoW[3])
+	}
+	foreign := append([]Block(nil), longer...)
+	foreign[0].Timestamp--
+	foreign[0].Hash = CalculateHash(foreign[0])
+	for name, chain := range map[string][]Block{"broken link": brokenLink, "bad PoW": badPoW, "foreign genesis": foreign} {
+		if err := bc.ReplaceChain(chain); err == nil {
+			t.Errorf("ReplaceChain accepted a chain with a %s", name)
+		}
+	}
+	if err := bc.ReplaceChain(longer[:2]); !errors.Is(err, ErrChainNotLonger) {
+		t.Errorf("expected %v for an equally long chain, got %v", ErrChainNotLonger, err)
+	}
+	if bc.Height() != 1 {
+		t.Fatalf("rejected chains changed the height to %d", bc.Height())
+	}
+
+	if err := bc.ReplaceChain(longer); err != nil {
+		t.Fatalf("ReplaceChain rejected a valid longer chain: %v", err)
+	}
+	if bc.Height() != 3 || bc.Tip().Hash != longer[3].Hash {
+		t.Fatalf("after ReplaceChain: height %d, tip %s; want height 3 at %s", bc.Height(), bc.Tip().Hash, longer[3].Hash)
+	}
+}
+
+func TestTxIDDistinguishesSameAmountTransfers(t *testing.T) {
+	bc := NewBlockchain()
+	priv, from := NewKeyPair()
+	tx1 := Transaction{From: from, To: receiver, Amount: 7}
+	tx2 := tx1
+	var err error
+	if tx1.Signature, err = SignTransaction(priv, tx1); err != nil {
+		t.Fatal(err)
+	}
+	if tx2.Signature, err = SignTransaction(priv, tx2); err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(HashTransaction(tx1)) != hex.EncodeToString(HashTransaction(tx2)) {
+		t.Fatal("same transfer should have the same signing hash")
+	}
+	if TxID(tx1) == TxID(tx2) {
+		t.Fatal("two signed same-amount transfers share a TxID")
+	}
+
+	if !bc.AddTransaction(tx1) || !bc.AddTransaction(tx2) {
+		t.Fatal("both transfers should be accepted into the pool")
+	}
+	if bc.AddTransaction(tx1) {
+		t.Fatal("resubmitted transfer accepted twice")
+	}
+	if n := len(bc.Mempool()); n != 2 {
+		t.Fatalf("pool has %d transactions, want 2", n)
+	}
+	if got, height, ok := bc.FindTransaction(TxID(tx2)); !ok || height != -1 || got.Signature != tx2.Signature {
+		t.Fatalf("FindTransaction(tx2) = %+v, %d, %v", got, height, ok)
+	}
+
+	// 打包其中一笔后，另一笔仍留在交易池中
+	if !bc.AddBlock(MineBlock([]Transaction{tx1}, bc.Tip())) {
+		t.Fatal("block with tx1 rejected")
+	}
+	if pool := bc.Mempool(); len(pool) != 1 || TxID(pool[0]) != TxID(tx2) {
+		t.Fatalf("pool after mining tx1 = %v, want only tx2", pool)
+	}
+	if _, height, ok := bc.FindTransaction(TxID(tx1)); !ok || height != 1 {
+		t.Fatalf("FindTransaction(tx1) height %d, %v; want confirmed at 1", height, ok)
+	}
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Chain 各节点实现共享的区块链操作接口，使不同实现可以用同一套测试验证
+// B为区块类型，T为交易池中的交易类型：
+// core.Blockchain实现Chain[Block, Transaction]，internal/blockchain.Blockchain实现Chain[blockchain.Block, blockchain.UTXOTx]
+type Chain[B any, T any] interface {
+	// AddBlock 校验并追加扩展当前链顶的区块，区块中的交易随之离开交易池
+	AddBlock(b B) bool
+	// Tip 返回当前链顶区块
+	Tip() B
+	// Height 返回链顶区块的高度，只有创世区块时为0
+	Height() int
+	// ReplaceChain 用更长的有效链替换本地链，候选链必须与本地链有相同的创世区块
+	ReplaceChain(chain []B) error
+	// AddTransaction 校验交易并加入交易池，无效或已在池中时返回false
+	AddTransaction(tx T) bool
+	// Mempool 返回交易池中交易的副本，按加入顺序排列
+	Mempool() []T
+}
+
+var _ Chain[Block, Transaction] = (*Blockchain)(nil)
+
+var (
+	// ErrChainNotLonger 候选链不比本地链长
+	ErrChainNotLonger = errors.New("candidate chain is not longer than the local chain")
+	// ErrGenesisMismatch 候选链的创世区块与本地链不同
+	ErrGenesisMismatch = errors.New("candidate chain has a different genesis block")
+)
+
+// ChainLink 候选链中一个区块参与链接校验的字段
+type ChainLink struct {
+	Index    int    // 区块索引
+	PrevHash string // 前一个区块的哈希值
+	Hash     string // 区块声明的哈希值
+	Computed string // 按区块内容重新计算的哈希值
+}
+
+// ValidateLinks 校验候选链的链接结构，规则与ReplaceChain一致：
+// 第一个区块是哈希为genesisHash的创世区块，之后每个区块的索引连续、PrevHash等于前一区块的Hash、
+// Hash与重新计算的哈希一致且满足difficulty对应的PoW目标；不检查区块中的交易
+// 供区块结构与Block不同的独立节点（p2p、libp2p）复用，link把各自的区块转换为ChainLink
+func ValidateLinks[B any](genesisHash string, chain []B, difficulty int, link func(B) ChainLink) error {
+	if len(chain) == 0 {
+		return ErrGenesisMismatch
+	}
+	prev := link(chain[0])
+	if prev.Hash != genesisHash || prev.Computed != genesisHash {
+		return ErrGenesisMismatch
+	}
+	for i := 1; i < len(chain); i++ {
+		b := link(chain[i])
+		switch {
+		case b.Index != i:
+			return fmt.Errorf("block %d has index %d", i, b.Index)
+		case b.PrevHash != prev.Hash:
+			return fmt.Errorf("block %d does not link to block %d", i, i-1)
+		case b.Computed != b.Hash:
+			return fmt.Errorf("block %d has an invalid hash", i)
+		case !HashMeetsTarget(b.Hash, difficulty):
+			return fmt.Errorf("block %d does not meet the PoW target", i)
+		}
+		prev = b
+	}
+	return nil
+}
+
+// BlockLink 返回core区块参与链接校验的字段，配合ValidateLinks校验Block组成的链
+func BlockLink(b Block) ChainLink {
+	return ChainLink{Index: b.Index, PrevHash: b.PrevHash, Hash: b.Hash, Computed: CalculateHash(b)}
+}
+
+// Tip 返回当前链顶区块
+func (bc *Blockchain) Tip() Block {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	return bc.chain[len(bc.chain)-1]
+}
+
+// Height 返回链顶区块的高度
+func (bc *Blockchain) Height() int {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	return len(bc.chain) - 1
+}
+
+// Mempool 返回交易池副本
+func (bc *Blockchain) Mempool() []Transaction {
+	return bc.GetTransactions()
+}
+
+// ReplaceChain 用更长的有效链替换本地链
+// 候选链从本地创世区块开始逐块校验索引、链接、哈希、PoW和交易，UTXO模式下从空集合重放UTXO交易
+// 替换后新链上已打包的交易离开交易池
+func (bc *Blockchain) ReplaceChain(newChain []Block) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	if len(newChain) <= len(bc.chain) {
+		return ErrChainNotLonger
+	}
+	if newChain[0].Hash != bc.chain[0].Hash {
+		return ErrGenesisMismatch
+	}
+	var utxos map[UTXOKey]UTXOEntry
+	if bc.utxoMode {
+		utxos = make(map[UTXOKey]UTXOEntry)
+	}
+	for i := 1; i < len(newChain); i++ {
+		if newChain[i].Index != i {
+			return fmt.Errorf("block %d has index %d", i, newChain[i].Index)
+		}
+		next, err := bc.checkBlockLocked(newChain[i-1], newChain[i], utxos)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", i, err)
+		}
+		if next != nil {
+			utxos = next
+		}
+	}
+
+	bc.chain = append([]Block(nil), newChain...)
+	bc.confirmed = make(map[string]int)
+	for _, b := range newChain[1:] {
+		bc.indexTxsLocked(b)
+		bc.removeTxsLocked(b.Transactions)
+	}
+	bc.pruneExpiredLocked(len(bc.chain))
+	if bc.utxoMode {
+		bc.utxos = utxos
+		bc.pruneUTXOPoolLocked()
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package core_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"mini_chain/gossip/core"
+	"mini_chain/gossip/core/chaintest"
+)
+
+func TestChainConformance(t *testing.T) {
+	var chainID string
+	chaintest.Run(t, chaintest.Harness[core.Block, core.Transaction]{
+		New: func(t *testing.T) core.Chain[core.Block, core.Transaction] {
+			bc := core.NewBlockchain()
+			chainID = bc.ChainID()
+			return bc
+		},
+		Mine: func(t *testing.T, prev core.Block, txs []core.Transaction) core.Block {
+			return core.MineBlock(txs, prev)
+		},
+		NewTx: func(t *testing.T) core.Transaction {
+			priv, from := core.NewKeyPair()
+			_, to := core.NewKeyPair()
+			tx := core.Transaction{From: from, To: to, Amount: 5}
+			sig, err := core.SignTransactionForChain(priv, tx, chainID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx.Signature = sig
+			return tx
+		},
+		Blocks: func(c core.Chain[core.Block, core.Transaction]) []core.Block {
+			return c.(*core.Blockchain).GetBlocks()
+		},
+		Hash:   func(b core.Block) string { return b.Hash },
+		Tamper: func(b core.Block) core.Block { b.Nonce++; return b },
+	})
+}
+
+func TestValidateLinks(t *testing.T) {
+	bc := core.NewBlockchain()
+	genesis := bc.GetBlocks()[0]
+	chain := []core.Block{genesis, core.MineBlock(nil, genesis)}
+	chain = append(chain, core.MineBlock(nil, chain[1]))
+	link := func(b core.Block) core.ChainLink {
+		return core.ChainLink{Index: b.Index, PrevHash: b.PrevHash, Hash: b.Hash, Computed: core.CalculateHash(b)}
+	}
+	if err := core.ValidateLinks(genesis.Hash, chain, core.Difficulty, link); err != nil {
+		t.Fatalf("valid chain rejected: %v", err)
+	}
+	if err := core.ValidateLinks("other", chain, core.Difficulty, link); !errors.Is(err, core.ErrGenesisMismatch) {
+		t.Fatalf("expected %v, got %v", core.ErrGenesisMismatch, err)
+	}
+
+	for _, c := range []struct {
+		want   string // 错误信息应包含的内容
+		tamper func(c []core.Block)
+	}{
+		{"has index", func(c []core.Block) { c[2].Index = 5 }},
+		{"does not link", func(c []core.Block) { c[2].PrevHash = genesis.Hash }},
+		{"invalid hash", func(c []core.Block) { c[2].Nonce++ }},
+		{"PoW target", func(c []core.Block) {
+			for c[2].Nonce = 0; core.HashMeetsTarget(core.CalculateHash(c[2]), core.Difficulty); c[2].Nonce++ {
+			}
+			c[2].Hash = core.CalculateHash(c[2])
+		}},
+	} {
+		bad := append([]core.Block(nil), chain...)
+		c.tamper(bad)
+		if err := core.ValidateLinks(genesis.Hash, bad, core.Difficulty, link); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("expected error containing %q, got %v", c.want, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package chaintest 提供core.Chain接口的一致性测试套件
+// 各区块链实现提供一个Harness，然后在自己的测试中调用Run，确保同一脚本化操作序列下行为一致
+package chaintest
+
+import (
+	"testing"
+
+	"mini_chain/gossip/core"
+)
+
+// Harness 描述被测实现：如何创建链、构造有效区块和有效交易
+type Harness[B any, T any] struct {
+	// New 创建只含创世区块、交易池为空的新链
+	New func(t *testing.T) core.Chain[B, T]
+	// Mine 构造一个接在prev之后、包含txs的有效区块（不修改链）
+	Mine func(t *testing.T, prev B, txs []T) B
+	// NewTx 构造一笔可以被交易池接受的新交易
+	NewTx func(t *testing.T) T
+	// Blocks 返回链上从创世区块开始的全部区块
+	Blocks func(c core.Chain[B, T]) []B
+	// Hash 返回区块哈希
+	Hash func(b B) string
+	// Tamper 返回修改了内容但未重新计算哈希的区块副本
+	Tamper func(b B) B
+}
+
+// Run 对Harness描述的实现执行一致性测试
+func Run[B any, T any](t *testing.T, h Harness[B, T]) {
+	c := h.New(t)
+
+	// 新链：高度0，链顶为创世区块，交易池为空
+	genesis := h.Blocks(c)[0]
+	if c.Height() != 0 || h.Hash(c.Tip()) != h.Hash(genesis) {
+		t.Fatalf("new chain: height %d, tip %s; want height 0 at genesis", c.Height(), h.Hash(c.Tip()))
+	}
+	if n := len(c.Mempool()); n != 0 {
+		t.Fatalf("new chain: mempool has %d txs, want 0", n)
+	}
+
+	// 交易进入交易池，重复提交被拒绝
+	tx := h.NewTx(t)
+	if !c.AddTransaction(tx) {
+		t.Fatal("AddTransaction rejected a valid tx")
+	}
+	if c.AddTransaction(tx) {
+		t.Error("AddTransaction accepted a duplicate tx")
+	}
+	if n := len(c.Mempool()); n != 1 {
+		t.Fatalf("mempool has %d txs, want 1", n)
+	}
+
+	// 打包交易池的区块扩展链顶，交易离开交易池
+	b1 := h.Mine(t, c.Tip(), c.Mempool())
+	if !c.AddBlock(b1) {
+		t.Fatal("AddBlock rejected a valid block")
+	}
+	if c.Height() != 1 || h.Hash(c.Tip()) != h.Hash(b1) {
+		t.Fatalf("after AddBlock: height %d, tip %s; want height 1 at %s", c.Height(), h.Hash(c.Tip()), h.Hash(b1))
+	}
+	if n := len(c.Mempool()); n != 0 {
+		t.Errorf("after AddBlock: mempool has %d txs, want 0", n)
+	}
+
+	// 重复的区块和不扩展链顶的区块被拒绝
+	if c.AddBlock(b1) {
+		t.Error("AddBlock accepted the same block twice")
+	}
+	if c.AddBlock(h.Mine(t, genesis, nil)) {
+		t.Error("AddBlock accepted a block that does not extend the tip")
+	}
+	if c.Height() != 1 {
+		t.Fatalf("rejected blocks changed height to %d", c.Height())
+	}
+
+	// 更长的有效链替换本地链
+	b2 := h.Mine(t, b1, nil)
+	b3 := h.Mine(t, b2, nil)
+	longer := append(h.Blocks(c), b2, b3)
+	if err := c.ReplaceChain(longer); err != nil {
+		t.Fatalf("ReplaceChain rejected a valid longer chain: %v", err)
+	}
+	if c.Height() != 3 || h.Hash(c.Tip()) != h.Hash(b3) {
+		t.Fatalf("after ReplaceChain: height %d, tip %s; want height 3 at %s", c.Height(), h.Hash(c.Tip()), h.Hash(b3))
+	}
+
+	// 不更长的链和中间区块被篡改的链被拒绝，本地链保持不变
+	if err := c.ReplaceChain(longer[:3]); err == nil {
+		t.Error("ReplaceChain accepted a shorter chain")
+	}
+	b4 := h.Mine(t, b3, nil)
+	tampered := append(h.Blocks(c), b4, h.Mine(t, b4, nil))
+	tampered[2] = h.Tamper(tampered[2])
+	if err := c.ReplaceChain(tampered); err == nil {
+		t.Error("ReplaceChain accepted a chain with a tampered block")
+	}
+	if c.Height() != 3 || h.Hash(c.Tip()) != h.Hash(b3) {
+		t.Errorf("rejected chains changed the tip to height %d at %s", c.Height(), h.Hash(c.Tip()))
+	}
+}
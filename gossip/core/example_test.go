@@ -14,10 +14,13 @@ func Example() {
 	priv, pub := NewKeyPair()
 	fmt.Printf("Generated wallet with public key: %s...\n", pub[:2])
 
+	// Generate the recipient's wallet
+	_, recipient := NewKeyPair()
+
 	// Create and sign a transaction
 	tx := Transaction{
 		From:   pub,
-		To:     "recipient_address",
+		To:     recipient,
 		Amount: 50,
 	}
 
@@ -16,7 +16,7 @@ func TestMining(t *testing.T) {
 	// 创建交易
 	tx := Transaction{
 		From:   pub,
-		To:     "receiver",
+		To:     receiver,
 		Amount: 100,
 	}
 	
@@ -89,7 +89,7 @@ func TestConcurrentMining(t *testing.T) {
 		go func(i int) {
 			tx := Transaction{
 				From:   pub,
-				To:     "receiver",
+				To:     receiver,
 				Amount: 100 + i,
 			}
 			
@@ -213,7 +213,7 @@ func BenchmarkMineBlock(b *testing.B) {
 	for i := range transactions {
 		transactions[i] = Transaction{
 			From:      "sender",
-			To:        "receiver",
+			To:        receiver,
 			Amount:    100 + i,
 			Signature: "signature",
 		}
@@ -223,4 +223,43 @@ func BenchmarkMineBlock(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		MineBlock(transactions, prevBlock)
 	}
-}
\ No newline at end of file
+}
+
+// signedBlockTxs 生成n笔签名有效的交易，用于签名验证基准测试
+func signedBlockTxs(b *testing.B, n int) []Transaction {
+	priv, pub := NewKeyPair()
+	txs := make([]Transaction, n)
+	for i := range txs {
+		txs[i] = Transaction{From: pub, To: receiver, Amount: i + 1}
+		sig, err := SignTransaction(priv, txs[i])
+		if err != nil {
+			b.Fatalf("Failed to sign transaction: %v", err)
+		}
+		txs[i].Signature = sig
+	}
+	return txs
+}
+
+// BenchmarkVerifyBlockSequential 基准测试逐笔验证区块中500笔交易的签名
+func BenchmarkVerifyBlockSequential(b *testing.B) {
+	txs := signedBlockTxs(b, 500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, tx := range txs {
+			if !VerifyTransaction(tx) {
+				b.Fatal("signature should be valid")
+			}
+		}
+	}
+}
+
+// BenchmarkVerifyBlockParallel 基准测试使用worker池并行验证区块中500笔交易的签名
+func BenchmarkVerifyBlockParallel(b *testing.B) {
+	txs := signedBlockTxs(b, 500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !VerifyTransactionsForChain(txs, DefaultChainID) {
+			b.Fatal("signatures should be valid")
+		}
+	}
+}
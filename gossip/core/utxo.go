@@ -0,0 +1,387 @@
+package core
+
+// UTXO交易模型
+// 与internal/blockchain的UTXOTx保持相同的结构、JSON编码和交易ID计算方式，
+// 使gossip节点可以选择以UTXO模式运行，并采用与internal/blockchain相同的校验规则：
+// 版本已知、至少有一个输入或输出、金额非负且不溢出、输入存在且未被花费、输出总额不超过输入总额。
+// 此外每个输入都必须由被花费输出的所有者（地址即十六进制公钥）为本链签名
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// UTXOTxVersion 当前UTXO交易版本
+const UTXOTxVersion = 1
+
+// CoinbaseReward 每个区块coinbase交易可以铸造的奖励（另加区块内交易的手续费）
+const CoinbaseReward = 50
+
+// TxInput 交易输入，通过txid:vout引用前一个UTXO，并携带签名和公钥
+type TxInput struct {
+	Txid      string `json:"txid"`      // 引用的前一个交易ID
+	Vout      int    `json:"vout"`      // 引用的前一个交易的输出索引
+	Signature string `json:"signature"` // 十六进制编码的签名
+	PubKey    string `json:"pubkey"`    // 十六进制编码的公钥（地址）
+}
+
+// TxOutput 交易输出
+type TxOutput struct {
+	Address string `json:"address"` // 接收地址
+	Amount  int    `json:"amount"`  // 金额
+}
+
+// UTXOTx UTXO模型中的交易
+type UTXOTx struct {
+	Version int        `json:"version"` // 交易版本，参与交易ID计算
+	Inputs  []TxInput  `json:"inputs"`  // 交易输入列表
+	Outputs []TxOutput `json:"outputs"` // 交易输出列表
+}
+
+// UTXOKey UTXO集合的键：交易ID和输出索引
+type UTXOKey struct {
+	Txid string
+	Vout int
+}
+
+// UTXOEntry 未花费输出
+type UTXOEntry struct {
+	Address string `json:"address"`
+	Amount  int    `json:"amount"`
+}
+
+// 交易校验错误
+var (
+	ErrUTXONotFound    = errors.New("input missing or already spent")
+	ErrUTXOBadSig      = errors.New("invalid input signature")
+	ErrUTXOOverspend   = errors.New("outputs exceed inputs")
+	ErrUTXODoubleSpend = errors.New("input spent twice")
+	ErrUTXOOverflow    = errors.New("amount overflow")
+)
+
+// UTXOTxID 返回确定性的交易ID：sha256(json(tx))
+func UTXOTxID(tx UTXOTx) (string, error) {
+	b, err := json.Marshal(tx)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NewCoinbaseUTXOTx 创建coinbase交易
+// data: 附加数据，应包含区块高度以保证不同区块的coinbase交易ID不同
+func NewCoinbaseUTXOTx(data, to string, amount int) UTXOTx {
+	return UTXOTx{
+		Version: UTXOTxVersion,
+		Inputs:  []TxInput{{Txid: "0", Vout: -1, Signature: data, PubKey: "coinbase"}},
+		Outputs: []TxOutput{{Address: to, Amount: amount}},
+	}
+}
+
+// IsCoinbaseUTXO 判断交易是否为coinbase交易
+func IsCoinbaseUTXO(tx UTXOTx) bool {
+	return len(tx.Inputs) == 1 && tx.Inputs[0].Txid == "0" && tx.Inputs[0].Vout == -1
+}
+
+// UTXOSigHash 计算交易在指定链上的签名哈希
+// 签名字段不参与哈希，链ID参与哈希以防止跨网络重放；与HashTransactionForChain一样以TxHashDomain开头
+func UTXOSigHash(tx UTXOTx, chainID string) []byte {
+	unsigned := tx
+	unsigned.Inputs = make([]TxInput, len(tx.Inputs))
+	for i, in := range tx.Inputs {
+		in.Signature = ""
+		unsigned.Inputs[i] = in
+	}
+	b, _ := json.Marshal(unsigned)
+	h := sha256.Sum256(append([]byte(TxHashDomain+"|"+chainID+"|"), b...))
+	return h[:]
+}
+
+// SignUTXOTx 用私钥为指定链签名交易的所有输入，返回签名后的交易
+func SignUTXOTx(priv *ecdsa.PrivateKey, tx UTXOTx, chainID string) (UTXOTx, error) {
+	pub := hex.EncodeToString(elliptic.Marshal(priv.PublicKey.Curve, priv.PublicKey.X, priv.PublicKey.Y))
+	signed := tx
+	signed.Inputs = make([]TxInput, len(tx.Inputs))
+	for i, in := range tx.Inputs {
+		in.PubKey = pub
+		signed.Inputs[i] = in
+	}
+	h := UTXOSigHash(signed, chainID)
+	for i := range signed.Inputs {
+		sig, err := ecdsa.SignASN1(rand.Reader, priv, h)
+		if err != nil {
+			return UTXOTx{}, err
+		}
+		signed.Inputs[i].Signature = hex.EncodeToString(sig)
+	}
+	return signed, nil
+}
+
+// verifyInputSig 验证输入签名
+func verifyInputSig(in TxInput, h []byte) bool {
+	pub, err := ParsePublicKey(in.PubKey)
+	if err != nil {
+		return false
+	}
+	sig, err := hex.DecodeString(in.Signature)
+	if err != nil {
+		return false
+	}
+	return ecdsa.VerifyASN1(pub, h, sig)
+}
+
+// checkedAdd 返回a+b，溢出时返回ErrUTXOOverflow
+func checkedAdd(a, b int) (int, error) {
+	if (b > 0 && a > math.MaxInt-b) || (b < 0 && a < math.MinInt-b) {
+		return 0, ErrUTXOOverflow
+	}
+	return a + b, nil
+}
+
+// ValidateUTXOTxStructure 结构检查：版本已知，至少有一个输入或输出，输出金额非负且总额不溢出
+func ValidateUTXOTxStructure(tx UTXOTx) error {
+	if tx.Version != UTXOTxVersion {
+		return fmt.Errorf("unknown tx version %d", tx.Version)
+	}
+	if len(tx.Inputs) == 0 && len(tx.Outputs) == 0 {
+		return errors.New("tx has no inputs and no outputs")
+	}
+	_, err := sumUTXOOutputs(tx)
+	return err
+}
+
+// sumUTXOOutputs 返回交易输出总额，金额为负或溢出时返回错误
+func sumUTXOOutputs(tx UTXOTx) (int, error) {
+	total := 0
+	for _, out := range tx.Outputs {
+		if out.Amount < 0 {
+			return 0, errors.New("negative amount")
+		}
+		var err error
+		if total, err = checkedAdd(total, out.Amount); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// ValidateUTXOTx 针对UTXO集合完整校验非coinbase交易，返回手续费（输入总额减输出总额）
+func ValidateUTXOTx(tx UTXOTx, set map[UTXOKey]UTXOEntry, chainID string) (int, error) {
+	if err := ValidateUTXOTxStructure(tx); err != nil {
+		return 0, err
+	}
+	if IsCoinbaseUTXO(tx) {
+		return 0, errors.New("unexpected coinbase tx")
+	}
+	if len(tx.Inputs) == 0 {
+		return 0, errors.New("tx has no inputs")
+	}
+	h := UTXOSigHash(tx, chainID)
+	seen := make(map[UTXOKey]bool, len(tx.Inputs))
+	in := 0
+	for _, input := range tx.Inputs {
+		k := UTXOKey{Txid: input.Txid, Vout: input.Vout}
+		if seen[k] {
+			return 0, ErrUTXODoubleSpend
+		}
+		seen[k] = true
+		e, ok := set[k]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s:%d", ErrUTXONotFound, input.Txid, input.Vout)
+		}
+		if e.Address != input.PubKey || !verifyInputSig(input, h) {
+			return 0, ErrUTXOBadSig
+		}
+		var err error
+		if in, err = checkedAdd(in, e.Amount); err != nil {
+			return 0, err
+		}
+	}
+	out, _ := sumUTXOOutputs(tx)
+	if out > in {
+		return 0, ErrUTXOOverspend
+	}
+	return in - out, nil
+}
+
+// applyUTXOTx 在集合中消费交易的输入并加入其输出
+// 交易ID已有未花费输出时返回错误（防止相同的交易覆盖已有输出）
+func applyUTXOTx(set map[UTXOKey]UTXOEntry, tx UTXOTx) error {
+	txid, err := UTXOTxID(tx)
+	if err != nil {
+		return err
+	}
+	if _, exists := set[UTXOKey{Txid: txid, Vout: 0}]; exists {
+		return fmt.Errorf("tx %s already has unspent outputs", txid)
+	}
+	if !IsCoinbaseUTXO(tx) {
+		for _, input := range tx.Inputs {
+			delete(set, UTXOKey{Txid: input.Txid, Vout: input.Vout})
+		}
+	}
+	for i, out := range tx.Outputs {
+		set[UTXOKey{Txid: txid, Vout: i}] = UTXOEntry{Address: out.Address, Amount: out.Amount}
+	}
+	return nil
+}
+
+// applyUTXOBlock 在集合副本上依次校验并应用区块的UTXO交易，成功时返回新的集合
+// coinbase交易只能位于第一个，且铸造金额不超过CoinbaseReward加区块内交易的手续费
+func applyUTXOBlock(set map[UTXOKey]UTXOEntry, txs []UTXOTx, chainID string) (map[UTXOKey]UTXOEntry, error) {
+	next := make(map[UTXOKey]UTXOEntry, len(set))
+	for k, v := range set {
+		next[k] = v
+	}
+	fees := 0
+	minted := -1
+	for i, tx := range txs {
+		if IsCoinbaseUTXO(tx) {
+			if i != 0 {
+				return nil, errors.New("coinbase must be the first tx")
+			}
+			if err := ValidateUTXOTxStructure(tx); err != nil {
+				return nil, err
+			}
+			minted, _ = sumUTXOOutputs(tx)
+		} else {
+			fee, err := ValidateUTXOTx(tx, next, chainID)
+			if err != nil {
+				return nil, fmt.Errorf("tx %d: %w", i, err)
+			}
+			if fees, err = checkedAdd(fees, fee); err != nil {
+				return nil, err
+			}
+		}
+		if err := applyUTXOTx(next, tx); err != nil {
+			return nil, fmt.Errorf("tx %d: %w", i, err)
+		}
+	}
+	if limit, err := checkedAdd(CoinbaseReward, fees); err != nil || minted > limit {
+		return nil, fmt.Errorf("coinbase mints %d, more than reward plus fees", minted)
+	}
+	return next, nil
+}
+
+// NewUTXOBlockchain 创建以UTXO模式运行的区块链实例
+// UTXO模式下区块可以携带UTXO交易，AddBlock按与internal/blockchain相同的规则校验并维护UTXO集合
+func NewUTXOBlockchain(chainID string) *Blockchain {
+	bc := NewBlockchainWithChainID(chainID)
+	bc.utxoMode = true
+	bc.utxos = make(map[UTXOKey]UTXOEntry)
+	return bc
+}
+
+// UTXOMode 返回区块链是否以UTXO模式运行
+func (bc *Blockchain) UTXOMode() bool {
+	return bc.utxoMode
+}
+
+// AddUTXOTransaction 校验UTXO交易并加入UTXO交易池
+// 交易必须针对当前UTXO集合有效，且不能与池中已有交易花费相同的输入
+func (bc *Blockchain) AddUTXOTransaction(tx UTXOTx) error {
+	if !bc.utxoMode {
+		return errors.New("blockchain is not in UTXO mode")
+	}
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	if _, err := ValidateUTXOTx(tx, bc.utxos, bc.chainID); err != nil {
+		return err
+	}
+	txid, _ := UTXOTxID(tx)
+	spent := make(map[UTXOKey]bool)
+	for _, p := range bc.utxoPool {
+		if id, _ := UTXOTxID(p); id == txid {
+			return errors.New("transaction already in pool")
+		}
+		for _, in := range p.Inputs {
+			spent[UTXOKey{Txid: in.Txid, Vout: in.Vout}] = true
+		}
+	}
+	for _, in := range tx.Inputs {
+		if spent[UTXOKey{Txid: in.Txid, Vout: in.Vout}] {
+			return ErrUTXODoubleSpend
+		}
+	}
+	bc.utxoPool = append(bc.utxoPool, tx)
+	return nil
+}
+
+// GetUTXOTransactions 获取UTXO交易池副本
+func (bc *Blockchain) GetUTXOTransactions() []UTXOTx {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	txs := make([]UTXOTx, len(bc.utxoPool))
+	copy(txs, bc.utxoPool)
+	return txs
+}
+
+// pruneUTXOPoolLocked 移除交易池中针对当前UTXO集合不再有效的交易（已打包或输入已被花费）
+func (bc *Blockchain) pruneUTXOPoolLocked() {
+	kept := bc.utxoPool[:0]
+	for _, tx := range bc.utxoPool {
+		if _, err := ValidateUTXOTx(tx, bc.utxos, bc.chainID); err == nil {
+			kept = append(kept, tx)
+		}
+	}
+	bc.utxoPool = kept
+}
+
+// Balance 返回地址拥有的未花费输出总额
+func (bc *Blockchain) Balance(address string) int {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	total := 0
+	for _, e := range bc.utxos {
+		if e.Address == address {
+			total += e.Amount
+		}
+	}
+	return total
+}
+
+// BuildUTXOTransfer 用from拥有的未花费输出构造并签名一笔转账，找零返回from
+// 已被交易池中交易花费的输出不会被选用
+func (bc *Blockchain) BuildUTXOTransfer(priv *ecdsa.PrivateKey, to string, amount int) (UTXOTx, error) {
+	if amount <= 0 {
+		return UTXOTx{}, errors.New("amount must be positive")
+	}
+	from := hex.EncodeToString(elliptic.Marshal(priv.PublicKey.Curve, priv.PublicKey.X, priv.PublicKey.Y))
+
+	bc.mutex.Lock()
+	pending := make(map[UTXOKey]bool)
+	for _, p := range bc.utxoPool {
+		for _, in := range p.Inputs {
+			pending[UTXOKey{Txid: in.Txid, Vout: in.Vout}] = true
+		}
+	}
+	tx := UTXOTx{Version: UTXOTxVersion}
+	total := 0
+	for k, e := range bc.utxos {
+		if total >= amount {
+			break
+		}
+		if e.Address != from || pending[k] {
+			continue
+		}
+		tx.Inputs = append(tx.Inputs, TxInput{Txid: k.Txid, Vout: k.Vout})
+		total += e.Amount
+	}
+	bc.mutex.Unlock()
+
+	if total < amount {
+		return UTXOTx{}, fmt.Errorf("insufficient funds: have %d, need %d", total, amount)
+	}
+	tx.Outputs = append(tx.Outputs, TxOutput{Address: to, Amount: amount})
+	if change := total - amount; change > 0 {
+		tx.Outputs = append(tx.Outputs, TxOutput{Address: from, Amount: change})
+	}
+	return SignUTXOTx(priv, tx, bc.chainID)
+}
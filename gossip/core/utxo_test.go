@@ -0,0 +1,113 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// mineUTXO 挖出包含coinbase和给定UTXO交易的区块并添加到链上
+func mineUTXO(t *testing.T, bc *Blockchain, miner string, txs ...UTXOTx) Block {
+	t.Helper()
+	blocks := bc.GetBlocks()
+	last := blocks[len(blocks)-1]
+	coinbase := NewCoinbaseUTXOTx(fmt.Sprintf("height %d", last.Index+1), miner, CoinbaseReward)
+	b := MineUTXOBlock(nil, append([]UTXOTx{coinbase}, txs...), last)
+	if !bc.AddBlock(b) {
+		t.Fatalf("block %d rejected", b.Index)
+	}
+	return b
+}
+
+// TestUTXOTransfer 测试通过core的UTXO路径完成一笔转账
+func TestUTXOTransfer(t *testing.T) {
+	bc := NewUTXOBlockchain(DefaultChainID)
+	alicePriv, alice := NewKeyPair()
+	_, bob := NewKeyPair()
+
+	mineUTXO(t, bc, alice)
+	if got := bc.Balance(alice); got != CoinbaseReward {
+		t.Fatalf("alice balance after coinbase = %d, want %d", got, CoinbaseReward)
+	}
+
+	tx, err := bc.BuildUTXOTransfer(alicePriv, bob, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddUTXOTransaction(tx); err != nil {
+		t.Fatalf("add transfer: %v", err)
+	}
+	// 花费同一输出的第二笔交易被交易池拒绝
+	if _, err := bc.BuildUTXOTransfer(alicePriv, bob, 10); err == nil {
+		t.Fatal("expected no spendable outputs while the transfer is pending")
+	}
+
+	mineUTXO(t, bc, alice, bc.GetUTXOTransactions()...)
+	if got := bc.Balance(bob); got != 30 {
+		t.Errorf("bob balance = %d, want 30", got)
+	}
+	if got := bc.Balance(alice); got != CoinbaseReward-30+CoinbaseReward {
+		t.Errorf("alice balance = %d, want %d", got, 2*CoinbaseReward-30)
+	}
+	if n := len(bc.GetUTXOTransactions()); n != 0 {
+		t.Errorf("mined transfer still in pool (%d txs)", n)
+	}
+
+	// 已打包的交易不能再次花费
+	if err := bc.AddUTXOTransaction(tx); !errors.Is(err, ErrUTXONotFound) {
+		t.Errorf("replayed transfer error = %v, want ErrUTXONotFound", err)
+	}
+}
+
+// TestUTXOInvalidBlocks 测试UTXO模式拒绝签名无效、超额花费和超额铸币的区块
+func TestUTXOInvalidBlocks(t *testing.T) {
+	bc := NewUTXOBlockchain(DefaultChainID)
+	alicePriv, alice := NewKeyPair()
+	malloryPriv, mallory := NewKeyPair()
+	b := mineUTXO(t, bc, alice)
+	coinbaseID, _ := UTXOTxID(b.UTXOTxs[0])
+
+	spend := UTXOTx{
+		Version: UTXOTxVersion,
+		Inputs:  []TxInput{{Txid: coinbaseID, Vout: 0}},
+		Outputs: []TxOutput{{Address: mallory, Amount: CoinbaseReward}},
+	}
+	stolen, _ := SignUTXOTx(malloryPriv, spend, DefaultChainID)
+	if err := bc.AddUTXOTransaction(stolen); !errors.Is(err, ErrUTXOBadSig) {
+		t.Errorf("spend by non-owner error = %v, want ErrUTXOBadSig", err)
+	}
+
+	spend.Outputs[0].Amount = CoinbaseReward + 1
+	over, _ := SignUTXOTx(alicePriv, spend, DefaultChainID)
+	if err := bc.AddUTXOTransaction(over); !errors.Is(err, ErrUTXOOverspend) {
+		t.Errorf("overspend error = %v, want ErrUTXOOverspend", err)
+	}
+
+	otherChain, _ := SignUTXOTx(alicePriv, UTXOTx{
+		Version: UTXOTxVersion,
+		Inputs:  []TxInput{{Txid: coinbaseID, Vout: 0}},
+		Outputs: []TxOutput{{Address: mallory, Amount: 1}},
+	}, "other-chain")
+	if err := bc.AddUTXOTransaction(otherChain); !errors.Is(err, ErrUTXOBadSig) {
+		t.Errorf("tx signed for another chain error = %v, want ErrUTXOBadSig", err)
+	}
+
+	last := bc.GetBlocks()[1]
+	for name, txs := range map[string][]UTXOTx{
+		"bad signature": {stolen},
+		"overspend":     {over},
+		"excess mint":   {NewCoinbaseUTXOTx("height 2", alice, CoinbaseReward+1)},
+		"late coinbase": {NewCoinbaseUTXOTx("height 2a", alice, 1), NewCoinbaseUTXOTx("height 2b", alice, 1)},
+	} {
+		if bc.AddBlock(MineUTXOBlock(nil, txs, last)) {
+			t.Errorf("%s: block accepted", name)
+		}
+	}
+
+	// 账户模式的链不接受UTXO交易
+	account := NewBlockchain()
+	genesis := account.GetBlocks()[0]
+	if account.AddBlock(MineUTXOBlock(nil, []UTXOTx{NewCoinbaseUTXOTx("h1", alice, 1)}, genesis)) {
+		t.Error("account-mode chain accepted a block with UTXO txs")
+	}
+}
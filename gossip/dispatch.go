@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// 订阅消息处理的默认参数，可通过GOSSIP_WORKERS和GOSSIP_BUFFER覆盖
+const (
+	DefaultGossipWorkers = 4   // 处理消息的worker数量
+	DefaultGossipBuffer  = 256 // 每个worker队列的容量
+)
+
+// inbound 从订阅收到的一条消息及其来源
+type inbound struct {
+	from peer.ID
+	msg  Message
+}
+
+// dispatcher 把订阅消息分发给固定数量的worker处理，订阅循环只负责读取和入队
+// 同一peer的消息总是进入同一个worker的队列，因此每个peer发来的区块按到达顺序处理；
+// 队列满时丢弃消息并计数，慢处理（如区块校验）不会让订阅积压
+type dispatcher struct {
+	queues  []chan inbound
+	handle  func(inbound)
+	dropped atomic.Uint64
+	wg      sync.WaitGroup
+}
+
+// newDispatcher 创建workers个worker、每个队列容量为buffer的分发器，参数小于1时使用默认值
+func newDispatcher(workers, buffer int, handle func(inbound)) *dispatcher {
+	if workers < 1 {
+		workers = DefaultGossipWorkers
+	}
+	if buffer < 1 {
+		buffer = DefaultGossipBuffer
+	}
+	d := &dispatcher{queues: make([]chan inbound, workers), handle: handle}
+	for i := range d.queues {
+		d.queues[i] = make(chan inbound, buffer)
+	}
+	return d
+}
+
+// start 启动worker
+func (d *dispatcher) start() {
+	for _, q := range d.queues {
+		d.wg.Add(1)
+		go func(q chan inbound) {
+			defer d.wg.Done()
+			for in := range q {
+				d.handle(in)
+			}
+		}(q)
+	}
+}
+
+// stop 关闭队列并等待worker处理完已入队的消息，之后不能再调用dispatch
+func (d *dispatcher) stop() {
+	for _, q := range d.queues {
+		close(q)
+	}
+	d.wg.Wait()
+}
+
+// dispatch 把消息放入来源peer对应的队列，不阻塞；队列已满时丢弃并返回false
+func (d *dispatcher) dispatch(in inbound) bool {
+	h := fnv.New32a()
+	h.Write([]byte(in.from))
+	select {
+	case d.queues[h.Sum32()%uint32(len(d.queues))] <- in:
+		return true
+	default:
+		d.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped 返回因队列已满被丢弃的消息数量
+func (d *dispatcher) Dropped() uint64 {
+	return d.dropped.Load()
+}
+
+// Queued 返回各队列中等待处理的消息总数和总容量
+func (d *dispatcher) Queued() (n, capacity int) {
+	for _, q := range d.queues {
+		n += len(q)
+		capacity += cap(q)
+	}
+	return n, capacity
+}
+
+// readLoop 从订阅读取消息并交给分发器，直到ctx取消
+// 忽略本节点发出的消息和无法解析的消息
+func readLoop(ctx context.Context, next func(context.Context) (*pubsub.Message, error), self peer.ID, d *dispatcher) {
+	for {
+		msg, err := next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+		if msg.ReceivedFrom == self {
+			continue
+		}
+		var m Message
+		if err := json.Unmarshal(msg.Data, &m); err != nil {
+			continue
+		}
+		if !d.dispatch(inbound{from: msg.ReceivedFrom, msg: m}) {
+			if n := d.Dropped(); n&(n-1) == 0 { // 丢弃数为2的幂时记录日志，避免刷屏
+				log.Printf("Gossip queue full, dropped %d messages so far", n)
+			}
+		}
+	}
+}
@@ -56,11 +56,12 @@ type Message struct {
 	Data json.RawMessage `json:"data"`
 }
 
+// Chain 节点同步和出块依赖的区块链操作接口，由core.Blockchain实现
+type Chain = core.Chain[core.Block, core.Transaction]
+
 var (
-	blockchain  *core.Blockchain   // 使用core包中的Blockchain类型
-	txPool      []core.Transaction // 使用core包中的Transaction类型
-	chainMutex  sync.Mutex
-	txPoolMutex sync.Mutex
+	blockchain *core.Blockchain // 使用core包中的Blockchain类型，交易池也由它管理
+	chainMutex sync.Mutex
 	// difficulty  = 3  // 不再需要，因为core包中已经定义
 
 	h           host.Host
@@ -72,6 +73,10 @@ var (
 	gsub  *pubsub.PubSub
 	topic *pubsub.Topic
 	sub   *pubsub.Subscription
+	// 订阅消息的worker池
+	gossipDispatcher *dispatcher
+	// 链同步请求复用的出站流
+	chainStreams *streamPool
 )
 
 // --- Wallet / TX utils ---
@@ -84,41 +89,41 @@ var (
 // 移除了core包中已实现的函数：InitGenesis, AddBlock, ReplaceChain
 
 // --- TX pool ---
+// 交易池由core.Blockchain持有：AddTransaction校验并入池，AddBlock/ReplaceChain移除已打包的交易
+
+// handleTx 校验交易并加入交易池，接受后转发给其他节点
 func handleTx(tx core.Transaction) { // 使用core.Transaction类型
-	if !core.VerifyTransaction(tx) {
+	if !core.VerifyTransactionForChain(tx, blockchain.ChainID()) {
 		log.Println("Invalid tx signature for tx from:", tx.From[:8], "to:", tx.To[:8], "amount:", tx.Amount)
 		return
 	}
-	txPoolMutex.Lock()
-	defer txPoolMutex.Unlock()
-	for _, t := range txPool {
-		if t.Signature == tx.Signature {
-			log.Println("Transaction already in pool")
-			return
-		}
+	if height, ok := blockchain.TxConfirmed(tx); ok {
+		log.Println("Rejected tx already confirmed in block", height)
+		return
+	}
+	if !blockchain.AddTransaction(tx) {
+		log.Println("Transaction already in pool or expired")
+		return
 	}
-	txPool = append(txPool, tx)
 	publish(Message{Type: "TX", Data: mustMarshal(tx)})
-	log.Println("Accepted tx into pool. Pool size:", len(txPool))
+	log.Println("Accepted tx into pool. Pool size:", len(blockchain.Mempool()))
 }
 
-func removeTxs(txs []core.Transaction) { // 使用core.Transaction类型
-	txPoolMutex.Lock()
-	defer txPoolMutex.Unlock()
-	newPool := []core.Transaction{}
-	for _, p := range txPool {
-		found := false
-		for _, t := range txs {
-			if p.Signature == t.Signature {
-				found = true
-				break
-			}
-		}
-		if !found {
-			newPool = append(newPool, p)
-		}
+// handleUTXOTx 校验UTXO交易并加入交易池，接受后转发给其他节点（仅UTXO模式）
+func handleUTXOTx(tx core.UTXOTx) {
+	if err := blockchain.AddUTXOTransaction(tx); err != nil {
+		log.Println("Rejected UTXO tx:", err)
+		return
+	}
+	publish(Message{Type: "UTXOTX", Data: mustMarshal(tx)})
+	log.Println("Accepted UTXO tx into pool. Pool size:", len(blockchain.GetUTXOTransactions()))
+}
+
+// handleBlock 处理收到的区块：无法接到链顶时向发送方请求完整链
+func handleBlock(b core.Block, from peer.ID) {
+	if !AddBlock(b) {
+		go requestChainFrom(from)
 	}
-	txPool = newPool
 }
 
 // --- gossipsub ---
@@ -137,41 +142,33 @@ func publish(msg Message) {
 	topic.Publish(ctxPub, b)
 }
 
+// subLoop 读取订阅消息并交给worker池处理，直到ctx取消
 func subLoop() {
-	for {
-		msg, err := sub.Next(ctx)
-		if err != nil {
-			if ctx.Err() != nil {
-				return
-			}
-			time.Sleep(time.Second)
-			continue
-		}
-		if msg.ReceivedFrom == h.ID() {
-			continue
+	defer gossipDispatcher.stop()
+	readLoop(ctx, sub.Next, h.ID(), gossipDispatcher)
+}
+
+// handleMessage 按类型处理一条订阅消息（由dispatcher的worker调用）
+func handleMessage(in inbound) {
+	m := in.msg
+	switch m.Type {
+	case "TX":
+		var tx core.Transaction
+		if err := json.Unmarshal(m.Data, &tx); err == nil {
+			log.Println("Received TX message via gossip from:", in.from)
+			handleTx(tx)
+		} else {
+			log.Println("Failed to unmarshal transaction:", err)
 		}
-		var m Message
-		if err := json.Unmarshal(msg.Data, &m); err != nil {
-			continue
+	case "UTXOTX":
+		var tx core.UTXOTx
+		if err := json.Unmarshal(m.Data, &tx); err == nil {
+			handleUTXOTx(tx)
 		}
-		switch m.Type {
-		case "TX":
-			var tx core.Transaction
-			if err := json.Unmarshal(m.Data, &tx); err == nil {
-				log.Println("Received TX message via gossip from:", msg.ReceivedFrom)
-				handleTx(tx)
-			} else {
-				log.Println("Failed to unmarshal transaction:", err)
-			}
-		case "BLOCK":
-			var b core.Block
-			if err := json.Unmarshal(m.Data, &b); err == nil {
-				if AddBlock(b) {
-					removeTxs(b.Transactions)
-				} else {
-					go requestChainFrom(msg.ReceivedFrom)
-				}
-			}
+	case "BLOCK":
+		var b core.Block
+		if err := json.Unmarshal(m.Data, &b); err == nil {
+			handleBlock(b, in.from)
 		}
 	}
 }
@@ -206,34 +203,100 @@ func setStreamHandler() {
 	})
 }
 
+// MaxChainRequests 同时向不同节点发出的链请求数量上限
+const MaxChainRequests = 3
+
+// ChainRequestTimeout 单个节点的链请求超时时间，超时的节点视为失败
+const ChainRequestTimeout = 10 * time.Second
+
+// chainFetcher 向单个节点请求完整链的函数类型
+type chainFetcher func(ctx context.Context, pid peer.ID) ([]core.Block, error)
+
+// requestChainFrom 从指定节点同步链；该节点失败或返回无效链时依次尝试其他已连接节点
 func requestChainFrom(pid peer.ID) {
-	s, err := h.NewStream(ctx, pid, ProtocolID)
-	if err != nil {
-		return
+	peers := []peer.ID{pid}
+	for _, p := range h.Network().Peers() {
+		if p != pid && p != h.ID() {
+			peers = append(peers, p)
+		}
 	}
-	defer s.Close()
-	msg := Message{Type: "GETCHAIN"}
-	data, _ := json.Marshal(msg)
-	data = append(data, '\n')
-	s.Write(data)
-	r := bufio.NewReader(s)
-	respRaw, err := r.ReadBytes('\n')
-	if err != nil {
-		return
+	if !syncChain(ctx, blockchain, peers, fetchChain, MaxChainRequests) {
+		log.Println("Chain sync failed: no peer returned a valid longer chain")
 	}
-	var resp Message
-	if err := json.Unmarshal(respRaw, &resp); err != nil {
-		return
+}
+
+// syncChain 并发（最多limit个）向候选节点请求链，用其中最长的有效链替换c
+// 只有比本地链更长的链才会被采用；返回是否采用了新链
+func syncChain(ctx context.Context, c Chain, peers []peer.ID, fetch chainFetcher, limit int) bool {
+	chain, from, ok := bestChainFrom(ctx, peers, fetch, limit)
+	if !ok || len(chain) <= c.Height()+1 {
+		return false
+	}
+	if err := c.ReplaceChain(chain); err != nil {
+		log.Println("Rejected chain from peer", from.String()+":", err)
+		return false
+	}
+	log.Println("Chain synchronized from peer:", from.String(), "height:", c.Height())
+	return true
+}
+
+// bestChainFrom 向候选节点请求链并返回最长的有效链，长度相同时优先靠前的节点
+// 请求失败、超时或返回无效链的节点会被记录并跳过
+func bestChainFrom(ctx context.Context, peers []peer.ID, fetch chainFetcher, limit int) ([]core.Block, peer.ID, bool) {
+	if limit < 1 {
+		limit = 1
+	}
+	chains := make([][]core.Block, len(peers))
+	genesisHash := core.GenesisBlock().Hash
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, pid := range peers {
+		wg.Add(1)
+		go func(i int, pid peer.ID) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			reqCtx, cancelReq := context.WithTimeout(ctx, ChainRequestTimeout)
+			defer cancelReq()
+			chain, err := fetch(reqCtx, pid)
+			if err == nil {
+				err = core.ValidateLinks(genesisHash, chain, core.Difficulty, core.BlockLink)
+			}
+			if err != nil {
+				log.Println("Chain request to", pid.String(), "failed:", err)
+				return
+			}
+			chains[i] = chain
+		}(i, pid)
+	}
+	wg.Wait()
+
+	best := -1
+	for i, c := range chains {
+		if c != nil && (best < 0 || len(c) > len(chains[best])) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil, "", false
+	}
+	return chains[best], peers[best], true
+}
+
+// fetchChain 通过流协议向单个节点发送GETCHAIN并读取返回的链，复用chainStreams中的流
+func fetchChain(ctx context.Context, pid peer.ID) ([]core.Block, error) {
+	resp, err := chainStreams.roundTrip(ctx, pid, Message{Type: "GETCHAIN"})
+	if err != nil {
+		return nil, err
 	}
 	if resp.Type != "CHAIN" {
-		return
+		return nil, fmt.Errorf("unexpected response type %q", resp.Type)
 	}
 	var newChain []core.Block
 	if err := json.Unmarshal(resp.Data, &newChain); err != nil {
-		return
+		return nil, err
 	}
-	ReplaceChain(newChain)
-	log.Println("Chain synchronized from peer:", pid.String())
+	return newChain, nil
 }
 
 // --- known peers ---
@@ -305,44 +368,59 @@ func setupDHTAndBootstrap(enable bool) (*kaddht.IpfsDHT, error) {
 }
 
 // --- miner ---
-func mineRoutine(priv *ecdsa.PrivateKey) {
+func mineRoutine(priv *ecdsa.PrivateKey, minerAddr string) {
+	if blockchain.UTXOMode() {
+		mineUTXORoutine(minerAddr)
+		return
+	}
 	for {
-		txPoolMutex.Lock()
-		if len(txPool) == 0 {
-			txPoolMutex.Unlock()
+		if _, ok := mineNext(); !ok {
 			time.Sleep(2 * time.Second)
-			continue
 		}
-		txs := make([]core.Transaction, len(txPool))
-		copy(txs, txPool)
-		txPoolMutex.Unlock()
+	}
+}
+
+// mineNext 打包交易池中的全部交易挖出一个区块并广播
+// 交易池为空或区块未能接到链顶（期间收到了其他区块）时返回false
+// 交易池在入池和出块时都会丢弃已过期的交易，因此池中交易总能打包进下一个区块
+func mineNext() (core.Block, bool) {
+	txs := blockchain.Mempool()
+	if len(txs) == 0 {
+		return core.Block{}, false
+	}
+	newB := core.MineBlock(txs, blockchain.Tip())
+	if !AddBlock(newB) {
+		return core.Block{}, false
+	}
+	publish(Message{Type: "BLOCK", Data: mustMarshal(newB)})
+	log.Println("Mined block:", newB.Index, newB.Hash[:10])
+	return newB, true
+}
 
-		// Get the last block from the blockchain
+// mineUTXORoutine UTXO模式的挖矿循环：每个区块包含给矿工的coinbase和交易池中的UTXO交易
+// 交易池为空时等待一段时间后仍然出块，使新节点可以通过coinbase获得余额
+func mineUTXORoutine(minerAddr string) {
+	for {
 		blocks := blockchain.GetBlocks()
 		last := blocks[len(blocks)-1]
-
-		newB := core.MineBlock(txs, last)
+		txs := blockchain.GetUTXOTransactions()
+		if len(txs) == 0 {
+			time.Sleep(2 * time.Second)
+		}
+		coinbase := core.NewCoinbaseUTXOTx(fmt.Sprintf("height %d", last.Index+1), minerAddr, core.CoinbaseReward)
+		newB := core.MineUTXOBlock(nil, append([]core.UTXOTx{coinbase}, txs...), last)
 		if AddBlock(newB) {
-			removeTxs(txs)
 			publish(Message{Type: "BLOCK", Data: mustMarshal(newB)})
-			log.Println("Mined block:", newB.Index, newB.Hash[:10])
+			log.Println("Mined UTXO block:", newB.Index, newB.Hash[:10])
 		}
 	}
 }
 
-// AddBlock 向区块链添加新区块
+// AddBlock 向区块链添加新区块，区块中的交易随之离开交易池
 func AddBlock(b core.Block) bool {
 	return blockchain.AddBlock(b)
 }
 
-// ReplaceChain 用更长的链替换当前链（共识机制的一部分）
-func ReplaceChain(newChain []core.Block) {
-	// For this simplified implementation, we're just logging
-	// A real implementation would validate and replace the chain in the core blockchain
-	log.Println("Received new chain with", len(newChain), "blocks")
-	// In a full implementation, we would update the core blockchain here
-}
-
 // --- CLI helpers ---
 func printChain() {
 	chainMutex.Lock()
@@ -366,15 +444,38 @@ func printPeers() {
 }
 
 // --- main ---
+// envInt 读取整数环境变量，未设置或无效时返回0（调用方据此使用默认值）
+func envInt(name string) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil && os.Getenv(name) != "" {
+		log.Printf("Invalid %s, using default", name)
+	}
+	return n
+}
+
 func main() {
 	ctx, cancel = context.WithCancel(context.Background())
 	defer cancel()
 
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run mini_chain_gossip_stream_mdns.go <port>")
+		fmt.Println("Usage: go run mini_chain_gossip_stream_mdns.go <port> [chain_id]")
+		fmt.Println("Set GOSSIP_UTXO=1 to run with the UTXO transaction model")
+		fmt.Println("Set GOSSIP_WORKERS / GOSSIP_BUFFER to size the gossip worker pool and its queues")
 	}
 
-	blockchain = core.NewBlockchain()  // 使用core包中的NewBlockchain函数
+	// 可选的链ID参数，不同链ID的节点互不接受对方签名的交易
+	chainID := core.DefaultChainID
+	if len(os.Args) >= 3 {
+		chainID = os.Args[2]
+	}
+	// 设置GOSSIP_UTXO=1时以UTXO模式运行，采用与internal/blockchain相同的交易模型
+	if os.Getenv("GOSSIP_UTXO") == "1" {
+		blockchain = core.NewUTXOBlockchain(chainID)
+		fmt.Println("Running in UTXO mode")
+	} else {
+		blockchain = core.NewBlockchainWithChainID(chainID)
+	}
+	fmt.Println("Chain ID:", chainID)
 	priv, pubAddr := core.NewKeyPair() // 使用core包中的NewKeyPair函数
 	fmt.Println("Wallet address:", pubAddr)
 
@@ -399,6 +500,8 @@ func main() {
 	}
 
 	setStreamHandler()
+	chainStreams = newStreamPool(h, ProtocolID, MaxStreamsPerPeer, StreamIdleTimeout)
+	go chainStreams.run(ctx)
 
 	gsub, err = pubsub.NewGossipSub(ctx, h)
 	if err != nil {
@@ -414,12 +517,15 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	// GOSSIP_WORKERS和GOSSIP_BUFFER设置处理订阅消息的worker数量和每个worker的队列容量
+	gossipDispatcher = newDispatcher(envInt("GOSSIP_WORKERS"), envInt("GOSSIP_BUFFER"), handleMessage)
+	gossipDispatcher.start()
 	go subLoop()
 
 	setupMdns()
 	setupDHTAndBootstrap(true)
 
-	go mineRoutine(priv)
+	go mineRoutine(priv, pubAddr)
 
 	time.Sleep(1 * time.Second)
 
@@ -444,17 +550,44 @@ func main() {
 				continue
 			}
 			to := parts[1]
-			amt, _ := strconv.Atoi(parts[2])
+			amt, err := core.ParseAmount(parts[2])
+			if err != nil {
+				fmt.Println("invalid amount:", err)
+				continue
+			}
 
 			tx := core.Transaction{From: pubAddr, To: to, Amount: amt}
-			sig, _ := core.SignTransaction(priv, tx)
+			sig, _ := core.SignTransactionForChain(priv, tx, blockchain.ChainID())
 			tx.Signature = sig
 
 			handleTx(tx)
+		case "utxo":
+			// UTXO模式转账：选取自己的未花费输出，找零返回自己
+			if len(parts) < 3 {
+				fmt.Println("usage: utxo <to> <amount>")
+				continue
+			}
+			amt, err := core.ParseAmount(parts[2])
+			if err != nil {
+				fmt.Println("invalid amount:", err)
+				continue
+			}
+			tx, err := blockchain.BuildUTXOTransfer(priv, parts[1], amt)
+			if err != nil {
+				fmt.Println("build tx failed:", err)
+				continue
+			}
+			handleUTXOTx(tx)
+		case "balance":
+			fmt.Println("Balance:", blockchain.Balance(pubAddr))
 		case "chain":
 			printChain()
 		case "peers":
 			printPeers()
+		case "stats":
+			queued, capacity := gossipDispatcher.Queued()
+			fmt.Printf("Gossip queue: %d/%d, dropped: %d\n", queued, capacity, gossipDispatcher.Dropped())
+			fmt.Printf("Sync streams: %d idle, %d opened\n", chainStreams.Idle(), chainStreams.Opened())
 		case "exit":
 			return
 		}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"mini_chain/gossip/core"
+
+	libp2p "github.com/libp2p/go-libp2p"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	peerstore "github.com/libp2p/go-libp2p/core/peerstore"
+)
+
+// mineChain 在创世区块之上挖出n个空区块
+func mineChain(genesis core.Block, n int) []core.Block {
+	chain := []core.Block{genesis}
+	for i := 0; i < n; i++ {
+		chain = append(chain, core.MineBlock(nil, chain[len(chain)-1]))
+	}
+	return chain
+}
+
+func TestSyncChainFallsBackToNextPeer(t *testing.T) {
+	blockchain = core.NewBlockchain()
+	valid := mineChain(blockchain.GetBlocks()[0], 3)
+
+	// 第二个节点返回更长但被篡改的链，应被拒绝
+	garbage := mineChain(blockchain.GetBlocks()[0], 4)
+	garbage[2].Nonce++
+
+	failing, bad, good := peer.ID("failing"), peer.ID("garbage"), peer.ID("good")
+	fetch := func(ctx context.Context, pid peer.ID) ([]core.Block, error) {
+		switch pid {
+		case failing:
+			return nil, errors.New("stream reset")
+		case bad:
+			return garbage, nil
+		default:
+			return valid, nil
+		}
+	}
+
+	chain, from, ok := bestChainFrom(context.Background(), []peer.ID{failing, bad, good}, fetch, 2)
+	if !ok || from != good {
+		t.Fatalf("best chain from %q (ok=%v), want %q", from, ok, good)
+	}
+	if len(chain) != len(valid) || chain[len(chain)-1].Hash != valid[len(valid)-1].Hash {
+		t.Fatal("expected the valid longer chain to be selected")
+	}
+	if !syncChain(context.Background(), blockchain, []peer.ID{failing, bad, good}, fetch, 2) {
+		t.Fatal("expected the valid longer chain to be adopted")
+	}
+	if blockchain.Height() != 3 || blockchain.Tip().Hash != valid[3].Hash {
+		t.Fatalf("chain at height %d after sync, want the valid chain at height 3", blockchain.Height())
+	}
+}
+
+func TestSyncChainRejectsShorterOrInvalid(t *testing.T) {
+	blockchain = core.NewBlockchain()
+	for i := 0; i < 2; i++ {
+		last := blockchain.GetBlocks()[i]
+		if !blockchain.AddBlock(core.MineBlock(nil, last)) {
+			t.Fatal("failed to extend local chain")
+		}
+	}
+	short := blockchain.GetBlocks()[:2]
+	fetch := func(ctx context.Context, pid peer.ID) ([]core.Block, error) {
+		if pid == "empty" {
+			return nil, nil
+		}
+		return short, nil
+	}
+	if syncChain(context.Background(), blockchain, []peer.ID{"empty", "short"}, fetch, MaxChainRequests) {
+		t.Fatal("shorter chain must not be adopted")
+	}
+}
+
+func TestBestChainFromRespectsLimit(t *testing.T) {
+	blockchain = core.NewBlockchain()
+	chain := blockchain.GetBlocks()
+	var (
+		mu             sync.Mutex
+		inflight, peak int
+	)
+	fetch := func(ctx context.Context, pid peer.ID) ([]core.Block, error) {
+		mu.Lock()
+		inflight++
+		if inflight > peak {
+			peak = inflight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inflight--
+		mu.Unlock()
+		return chain, nil
+	}
+	peers := []peer.ID{"a", "b", "c", "d", "e", "f"}
+	if _, _, ok := bestChainFrom(context.Background(), peers, fetch, 2); !ok {
+		t.Fatal("expected a chain")
+	}
+	if peak > 2 {
+		t.Fatalf("peak concurrent requests %d exceeds limit 2", peak)
+	}
+}
+
+// signedTx 构造一笔为当前链签名的交易
+func signedTx(t *testing.T, amount int) core.Transaction {
+	t.Helper()
+	priv, from := core.NewKeyPair()
+	_, to := core.NewKeyPair()
+	tx := core.Transaction{From: from, To: to, Amount: amount}
+	sig, err := core.SignTransactionForChain(priv, tx, blockchain.ChainID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Signature = sig
+	return tx
+}
+
+func TestMinedBlockClearsMempool(t *testing.T) {
+	blockchain = core.NewBlockchain()
+	handleTx(signedTx(t, 1))
+	handleTx(signedTx(t, 2))
+	if n := len(blockchain.Mempool()); n != 2 {
+		t.Fatalf("mempool has %d txs, want 2", n)
+	}
+
+	b, ok := mineNext()
+	if !ok || len(b.Transactions) != 2 {
+		t.Fatalf("mineNext = %d txs, %v; want a block with both txs", len(b.Transactions), ok)
+	}
+	if n := len(blockchain.Mempool()); n != 0 {
+		t.Fatalf("mempool has %d txs after mining, want 0", n)
+	}
+	if _, ok := mineNext(); ok {
+		t.Fatal("mineNext mined a block from an empty mempool")
+	}
+}
+
+func TestReceivedBlockClearsMempool(t *testing.T) {
+	blockchain = core.NewBlockchain()
+	included, pending := signedTx(t, 1), signedTx(t, 2)
+	handleTx(included)
+	handleTx(pending)
+	handleTx(included) // 重复交易不会再次入池
+	if n := len(blockchain.Mempool()); n != 2 {
+		t.Fatalf("mempool has %d txs, want 2", n)
+	}
+
+	// 对端挖出只包含其中一笔交易的区块
+	handleBlock(core.MineBlock([]core.Transaction{included}, blockchain.Tip()), "peer")
+	if blockchain.Height() != 1 {
+		t.Fatalf("received block not applied, height %d", blockchain.Height())
+	}
+	pool := blockchain.Mempool()
+	if len(pool) != 1 || pool[0].Signature != pending.Signature {
+		t.Fatalf("mempool after block = %+v, want only the pending tx", pool)
+	}
+}
+
+// TestDispatcherFloodDropsWithoutBlocking 处理被阻塞时大量涌入的消息：读取循环不被卡住，满队列的消息被丢弃并计数，
+// 被处理的消息在同一peer内保持到达顺序
+func TestDispatcherFloodDropsWithoutBlocking(t *testing.T) {
+	const total = 1000
+	peers := []peer.ID{"peer-a", "peer-b", "peer-c"}
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	handled := make(map[peer.ID][]int)
+	d := newDispatcher(2, 8, func(in inbound) {
+		<-release
+		var seq int
+		json.Unmarshal(in.msg.Data, &seq)
+		mu.Lock()
+		handled[in.from] = append(handled[in.from], seq)
+		mu.Unlock()
+	})
+	d.start()
+
+	// 模拟订阅：依次产生total条消息后阻塞直到ctx取消
+	ctx, cancel := context.WithCancel(context.Background())
+	read := 0
+	drained := make(chan struct{})
+	next := func(ctx context.Context) (*pubsub.Message, error) {
+		if read == total {
+			close(drained)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		data, _ := json.Marshal(Message{Type: "BLOCK", Data: mustMarshal(read)})
+		msg := &pubsub.Message{Message: &pb.Message{Data: data}, ReceivedFrom: peers[read%len(peers)]}
+		read++
+		return msg, nil
+	}
+	done := make(chan struct{})
+	go func() {
+		readLoop(ctx, next, "self", d)
+		close(done)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("read loop stuck after %d of %d messages", read, total)
+	}
+	cancel()
+	<-done
+	if d.Dropped() == 0 {
+		t.Fatal("expected dropped messages while workers were blocked")
+	}
+
+	close(release)
+	d.stop()
+	n := 0
+	for from, seqs := range handled {
+		n += len(seqs)
+		for i := 1; i < len(seqs); i++ {
+			if seqs[i] <= seqs[i-1] {
+				t.Fatalf("messages from %s handled out of order: %v", from, seqs)
+			}
+		}
+	}
+	if uint64(n)+d.Dropped() != total {
+		t.Fatalf("handled %d + dropped %d != %d", n, d.Dropped(), total)
+	}
+	if queued, capacity := d.Queued(); queued != 0 || capacity != 16 {
+		t.Fatalf("Queued() = %d/%d after stop, want 0/16", queued, capacity)
+	}
+	t.Logf("handled %d, dropped %d", n, d.Dropped())
+}
+
+func TestFetchChainReusesStream(t *testing.T) {
+	server, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	client, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Peerstore().AddAddrs(server.ID(), server.Addrs(), peerstore.TempAddrTTL)
+
+	// 服务端使用节点自己的流处理器
+	oldHost, oldChain, oldPool := h, blockchain, chainStreams
+	defer func() { h, blockchain, chainStreams = oldHost, oldChain, oldPool }()
+	h, blockchain = server, core.NewBlockchain()
+	setStreamHandler()
+	chainStreams = newStreamPool(client, ProtocolID, 0, 0)
+
+	fetch := func() {
+		t.Helper()
+		reqCtx, cancelReq := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelReq()
+		chain, err := fetchChain(reqCtx, server.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chain) != 1 {
+			t.Fatalf("fetched chain of length %d, want 1", len(chain))
+		}
+	}
+	openStreams := func() int {
+		n := 0
+		for _, c := range client.Network().ConnsToPeer(server.ID()) {
+			for _, s := range c.GetStreams() {
+				if s.Protocol() == ProtocolID {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	for i := 0; i < 5; i++ {
+		fetch()
+	}
+	if n := chainStreams.Opened(); n != 1 {
+		t.Fatalf("5 sequential requests opened %d streams, want 1", n)
+	}
+	if n := openStreams(); n != 1 {
+		t.Fatalf("%d sync streams open to the peer, want 1", n)
+	}
+
+	// 空闲超时后流被关闭，下一次请求新建流
+	if n := chainStreams.closeIdle(time.Now().Add(StreamIdleTimeout)); n != 1 {
+		t.Fatalf("closeIdle closed %d streams, want 1", n)
+	}
+	fetch()
+	if n := chainStreams.Opened(); n != 2 {
+		t.Fatalf("request after idle close opened %d streams in total, want 2", n)
+	}
+
+	// 对端关闭了池中的流时，请求换用新流并成功
+	for _, c := range server.Network().ConnsToPeer(client.ID()) {
+		for _, s := range c.GetStreams() {
+			s.Reset()
+		}
+	}
+	fetch()
+	if n := chainStreams.Opened(); n != 3 {
+		t.Fatalf("request after remote reset opened %d streams in total, want 3", n)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	network "github.com/libp2p/go-libp2p/core/network"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// 同步请求使用的出站流池参数
+const (
+	MaxStreamsPerPeer = 2                // 每个节点保留的空闲流数量上限
+	StreamIdleTimeout = 30 * time.Second // 空闲超过该时间的流被关闭
+)
+
+// pooledStream 池中的一条流及其读取器
+// 读取器可能已缓存了流上的数据，必须随流一起复用
+type pooledStream struct {
+	s    network.Stream
+	r    *bufio.Reader
+	idle time.Time // 归还到池中的时间
+}
+
+// streamPool 按节点缓存到同一协议的出站流，供请求/响应复用
+// 消息以换行分隔的JSON编码，对端的流处理器在同一条流上循环读取请求，因此一条流可以依次承载多个请求；
+// 取出的流由调用者独占，一条流上同时只有一个请求
+type streamPool struct {
+	host    host.Host
+	proto   protocol.ID
+	max     int
+	timeout time.Duration
+
+	mu     sync.Mutex
+	idle   map[peer.ID][]*pooledStream
+	opened atomic.Uint64 // 累计新建的流数量
+}
+
+// newStreamPool 创建出站流池，max和timeout小于1时使用默认值
+func newStreamPool(h host.Host, proto protocol.ID, max int, timeout time.Duration) *streamPool {
+	if max < 1 {
+		max = MaxStreamsPerPeer
+	}
+	if timeout <= 0 {
+		timeout = StreamIdleTimeout
+	}
+	return &streamPool{host: h, proto: proto, max: max, timeout: timeout, idle: make(map[peer.ID][]*pooledStream)}
+}
+
+// roundTrip 在到pid的流上发送req并读取一条响应
+// 复用的流可能已被对端关闭，此时丢弃它并换一条流重试；新建的流失败时直接返回错误
+func (p *streamPool) roundTrip(ctx context.Context, pid peer.ID, req Message) (Message, error) {
+	for {
+		ps, reused, err := p.get(ctx, pid)
+		if err != nil {
+			return Message{}, err
+		}
+		resp, err := ps.roundTrip(ctx, req)
+		if err == nil {
+			p.put(pid, ps)
+			return resp, nil
+		}
+		ps.s.Reset()
+		if !reused || ctx.Err() != nil {
+			return Message{}, err
+		}
+	}
+}
+
+// roundTrip 写入一条请求并读取一条响应，读写受ctx的截止时间约束
+func (ps *pooledStream) roundTrip(ctx context.Context, req Message) (Message, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		ps.s.SetDeadline(deadline)
+	}
+	data, _ := json.Marshal(req)
+	data = append(data, '\n')
+	if _, err := ps.s.Write(data); err != nil {
+		return Message{}, err
+	}
+	raw, err := ps.r.ReadBytes('\n')
+	if err != nil {
+		return Message{}, err
+	}
+	var resp Message
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return Message{}, err
+	}
+	ps.s.SetDeadline(time.Time{})
+	return resp, nil
+}
+
+// get 取出到pid的一条空闲流（最近归还的优先），没有时新建；reused表示流来自池
+func (p *streamPool) get(ctx context.Context, pid peer.ID) (ps *pooledStream, reused bool, err error) {
+	p.mu.Lock()
+	for list := p.idle[pid]; len(list) > 0; list = p.idle[pid] {
+		ps = list[len(list)-1]
+		p.idle[pid] = list[:len(list)-1]
+		if time.Since(ps.idle) < p.timeout {
+			p.mu.Unlock()
+			return ps, true, nil
+		}
+		ps.s.Close()
+	}
+	delete(p.idle, pid)
+	p.mu.Unlock()
+
+	s, err := p.host.NewStream(ctx, pid, p.proto)
+	if err != nil {
+		return nil, false, err
+	}
+	p.opened.Add(1)
+	return &pooledStream{s: s, r: bufio.NewReader(s)}, false, nil
+}
+
+// put 归还请求成功完成的流，池中该节点的空闲流已满时关闭它
+func (p *streamPool) put(pid peer.ID, ps *pooledStream) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.idle[pid]) >= p.max {
+		ps.s.Close()
+		return
+	}
+	ps.idle = time.Now()
+	p.idle[pid] = append(p.idle[pid], ps)
+}
+
+// closeIdle 关闭在now之前已空闲超过timeout的流，返回关闭的数量
+func (p *streamPool) closeIdle(now time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for pid, list := range p.idle {
+		kept := list[:0]
+		for _, ps := range list {
+			if now.Sub(ps.idle) >= p.timeout {
+				ps.s.Close()
+				n++
+				continue
+			}
+			kept = append(kept, ps)
+		}
+		if len(kept) == 0 {
+			delete(p.idle, pid)
+		} else {
+			p.idle[pid] = kept
+		}
+	}
+	return n
+}
+
+// run 定期关闭空闲超时的流，ctx取消时关闭池中全部流
+func (p *streamPool) run(ctx context.Context) {
+	ticker := time.NewTicker(p.timeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			p.closeIdle(time.Now().Add(p.timeout))
+			return
+		case now := <-ticker.C:
+			p.closeIdle(now)
+		}
+	}
+}
+
+// Opened 返回累计新建的流数量
+func (p *streamPool) Opened() uint64 {
+	return p.opened.Load()
+}
+
+// Idle 返回池中空闲流的数量
+func (p *streamPool) Idle() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, list := range p.idle {
+		n += len(list)
+	}
+	return n
+}
@@ -1,11 +1,18 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"mini_chain/internal/blockchain"
 	"mini_chain/internal/p2p"
 )
@@ -15,44 +22,492 @@ type API struct {
 	BC  *blockchain.Blockchain // 区块链实例
 	P2P *p2p.Node              // P2P节点实例
 	WS  *WSManager             // WebSocket管理器实例
+
+	// AuthToken 管理端点的Bearer令牌，为空时管理端点禁用
+	AuthToken string
+	// TestMode 启用测试端点（如 /block/submit），生产环境必须关闭
+	TestMode bool
+	// MaxReorgFrameBlocks 重组WS帧中removed/added各自最多列出的区块数，超出部分截断
+	MaxReorgFrameBlocks int
+	// MiningGate 挖矿启动门槛，为nil时不在 /status 中报告
+	MiningGate *p2p.MiningGate
+	// Watchdog 挖矿停滞检测器，为nil时 /readyz 不检查挖矿
+	Watchdog *blockchain.MiningWatchdog
+	// MinRelayFee 交易进入内存池所需的最低手续费
+	MinRelayFee int
+	// AccessLog 每个请求的结构化访问日志，为nil时不记录
+	AccessLog *slog.Logger
 }
 
 // NewAPI 创建新的API实例
 // bc: 区块链实例
 // p2p: P2P节点实例
-func NewAPI(bc *blockchain.Blockchain, p2p *p2p.Node) *API {
+func NewAPI(bc *blockchain.Blockchain, node *p2p.Node) *API {
 	ws := NewWSManager() // 创建WebSocket管理器
 	go ws.Run()          // 启动WebSocket管理器
-	return &API{
-		BC:  bc,
-		P2P: p2p,
-		WS:  ws,
+	api := &API{
+		BC:                  bc,
+		P2P:                 node,
+		WS:                  ws,
+		MaxReorgFrameBlocks: DefaultMaxReorgFrameBlocks,
+		MinRelayFee:         blockchain.DefaultMinRelayFee,
+		AccessLog:           slog.Default(),
+	}
+	bc.OnReorg(api.notifyReorg) // 链重组时通知WebSocket客户端
+	bc.OnTx(api.notifyTx)       // 交易进入内存池时通知WebSocket客户端
+	if node != nil {
+		node.Handle(p2p.MsgTx, api.handleGossipTx) // 接受gossip收到的交易
+		node.Handle(p2p.MsgInv, api.handleTxInv)   // 取回peer公告的未知交易
 	}
+	return api
 }
 
-// Run 启动API服务器
-// addr: 服务器监听地址
-func (api *API) Run(addr string) {
+// MaxHeadersPerRequest 单次/headers请求最多返回的区块头数量
+const MaxHeadersPerRequest = 2000
+
+// /chain 分页参数
+const (
+	DefaultChainLimit = 50  // 未指定limit时返回的区块数
+	MaxChainLimit     = 500 // 单次请求最多返回的区块数
+)
+
+// DefaultMineTimeout /mine 端点的默认挖矿超时，也是调用者可以指定的最长超时
+const DefaultMineTimeout = 30 * time.Second
+
+// Router 构建包含所有端点的路由器
+func (api *API) Router() *mux.Router {
 	r := mux.NewRouter()
 
 	// REST端点
-	r.HandleFunc("/chain", api.GetChain).Methods("GET")   // 获取区块链信息
-	r.HandleFunc("/tx", api.PostTx).Methods("POST")       // 提交交易
+	r.HandleFunc("/chain", api.GetChain).Methods("GET")     // 分页获取主链区块
+	r.HandleFunc("/genesis", api.GetGenesis).Methods("GET") // 创世区块
+	r.HandleFunc("/headers", api.GetHeaders).Methods("GET") // 获取区块头
+	r.HandleFunc("/tx", api.PostTx).Methods("POST")         // 提交交易
+	r.HandleFunc("/tx/{txid}", api.GetTx).Methods("GET")    // 交易状态
+	r.HandleFunc("/rpc", api.PostRPC).Methods("POST")       // JSON-RPC 2.0
+	r.HandleFunc("/peers", api.GetPeers).Methods("GET")     // 已连接peer详情
+	r.HandleFunc("/status", api.GetStatus).Methods("GET")   // 节点状态和监听地址
+	r.HandleFunc("/readyz", api.GetReadyz).Methods("GET")   // 就绪检查
+
+	r.HandleFunc("/mempool", api.GetMempool).Methods("GET")                    // 内存池交易ID和首次收到时间
+	r.HandleFunc("/mempool/stats", api.GetMempoolStats).Methods("GET")         // 内存池指标
+	r.HandleFunc("/mempool/ordered", api.GetMempoolOrdered).Methods("GET")     // 按打包顺序排列的内存池交易
+	r.HandleFunc("/identity", api.GetIdentity).Methods("GET")                  // 节点身份和签名地址记录
+	r.HandleFunc("/addr/{address}/utxos", api.GetAddrUTXOs).Methods("GET")     // 地址可花费的UTXO
+	r.HandleFunc("/addr/{address}/balance", api.GetAddrBalance).Methods("GET") // 地址在指定高度的余额
+	r.HandleFunc("/balance/{address}", api.GetBalance).Methods("GET")          // 地址当前的可花费余额
+	r.HandleFunc("/metrics/bandwidth", api.GetBandwidth).Methods("GET")        // 按协议分类的网络流量
+	r.HandleFunc("/metrics/sidechain", api.GetSideChain).Methods("GET")        // stale/orphan区块计数和侧链存储
+	r.HandleFunc("/stats", api.GetStats).Methods("GET")                        // 区块数、交易数、流通总量和当前难度
+
+	// 管理端点（需要鉴权）
+	r.HandleFunc("/chain/import", api.requireAuth(api.PostChainImport)).Methods("POST")     // 导入并校验外部链
+	r.HandleFunc("/difficulty/retarget", api.requireAuth(api.PostRetarget)).Methods("POST") // 立即重新计算难度
+	r.HandleFunc("/utxo/rebuild", api.requireAuth(api.PostUTXORebuild)).Methods("POST")     // 从链重建UTXO集合
+	r.HandleFunc("/mempool/{txid}", api.requireAuth(api.DeleteMempoolTx)).Methods("DELETE") // 从本地内存池逐出交易
+	r.HandleFunc("/mining/pause", api.requireAuth(api.PostMiningPause)).Methods("POST")     // 暂停挖矿
+	r.HandleFunc("/mining/resume", api.requireAuth(api.PostMiningResume)).Methods("POST")   // 恢复挖矿
+	r.HandleFunc("/mine", api.requireAuth(api.PostMine)).Methods("POST")                    // 挖取包含内存池交易的区块
+
+	// 调试端点
+	r.HandleFunc("/debug/rejections", api.GetRejections).Methods("GET") // 区块拒绝统计
+
+	// 测试端点（仅测试模式）
+	if api.TestMode {
+		r.HandleFunc("/block/submit", api.PostBlockSubmit).Methods("POST") // 提交任意区块
+	}
 
 	// WebSocket端点
 	r.HandleFunc("/ws", api.WS.ServeWS)
 
+	// 访问日志
+	if api.AccessLog != nil {
+		r.Use(api.accessLog)
+	}
+	return r
+}
+
+// Run 启动API服务器
+// addr: 服务器监听地址
+func (api *API) Run(addr string) {
 	log.Println("REST + WS server running at", addr)
-	http.ListenAndServe(addr, r)
+	http.ListenAndServe(addr, api.Router())
 }
 
-// GET /chain 处理获取区块链信息的请求
+// GET /chain?from=<index>&limit=<n> 按高度升序返回从from（默认0）开始最多limit（默认50）个主链区块
+// from超过链高时返回空数组
 func (api *API) GetChain(w http.ResponseWriter, r *http.Request) {
-	// 从区块链获取最新区块
+	from, err := queryInt(r, "from", 0)
+	if err != nil || from < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid from")
+		return
+	}
+	limit, err := queryInt(r, "limit", DefaultChainLimit)
+	if err != nil || limit < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid limit")
+		return
+	}
+	if limit > MaxChainLimit {
+		limit = MaxChainLimit
+	}
+	blocks, err := api.BC.GetBlocks(from, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	writeJSONCompressed(w, r, http.StatusOK, blocks)
+}
+
+// GET /genesis 返回创世区块（主链高度0），客户端可据此确认与节点处于同一网络
+func (api *API) GetGenesis(w http.ResponseWriter, r *http.Request) {
+	genesis, err := api.BC.GetBlockByIndex(0)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, genesis)
+}
+
+// GET /headers?from=&count= 返回区块头列表（不含交易体），供轻客户端先同步区块头
+// 请求带Accept-Encoding: gzip且响应较大时压缩传输
+// from 默认为0，count 默认为 MaxHeadersPerRequest，且不能超过该上限
+func (api *API) GetHeaders(w http.ResponseWriter, r *http.Request) {
+	from, err := queryInt(r, "from", 0)
+	if err != nil || from < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid from")
+		return
+	}
+	count, err := queryInt(r, "count", MaxHeadersPerRequest)
+	if err != nil || count < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid count")
+		return
+	}
+	if count > MaxHeadersPerRequest {
+		count = MaxHeadersPerRequest
+	}
+	writeJSONCompressed(w, r, http.StatusOK, api.BC.GetHeaders(from, count))
+}
+
+// NodeStatus GET /status 返回的节点状态
+type NodeStatus struct {
+	NodeID      string   `json:"node_id,omitempty"` // libp2p节点ID
+	ListenAddrs []string `json:"listen_addrs"`      // 所有传输协议上的监听地址
+	Peers       int      `json:"peers"`             // 已连接peer数量
+	Height      int      `json:"height"`            // 主链高度
+	LatestHash  string   `json:"latest_hash"`       // 最新区块哈希
+	Difficulty  int      `json:"difficulty"`        // 当前难度
+	MempoolSize int      `json:"mempool_size"`      // 内存池交易数量
+	UTXORoot    string   `json:"utxo_root"`         // UTXO集合承诺，节点间比较可发现状态分叉
+
+	Sync      blockchain.SyncProgress `json:"sync"`                // 链同步进度
+	Mining    *p2p.MiningGateState    `json:"mining,omitempty"`    // 挖矿启动门槛状态
+	Bandwidth *p2p.BandwidthStats     `json:"bandwidth,omitempty"` // 网络流量统计
+}
+
+// GET /status 返回节点ID、全部监听地址、连接数和链头信息
+func (api *API) GetStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, api.Status())
+}
+
+// Status 计算当前节点状态（GET /status 和状态快照共用）
+func (api *API) Status() NodeStatus {
 	latest := api.BC.GetLatest()
-	// 目前只返回最新区块
-	// 在实际实现中，会返回完整链
-	json.NewEncoder(w).Encode(latest)
+	status := NodeStatus{
+		ListenAddrs: []string{},
+		Height:      latest.Index,
+		LatestHash:  latest.Hash,
+		Difficulty:  api.BC.Difficulty(),
+		MempoolSize: len(blockchain.ListMempool()),
+		UTXORoot:    blockchain.UTXORoot(),
+		Sync:        api.BC.SyncProgress(),
+	}
+	if api.P2P != nil {
+		status.NodeID = api.P2P.Host.ID().String()
+		status.ListenAddrs = api.P2P.ListenAddrs()
+		status.Peers = len(api.P2P.Host.Network().Peers())
+		bw := api.P2P.Bandwidth()
+		status.Bandwidth = &bw
+	}
+	if api.MiningGate != nil {
+		st := api.MiningGate.State()
+		status.Mining = &st
+	}
+	return status
+}
+
+// POST /mining/pause 暂停本节点挖矿，返回挖矿门槛状态
+func (api *API) PostMiningPause(w http.ResponseWriter, r *http.Request) {
+	if api.MiningGate == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "mining not configured")
+		return
+	}
+	api.MiningGate.Pause()
+	writeJSON(w, http.StatusOK, api.MiningGate.State())
+}
+
+// POST /mining/resume 恢复本节点挖矿，返回挖矿门槛状态
+func (api *API) PostMiningResume(w http.ResponseWriter, r *http.Request) {
+	if api.MiningGate == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "mining not configured")
+		return
+	}
+	api.MiningGate.Resume()
+	writeJSON(w, http.StatusOK, api.MiningGate.State())
+}
+
+// Readiness GET /readyz 的响应
+type Readiness struct {
+	Ready  bool   `json:"ready"`            // 节点是否就绪
+	Reason string `json:"reason,omitempty"` // 未就绪的原因
+}
+
+// GET /readyz 节点就绪时返回200，挖矿停滞等不健康状态返回503
+func (api *API) GetReadyz(w http.ResponseWriter, r *http.Request) {
+	if api.Watchdog != nil && !api.Watchdog.Healthy() {
+		writeJSON(w, http.StatusServiceUnavailable, Readiness{Reason: "mining stalled"})
+		return
+	}
+	writeJSON(w, http.StatusOK, Readiness{Ready: true})
+}
+
+// GET /identity 返回节点ID、公钥、监听地址和签名地址记录
+func (api *API) GetIdentity(w http.ResponseWriter, r *http.Request) {
+	if api.P2P == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "p2p node not running")
+		return
+	}
+	id, err := api.P2P.Identity()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, id)
+}
+
+// GET /addr/{address}/utxos 返回地址可花费的UTXO（txid、vout、金额），供钱包选择交易输入
+// 可选参数 min 过滤金额小于min的粉尘输出
+func (api *API) GetAddrUTXOs(w http.ResponseWriter, r *http.Request) {
+	min, err := queryInt(r, "min", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, blockchain.SpendableUTXOs(mux.Vars(r)["address"], min))
+}
+
+// AddrBalance GET /addr/{address}/balance 的响应
+type AddrBalance struct {
+	Address string `json:"address"`
+	Height  int    `json:"height"`  // 余额对应的区块高度
+	Balance int    `json:"balance"` // 该高度时地址拥有的UTXO总额
+}
+
+// GET /addr/{address}/balance?height=N 返回地址在主链高度N（含）时的余额，height 默认为当前高度
+// 高度超出主链范围时返回400
+func (api *API) GetAddrBalance(w http.ResponseWriter, r *http.Request) {
+	height, err := queryInt(r, "height", api.BC.Height())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid height")
+		return
+	}
+	address := mux.Vars(r)["address"]
+	balance, err := api.BC.BalanceAt(address, height)
+	if err != nil {
+		status, code := http.StatusInternalServerError, ErrCodeInternal
+		if errors.Is(err, blockchain.ErrHeightOutOfRange) {
+			status, code = http.StatusBadRequest, ErrCodeBadRequest
+		}
+		writeError(w, status, code, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, AddrBalance{Address: address, Height: height, Balance: balance})
+}
+
+// Balance GET /balance/{address} 的响应
+type Balance struct {
+	Address string `json:"address"`
+	Balance int    `json:"balance"` // 地址当前拥有的UTXO总额
+}
+
+// GET /balance/{address} 返回地址在当前UTXO集合中的余额，没有UTXO的地址返回0
+func (api *API) GetBalance(w http.ResponseWriter, r *http.Request) {
+	address := mux.Vars(r)["address"]
+	writeJSON(w, http.StatusOK, Balance{Address: address, Balance: blockchain.GetBalance(address)})
+}
+
+// GET /metrics/bandwidth 返回累计收发字节数、当前速率和按协议分类的流量
+func (api *API) GetBandwidth(w http.ResponseWriter, r *http.Request) {
+	if api.P2P == nil {
+		writeJSON(w, http.StatusOK, p2p.BandwidthStats{Protocols: map[string]p2p.ProtocolBandwidth{}})
+		return
+	}
+	writeJSON(w, http.StatusOK, api.P2P.Bandwidth())
+}
+
+// GET /metrics/sidechain 返回stale/orphan区块计数、侧链重组次数和侧链存储中的区块数
+func (api *API) GetSideChain(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, api.BC.SideChainStats())
+}
+
+// GET /stats 返回主链区块数、交易总数、流通中的货币总量、平均每块交易数和当前难度
+func (api *API) GetStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, api.BC.Stats())
+}
+
+// GET /peers 返回已连接peer的地址、连接方向、打开的流数量和ping延迟
+func (api *API) GetPeers(w http.ResponseWriter, r *http.Request) {
+	if api.P2P == nil {
+		writeJSON(w, http.StatusOK, []p2p.PeerInfo{})
+		return
+	}
+	writeJSON(w, http.StatusOK, api.P2P.PeerDetails(r.Context()))
+}
+
+// GET /mempool 按进入顺序返回内存池中的交易ID及其首次收到时间
+func (api *API) GetMempool(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, blockchain.ListMempoolEntries())
+}
+
+// GET /mempool/ordered 按打包区块时使用的顺序返回内存池交易：父交易在子交易之前，其余按手续费从高到低
+func (api *API) GetMempoolOrdered(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, blockchain.OrderedMempool())
+}
+
+// GET /tx/{txid} 返回交易状态，待处理交易附带首次收到时间；节点未见过该交易时返回404
+func (api *API) GetTx(w http.ResponseWriter, r *http.Request) {
+	st := api.txStatus(mux.Vars(r)["txid"])
+	if st.Status == TxStatusUnknown {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "transaction not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// DELETE /mempool/{txid} 从本地内存池和交易体存储中逐出交易，交易不在内存池中时返回404
+// 只影响本节点，不会通知其他节点
+func (api *API) DeleteMempoolTx(w http.ResponseWriter, r *http.Request) {
+	txid := mux.Vars(r)["txid"]
+	if !blockchain.EvictFromMempool(txid) {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "transaction not in mempool")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /mempool/stats 返回内存池交易数、总大小、手续费最小/中位/最大值和最早交易的等待时间
+func (api *API) GetMempoolStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, blockchain.GetMempoolStats())
+}
+
+// POST /chain/import 完整校验外部提供的链，有效且更优时采用，返回校验报告
+// 采用时返回200；链无效返回422；链有效但不更优返回409
+func (api *API) PostChainImport(w http.ResponseWriter, r *http.Request) {
+	var imp blockchain.ChainImport
+	if err := json.NewDecoder(r.Body).Decode(&imp); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+		return
+	}
+	report, err := api.BC.ImportChain(imp)
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, report)
+	case report.Valid:
+		writeJSON(w, http.StatusConflict, report)
+	default:
+		writeJSON(w, http.StatusUnprocessableEntity, report)
+	}
+}
+
+// BlockSubmitResult POST /block/submit 的结果
+type BlockSubmitResult struct {
+	Accepted bool                    `json:"accepted"`         // 区块是否被接受
+	Height   int                     `json:"height"`           // 处理后的主链高度
+	Reason   blockchain.RejectReason `json:"reason,omitempty"` // 拒绝原因分类
+	Error    string                  `json:"error,omitempty"`  // 具体错误描述
+}
+
+// POST /block/submit 测试模式下提交完整区块，经ValidateAndApplyBlock处理后返回详细结果
+// 接受时返回200；被拒绝时返回422并附带拒绝原因
+func (api *API) PostBlockSubmit(w http.ResponseWriter, r *http.Request) {
+	var b blockchain.Block
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+		return
+	}
+	err := api.BC.ValidateAndApplyBlock(b)
+	res := BlockSubmitResult{Accepted: err == nil, Height: api.BC.Height()}
+	if err != nil {
+		res.Error = err.Error()
+		if rerr, ok := err.(*blockchain.BlockRejectError); ok {
+			res.Reason = rerr.Reason
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, res)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// POST /mine?address=&timeout= 挖取包含内存池交易的新区块并应用（需要鉴权）
+// 区块奖励为共识参数规定的下一个区块的奖励
+// timeout 为Go时长格式（如 "10s"），不超过DefaultMineTimeout，超时未找到解时返回504
+func (api *API) PostMine(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+	if address == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "missing address")
+		return
+	}
+	timeout := DefaultMineTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		var err error
+		if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 || timeout > DefaultMineTimeout {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("invalid timeout (must be between 0 and %s)", DefaultMineTimeout))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	b, err := api.BC.MinePending(ctx, address)
+	switch err {
+	case nil:
+	case blockchain.ErrMiningDeadline:
+		writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
+		return
+	case blockchain.ErrNoTxsToMine:
+		writeError(w, http.StatusConflict, ErrCodeConflict, err.Error())
+		return
+	default:
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+
+	if err := api.BC.ValidateAndApplyBlock(b); err != nil {
+		writeError(w, http.StatusConflict, ErrCodeConflict, err.Error())
+		return
+	}
+	api.broadcast(&p2p.Message{Type: p2p.MsgBlock, Data: mustMarshal(b)})
+	writeJSON(w, http.StatusCreated, b)
+}
+
+// POST /difficulty/retarget 按共识参数立即重新计算难度（需要鉴权）
+// 应用的难度由链按共识规则推导；按最近窗口出块时间得出的调整结果在projected中返回，到下一个调整点生效
+func (api *API) PostRetarget(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, api.BC.Retarget())
+}
+
+// POST /utxo/rebuild 清空UTXO集合并从创世区块重放主链重新推导
+func (api *API) PostUTXORebuild(w http.ResponseWriter, r *http.Request) {
+	n, err := api.BC.RebuildUTXOSet()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"utxos": n})
+}
+
+// GET /debug/rejections 返回按原因统计的区块拒绝计数和最近的拒绝记录
+func (api *API) GetRejections(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, api.BC.Rejections())
 }
 
 // POST /tx 处理提交交易的请求
@@ -60,36 +515,95 @@ func (api *API) PostTx(w http.ResponseWriter, r *http.Request) {
 	var tx blockchain.UTXOTx
 	// 解析请求体中的交易数据
 	if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
-		http.Error(w, err.Error(), 400)
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
 		return
 	}
-	
-	// 验证交易结构
-	if err := blockchain.ValidateTxStructure(tx); err != nil {
-		http.Error(w, err.Error(), 400)
+
+	// 校验失败时按失败类型返回不同的错误码
+	if _, err := api.submitTx(tx); err != nil {
+		writeError(w, http.StatusBadRequest, txErrorCode(err), err.Error())
 		return
 	}
+	w.WriteHeader(http.StatusCreated)
+}
 
-	// 生成交易ID
-	txid, err := blockchain.TxID(tx)
+// submitTx 验证交易，加入内存池并广播到P2P网络和WebSocket客户端
+// 返回交易ID
+func (api *API) submitTx(tx blockchain.UTXOTx) (string, error) {
+	// 验证交易大小、结构、输入、签名和手续费，保存交易体并加入内存池
+	// 新进入内存池的交易由OnTx回调推送给WebSocket客户端
+	txid, _, err := api.BC.AcceptTx(tx, api.MinRelayFee)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
-		return
+		return "", err
 	}
 
-	// 将交易添加到内存池
-	blockchain.AddToMempool(txid)
-
 	// 广播交易到P2P网络
 	msg := &p2p.Message{
 		Type: p2p.MsgTx,
 		Data: mustMarshal(tx),
 	}
+	api.broadcast(msg)
+	return txid, nil
+}
+
+// handleGossipTx 校验gossip收到的交易并加入内存池，无效交易只记录日志
+func (api *API) handleGossipTx(from peer.ID, m *p2p.Message) {
+	var tx blockchain.UTXOTx
+	if err := json.Unmarshal(m.Data, &tx); err != nil {
+		log.Println("invalid gossip tx from", from, ":", err)
+		return
+	}
+	if _, _, err := api.BC.AcceptTx(tx, api.MinRelayFee); err != nil {
+		log.Println("rejected gossip tx from", from, ":", err)
+	}
+}
+
+// handleTxInv 向公告交易的peer取回本地没有的交易体，校验后加入内存池
+func (api *API) handleTxInv(from peer.ID, m *p2p.Message) {
+	var txids []string
+	if err := json.Unmarshal(m.Data, &txids); err != nil {
+		log.Println("invalid tx inv from", from, ":", err)
+		return
+	}
+	src := api.P2P.PeerSource(from)
+	for _, txid := range txids {
+		if _, err := blockchain.GetTransaction(txid); err == nil {
+			continue
+		}
+		tx, err := src.GetTx(txid)
+		if err != nil {
+			log.Println("fetch announced tx", txid, "from", from, ":", err)
+			continue
+		}
+		if id, err := blockchain.TxID(tx); err != nil || id != txid {
+			log.Println("peer", from, "returned a different tx for", txid)
+			continue
+		}
+		if _, _, err := api.BC.AcceptTx(tx, api.MinRelayFee); err != nil {
+			log.Println("rejected announced tx from", from, ":", err)
+		}
+	}
+}
+
+// broadcast 通过P2P网络广播消息（未配置P2P节点时忽略，便于测试）
+func (api *API) broadcast(msg *p2p.Message) {
+	if api.P2P == nil {
+		return
+	}
 	api.P2P.Broadcast(msg)
+}
 
-	// 推送给所有WebSocket客户端
-	api.WS.broadcast <- mustMarshal(tx)
-	w.WriteHeader(http.StatusCreated)
+// queryInt 解析整数查询参数，参数缺失时返回默认值
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return n, nil
 }
 
 // mustMarshal 将接口对象序列化为JSON字节切片
@@ -0,0 +1,1282 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mini_chain/internal/blockchain"
+	"mini_chain/internal/p2p"
+	"mini_chain/internal/wallet"
+)
+
+// newTestChain 创建低难度区块链并挖出n个区块
+func newTestChain(t *testing.T, n int) *blockchain.Blockchain {
+	t.Helper()
+	// 测试区块间隔1秒，目标间隔设为1秒使难度不被自动调整
+	params := blockchain.DefaultConsensusParams()
+	params.Difficulty = 1
+	params.TargetSpacing = 1
+	bc, err := blockchain.NewBlockchainWithParams(params, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		b := blockchain.MineBlock(bc.GetLatest(), []string{testCoinbase(t, fmt.Sprintf("tx-%d", i))}, 1)
+		if err := bc.ValidateAndApplyBlock(b); err != nil {
+			t.Fatalf("apply block %d: %v", i, err)
+		}
+	}
+	return bc
+}
+
+// testCoinbase 保存以tag为附加数据的coinbase交易并返回其ID，用作测试区块中的交易
+func testCoinbase(t *testing.T, tag string) string {
+	t.Helper()
+	txid, err := blockchain.PutTransaction(blockchain.CoinbaseTx(tag, "test-miner", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txid
+}
+
+// testAuthToken 测试服务器使用的管理端点令牌
+const testAuthToken = "test-token"
+
+// newTestServer 基于区块链创建测试HTTP服务器（不连接P2P网络）
+func newTestServer(t *testing.T, bc *blockchain.Blockchain) (*API, *httptest.Server) {
+	t.Helper()
+	a := NewAPI(bc, nil)
+	a.AuthToken = testAuthToken
+	srv := httptest.NewServer(a.Router())
+	t.Cleanup(srv.Close)
+	return a, srv
+}
+
+// fundedTx 为新账户在UTXO集合中创建金额为fund的输出，并构造一笔向to转账amount、手续费为fee的签名交易
+func fundedTx(t *testing.T, fund int, to string, amount, fee int) (blockchain.UTXOTx, *wallet.Account) {
+	t.Helper()
+	acc, err := wallet.NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := "fund-" + acc.Address[2:18]
+	blockchain.PutUTXO(prev, 0, blockchain.UTXOEntry{Address: acc.Address, Amount: fund})
+	t.Cleanup(func() { blockchain.DeleteUTXO(prev, 0) })
+	wtx, err := wallet.BuildTransaction(acc, to, amount, fee, []wallet.UTXO{{Txid: prev, Vout: 0, Amount: fund}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blockchain.TxFromWallet(wtx), acc
+}
+
+// newAddress 返回新生成账户的地址，用作转账接收方
+func newAddress(t *testing.T) string {
+	t.Helper()
+	acc, err := wallet.NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acc.Address
+}
+
+func TestGetHeaders(t *testing.T) {
+	bc := newTestChain(t, 5)
+	_, srv := newTestServer(t, bc)
+
+	resp, err := http.Get(srv.URL + "/headers?from=2&count=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var headers []blockchain.BlockHeader
+	if err := json.NewDecoder(resp.Body).Decode(&headers); err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 3 {
+		t.Fatalf("expected 3 headers, got %d", len(headers))
+	}
+	for i, h := range headers {
+		full, err := bc.GetBlockByIndex(2 + i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Index != full.Index || h.Hash != full.Hash {
+			t.Errorf("header %d does not match block: %+v vs %s", i, h, full.Hash)
+		}
+		if blockchain.HeaderHash(h) != h.Hash {
+			t.Errorf("header %d hash cannot be recomputed from header fields", i)
+		}
+	}
+}
+
+func TestGetHeadersBadParams(t *testing.T) {
+	bc := newTestChain(t, 1)
+	_, srv := newTestServer(t, bc)
+
+	resp, err := http.Get(srv.URL + "/headers?from=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	var apiErr APIError
+	if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil {
+		t.Fatal(err)
+	}
+	if apiErr.Code != ErrCodeBadRequest {
+		t.Errorf("expected code %s, got %s", ErrCodeBadRequest, apiErr.Code)
+	}
+}
+
+func TestGetChain(t *testing.T) {
+	bc := newTestChain(t, 6)
+	_, srv := newTestServer(t, bc)
+
+	getChain := func(query string) []blockchain.Block {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/chain" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /chain%s: expected 200, got %d", query, resp.StatusCode)
+		}
+		var blocks []blockchain.Block
+		if err := json.NewDecoder(resp.Body).Decode(&blocks); err != nil {
+			t.Fatal(err)
+		}
+		return blocks
+	}
+	assertRange := func(blocks []blockchain.Block, from, n int) {
+		t.Helper()
+		if len(blocks) != n {
+			t.Fatalf("expected %d blocks from %d, got %d", n, from, len(blocks))
+		}
+		for i, b := range blocks {
+			want, _ := bc.GetBlockByIndex(from + i)
+			if b.Index != from+i || b.Hash != want.Hash {
+				t.Errorf("block %d: got index %d hash %s, want %s", i, b.Index, b.Hash, want.Hash)
+			}
+		}
+	}
+
+	assertRange(getChain(""), 0, 7) // 创世区块加6个区块
+	assertRange(getChain("?from=2&limit=3"), 2, 3)
+	assertRange(getChain("?from=5&limit=10"), 5, 2)
+	if blocks := getChain("?from=7"); blocks == nil || len(blocks) != 0 {
+		t.Errorf("from beyond height: expected empty array, got %v", blocks)
+	}
+
+	resp, err := http.Get(srv.URL + "/chain?limit=-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("negative limit: expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestDebugRejections(t *testing.T) {
+	bc := newTestChain(t, 1)
+	_, srv := newTestServer(t, bc)
+
+	// 篡改nonce使哈希与头部不符
+	badHeader := blockchain.MineBlock(bc.GetLatest(), []string{"tx-a"}, 1)
+	badHeader.Nonce++
+	// 难度与本链不一致
+	badDifficulty := blockchain.MineBlock(bc.GetLatest(), []string{"tx-b"}, 2)
+	// 未链接到最新区块
+	genesis, _ := bc.GetBlockByIndex(0)
+	badLink := blockchain.MineBlock(genesis, []string{"tx-c"}, 1)
+
+	want := []blockchain.RejectReason{
+		blockchain.RejectBadHeader,
+		blockchain.RejectBadDifficulty,
+		blockchain.RejectBadLink,
+	}
+	for i, b := range []blockchain.Block{badHeader, badDifficulty, badLink} {
+		err := bc.ValidateAndApplyBlock(b)
+		rerr, ok := err.(*blockchain.BlockRejectError)
+		if !ok {
+			t.Fatalf("block %d: expected *BlockRejectError, got %v", i, err)
+		}
+		if rerr.Reason != want[i] {
+			t.Errorf("block %d: reason %s, want %s", i, rerr.Reason, want[i])
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/debug/rejections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var stats blockchain.RejectionStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Recent) != len(want) {
+		t.Fatalf("expected %d recent rejections, got %d", len(want), len(stats.Recent))
+	}
+	for i, rej := range stats.Recent {
+		if rej.Reason != want[i] {
+			t.Errorf("recent[%d] reason %s, want %s", i, rej.Reason, want[i])
+		}
+		if rej.Message == "" {
+			t.Errorf("recent[%d] has empty message", i)
+		}
+	}
+	for _, r := range want {
+		if stats.Counts[r] != 1 {
+			t.Errorf("count[%s] = %d, want 1", r, stats.Counts[r])
+		}
+	}
+}
+
+// postAuthJSON 携带鉴权令牌POST JSON请求体
+func postAuthJSON(t *testing.T, url, token string, v interface{}) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest("POST", url, bytes.NewReader(mustMarshal(v)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// buildImportChain 从创世区块开始挖出n个区块，每个区块带有coinbase交易，
+// 第2个区块额外花费第1个区块的coinbase输出，返回可导入的链和交易体
+func buildImportChain(t *testing.T, n int) blockchain.ChainImport {
+	t.Helper()
+	imp := blockchain.ChainImport{Blocks: []blockchain.Block{blockchain.NewGenesis()}}
+	var firstCoinbase string
+	for i := 1; i <= n; i++ {
+		cb := blockchain.CoinbaseTx(fmt.Sprintf("import height %d", i), "alice", blockchain.DefaultBlockReward)
+		cbid, _ := blockchain.TxID(cb)
+		txs := []blockchain.UTXOTx{cb}
+		if i == 1 {
+			firstCoinbase = cbid
+		}
+		if i == 2 {
+			txs = append(txs, blockchain.UTXOTx{
+				Version: blockchain.TxVersion,
+				Inputs:  []blockchain.TxInput{{Txid: firstCoinbase, Vout: 0}},
+				Outputs: []blockchain.TxOutput{{Address: "bob", Amount: 6}, {Address: "alice", Amount: 4}},
+			})
+		}
+		var txids []string
+		for _, tx := range txs {
+			id, _ := blockchain.TxID(tx)
+			txids = append(txids, id)
+			imp.Transactions = append(imp.Transactions, tx)
+		}
+		// 交易体只随导入提供，不写入本地存储
+		imp.Blocks = append(imp.Blocks, blockchain.MineBlock(imp.Blocks[len(imp.Blocks)-1], txids, 1))
+	}
+	return imp
+}
+
+func decodeReport(t *testing.T, resp *http.Response) blockchain.ImportReport {
+	t.Helper()
+	defer resp.Body.Close()
+	var report blockchain.ImportReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestChainImportValid(t *testing.T) {
+	bc := newTestChain(t, 0)
+	_, srv := newTestServer(t, bc)
+	imp := buildImportChain(t, 3)
+
+	resp := postAuthJSON(t, srv.URL+"/chain/import", testAuthToken, imp)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	report := decodeReport(t, resp)
+	if !report.Valid || !report.Adopted {
+		t.Fatalf("expected valid adopted chain, got %+v", report)
+	}
+	// 3个coinbase输出，其中第1个被花费并产生2个新输出
+	if report.UTXOs != 4 {
+		t.Errorf("expected 4 derived utxos, got %d", report.UTXOs)
+	}
+	if bc.Height() != 3 || bc.GetLatest().Hash != imp.Blocks[3].Hash {
+		t.Errorf("chain not adopted: height %d", bc.Height())
+	}
+	if _, err := blockchain.GetUTXO(imp.Blocks[2].Transactions[1], 0); err != nil {
+		t.Errorf("spend output missing from utxo set: %v", err)
+	}
+}
+
+func TestChainImportInvalidLink(t *testing.T) {
+	bc := newTestChain(t, 0)
+	_, srv := newTestServer(t, bc)
+	imp := buildImportChain(t, 3)
+	imp.Blocks[2].PrevHash = imp.Blocks[0].Hash
+
+	resp := postAuthJSON(t, srv.URL+"/chain/import", testAuthToken, imp)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", resp.StatusCode)
+	}
+	report := decodeReport(t, resp)
+	if report.Valid || report.Adopted {
+		t.Errorf("invalid chain reported as valid: %+v", report)
+	}
+	if report.FailedBlock == nil || *report.FailedBlock != 2 || report.Error == "" {
+		t.Errorf("expected failure at block 2 with reason, got %+v", report)
+	}
+	if bc.Height() != 0 {
+		t.Errorf("local chain changed: height %d", bc.Height())
+	}
+}
+
+func TestChainImportWorseChain(t *testing.T) {
+	bc := newTestChain(t, 3)
+	_, srv := newTestServer(t, bc)
+	imp := buildImportChain(t, 2)
+
+	resp := postAuthJSON(t, srv.URL+"/chain/import", testAuthToken, imp)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", resp.StatusCode)
+	}
+	report := decodeReport(t, resp)
+	if !report.Valid || report.Adopted {
+		t.Errorf("expected valid but not adopted, got %+v", report)
+	}
+	if bc.Height() != 3 {
+		t.Errorf("local chain changed: height %d", bc.Height())
+	}
+}
+
+func TestChainImportRequiresAuth(t *testing.T) {
+	bc := newTestChain(t, 0)
+	a, srv := newTestServer(t, bc)
+	imp := buildImportChain(t, 1)
+
+	resp := postAuthJSON(t, srv.URL+"/chain/import", "wrong", imp)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with wrong token, got %d", resp.StatusCode)
+	}
+
+	a.AuthToken = ""
+	resp = postAuthJSON(t, srv.URL+"/chain/import", "", imp)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 without configured token, got %d", resp.StatusCode)
+	}
+}
+
+func TestMineTimeout(t *testing.T) {
+	bc := blockchain.NewBlockchain(16, nil)
+	_, srv := newTestServer(t, bc)
+	blockchain.AddToMempool("mine-timeout-tx")
+	defer blockchain.RemoveFromMempool([]string{"mine-timeout-tx"})
+
+	resp := postAuthJSON(t, srv.URL+"/mine?address=miner&timeout=50ms", testAuthToken, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", resp.StatusCode)
+	}
+	if bc.Height() != 0 {
+		t.Errorf("no block should be applied, height %d", bc.Height())
+	}
+}
+
+func TestMineRequiresAuth(t *testing.T) {
+	bc := newTestChain(t, 0)
+	_, srv := newTestServer(t, bc)
+
+	resp := postAuthJSON(t, srv.URL+"/mine?address=miner", "", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", resp.StatusCode)
+	}
+	// 调用者不能指定超过DefaultMineTimeout的超时
+	resp = postAuthJSON(t, srv.URL+"/mine?address=miner&timeout=1h", testAuthToken, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a timeout above the limit, got %d", resp.StatusCode)
+	}
+	if bc.Height() != 0 {
+		t.Errorf("no block should be applied, height %d", bc.Height())
+	}
+}
+
+// seedTimedChain 以目标出块间隔spacing创建区块链，并挖出时间戳间隔为gap秒的n个区块
+func seedTimedChain(t *testing.T, n int, gap, spacing int64) *blockchain.Blockchain {
+	t.Helper()
+	params := blockchain.DefaultConsensusParams()
+	params.Difficulty = 1
+	params.TargetSpacing = spacing
+	bc, err := blockchain.NewBlockchainWithParams(params, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Now().Unix() - int64(n)*gap
+	for i := 0; i < n; i++ {
+		b := blockchain.MineBlock(bc.GetLatest(), []string{testCoinbase(t, fmt.Sprintf("retarget-tx-%d-%d", gap, i))}, 1)
+		b.Timestamp = ts + int64(i)*gap
+		nonce, hash := blockchain.NewProofOfWork(&b, 1).Run()
+		b.Nonce = nonce
+		b.Hash = hex.EncodeToString(hash)
+		if err := bc.ValidateAndApplyBlock(b); err != nil {
+			t.Fatalf("apply block %d: %v", i, err)
+		}
+	}
+	return bc
+}
+
+func TestDifficultyRetarget(t *testing.T) {
+	cases := []struct {
+		name    string
+		gap     int64
+		spacing int64
+		want    int
+	}{
+		// 实际间隔1秒、目标600秒：理论上应提高2级，被限制为1级
+		{"fast blocks clamped to one step", 1, 600, 2},
+		// 实际间隔1000秒、目标10秒：应降低难度，但不低于最小难度
+		{"slow blocks clamped to minimum", 1000, 10, blockchain.MinDifficulty},
+	}
+	for _, c := range cases {
+		bc := seedTimedChain(t, 6, c.gap, c.spacing)
+		_, srv := newTestServer(t, bc)
+
+		resp := postAuthJSON(t, srv.URL+"/difficulty/retarget", "", nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("%s: retarget without token: expected 401, got %d", c.name, resp.StatusCode)
+		}
+		resp = postAuthJSON(t, srv.URL+"/difficulty/retarget", testAuthToken, nil)
+		var res blockchain.RetargetResult
+		err := json.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", c.name, resp.StatusCode)
+		}
+		if res.Projected != c.want || res.TargetSpacing != c.spacing || res.NextRetarget != blockchain.DefaultRetargetWindow {
+			t.Errorf("%s: result %+v, want projected %d at height %d", c.name, res, c.want, blockchain.DefaultRetargetWindow)
+		}
+		// 窗口中途不改变共识难度，否则本节点挖出的区块会被其他节点拒绝
+		if res.OldDifficulty != 1 || res.NewDifficulty != 1 || bc.Difficulty() != 1 {
+			t.Errorf("%s: consensus difficulty changed mid-window: %+v, now %d", c.name, res, bc.Difficulty())
+		}
+	}
+}
+
+func TestUTXORebuild(t *testing.T) {
+	bc := blockchain.NewBlockchain(1, nil, blockchain.GenesisAlloc{Address: "rebuild-alice", Amount: 100})
+	_, srv := newTestServer(t, bc)
+	genesis, _ := bc.GetBlockByIndex(0)
+	allocTxid := genesis.Transactions[0]
+
+	cbid, _ := blockchain.PutTransaction(blockchain.CoinbaseTx("rebuild height 1", "rebuild-miner", 10))
+	spend := blockchain.UTXOTx{
+		Version: blockchain.TxVersion,
+		Inputs:  []blockchain.TxInput{{Txid: allocTxid, Vout: 0}},
+		Outputs: []blockchain.TxOutput{{Address: "rebuild-bob", Amount: 60}, {Address: "rebuild-alice", Amount: 40}},
+	}
+	spendid, _ := blockchain.PutTransaction(spend)
+	b := blockchain.MineBlock(bc.GetLatest(), []string{cbid, spendid}, 1)
+	if err := bc.ValidateAndApplyBlock(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// 破坏UTXO集合：删除有效输出，恢复已花费输出，添加伪造输出
+	blockchain.DeleteUTXO(spendid, 0)
+	blockchain.PutUTXO(allocTxid, 0, blockchain.UTXOEntry{Address: "rebuild-alice", Amount: 100})
+	blockchain.PutUTXO("forged", 0, blockchain.UTXOEntry{Address: "rebuild-mallory", Amount: 1000})
+
+	resp := postAuthJSON(t, srv.URL+"/utxo/rebuild", testAuthToken, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	want := map[blockchain.UTXOKey]blockchain.UTXOEntry{
+		{Txid: cbid, Vout: 0}:    {Address: "rebuild-miner", Amount: 10},
+		{Txid: spendid, Vout: 0}: {Address: "rebuild-bob", Amount: 60},
+		{Txid: spendid, Vout: 1}: {Address: "rebuild-alice", Amount: 40},
+	}
+	got := blockchain.SnapshotUTXOs()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d utxos after rebuild, got %d: %v", len(want), len(got), got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("utxo %s:%d = %+v, want %+v", k.Txid, k.Vout, got[k], v)
+		}
+	}
+}
+
+// submitBlock 向 /block/submit 提交区块并解析结果
+func submitBlock(t *testing.T, url string, b blockchain.Block) (int, BlockSubmitResult) {
+	t.Helper()
+	body, _ := json.Marshal(b)
+	resp, err := http.Post(url+"/block/submit", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var res BlockSubmitResult
+	if resp.StatusCode != http.StatusNotFound {
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, res
+}
+
+func TestBlockSubmit(t *testing.T) {
+	bc := newTestChain(t, 1)
+	a := NewAPI(bc, nil)
+	a.TestMode = true
+	srv := httptest.NewServer(a.Router())
+	defer srv.Close()
+
+	valid := blockchain.MineBlock(bc.GetLatest(), []string{testCoinbase(t, "submit-ok")}, 1)
+	code, res := submitBlock(t, srv.URL, valid)
+	if code != http.StatusOK || !res.Accepted || res.Height != 2 {
+		t.Fatalf("valid block: status %d, result %+v", code, res)
+	}
+
+	// 难度与本链不一致
+	invalid := blockchain.MineBlock(bc.GetLatest(), []string{"submit-bad"}, 2)
+	code, res = submitBlock(t, srv.URL, invalid)
+	if code != http.StatusUnprocessableEntity || res.Accepted || res.Reason != blockchain.RejectBadDifficulty || res.Error == "" {
+		t.Fatalf("invalid block: status %d, result %+v", code, res)
+	}
+	if bc.Height() != 2 {
+		t.Errorf("rejected block changed the chain height to %d", bc.Height())
+	}
+}
+
+func TestBlockSubmitDisabled(t *testing.T) {
+	bc := newTestChain(t, 0)
+	_, srv := newTestServer(t, bc)
+
+	b := blockchain.MineBlock(bc.GetLatest(), []string{"submit-off"}, 1)
+	if code, _ := submitBlock(t, srv.URL, b); code != http.StatusNotFound {
+		t.Fatalf("expected 404 outside test mode, got %d", code)
+	}
+}
+
+func TestGetIdentity(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	node, err := p2p.NewNode(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Host.Close()
+
+	a := NewAPI(newTestChain(t, 0), node)
+	srv := httptest.NewServer(a.Router())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var id p2p.Identity
+	if err := json.NewDecoder(resp.Body).Decode(&id); err != nil {
+		t.Fatal(err)
+	}
+	if id.PeerID != node.Host.ID().String() {
+		t.Fatalf("peer id = %s, want %s", id.PeerID, node.Host.ID())
+	}
+	if len(id.PublicKey) == 0 || len(id.ListenAddrs) == 0 {
+		t.Fatalf("missing public key or listen addrs: %+v", id)
+	}
+	rec, err := p2p.VerifyPeerRecord(id.SignedPeerRecord)
+	if err != nil {
+		t.Fatalf("signed peer record does not verify: %v", err)
+	}
+	if rec.PeerID != node.Host.ID() {
+		t.Fatalf("record peer id = %s, want %s", rec.PeerID, node.Host.ID())
+	}
+
+	// 篡改后的记录必须校验失败
+	tampered := append([]byte(nil), id.SignedPeerRecord...)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := p2p.VerifyPeerRecord(tampered); err == nil {
+		t.Fatal("expected tampered record to fail verification")
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := blockchain.OpenAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+	a, srv := newTestServer(t, newTestChain(t, 0))
+	a.BC.OnTx(audit.LogEvent)
+
+	bob := newAddress(t)
+	tx1, alice := fundedTx(t, 36, bob, 30, 1)
+	tx2, _ := fundedTx(t, 10, newAddress(t), 7, 3)
+	gossiped, _ := fundedTx(t, 12, newAddress(t), 5, 2)
+	valid := []blockchain.UTXOTx{tx1, tx2, gossiped}
+	invalid := []blockchain.UTXOTx{
+		{Version: 99, Outputs: []blockchain.TxOutput{{Address: "bob", Amount: 1}}},
+		{Version: blockchain.TxVersion, Outputs: []blockchain.TxOutput{{Address: "bob", Amount: -1}}},
+	}
+	post := func(tx blockchain.UTXOTx) int {
+		body, _ := json.Marshal(tx)
+		resp, err := http.Post(srv.URL+"/tx", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, tx := range valid[:2] {
+		if code := post(tx); code != http.StatusCreated {
+			t.Fatalf("valid tx rejected with %d", code)
+		}
+	}
+	// 重复提交已在内存池中的交易不再写入审计记录
+	post(tx1)
+	// gossip收到的交易同样记录一次，重复收到不再记录
+	for i := 0; i < 2; i++ {
+		a.handleGossipTx("", &p2p.Message{Type: p2p.MsgTx, Data: mustMarshal(gossiped)})
+	}
+	for _, tx := range invalid {
+		if code := post(tx); code == http.StatusCreated {
+			t.Fatal("invalid tx accepted")
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(valid) {
+		t.Fatalf("expected %d audit lines, got %d: %q", len(valid), len(lines), data)
+	}
+	var rec blockchain.AuditRecord
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	txid, _ := blockchain.TxID(valid[0])
+	if rec.TxID != txid || rec.From != alice.Address || rec.To != bob || rec.Amount != 30 || rec.Timestamp == 0 {
+		t.Fatalf("unexpected audit record %+v", rec)
+	}
+}
+
+func TestGetAddrUTXOs(t *testing.T) {
+	_, srv := newTestServer(t, newTestChain(t, 0))
+	blockchain.PutUTXO("addr-utxo-b", 1, blockchain.UTXOEntry{Address: "utxo-owner", Amount: 40})
+	blockchain.PutUTXO("addr-utxo-a", 0, blockchain.UTXOEntry{Address: "utxo-owner", Amount: 2})
+	blockchain.PutUTXO("addr-utxo-b", 0, blockchain.UTXOEntry{Address: "utxo-owner", Amount: 15})
+	blockchain.PutUTXO("addr-utxo-c", 0, blockchain.UTXOEntry{Address: "someone-else", Amount: 99})
+
+	get := func(query string) []blockchain.SpendableUTXO {
+		resp, err := http.Get(srv.URL + "/addr/utxo-owner/utxos" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		var out []blockchain.SpendableUTXO
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	all := get("")
+	want := []blockchain.SpendableUTXO{
+		{Txid: "addr-utxo-a", Vout: 0, Amount: 2},
+		{Txid: "addr-utxo-b", Vout: 0, Amount: 15},
+		{Txid: "addr-utxo-b", Vout: 1, Amount: 40},
+	}
+	if fmt.Sprint(all) != fmt.Sprint(want) {
+		t.Fatalf("utxos = %v, want %v", all, want)
+	}
+	if got := get("?min=10"); fmt.Sprint(got) != fmt.Sprint(want[1:]) {
+		t.Fatalf("utxos with min=10 = %v, want %v", got, want[1:])
+	}
+
+	resp, err := http.Get(srv.URL + "/addr/utxo-owner/utxos?min=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad min, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	_, srv := newTestServer(t, newTestChain(t, 0))
+	seed := []struct {
+		txid    string
+		vout    int
+		address string
+		amount  int
+	}{
+		{"bal-a", 0, "bal-alice", 25},
+		{"bal-a", 1, "bal-bob", 4},
+		{"bal-b", 0, "bal-alice", 10},
+		{"bal-b", 1, "bal-bob", 6},
+	}
+	for _, u := range seed {
+		blockchain.PutUTXO(u.txid, u.vout, blockchain.UTXOEntry{Address: u.address, Amount: u.amount})
+	}
+	t.Cleanup(func() {
+		for _, u := range seed {
+			blockchain.DeleteUTXO(u.txid, u.vout)
+		}
+	})
+
+	for address, want := range map[string]int{"bal-alice": 35, "bal-bob": 10, "bal-nobody": 0} {
+		resp, err := http.Get(srv.URL + "/balance/" + address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out Balance
+		err = json.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || out.Address != address || out.Balance != want {
+			t.Errorf("%s: status %d, body %+v; want balance %d", address, resp.StatusCode, out, want)
+		}
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	bc := newTestChain(t, 4) // 4个区块，每个区块1笔交易
+	_, srv := newTestServer(t, bc)
+	base := blockchain.UTXOTotal()
+	blockchain.PutUTXO("stats-a", 0, blockchain.UTXOEntry{Address: "stats-alice", Amount: 30})
+	blockchain.PutUTXO("stats-a", 1, blockchain.UTXOEntry{Address: "stats-bob", Amount: 12})
+	blockchain.DeleteUTXO("stats-a", 1)
+	blockchain.PutUTXO("stats-b", 0, blockchain.UTXOEntry{Address: "stats-bob", Amount: 8})
+	t.Cleanup(func() {
+		blockchain.DeleteUTXO("stats-a", 0)
+		blockchain.DeleteUTXO("stats-b", 0)
+	})
+
+	resp, err := http.Get(srv.URL + "/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out blockchain.ChainStats
+	err = json.NewDecoder(resp.Body).Decode(&out)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := blockchain.ChainStats{Blocks: 5, Transactions: 4, Circulation: base + 38, AvgTxsPerBlock: 0.8, Difficulty: 1}
+	if resp.StatusCode != http.StatusOK || out != want {
+		t.Fatalf("status %d, stats %+v; want %+v", resp.StatusCode, out, want)
+	}
+}
+
+func TestGetMempoolOrdered(t *testing.T) {
+	_, srv := newTestServer(t, newTestChain(t, 0))
+	bob, err := wallet.NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, _ := fundedTx(t, 100, bob.Address, 60, 1)
+	parentID, err := blockchain.PutTransaction(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 子交易花费父交易的输出，手续费更高且先进入内存池
+	childW, err := wallet.BuildTransaction(bob, newAddress(t), 40, 10, []wallet.UTXO{{Txid: parentID, Vout: 0, Amount: 60}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	childID, err := blockchain.PutTransaction(blockchain.TxFromWallet(childW))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockchain.AddToMempool(childID)
+	blockchain.AddToMempool(parentID)
+	t.Cleanup(func() { blockchain.RemoveFromMempool([]string{childID, parentID}) })
+
+	resp, err := http.Get(srv.URL + "/mempool/ordered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out []blockchain.OrderedMempoolTx
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	pos := make(map[string]int)
+	for i, e := range out {
+		pos[e.Txid] = i
+	}
+	pi, okP := pos[parentID]
+	ci, okC := pos[childID]
+	if !okP || !okC || pi > ci {
+		t.Fatalf("parent at %d (%v), child at %d (%v); want parent before child", pi, okP, ci, okC)
+	}
+	if out[ci].Fee != 10 || out[ci].Tx == nil {
+		t.Errorf("child entry = %+v, want fee 10 with body", out[ci])
+	}
+}
+
+func TestDeleteMempoolTx(t *testing.T) {
+	_, srv := newTestServer(t, newTestChain(t, 0))
+	tx, _ := fundedTx(t, 5, newAddress(t), 3, 1)
+	resp, err := http.Post(srv.URL+"/tx", "application/json", bytes.NewReader(mustMarshal(tx)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	txid, _ := blockchain.TxID(tx)
+
+	inMempool := func() bool {
+		resp, err := http.Get(srv.URL + "/mempool")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var entries []blockchain.MempoolEntry
+		if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			if e.Txid == txid {
+				return true
+			}
+		}
+		return false
+	}
+	del := func(token string) int {
+		req, _ := http.NewRequest("DELETE", srv.URL+"/mempool/"+txid, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if !inMempool() {
+		t.Fatal("submitted tx not in mempool")
+	}
+	if code := del(""); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", code)
+	}
+	if code := del(testAuthToken); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	if inMempool() {
+		t.Fatal("evicted tx still in mempool")
+	}
+	if _, err := blockchain.GetTransaction(txid); err == nil {
+		t.Fatal("evicted tx still in tx store")
+	}
+	if code := del(testAuthToken); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing tx, got %d", code)
+	}
+}
+
+func TestReadyzReportsMiningStall(t *testing.T) {
+	bc := newTestChain(t, 0)
+	a, srv := newTestServer(t, bc)
+	a.Watchdog = blockchain.NewMiningWatchdog(bc)
+	a.Watchdog.Timeout = time.Minute
+	blockchain.AddToMempool("readyz-tx")
+	defer blockchain.RemoveFromMempool([]string{"readyz-tx"})
+
+	readyz := func() (int, Readiness) {
+		resp, err := http.Get(srv.URL + "/readyz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var r Readiness
+		if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, r
+	}
+
+	now := time.Now()
+	a.Watchdog.Check(now)
+	if code, r := readyz(); code != http.StatusOK || !r.Ready {
+		t.Fatalf("expected ready, got %d %+v", code, r)
+	}
+	a.Watchdog.Check(now.Add(2 * time.Minute))
+	if code, r := readyz(); code != http.StatusServiceUnavailable || r.Ready || r.Reason == "" {
+		t.Fatalf("expected 503 after stall, got %d %+v", code, r)
+	}
+}
+
+func TestPostTxErrorCodes(t *testing.T) {
+	_, srv := newTestServer(t, newTestChain(t, 0))
+
+	negative, _ := fundedTx(t, 10, newAddress(t), 5, 1)
+	negative.Outputs[0].Amount = -5
+	duplicate, _ := fundedTx(t, 10, newAddress(t), 5, 1)
+	duplicate.Inputs = append(duplicate.Inputs, duplicate.Inputs[0])
+	missing, _ := fundedTx(t, 10, newAddress(t), 5, 1)
+	missing.Inputs[0].Txid = "no-such-output"
+	badSig, _ := fundedTx(t, 10, newAddress(t), 5, 1)
+	badSig.Outputs[0].Amount = 6 // 签名后修改输出
+	noFee, _ := fundedTx(t, 10, newAddress(t), 10, 0)
+	tooLarge, _ := fundedTx(t, 10, newAddress(t), 5, 1)
+	for len(mustMarshal(tooLarge)) <= blockchain.DefaultMaxTxSize {
+		for i := 0; i < 100; i++ {
+			tooLarge.Outputs = append(tooLarge.Outputs, blockchain.TxOutput{Address: "dust", Amount: 0})
+		}
+	}
+
+	// 格式错误的接收地址：非十六进制名字、长度不对、非十六进制字符、前缀错误、不在曲线上
+	valid := newAddress(t)
+	offCurve, _ := hex.DecodeString(valid)
+	offCurve[len(offCurve)-1] ^= 1
+	badAddrs := []string{"bob", valid[:128], "zz" + valid[2:], "02" + valid[2:], hex.EncodeToString(offCurve)}
+
+	type errCase struct {
+		name string
+		body []byte
+		code string
+	}
+	cases := []errCase{
+		{"malformed json", []byte("{"), ErrCodeBadRequest},
+		{"bad structure", mustMarshal(blockchain.UTXOTx{Version: 99}), ErrCodeTxBadStructure},
+		{"negative amount", mustMarshal(negative), ErrCodeTxNegativeAmount},
+		{"duplicate input", mustMarshal(duplicate), ErrCodeTxDuplicateInput},
+		{"missing input", mustMarshal(missing), ErrCodeTxMissingInput},
+		{"bad signature", mustMarshal(badSig), ErrCodeTxBadSignature},
+		{"insufficient fee", mustMarshal(noFee), ErrCodeTxInsufficientFee},
+		{"too large", mustMarshal(tooLarge), ErrCodeTxTooLarge},
+	}
+	for i, addr := range badAddrs {
+		tx, _ := fundedTx(t, 10, addr, 5, 1)
+		cases = append(cases, errCase{fmt.Sprintf("bad address %d", i), mustMarshal(tx), ErrCodeTxBadAddress})
+	}
+	for _, c := range cases {
+		resp, err := http.Post(srv.URL+"/tx", "application/json", bytes.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var apiErr APIError
+		err = json.NewDecoder(resp.Body).Decode(&apiErr)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: decode error response: %v", c.name, err)
+		}
+		if resp.StatusCode != http.StatusBadRequest || apiErr.Code != c.code {
+			t.Errorf("%s: got %d %q (%s), want 400 %q", c.name, resp.StatusCode, apiErr.Code, apiErr.Message, c.code)
+		}
+	}
+
+	ok, _ := fundedTx(t, 10, newAddress(t), 5, 1)
+	resp, err := http.Post(srv.URL+"/tx", "application/json", bytes.NewReader(mustMarshal(ok)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("valid tx rejected with %d", resp.StatusCode)
+	}
+	txid, _ := blockchain.TxID(ok)
+	blockchain.EvictFromMempool(txid)
+}
+
+func TestGetHeadersGzip(t *testing.T) {
+	bc := newTestChain(t, 20)
+	_, srv := newTestServer(t, bc)
+
+	get := func(path, acceptEncoding string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		// 显式设置Accept-Encoding后Transport不会自动解压，可以检查原始响应
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	plain := get("/headers", "")
+	if enc := plain.Header.Get("Content-Encoding"); enc != "" {
+		t.Fatalf("uncompressed request got Content-Encoding %q", enc)
+	}
+	var want []blockchain.BlockHeader
+	if err := json.NewDecoder(plain.Body).Decode(&want); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := get("/headers", "gzip")
+	if enc := resp.Header.Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("expected gzip response, got Content-Encoding %q", enc)
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []blockchain.BlockHeader
+	if err := json.NewDecoder(zr).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) || len(got) != 21 {
+		t.Fatalf("gzip headers differ from uncompressed response (%d vs %d headers)", len(got), len(want))
+	}
+
+	// 小响应和q=0时不压缩
+	if enc := get("/headers?count=1", "gzip").Header.Get("Content-Encoding"); enc != "" {
+		t.Errorf("small response compressed with %q", enc)
+	}
+	if enc := get("/headers", "gzip;q=0, identity").Header.Get("Content-Encoding"); enc != "" {
+		t.Errorf("gzip;q=0 response compressed with %q", enc)
+	}
+}
+
+func TestGetTxFirstSeen(t *testing.T) {
+	_, srv := newTestServer(t, newTestChain(t, 0))
+	tx, _ := fundedTx(t, 5, newAddress(t), 3, 1)
+	before := time.Now()
+	resp, err := http.Post(srv.URL+"/tx", "application/json", bytes.NewReader(mustMarshal(tx)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	txid, _ := blockchain.TxID(tx)
+	t.Cleanup(func() { blockchain.EvictFromMempool(txid) })
+
+	resp, err = http.Get(srv.URL + "/tx/" + txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var st TxStatus
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Status != TxStatusPending || st.FirstSeen == nil || st.FirstSeen.Before(before.Add(-time.Second)) {
+		t.Fatalf("pending tx status = %+v, want pending with first_seen", st)
+	}
+
+	resp, err = http.Get(srv.URL + "/tx/unknown-txid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown tx, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetAddrBalanceAtHeight(t *testing.T) {
+	bc := blockchain.NewBlockchain(1, nil, blockchain.GenesisAlloc{Address: "hist-alice", Amount: 100})
+	spend := blockchain.UTXOTx{Version: blockchain.TxVersion,
+		Inputs:  []blockchain.TxInput{{Txid: bc.GetLatest().Transactions[0], Vout: 0}},
+		Outputs: []blockchain.TxOutput{{Address: "hist-bob", Amount: 40}, {Address: "hist-alice", Amount: 59}},
+	}
+	txid, err := blockchain.PutTransaction(spend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, txs := range [][]string{nil, {txid}, nil} {
+		if err := bc.ValidateAndApplyBlock(blockchain.MineBlock(bc.GetLatest(), txs, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, srv := newTestServer(t, bc)
+
+	get := func(path string) (int, AddrBalance) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out AddrBalance
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	for _, c := range []struct {
+		path   string
+		height int
+		want   int
+	}{
+		{"/addr/hist-alice/balance?height=1", 1, 100},
+		{"/addr/hist-bob/balance?height=1", 1, 0},
+		{"/addr/hist-alice/balance?height=2", 2, 59},
+		{"/addr/hist-bob/balance?height=2", 2, 40},
+		{"/addr/hist-bob/balance", 3, 40},
+	} {
+		code, got := get(c.path)
+		if code != http.StatusOK || got.Height != c.height || got.Balance != c.want {
+			t.Errorf("%s: %d %+v, want height %d balance %d", c.path, code, got, c.height, c.want)
+		}
+	}
+	for _, path := range []string{"/addr/hist-bob/balance?height=4", "/addr/hist-bob/balance?height=-1", "/addr/hist-bob/balance?height=x"} {
+		if code, _ := get(path); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, code)
+		}
+	}
+}
+
+// defaultGenesisHash 无创世分配时的创世区块哈希，创世区块构造改变时该值随之改变
+const defaultGenesisHash = "4a7b2332354c3c5c4ae306cb1e2870c998b4e8499532e3843a0d4ae9aac577fd"
+
+func TestGetGenesis(t *testing.T) {
+	_, srv := newTestServer(t, newTestChain(t, 3))
+	resp, err := http.Get(srv.URL + "/genesis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var genesis blockchain.Block
+	if err := json.NewDecoder(resp.Body).Decode(&genesis); err != nil {
+		t.Fatal(err)
+	}
+	if genesis.Index != 0 || genesis.Hash != defaultGenesisHash {
+		t.Fatalf("genesis = index %d hash %s, want index 0 hash %s", genesis.Index, genesis.Hash, defaultGenesisHash)
+	}
+	if genesis.Hash != blockchain.NewGenesis().Hash || !genesis.ValidateBasic() {
+		t.Fatal("genesis does not match a locally constructed genesis block")
+	}
+}
+
+func TestPostTxAlreadyConfirmed(t *testing.T) {
+	a, srv := newTestServer(t, newTestChain(t, 0))
+	tx, _ := fundedTx(t, 10, newAddress(t), 5, 1)
+	post := func() (int, APIError) {
+		resp, err := http.Post(srv.URL+"/tx", "application/json", bytes.NewReader(mustMarshal(tx)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var apiErr APIError
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return resp.StatusCode, apiErr
+	}
+	if code, _ := post(); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	b, err := a.BC.MinePending(context.Background(), "miner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.BC.ValidateAndApplyBlock(b); err != nil {
+		t.Fatal(err)
+	}
+
+	code, apiErr := post()
+	if code != http.StatusBadRequest || apiErr.Code != ErrCodeTxConfirmed {
+		t.Fatalf("resubmitted confirmed tx: got %d %q (%s), want 400 %q", code, apiErr.Code, apiErr.Message, ErrCodeTxConfirmed)
+	}
+	txid, _ := blockchain.TxID(tx)
+	for _, id := range blockchain.ListMempool() {
+		if id == txid {
+			t.Fatal("confirmed tx re-entered the mempool")
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	a := NewAPI(newTestChain(t, 0), nil)
+	var buf bytes.Buffer
+	a.AccessLog = slog.New(slog.NewJSONHandler(&buf, nil))
+	router := a.Router()
+
+	for _, c := range []struct {
+		path   string
+		route  string
+		status int
+	}{
+		{"/genesis", "/genesis", http.StatusOK},
+		{"/tx/unknown", "/tx/{txid}", http.StatusNotFound},
+	} {
+		buf.Reset()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.path, nil))
+
+		var entry struct {
+			Level   string `json:"level"`
+			Method  string `json:"method"`
+			Path    string `json:"path"`
+			Route   string `json:"route"`
+			Status  int    `json:"status"`
+			Size    int    `json:"size"`
+			Latency int64  `json:"latency"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: no access log entry: %v (%q)", c.path, err, buf.String())
+		}
+		if entry.Level != "INFO" || entry.Method != http.MethodGet || entry.Path != c.path || entry.Route != c.route {
+			t.Errorf("%s: unexpected entry %s", c.path, buf.Bytes())
+		}
+		if entry.Status != c.status || entry.Status != rec.Code {
+			t.Errorf("%s: logged status %d, response %d, want %d", c.path, entry.Status, rec.Code, c.status)
+		}
+		if entry.Size != rec.Body.Len() || entry.Latency <= 0 {
+			t.Errorf("%s: size %d (body %d), latency %d", c.path, entry.Size, rec.Body.Len(), entry.Latency)
+		}
+	}
+
+	// 关闭访问日志后不再记录
+	buf.Reset()
+	a.AccessLog = nil
+	a.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/genesis", nil))
+	if buf.Len() != 0 {
+		t.Errorf("access log disabled but got %q", buf.String())
+	}
+}
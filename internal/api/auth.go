@@ -0,0 +1,24 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// requireAuth 管理端点的鉴权中间件
+// 请求须携带 "Authorization: Bearer <AuthToken>"；未配置AuthToken时管理端点一律禁用
+func (api *API) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if api.AuthToken == "" {
+			writeError(w, http.StatusForbidden, ErrCodeForbidden, "admin endpoints disabled: no auth token configured")
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(api.AuthToken)) != 1 {
+			writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "invalid or missing bearer token")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gzipMinSize 响应体不少于该字节数时才压缩，小响应保持未压缩
+const gzipMinSize = 1024
+
+// acceptsGzip 判断请求的Accept-Encoding是否允许gzip（q=0表示明确拒绝）
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.TrimSpace(name)
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(v, 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// writeJSONCompressed 以JSON格式写出成功响应，客户端接受gzip且响应体较大时压缩传输
+func writeJSONCompressed(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(v)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept-Encoding")
+	if body.Len() < gzipMinSize || !acceptsGzip(r) {
+		w.WriteHeader(status)
+		w.Write(body.Bytes())
+		return
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	w.WriteHeader(status)
+	zw := gzip.NewWriter(w)
+	zw.Write(body.Bytes())
+	zw.Close()
+}
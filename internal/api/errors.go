@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mini_chain/internal/blockchain"
+)
+
+// APIError 统一的JSON错误响应结构
+// Code 为机器可读的错误码，Message 为人类可读的描述
+type APIError struct {
+	Code    string `json:"code"`    // 错误码
+	Message string `json:"message"` // 错误描述
+}
+
+// 通用错误码
+const (
+	ErrCodeBadRequest = "bad_request" // 请求参数不合法
+	ErrCodeNotFound   = "not_found"   // 资源不存在
+	ErrCodeInternal   = "internal"    // 服务器内部错误
+
+	ErrCodeUnauthorized = "unauthorized" // 缺少或错误的鉴权令牌
+	ErrCodeForbidden    = "forbidden"    // 端点未启用或无权访问
+	ErrCodeConflict     = "conflict"     // 与当前状态冲突
+	ErrCodeTimeout      = "timeout"      // 处理超时
+	ErrCodeUnavailable  = "unavailable"  // 依赖的服务未启用
+)
+
+// 交易校验错误码（POST /tx）
+const (
+	ErrCodeTxBadStructure    = "tx_bad_structure"     // 交易结构无效
+	ErrCodeTxNegativeAmount  = "tx_negative_amount"   // 输出金额为负数
+	ErrCodeTxDuplicateInput  = "tx_duplicate_input"   // 多个输入引用同一个输出
+	ErrCodeTxMissingInput    = "tx_missing_input"     // 输入引用的输出不存在或已花费
+	ErrCodeTxBadSignature    = "tx_bad_signature"     // 输入签名无效
+	ErrCodeTxInsufficientFee = "tx_insufficient_fee"  // 手续费不足
+	ErrCodeTxTooLarge        = "tx_too_large"         // 交易超过大小上限
+	ErrCodeTxConfirmed       = "tx_already_confirmed" // 交易已被打包
+	ErrCodeTxBadAddress      = "tx_bad_address"       // 输出地址格式无效
+	ErrCodeTxMempoolFull     = "tx_mempool_full"      // 内存池已满且手续费不足以替换池中交易
+)
+
+// txErrorCodes 交易校验错误到错误码的映射，按顺序匹配（更具体的错误在前）
+var txErrorCodes = []struct {
+	err  error
+	code string
+}{
+	{blockchain.ErrTxTooLarge, ErrCodeTxTooLarge},
+	{blockchain.ErrTxAlreadyConfirmed, ErrCodeTxConfirmed},
+	{blockchain.ErrBadAddress, ErrCodeTxBadAddress},
+	{blockchain.ErrNegativeAmount, ErrCodeTxNegativeAmount},
+	{blockchain.ErrDuplicateInput, ErrCodeTxDuplicateInput},
+	{blockchain.ErrMissingInput, ErrCodeTxMissingInput},
+	{blockchain.ErrBadSignature, ErrCodeTxBadSignature},
+	{blockchain.ErrInsufficientFee, ErrCodeTxInsufficientFee},
+	{blockchain.ErrMempoolFull, ErrCodeTxMempoolFull},
+	{blockchain.ErrBadTxStructure, ErrCodeTxBadStructure},
+}
+
+// txErrorCode 返回交易校验错误对应的错误码，未知错误返回bad_request
+func txErrorCode(err error) string {
+	for _, m := range txErrorCodes {
+		if errors.Is(err, m.err) {
+			return m.code
+		}
+	}
+	return ErrCodeBadRequest
+}
+
+// writeError 以JSON格式写出错误响应
+// w: HTTP响应写入器
+// status: HTTP状态码
+// code: 错误码
+// msg: 错误描述
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(APIError{Code: code, Message: msg})
+}
+
+// writeJSON 以JSON格式写出成功响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
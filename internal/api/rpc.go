@@ -0,0 +1,230 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"mini_chain/internal/blockchain"
+)
+
+// JSON-RPC 2.0 标准错误码
+const (
+	RPCParseError     = -32700 // 请求体不是合法JSON
+	RPCInvalidRequest = -32600 // 请求对象不合法
+	RPCMethodNotFound = -32601 // 方法不存在
+	RPCInvalidParams  = -32602 // 参数不合法
+	RPCInternalError  = -32603 // 内部错误
+	RPCNotFound       = -32000 // 请求的区块或交易不存在（服务端自定义）
+)
+
+// 交易状态
+const (
+	TxStatusConfirmed = "confirmed" // 已被主链区块包含
+	TxStatusPending   = "pending"   // 位于内存池中
+	TxStatusUnknown   = "unknown"   // 节点未见过该交易
+)
+
+// RPCRequest JSON-RPC 2.0 请求对象
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"` // 缺省时为通知，不返回响应
+}
+
+// RPCResponse JSON-RPC 2.0 响应对象，Result 和 Error 二者只出现其一
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// RPCError JSON-RPC 2.0 错误对象
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// TxStatus tx_status 方法的返回值
+type TxStatus struct {
+	Txid       string     `json:"txid"`
+	Status     string     `json:"status"`
+	BlockHash  string     `json:"block_hash,omitempty"`
+	BlockIndex int        `json:"block_index,omitempty"`
+	FirstSeen  *time.Time `json:"first_seen,omitempty"` // 待处理交易首次被本节点收到的时间
+}
+
+// rpcMethod RPC方法处理函数
+type rpcMethod func(api *API, params json.RawMessage) (interface{}, *RPCError)
+
+// rpcMethods 支持的RPC方法表
+var rpcMethods = map[string]rpcMethod{
+	"chain_height":   rpcChainHeight,
+	"chain_getBlock": rpcChainGetBlock,
+	"tx_send":        rpcTxSend,
+	"tx_status":      rpcTxStatus,
+}
+
+// POST /rpc 处理JSON-RPC 2.0请求，支持单个请求和批量请求
+func (api *API) PostRPC(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusOK, rpcErrorResponse(nil, RPCParseError, "parse error"))
+		return
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		resp := api.handleRPC(body)
+		if resp == nil {
+			// 通知不需要响应
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	// 批量请求
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		writeJSON(w, http.StatusOK, rpcErrorResponse(nil, RPCParseError, "parse error"))
+		return
+	}
+	if len(batch) == 0 {
+		writeJSON(w, http.StatusOK, rpcErrorResponse(nil, RPCInvalidRequest, "empty batch"))
+		return
+	}
+	responses := make([]*RPCResponse, 0, len(batch))
+	for _, raw := range batch {
+		if resp := api.handleRPC(raw); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses)
+}
+
+// handleRPC 处理单个RPC请求，通知返回nil
+func (api *API) handleRPC(raw json.RawMessage) *RPCResponse {
+	var req RPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcErrorResponse(req.ID, RPCInvalidRequest, "invalid request")
+	}
+
+	method, ok := rpcMethods[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil
+		}
+		return rpcErrorResponse(req.ID, RPCMethodNotFound, "method not found: "+req.Method)
+	}
+
+	result, rpcErr := method(api, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if rpcErr != nil {
+		return &RPCResponse{JSONRPC: "2.0", Error: rpcErr, ID: req.ID}
+	}
+	return &RPCResponse{JSONRPC: "2.0", Result: result, ID: req.ID}
+}
+
+// rpcErrorResponse 构造错误响应，id未知时为null
+func rpcErrorResponse(id json.RawMessage, code int, msg string) *RPCResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &RPCResponse{JSONRPC: "2.0", Error: &RPCError{Code: code, Message: msg}, ID: id}
+}
+
+// firstParam 从位置参数数组中取出第一个参数
+func firstParam(params json.RawMessage) (json.RawMessage, *RPCError) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(params, &list); err != nil || len(list) != 1 {
+		return nil, &RPCError{Code: RPCInvalidParams, Message: "expected exactly one positional param"}
+	}
+	return list[0], nil
+}
+
+// chain_height 返回当前链高
+func rpcChainHeight(api *API, params json.RawMessage) (interface{}, *RPCError) {
+	return api.BC.Height(), nil
+}
+
+// chain_getBlock [index|hash] 按高度或哈希返回区块
+func rpcChainGetBlock(api *API, params json.RawMessage) (interface{}, *RPCError) {
+	p, rpcErr := firstParam(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	var (
+		b   blockchain.Block
+		err error
+	)
+	var index int
+	var hash string
+	if json.Unmarshal(p, &index) == nil {
+		b, err = api.BC.GetBlockByIndex(index)
+	} else if json.Unmarshal(p, &hash) == nil {
+		b, err = api.BC.GetBlockByHash(hash)
+	} else {
+		return nil, &RPCError{Code: RPCInvalidParams, Message: "param must be a block index or hash"}
+	}
+	if err != nil {
+		return nil, &RPCError{Code: RPCNotFound, Message: err.Error()}
+	}
+	return b, nil
+}
+
+// tx_send [tx] 提交交易，返回交易ID
+func rpcTxSend(api *API, params json.RawMessage) (interface{}, *RPCError) {
+	p, rpcErr := firstParam(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	var tx blockchain.UTXOTx
+	if err := json.Unmarshal(p, &tx); err != nil {
+		return nil, &RPCError{Code: RPCInvalidParams, Message: err.Error()}
+	}
+	txid, err := api.submitTx(tx)
+	if err != nil {
+		return nil, &RPCError{Code: RPCInvalidParams, Message: err.Error()}
+	}
+	return txid, nil
+}
+
+// tx_status [txid] 返回交易状态：已确认、待处理或未知
+func rpcTxStatus(api *API, params json.RawMessage) (interface{}, *RPCError) {
+	p, rpcErr := firstParam(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	var txid string
+	if err := json.Unmarshal(p, &txid); err != nil || txid == "" {
+		return nil, &RPCError{Code: RPCInvalidParams, Message: "param must be a txid string"}
+	}
+	return api.txStatus(txid), nil
+}
+
+// txStatus 查询交易状态（tx_status 和 GET /tx/{txid} 共用）
+func (api *API) txStatus(txid string) TxStatus {
+	if b, ok := api.BC.FindTxBlock(txid); ok {
+		return TxStatus{Txid: txid, Status: TxStatusConfirmed, BlockHash: b.Hash, BlockIndex: b.Index}
+	}
+	if seen, ok := blockchain.MempoolFirstSeen(txid); ok {
+		return TxStatus{Txid: txid, Status: TxStatusPending, FirstSeen: &seen}
+	}
+	return TxStatus{Txid: txid, Status: TxStatusUnknown}
+}
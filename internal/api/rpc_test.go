@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"mini_chain/internal/blockchain"
+)
+
+// rpcCall 用于解析批量响应中的单个结果
+type rpcCall struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *RPCError       `json:"error"`
+	ID      int             `json:"id"`
+}
+
+func TestRPCBatch(t *testing.T) {
+	bc := newTestChain(t, 3)
+	_, srv := newTestServer(t, bc)
+
+	tx, _ := fundedTx(t, 8, newAddress(t), 7, 1)
+	txid, err := blockchain.TxID(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { blockchain.RemoveFromMempool([]string{txid}) })
+	txJSON, _ := json.Marshal(tx)
+	confirmed, _ := bc.GetBlockByIndex(2)
+
+	body := `[
+		{"jsonrpc":"2.0","id":1,"method":"chain_height"},
+		{"jsonrpc":"2.0","id":2,"method":"chain_getBlock","params":[2]},
+		{"jsonrpc":"2.0","id":3,"method":"tx_status","params":["` + confirmed.Transactions[0] + `"]},
+		{"jsonrpc":"2.0","id":4,"method":"tx_send","params":[` + string(txJSON) + `]},
+		{"jsonrpc":"2.0","id":5,"method":"tx_status","params":["` + txid + `"]},
+		{"jsonrpc":"2.0","id":6,"method":"no_such_method"},
+		{"jsonrpc":"2.0","id":7,"method":"chain_getBlock","params":[99]},
+		{"jsonrpc":"2.0","method":"chain_height"}
+	]`
+	resp, err := http.Post(srv.URL+"/rpc", "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var calls []rpcCall
+	if err := json.NewDecoder(resp.Body).Decode(&calls); err != nil {
+		t.Fatal(err)
+	}
+	// 通知（无id）不产生响应
+	if len(calls) != 7 {
+		t.Fatalf("expected 7 responses, got %d", len(calls))
+	}
+	byID := make(map[int]rpcCall)
+	for _, c := range calls {
+		if c.JSONRPC != "2.0" {
+			t.Errorf("response %d: jsonrpc = %q", c.ID, c.JSONRPC)
+		}
+		byID[c.ID] = c
+	}
+
+	var height int
+	if err := json.Unmarshal(byID[1].Result, &height); err != nil || height != 3 {
+		t.Errorf("chain_height = %s, want 3", byID[1].Result)
+	}
+
+	var b blockchain.Block
+	if err := json.Unmarshal(byID[2].Result, &b); err != nil || b.Index != 2 {
+		t.Errorf("chain_getBlock returned %s", byID[2].Result)
+	}
+
+	var status TxStatus
+	if err := json.Unmarshal(byID[3].Result, &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != TxStatusConfirmed || status.BlockIndex != 2 {
+		t.Errorf("block 2 tx status = %+v, want confirmed in block 2", status)
+	}
+
+	var sentID string
+	if err := json.Unmarshal(byID[4].Result, &sentID); err != nil || sentID != txid {
+		t.Errorf("tx_send = %s, want %q", byID[4].Result, txid)
+	}
+	if err := json.Unmarshal(byID[5].Result, &status); err != nil || status.Status != TxStatusPending {
+		t.Errorf("sent tx status = %s, want pending", byID[5].Result)
+	}
+
+	if e := byID[6].Error; e == nil || e.Code != RPCMethodNotFound {
+		t.Errorf("unknown method error = %+v, want %d", e, RPCMethodNotFound)
+	}
+	if e := byID[7].Error; e == nil || e.Code != RPCNotFound {
+		t.Errorf("missing block error = %+v, want %d", e, RPCNotFound)
+	}
+}
+
+func TestRPCParseError(t *testing.T) {
+	bc := newTestChain(t, 0)
+	_, srv := newTestServer(t, bc)
+
+	resp, err := http.Post(srv.URL+"/rpc", "application/json", bytes.NewBufferString("{not json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var r rpcCall
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Error == nil || r.Error.Code != RPCParseError {
+		t.Fatalf("expected parse error, got %+v", r.Error)
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+// internal/api/snapshot.go
+// 周期性状态快照
+// 按固定间隔把节点状态（与 GET /status 相同的计算）作为一行JSON追加写入文件，供离线分析
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// DefaultSnapshotInterval 状态快照的默认间隔
+const DefaultSnapshotInterval = time.Minute
+
+// StatusSnapshot 快照文件中的一行
+type StatusSnapshot struct {
+	TimestampMs int64 `json:"timestamp_ms"` // 快照时间（Unix毫秒）
+	NodeStatus
+}
+
+// Snapshotter 定期把节点状态追加写入快照文件
+type Snapshotter struct {
+	API      *API          // 计算状态的API实例
+	Path     string        // 快照文件路径
+	Interval time.Duration // 快照间隔
+}
+
+// NewSnapshotter 使用默认间隔创建快照器
+func NewSnapshotter(api *API, path string) *Snapshotter {
+	return &Snapshotter{API: api, Path: path, Interval: DefaultSnapshotInterval}
+}
+
+// Run 每隔Interval写入一次快照，直到ctx取消；写入失败只记录日志
+func (s *Snapshotter) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultSnapshotInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.WriteSnapshot(now); err != nil {
+				log.Println("snapshot error:", err)
+			}
+		}
+	}
+}
+
+// WriteSnapshot 计算当前状态并作为一行JSON追加写入快照文件
+func (s *Snapshotter) WriteSnapshot(now time.Time) error {
+	line, err := json.Marshal(StatusSnapshot{TimestampMs: now.UnixMilli(), NodeStatus: s.API.Status()})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("open snapshot file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mini_chain/internal/blockchain"
+)
+
+func TestSnapshotter(t *testing.T) {
+	bc := newTestChain(t, 2)
+	a, _ := newTestServer(t, bc)
+	blockchain.AddToMempool("snapshot-tx")
+	defer blockchain.RemoveFromMempool([]string{"snapshot-tx"})
+	wantMempool := len(blockchain.ListMempool())
+
+	path := filepath.Join(t.TempDir(), "snapshots.jsonl")
+	s := NewSnapshotter(a, path)
+	s.Interval = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	var snaps []StatusSnapshot
+	deadline := time.Now().Add(5 * time.Second)
+	for len(snaps) < 3 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		snaps = readSnapshots(t, path)
+	}
+	cancel()
+	<-done
+	if len(snaps) < 3 {
+		t.Fatalf("expected at least 3 snapshots, got %d", len(snaps))
+	}
+
+	for i, snap := range snaps {
+		if snap.Height != 2 || snap.LatestHash != bc.GetLatest().Hash || snap.Peers != 0 || snap.MempoolSize != wantMempool {
+			t.Fatalf("snapshot %d has wrong values: %+v", i, snap)
+		}
+		if i == 0 {
+			continue
+		}
+		// 相邻快照间隔约为配置的Interval（ticker可能略有抖动）
+		if gap := snap.TimestampMs - snaps[i-1].TimestampMs; gap < 40 {
+			t.Fatalf("snapshots %d and %d only %dms apart", i-1, i, gap)
+		}
+	}
+}
+
+// readSnapshots 读取快照文件中的所有行，文件不存在时返回nil
+func readSnapshots(t *testing.T, path string) []StatusSnapshot {
+	t.Helper()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var out []StatusSnapshot
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var snap StatusSnapshot
+		if err := json.Unmarshal(sc.Bytes(), &snap); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, snap)
+	}
+	return out
+}
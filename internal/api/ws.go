@@ -3,8 +3,10 @@ package api
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"mini_chain/internal/blockchain"
 )
 
 // WSManager 管理所有WebSocket客户端
@@ -72,4 +74,55 @@ func (m *WSManager) ServeWS(w http.ResponseWriter, r *http.Request) {
 	
 	// 将新连接注册到管理器
 	m.register <- conn
-}
\ No newline at end of file
+}
+// DefaultMaxReorgFrameBlocks 重组WS帧默认最多列出的区块数
+const DefaultMaxReorgFrameBlocks = 100
+
+// ReorgFrame 链重组时推送给WebSocket客户端的消息
+// removed 为被断开的区块哈希，added 为新接入的区块哈希（均按高度升序，从分叉点之后开始）
+// 任一列表超过MaxReorgFrameBlocks时只保留前面的部分，并将truncated置为true
+type ReorgFrame struct {
+	Type         string   `json:"type"` // 固定为 "reorg"
+	ForkHeight   int      `json:"fork_height"`
+	Removed      []string `json:"removed"`
+	Added        []string `json:"added"`
+	RemovedCount int      `json:"removed_count"`
+	AddedCount   int      `json:"added_count"`
+	Truncated    bool     `json:"truncated"`
+}
+
+// notifyReorg 将链重组事件转换为reorg帧推送给所有WebSocket客户端
+func (api *API) notifyReorg(ev blockchain.ReorgEvent) {
+	frame := ReorgFrame{
+		Type:         "reorg",
+		ForkHeight:   ev.ForkHeight,
+		Removed:      ev.Removed,
+		Added:        ev.Added,
+		RemovedCount: len(ev.Removed),
+		AddedCount:   len(ev.Added),
+	}
+	if max := api.MaxReorgFrameBlocks; max > 0 {
+		if len(frame.Removed) > max {
+			frame.Removed = frame.Removed[:max]
+			frame.Truncated = true
+		}
+		if len(frame.Added) > max {
+			frame.Added = frame.Added[:max]
+			frame.Truncated = true
+		}
+	}
+	api.WS.broadcast <- mustMarshal(frame)
+}
+
+// TxFrame 交易进入内存池时推送给WebSocket客户端的消息，包含完整交易体
+type TxFrame struct {
+	Type      string            `json:"type"` // 固定为 "tx"
+	Txid      string            `json:"txid"`
+	Tx        blockchain.UTXOTx `json:"tx"`
+	FirstSeen time.Time         `json:"first_seen"` // 进入内存池的时间
+}
+
+// notifyTx 将交易事件转换为tx帧推送给所有WebSocket客户端
+func (api *API) notifyTx(ev blockchain.TxEvent) {
+	api.WS.broadcast <- mustMarshal(TxFrame{Type: "tx", Txid: ev.Txid, Tx: ev.Tx, FirstSeen: ev.FirstSeen})
+}
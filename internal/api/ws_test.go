@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"mini_chain/internal/blockchain"
+	"mini_chain/internal/p2p"
+)
+
+func TestWSReorgFrame(t *testing.T) {
+	bc := blockchain.NewBlockchain(1, nil)
+	_, srv := newTestServer(t, bc)
+
+	// 本地挖出一个区块，远端从创世区块分叉挖出更长的链
+	local := blockchain.MineBlock(bc.GetLatest(), []string{testCoinbase(t, "ws-local")}, 1)
+	if err := bc.ValidateAndApplyBlock(local); err != nil {
+		t.Fatal(err)
+	}
+	genesis, _ := bc.GetBlockByIndex(0)
+	candidate := []blockchain.Block{genesis}
+	for _, tag := range []string{"ws-remote-1", "ws-remote-2"} {
+		candidate = append(candidate, blockchain.MineBlock(candidate[len(candidate)-1], []string{testCoinbase(t, tag)}, 1))
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	time.Sleep(50 * time.Millisecond) // 等待管理器完成注册
+
+	if err := bc.ReplaceChain(candidate); err != nil {
+		t.Fatalf("replace chain: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var frame ReorgFrame
+	if err := json.Unmarshal(msg, &frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != "reorg" || frame.ForkHeight != 0 || frame.Truncated {
+		t.Fatalf("unexpected frame: %s", msg)
+	}
+	if len(frame.Removed) != 1 || frame.Removed[0] != local.Hash {
+		t.Errorf("removed = %v, want [%s]", frame.Removed, local.Hash)
+	}
+	if len(frame.Added) != 2 || frame.Added[0] != candidate[1].Hash || frame.Added[1] != candidate[2].Hash {
+		t.Errorf("added = %v, want [%s %s]", frame.Added, candidate[1].Hash, candidate[2].Hash)
+	}
+}
+
+func TestWSTxFrameFromGossip(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	sender, err := p2p.NewNode(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Host.Close()
+	node, err := p2p.NewNode(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Host.Close()
+	if err := sender.Host.Connect(ctx, peer.AddrInfo{ID: node.Host.ID(), Addrs: node.Host.Addrs()}); err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewAPI(blockchain.NewBlockchain(1, nil), node)
+	srv := httptest.NewServer(a.Router())
+	defer srv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	time.Sleep(50 * time.Millisecond) // 等待管理器完成注册
+
+	tx, _ := fundedTx(t, 100, newAddress(t), 40, 1)
+	txid, err := blockchain.TxID(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { blockchain.RemoveFromMempool([]string{txid}) })
+
+	// gossipsub网格建立需要时间，重复发布直到收到tx帧；重复的交易不会产生新的帧
+	msg := &p2p.Message{Type: p2p.MsgTx, Data: mustMarshal(tx)}
+	frames := make(chan []byte)
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				close(frames)
+				return
+			}
+			frames <- data
+		}
+	}()
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		sender.Broadcast(msg)
+		select {
+		case data, ok := <-frames:
+			if !ok {
+				t.Fatal("websocket closed before tx frame")
+			}
+			var frame TxFrame
+			if err := json.Unmarshal(data, &frame); err != nil {
+				t.Fatal(err)
+			}
+			if frame.Type != "tx" || frame.Txid != txid {
+				t.Fatalf("unexpected frame: %s", data)
+			}
+			if frame.FirstSeen.IsZero() || len(frame.Tx.Inputs) != len(tx.Inputs) || len(frame.Tx.Outputs) != len(tx.Outputs) {
+				t.Errorf("frame missing tx body or first_seen: %s", data)
+			}
+			return
+		case <-ctx.Done():
+			t.Fatal("no tx frame for gossiped transaction")
+		case <-ticker.C:
+		}
+	}
+}
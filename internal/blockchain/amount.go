@@ -0,0 +1,43 @@
+package blockchain
+
+// internal/blockchain/amount.go
+// 金额的溢出检查运算
+// 金额和余额使用int表示，累加大量大额输出可能静默溢出为负数，
+// 所有余额和手续费的累加都应通过这里的函数完成
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrAmountOverflow 金额累加溢出
+var ErrAmountOverflow = errors.New("amount overflow")
+
+// CheckedAdd 返回a+b，结果超出int范围时返回ErrAmountOverflow
+func CheckedAdd(a, b int) (int, error) {
+	if (b > 0 && a > math.MaxInt-b) || (b < 0 && a < math.MinInt-b) {
+		return 0, ErrAmountOverflow
+	}
+	return a + b, nil
+}
+
+// SumAmounts 对金额列表求和，溢出时返回ErrAmountOverflow
+func SumAmounts(amounts ...int) (int, error) {
+	total := 0
+	for _, a := range amounts {
+		var err error
+		if total, err = CheckedAdd(total, a); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// SumOutputs 返回交易所有输出金额之和，溢出时返回ErrAmountOverflow
+func SumOutputs(tx UTXOTx) (int, error) {
+	amounts := make([]int, len(tx.Outputs))
+	for i, out := range tx.Outputs {
+		amounts[i] = out.Amount
+	}
+	return SumAmounts(amounts...)
+}
@@ -0,0 +1,42 @@
+package blockchain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCheckedAdd(t *testing.T) {
+	if v, err := CheckedAdd(1, 2); err != nil || v != 3 {
+		t.Errorf("1+2: got %d, %v", v, err)
+	}
+	if _, err := CheckedAdd(math.MaxInt, 1); err != ErrAmountOverflow {
+		t.Errorf("MaxInt+1 应溢出，实际 %v", err)
+	}
+	if _, err := CheckedAdd(math.MinInt, -1); err != ErrAmountOverflow {
+		t.Errorf("MinInt-1 应溢出，实际 %v", err)
+	}
+}
+
+func TestSumAmountsNearLimit(t *testing.T) {
+	// 接近上限但未溢出
+	v, err := SumAmounts(math.MaxInt-10, 5, 5)
+	if err != nil || v != math.MaxInt {
+		t.Errorf("期望 MaxInt，实际 %d, %v", v, err)
+	}
+
+	// 溢出时返回错误而不是回绕为负数
+	v, err = SumAmounts(math.MaxInt/2, math.MaxInt/2, 10)
+	if err != ErrAmountOverflow {
+		t.Errorf("期望溢出错误，实际 %d, %v", v, err)
+	}
+}
+
+func TestValidateTxStructureOutputOverflow(t *testing.T) {
+	tx := UTXOTx{Version: TxVersion, Outputs: []TxOutput{
+		{Address: "a", Amount: math.MaxInt},
+		{Address: "b", Amount: 1},
+	}}
+	if err := ValidateTxStructure(tx); err == nil {
+		t.Error("输出总额溢出的交易应被拒绝")
+	}
+}
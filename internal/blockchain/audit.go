@@ -0,0 +1,87 @@
+package blockchain
+
+// internal/blockchain/audit.go
+// 交易审计日志
+// 每笔被接受的交易以一行JSON追加写入审计文件，每次写入后fsync，
+// 节点崩溃时已返回成功的记录不会丢失。文件只追加，不会被改写或截断
+// 通过bc.OnTx(l.LogEvent)注册后，无论交易来自API还是gossip，每笔新进入内存池的交易恰好记录一行
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord 审计文件中的一行
+type AuditRecord struct {
+	TxID      string `json:"txid"`      // 交易ID
+	From      string `json:"from"`      // 付款方（第一个输入的公钥，coinbase交易为 "coinbase"）
+	To        string `json:"to"`        // 收款方（第一个不属于付款方的输出地址）
+	Amount    int    `json:"amount"`    // 付给付款方以外地址的总金额（不含找零）
+	Timestamp int64  `json:"timestamp"` // 交易被接受的Unix时间
+}
+
+// NewAuditRecord 根据交易体构造审计记录
+func NewAuditRecord(txid string, tx UTXOTx, now time.Time) AuditRecord {
+	rec := AuditRecord{TxID: txid, Timestamp: now.Unix()}
+	if len(tx.Inputs) > 0 {
+		rec.From = tx.Inputs[0].PubKey
+	}
+	for _, out := range tx.Outputs {
+		if out.Address == rec.From {
+			continue // 找零输出
+		}
+		if rec.To == "" {
+			rec.To = out.Address
+		}
+		rec.Amount += out.Amount
+	}
+	return rec
+}
+
+// AuditLogger 追加写入的交易审计日志，可并发使用
+type AuditLogger struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// OpenAuditLog 以追加方式打开（不存在时创建）审计文件
+func OpenAuditLog(path string) (*AuditLogger, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	return &AuditLogger{f: f}, nil
+}
+
+// LogTx 写入一笔已接受交易的审计记录，并在返回前刷到磁盘
+func (l *AuditLogger) LogTx(txid string, tx UTXOTx, now time.Time) error {
+	line, err := json.Marshal(NewAuditRecord(txid, tx, now))
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return l.f.Sync()
+}
+
+// LogEvent 把交易进入内存池的事件写入审计日志，时间戳为进入内存池的时间，供OnTx回调使用
+// 写入失败只记录日志，不影响已接受的交易
+func (l *AuditLogger) LogEvent(ev TxEvent) {
+	if err := l.LogTx(ev.Txid, ev.Tx, ev.FirstSeen); err != nil {
+		log.Println("audit log error:", err)
+	}
+}
+
+// Close 关闭审计文件
+func (l *AuditLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
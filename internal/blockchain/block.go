@@ -8,62 +8,113 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"sort"
 	"strconv"
-	"time"
 )
 
 // Block 区块结构体，代表区块链中的一个区块
 type Block struct {
+	Version      int      `json:"version"`      // 区块版本，参与哈希计算
 	Index        int      `json:"index"`        // 区块索引（高度）
 	Timestamp    int64    `json:"timestamp"`    // 区块生成时间戳
 	Transactions []string `json:"transactions"` // 包含的交易ID列表
 	PrevHash     string   `json:"prev_hash"`    // 前一个区块的哈希值
+	MerkleRoot   string   `json:"merkle_root"`  // 交易ID的Merkle根
 	Nonce        int64    `json:"nonce"`        // 工作量证明的随机数
 	Hash         string   `json:"hash"`         // 当前区块的哈希值
+	Difficulty   int      `json:"difficulty"`   // 挖矿时使用的难度
+	// coinbase交易中的附加数据，通过Merkle根被区块哈希覆盖，校验时必须与coinbase交易一致
+	CoinbaseData string `json:"coinbase_data,omitempty"`
+	// PoA共识下的签名者公钥和其对区块哈希的签名（不参与哈希计算）
+	Signer    string `json:"signer,omitempty"`
+	Signature string `json:"signature,omitempty"`
 }
 
-// calcHash 计算区块头部字段的SHA256哈希值
-// 该函数用于生成区块的唯一标识，包含区块索引、时间戳、前一区块哈希、随机数和交易ID等信息
-func calcHash(b *Block) string {
-    var buf bytes.Buffer
+// BlockHeader 区块头，不包含交易体
+// 轻客户端和先同步区块头的节点只需要这些字段即可校验哈希链接与工作量证明
+type BlockHeader struct {
+	Version    int    `json:"version"`     // 区块版本
+	Index      int    `json:"index"`       // 区块索引（高度）
+	Timestamp  int64  `json:"timestamp"`   // 区块生成时间戳
+	PrevHash   string `json:"prev_hash"`   // 前一个区块的哈希值
+	MerkleRoot string `json:"merkle_root"` // 交易ID的Merkle根
+	Nonce      int64  `json:"nonce"`       // 工作量证明的随机数
+	Hash       string `json:"hash"`        // 区块哈希值
+	Difficulty int    `json:"difficulty"`  // 挖矿时使用的难度
+	Signer     string `json:"signer,omitempty"`    // PoA签名者公钥
+	Signature  string `json:"signature,omitempty"` // PoA签名者对区块哈希的签名
+}
+
+// Header 返回区块的区块头
+func (b *Block) Header() BlockHeader {
+	return BlockHeader{
+		Version:    b.Version,
+		Index:      b.Index,
+		Timestamp:  b.Timestamp,
+		PrevHash:   b.PrevHash,
+		MerkleRoot: b.MerkleRoot,
+		Nonce:      b.Nonce,
+		Hash:       b.Hash,
+		Difficulty: b.Difficulty,
+		Signer:     b.Signer,
+		Signature:  b.Signature,
+	}
+}
+
+// encode 返回区块头部字段的规范编码（不含Hash自身）
+// calcHash与工作量证明共用这一编码，保证存储的Hash与PoW校验的是同一个值
+// 交易只通过Merkle根参与编码，因此仅凭区块头即可重新计算哈希
+func (h *BlockHeader) encode() []byte {
+	var buf bytes.Buffer
 
-    // 1. 固定顺序写入基本字段（不含 Hash 自身）
-    buf.WriteString(strconv.Itoa(b.Index))
-    buf.WriteString("|")
-    buf.WriteString(strconv.FormatInt(b.Timestamp, 10))
-    buf.WriteString("|")
-    buf.WriteString(b.PrevHash)
-    buf.WriteString("|")
-    buf.WriteString(strconv.FormatInt(b.Nonce, 10))
-    buf.WriteString("|")
+	// 固定顺序写入各字段，使用"|"分隔
+	buf.WriteString(strconv.Itoa(h.Version))
+	buf.WriteString("|")
+	buf.WriteString(strconv.Itoa(h.Index))
+	buf.WriteString("|")
+	buf.WriteString(strconv.FormatInt(h.Timestamp, 10))
+	buf.WriteString("|")
+	buf.WriteString(h.PrevHash)
+	buf.WriteString("|")
+	buf.WriteString(h.MerkleRoot)
+	buf.WriteString("|")
+	buf.WriteString(strconv.Itoa(h.Difficulty))
+	buf.WriteString("|")
+	buf.WriteString(strconv.FormatInt(h.Nonce, 10))
+	return buf.Bytes()
+}
 
-    // 2. 为防止因交易顺序不同导致分叉，先排序
-    if len(b.Transactions) > 0 {
-        txCopy := make([]string, len(b.Transactions))
-        copy(txCopy, b.Transactions)
-        sort.Strings(txCopy) // 固定序
+// headerBytes 返回区块的规范头部编码
+func headerBytes(b *Block) []byte {
+	h := b.Header()
+	return h.encode()
+}
 
-        // 使用不可分割的分隔符，避免 "|" 与 "," 模糊边界
-        for _, tx := range txCopy {
-            buf.WriteString(tx)
-            buf.WriteString(";")  // 用分号做交易间隔
-        }
-    }
+// HeaderHash 仅根据区块头重新计算区块哈希
+func HeaderHash(h BlockHeader) string {
+	sum := sha256.Sum256(h.encode())
+	return fmt.Sprintf("%x", sum[:])
+}
 
-    // 3. 计算 SHA-256
-    sum := sha256.Sum256(buf.Bytes())
-    return fmt.Sprintf("%x", sum[:])
+// calcHash 计算区块头部字段的SHA256哈希值
+// 该函数用于生成区块的唯一标识，包含区块索引、时间戳、前一区块哈希、Merkle根、难度和随机数等信息
+func calcHash(b *Block) string {
+	return HeaderHash(b.Header())
 }
 
+// GenesisTimestamp 创世区块的固定时间戳
+// 所有节点必须得到相同的创世区块哈希，否则无法互相同步
+const GenesisTimestamp int64 = 1700000000
+
 // NewGenesis 创建一个创世区块实例（确定性的）
 // 创世区块是区块链的第一个区块，具有固定的参数值
 func NewGenesis() Block {
 	g := Block{
+		Version:      BlockVersion,          // 区块版本
 		Index:        0,                     // 创世区块索引为0
-		Timestamp:    time.Now().Unix(),     // 当前时间戳
+		Timestamp:    GenesisTimestamp,      // 固定时间戳
 		Transactions: []string{},            // 初始无交易
 		PrevHash:     "0",                   // 前一区块哈希为"0"
+		MerkleRoot:   MerkleRoot(nil),       // 无交易时Merkle根为空
 		Nonce:        0,                     // 随机数初始为0
 	}
 	g.Hash = calcHash(&g) // 计算并设置创世区块的哈希值
@@ -71,8 +122,11 @@ func NewGenesis() Block {
 }
 
 // ValidateBasic 检查区块头部和哈希的一致性（不包括工作量证明检查）
-// 用于验证区块的基本完整性
+// 用于验证区块的基本完整性：Merkle根必须与交易列表匹配，哈希必须与头部匹配
 func (b *Block) ValidateBasic() bool {
+	if MerkleRoot(b.Transactions) != b.MerkleRoot {
+		return false
+	}
 	return calcHash(b) == b.Hash
 }
 
@@ -2,35 +2,155 @@ package blockchain
 
 // internal/blockchain/blockchain.go
 // 高层区块链对象，使用上述组件构建
-// 在这个概念验证实现中，该对象管理内存中的最新区块高度和基本存储操作，
-// 后续会接入持久化数据库
+// 该对象在内存中维护主链和最新区块，配置了BlockStore时主链区块同时持久化，重启后从存储恢复
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log"
+	"math/big"
 	"sync"
+	"time"
 )
 
 // Blockchain 区块链结构体，管理区块的验证、存储和同步
 type Blockchain struct {
 	lock       sync.RWMutex // 读写锁，保护区块链数据的并发访问
 	difficulty int          // 工作量证明难度（前导十六进制0的个数）
-	// 注意：区块存储预计由存储模块处理
-	// 这里我们在内存中缓存最新区块，以便快速挖矿
+	// 已校验的共识参数，难度调整、区块大小和区块奖励都从这里读取
+	params ConsensusParams
+	// 内存中缓存最新区块，以便快速挖矿
 	latest Block // 最新区块缓存
+	// 按高度索引的内存区块列表，chain[i].Index == i
+	// 用于区块头查询和同步；配置了store时与存储中的主链一致
+	chain []Block
+	// chain中所有区块包含的交易数，随区块接入和重组更新，避免统计时扫描全链
+	txCount int
+	// 区块存储，未指定时为MemoryBlockStore
+	store BlockStore
+	// 本节点挖矿时写入coinbase交易的附加数据
+	coinbaseData string
+	// coinbase交易最多允许的输出数量
+	maxCoinbaseOutputs int
+	// 单笔交易规范编码的最大字节数
+	maxTxSize int
+	// 本节点挖矿时每个区块最多打包的内存池交易数（不含coinbase），0表示不限制
+	maxBlockTxs int
+	// 应用区块或重组后是否针对新的UTXO集合重新校验整个内存池
+	revalidateMempool bool
+	// 共识引擎，默认为PoW
+	consensus Consensus
+	// 计算median-time-past的区块数
+	mtpWindow int
+	// 区块拒绝计数和最近拒绝记录
+	rejections *rejectionLog
+	// 区块应用锁：本地挖出的区块、peer发来的区块和候选链依次应用，
+	// 同一时刻只有一个操作改变链顶、UTXO集合和内存池；需要同时持有时先获取applyLock再获取lock
+	applyLock sync.Mutex
+	// 链重组通知回调
+	reorgMu    sync.Mutex
+	reorgHooks []func(ReorgEvent)
+	// 交易进入内存池通知回调
+	txHooksMu sync.Mutex
+	txHooks   []func(TxEvent)
+	// 初始同步进度
+	progress syncTracker
+	// 历史余额查询缓存
+	balances *balanceCache
+	// 不在主链上的区块（stale/orphan），用于无需重新下载的重组
+	side *sideChainStore
 }
 
-// NewBlockchain 创建区块链实例并用创世区块初始化
+// NewBlockchain 使用默认共识参数创建区块链实例
 // difficulty: PoW难度（前导十六进制0的个数）
-func NewBlockchain(difficulty int) *Blockchain {
-	gen := NewGenesis() // 创建创世区块
-	bc := &Blockchain{
-		difficulty: difficulty,
-		latest:     gen, // 初始化最新区块为创世区块
+// store: 区块存储，从中恢复主链（存储为空时写入创世区块）；为nil时使用新的MemoryBlockStore，无法使用时记录日志并改用MemoryBlockStore
+// alloc: 可选的创世分配，在创世时为指定地址创建UTXO；分配无效时记录日志并忽略
+func NewBlockchain(difficulty int, store BlockStore, alloc ...GenesisAlloc) *Blockchain {
+	params := DefaultConsensusParams()
+	params.Difficulty = difficulty
+	bc, err := newBlockchain(params, store, alloc)
+	if err != nil {
+		log.Printf("block store unusable, keeping chain in memory only: %v", err)
+		bc, _ = newBlockchain(params, nil, alloc)
 	}
-	// 注意：存储持久化由存储模块处理（调用者负责）
 	return bc
 }
 
+// NewBlockchainWithParams 校验共识参数后创建区块链实例，节点启动时使用
+// 参数无效、互相矛盾或创世分配加上奖励计划的发行量超过MaxSupply时返回ErrInvalidConsensusParams；
+// 无法从store恢复主链（读取失败、链接损坏或创世区块不一致）时返回错误
+func NewBlockchainWithParams(params ConsensusParams, store BlockStore, alloc ...GenesisAlloc) (*Blockchain, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	amounts := make([]int, len(alloc))
+	for i, a := range alloc {
+		amounts[i] = a.Amount
+	}
+	allocated, err := SumAmounts(amounts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: genesis allocation: %v", ErrInvalidConsensusParams, err)
+	}
+	if issued, _ := params.scheduledIssuance(); allocated > params.MaxSupply-issued {
+		return nil, fmt.Errorf("%w: genesis allocation %d plus scheduled issuance %d exceeds max supply %d",
+			ErrInvalidConsensusParams, allocated, issued, params.MaxSupply)
+	}
+	return newBlockchain(params, store, alloc)
+}
+
+// newBlockchain 用给定的共识参数创建区块链实例并从store恢复主链，store为nil时使用MemoryBlockStore
+func newBlockchain(params ConsensusParams, store BlockStore, alloc []GenesisAlloc) (*Blockchain, error) {
+	if store == nil {
+		store = NewMemoryBlockStore()
+	}
+	if err := applyGenesisAlloc(alloc); err != nil {
+		log.Printf("invalid genesis allocation ignored: %v", err)
+		alloc = nil
+	}
+	gen := NewGenesisWithAlloc(alloc) // 创建创世区块
+	chain := []Block{gen}             // 区块列表从创世区块开始
+	stored, err := loadChain(store)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("load chain: %w", err)
+	case stored == nil:
+		if err := putBlockWithTxs(store, gen); err != nil {
+			return nil, fmt.Errorf("store genesis: %w", err)
+		}
+	case stored[0].Hash != gen.Hash:
+		return nil, fmt.Errorf("stored genesis %s does not match %s", stored[0].Hash, gen.Hash)
+	default:
+		// 恢复的主链的交易体读回内存，UTXO集合从创世区块重放重建
+		if err := restoreChainState(store, stored); err != nil {
+			return nil, fmt.Errorf("restore chain state: %w", err)
+		}
+		chain = stored
+	}
+	latest := chain[len(chain)-1]
+	// 恢复的链从最新区块的难度出发，按与接入区块相同的规则得出下一个区块的难度
+	// （最新区块恰好位于调整窗口边界时，下一个区块使用调整后的难度）
+	difficulty := nextDifficulty(chain, params)
+	bc := &Blockchain{
+		difficulty:         difficulty,
+		params:             params,
+		latest:             latest,
+		chain:              chain,
+		txCount:            countTxs(chain),
+		store:              store,
+		coinbaseData:       DefaultCoinbaseData,
+		maxCoinbaseOutputs: DefaultMaxCoinbaseOutputs,
+		maxTxSize:          DefaultMaxTxSize,
+		revalidateMempool:  true,
+		consensus:          PoWConsensus{},
+		mtpWindow:          DefaultMedianTimeWindow,
+		rejections:         newRejectionLog(),
+		balances:           newBalanceCache(DefaultBalanceCacheSize),
+		side:               newSideChainStore(DefaultMaxSideChainBlocks),
+	}
+	return bc, nil
+}
+
 // GetLatest 返回缓存的最新区块
 // 使用读锁确保并发安全
 func (bc *Blockchain) GetLatest() Block {
@@ -40,68 +160,518 @@ func (bc *Blockchain) GetLatest() Block {
 }
 
 // SetLatest 更新最新区块（在成功持久化新区块后调用）
+// 区块同时记录到内存区块列表中对应高度的位置，高于它的区块被丢弃；到达难度调整窗口边界时重新计算难度
 func (bc *Blockchain) SetLatest(b Block) {
 	bc.lock.Lock()
 	defer bc.lock.Unlock()
 	bc.latest = b
+	if b.Index >= 0 && b.Index <= len(bc.chain) {
+		bc.txCount += len(b.Transactions) - countTxs(bc.chain[b.Index:])
+		bc.chain = append(bc.chain[:b.Index], b)
+		bc.difficulty = difficultyAfter(bc.chain, bc.difficulty, bc.params)
+	}
+}
+
+// Height 返回当前链高（最新区块的索引）
+func (bc *Blockchain) Height() int {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	return bc.latest.Index
+}
+
+// GetBlockByIndex 按高度返回区块
+func (bc *Blockchain) GetBlockByIndex(index int) (Block, error) {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	if index < 0 || index >= len(bc.chain) {
+		return Block{}, fmt.Errorf("block %d not found", index)
+	}
+	return bc.chain[index], nil
+}
+
+// GetBlockByHash 按哈希返回主链上的区块
+func (bc *Blockchain) GetBlockByHash(hash string) (Block, error) {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	for i := len(bc.chain) - 1; i >= 0; i-- {
+		if bc.chain[i].Hash == hash {
+			return bc.chain[i], nil
+		}
+	}
+	return Block{}, fmt.Errorf("block %s not found", hash)
+}
+
+// FindTxBlock 在主链上查找包含指定交易的区块
+// 找到时返回该区块和true
+func (bc *Blockchain) FindTxBlock(txid string) (Block, bool) {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	for i := len(bc.chain) - 1; i >= 0; i-- {
+		for _, id := range bc.chain[i].Transactions {
+			if id == txid {
+				return bc.chain[i], true
+			}
+		}
+	}
+	return Block{}, false
+}
+
+// ChainWork 返回主链的累计工作量（不含创世区块）
+func (bc *Blockchain) ChainWork() *big.Int {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	return chainWork(bc.chain)
+}
+
+// GetHeaders 返回从from开始的至多count个区块头（不含交易体）
+// from超出链高时返回空切片
+func (bc *Blockchain) GetHeaders(from, count int) []BlockHeader {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	headers := []BlockHeader{}
+	for i := from; i >= 0 && i < len(bc.chain) && len(headers) < count; i++ {
+		headers = append(headers, bc.chain[i].Header())
+	}
+	return headers
+}
+
+// GetBlocks 按高度升序返回从from开始最多limit个主链区块，from超过链高时返回空列表
+// 从链顶沿PrevHash回溯到from，区块从存储读取；链接断裂时返回错误
+func (bc *Blockchain) GetBlocks(from, limit int) ([]Block, error) {
+	tip := bc.GetLatest()
+	if from < 0 || from > tip.Index || limit <= 0 {
+		return []Block{}, nil
+	}
+	last := tip.Index
+	if limit < last-from+1 {
+		last = from + limit - 1
+	}
+	blocks := make([]Block, last-from+1)
+	for b := tip; ; {
+		if b.Index <= last {
+			blocks[b.Index-from] = b
+		}
+		if b.Index == from {
+			return blocks, nil
+		}
+		prev, err := bc.parentBlock(b)
+		if err != nil {
+			return nil, err
+		}
+		b = prev
+	}
+}
+
+// parentBlock 按PrevHash从存储读取b的父区块，并核对它位于b的前一高度
+func (bc *Blockchain) parentBlock(b Block) (Block, error) {
+	if b.Index <= 0 {
+		return Block{}, fmt.Errorf("block %d has no parent", b.Index)
+	}
+	parent, err := bc.store.GetBlockByHash(b.PrevHash)
+	if err != nil {
+		return Block{}, err
+	}
+	if parent.Hash != b.PrevHash || parent.Index != b.Index-1 {
+		return Block{}, fmt.Errorf("block %d does not link to its parent", b.Index)
+	}
+	return parent, nil
 }
 
 // ValidateAndApplyBlock 执行区块验证（PoW + 前一区块哈希链接）并应用交易到UTXO集合
 // 该函数期望调用者在调用前后根据设计持久化区块
+// 区块被拒绝时返回*BlockRejectError，并记录到拒绝统计中
+// 不扩展链顶的区块保存到侧链存储；它所在的侧链分支累计工作量超过主链时切换到该分支并返回nil
 func (bc *Blockchain) ValidateAndApplyBlock(b Block) error {
+	bc.applyLock.Lock()
+	rerr := bc.validateAndApplyBlock(b)
+	bc.applyLock.Unlock()
+	if rerr == nil {
+		bc.connectSideChildren(b)
+		return nil
+	}
+	bc.rejections.record(rerr)
+	if rerr.Reason == RejectBadLink {
+		if candidate := bc.storeSideBlock(b); candidate != nil {
+			if err := bc.ReplaceChain(candidate); err == nil {
+				bc.side.countReorg()
+				return nil
+			}
+		}
+	}
+	return rerr
+}
+
+// validateAndApplyBlock 执行验证和应用，返回分类的拒绝原因
+func (bc *Blockchain) validateAndApplyBlock(b Block) *BlockRejectError {
 	// 1. 基本头部哈希检查
 	if !b.ValidateBasic() {
-		return errors.New("block header invalid")
+		return rejectBlock(&b, RejectBadHeader, errors.New("block header invalid"))
+	}
+	if err := CheckBlockVersion(&b); err != nil {
+		return rejectBlock(&b, RejectBadVersion, err)
 	}
-	// 2. 工作量证明验证
-	if !CheckPoW(&b, bc.difficulty) {
-		return errors.New("block PoW invalid")
+	// 2. 共识校验：区块声明的难度必须与本链一致，且封装（PoW或PoA签名）有效
+	if rerr := bc.Consensus().ValidateBlock(&b, bc.Difficulty()); rerr != nil {
+		return rerr
 	}
 	// 3. 前一区块链接验证
 	latest := bc.GetLatest()
 	if b.PrevHash != latest.Hash {
-		return errors.New("block does not extend latest")
+		return rejectBlock(&b, RejectBadLink, errors.New("block does not extend latest"))
 	}
-	// 4. 验证包含的交易（validateRawTx确保输入存在）
-	for _, txid := range b.Transactions {
-		if err := validateRawTx(txid); err != nil {
-			return err
-		}
+	// 时间戳必须晚于median-time-past且不过于超前
+	if err := bc.checkTimestamp(&b); err != nil {
+		return rejectBlock(&b, RejectBadTimestamp, err)
+	}
+	// 4. 验证包含的交易：输入存在于UTXO集合（或由区块内前面的交易产生）且未被重复花费，输入总额不小于输出总额
+	fees, err := checkBlockTxs(b.Transactions, newUTXOView(GetUTXO))
+	if err != nil {
+		return rejectBlock(&b, RejectBadTx, err)
+	}
+	// 价值守恒：coinbase不能领取超过区块奖励加手续费总额的金额
+	if err := checkBlockValue(&b, bc.Params().BlockSubsidy(b.Index), fees); err != nil {
+		return rejectBlock(&b, RejectBadTx, err)
+	}
+	// coinbase交易只能位于第一位，其他交易必须有输入
+	if err := checkBlockCoinbase(&b); err != nil {
+		return rejectBlock(&b, RejectBadTx, err)
+	}
+	if err := checkBlockCoinbaseData(&b); err != nil {
+		return rejectBlock(&b, RejectBadTx, err)
+	}
+	if err := checkBlockCoinbaseOutputs(&b, bc.MaxCoinbaseOutputs()); err != nil {
+		return rejectBlock(&b, RejectBadTx, err)
+	}
+	if err := checkBlockSize(&b, bc.Params().MaxBlockSize); err != nil {
+		return rejectBlock(&b, RejectTooLarge, err)
 	}
 	// 5. 应用UTXO变更
 	if err := applyTxsInBlock(b.Transactions); err != nil {
-		return err
+		return rejectBlock(&b, RejectApplyFailed, err)
+	}
+	// 6. 持久化区块后更新最新区块
+	if err := bc.persist(b); err != nil {
+		return rejectBlock(&b, RejectStorage, err)
 	}
-	// 6. 更新最新区块
 	bc.SetLatest(b)
-	// 7. 从内存池中移除已打包的交易
+	// 7. 从内存池中移除已打包的交易，并按需移除与新UTXO集合冲突的交易
 	RemoveFromMempool(b.Transactions)
+	if bc.MempoolRevalidation() {
+		RevalidateMempool()
+	}
+	return nil
+}
+
+// SetMempoolRevalidation 设置应用区块或重组后是否重新校验整个内存池（默认开启）
+func (bc *Blockchain) SetMempoolRevalidation(enabled bool) {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	bc.revalidateMempool = enabled
+}
+
+// MempoolRevalidation 返回是否在应用区块或重组后重新校验整个内存池
+func (bc *Blockchain) MempoolRevalidation() bool {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	return bc.revalidateMempool
+}
+
+// ReplaceChain 用累计工作量更大的有效候选链替换本地链
+// 候选链必须从相同的创世区块开始，并逐块通过链接、哈希、难度、PoW和交易校验
+// 候选链工作量不大于本地链时返回ErrChainNotBetter
+// 替换导致本地区块被断开时，在释放锁后通知OnReorg注册的回调
+func (bc *Blockchain) ReplaceChain(newChain []Block) error {
+	ev, err := bc.replaceChain(newChain)
+	if err != nil {
+		return err
+	}
+	if len(ev.Removed) > 0 {
+		bc.notifyReorg(ev)
+	}
+	return nil
+}
+
+// replaceChain 在持有写锁的情况下执行链替换，返回断开和接入的区块
+func (bc *Blockchain) replaceChain(newChain []Block) (ReorgEvent, error) {
+	bc.applyLock.Lock()
+	defer bc.applyLock.Unlock()
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+
+	view, err := bc.validateCandidateLocked(newChain)
+	if err != nil {
+		return ReorgEvent{}, err
+	}
+	if chainWork(newChain).Cmp(chainWork(bc.chain)) <= 0 {
+		return ReorgEvent{}, ErrChainNotBetter
+	}
+
+	// 找到分叉点
+	fork := 0
+	for fork+1 < len(newChain) && fork+1 < len(bc.chain) && newChain[fork+1].Hash == bc.chain[fork+1].Hash {
+		fork++
+	}
+
+	// 被放弃分支中不在新分支里的交易重新校验后放回内存池
+	orphaned := orphanedTxids(bc.chain[fork+1:], newChain[fork+1:])
+	ev := newReorgEvent(fork, bc.chain[fork+1:], newChain[fork+1:])
+	// 按高度依次写入新分支，最后写入的区块成为存储中的最新区块
+	for _, b := range newChain[fork+1:] {
+		if err := bc.persist(b); err != nil {
+			return ReorgEvent{}, fmt.Errorf("persist block %d: %w", b.Index, err)
+		}
+	}
+	bc.side.swap(bc.chain[fork+1:], newChain[fork+1:])
+
+	// 候选链从创世区块重放得到的UTXO视图整体替换全局集合，被放弃分支的花费和输出随之撤销
+	replaceUTXOSet(view.added)
+	for _, b := range newChain[fork+1:] {
+		RemoveFromMempool(b.Transactions)
+	}
+	bc.chain = append([]Block(nil), newChain...)
+	bc.latest = bc.chain[len(bc.chain)-1]
+	bc.txCount = countTxs(bc.chain)
+	bc.difficulty = difficultyAfter(bc.chain, bc.latest.Difficulty, bc.params)
+	restoreToMempool(orphaned)
+	if bc.revalidateMempool {
+		RevalidateMempool()
+	}
+	return ev, nil
+}
+
+// orphanedTxids 返回abandoned分支中出现、但不在adopted分支中的交易ID（保持原顺序）
+func orphanedTxids(abandoned, adopted []Block) []string {
+	inAdopted := make(map[string]bool)
+	for _, b := range adopted {
+		for _, txid := range b.Transactions {
+			inAdopted[txid] = true
+		}
+	}
+	var orphaned []string
+	for _, b := range abandoned {
+		for _, txid := range b.Transactions {
+			if !inAdopted[txid] {
+				orphaned = append(orphaned, txid)
+				inAdopted[txid] = true // 去重
+			}
+		}
+	}
+	return orphaned
+}
+
+// restoreToMempool 重组后把孤立交易放回内存池
+// coinbase交易随其区块失效，不放回；未通过重新校验（validateRawTx）的交易被丢弃
+// 本地没有交易体的交易无法校验，原样放回
+func restoreToMempool(txids []string) {
+	for _, txid := range txids {
+		if tx, err := GetTransaction(txid); err == nil {
+			if IsCoinbase(tx) || validateRawTx(txid) != nil {
+				continue
+			}
+		}
+		AddToMempool(txid)
+	}
+}
+
+// validateCandidateLocked 校验候选链的创世区块和每个区块，调用者需持有锁
+// 返回候选链从创世区块重放得到的UTXO视图（底层为空集合，added即完整的UTXO集合）
+// 区块被拒绝时记录到拒绝统计中
+func (bc *Blockchain) validateCandidateLocked(newChain []Block) (*utxoView, error) {
+	if len(newChain) == 0 || newChain[0].Hash != bc.chain[0].Hash {
+		return nil, errors.New("genesis mismatch")
+	}
+	// 交易按候选链从创世区块开始重放得到的UTXO集合校验，与本地链顶的UTXO状态无关
+	view := newUTXOView(nil)
+	if err := view.apply(newChain[0].Transactions); err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(newChain); i++ {
+		if err := bc.checkChainBlock(newChain, i, view); err != nil {
+			bc.rejections.record(err)
+			return nil, err
+		}
+	}
+	return view, nil
+}
+
+// checkChainBlock 校验候选链中第i个区块的链接、哈希、难度、PoW和交易
+// view为候选链前i个区块重放后的UTXO视图，校验通过的区块的交易记入其中
+// 与本地链相同高度、相同哈希的区块已校验过，直接跳过（也避免难度调整后旧区块难度不符）
+// 跳过前仍需确认区块内容与声明的哈希一致，防止篡改内容后沿用原哈希
+func (bc *Blockchain) checkChainBlock(newChain []Block, i int, view *utxoView) *BlockRejectError {
+	b := newChain[i]
+	if i < len(bc.chain) && bc.chain[i].Hash == b.Hash && b.PrevHash == newChain[i-1].Hash && b.ValidateBasic() {
+		if err := view.apply(b.Transactions); err != nil {
+			return rejectBlock(&b, RejectApplyFailed, fmt.Errorf("block %d: %w", i, err))
+		}
+		return nil
+	}
+	if b.Index != i || b.PrevHash != newChain[i-1].Hash {
+		return rejectBlock(&b, RejectBadLink, fmt.Errorf("block %d does not link to its parent", i))
+	}
+	if !b.ValidateBasic() {
+		return rejectBlock(&b, RejectBadHeader, fmt.Errorf("block %d header invalid", i))
+	}
+	if err := CheckBlockVersion(&b); err != nil {
+		return rejectBlock(&b, RejectBadVersion, fmt.Errorf("block %d: %v", i, err))
+	}
+	// 候选链上每个区块的难度由它之前的区块决定，从创世区块之后的初始难度开始
+	difficulty := bc.params.Difficulty
+	if i > 1 {
+		difficulty = newChain[i-1].Difficulty
+	}
+	if rerr := bc.consensus.ValidateBlock(&b, difficultyAfter(newChain[:i], difficulty, bc.params)); rerr != nil {
+		rerr.Err = fmt.Errorf("block %d: %v", i, rerr.Err)
+		return rerr
+	}
+	if err := checkBlockTimestamp(&b, newChain[:i], bc.mtpWindow, time.Now().Unix()); err != nil {
+		return rejectBlock(&b, RejectBadTimestamp, fmt.Errorf("block %d: %v", i, err))
+	}
+	fees, err := checkBlockTxs(b.Transactions, view)
+	if err != nil {
+		return rejectBlock(&b, RejectBadTx, fmt.Errorf("block %d: %w", i, err))
+	}
+	if err := checkBlockValue(&b, bc.params.BlockSubsidy(i), fees); err != nil {
+		return rejectBlock(&b, RejectBadTx, fmt.Errorf("block %d: %w", i, err))
+	}
+	if err := checkBlockCoinbase(&b); err != nil {
+		return rejectBlock(&b, RejectBadTx, fmt.Errorf("block %d: %w", i, err))
+	}
+	if err := checkBlockCoinbaseData(&b); err != nil {
+		return rejectBlock(&b, RejectBadTx, fmt.Errorf("block %d: %v", i, err))
+	}
+	if err := checkBlockCoinbaseOutputs(&b, bc.maxCoinbaseOutputs); err != nil {
+		return rejectBlock(&b, RejectBadTx, fmt.Errorf("block %d: %v", i, err))
+	}
+	if err := checkBlockSize(&b, bc.params.MaxBlockSize); err != nil {
+		return rejectBlock(&b, RejectTooLarge, fmt.Errorf("block %d: %v", i, err))
+	}
+	return nil
+}
+
+// chainWork 返回区块列表的累计工作量（不含创世区块）
+func chainWork(blocks []Block) *big.Int {
+	total := new(big.Int)
+	for _, b := range blocks {
+		if b.Index == 0 {
+			continue
+		}
+		total.Add(total, BlockWork(b.Difficulty))
+	}
+	return total
+}
+
+// ErrNoTxsToMine 内存池为空，没有可打包的交易
+var ErrNoTxsToMine = errors.New("no txs to mine")
+
+// ErrInvalidTip 链顶区块未通过校验，拒绝在其上挖矿
+var ErrInvalidTip = errors.New("invalid chain tip")
+
+// ValidateTip 校验当前链顶区块，见checkTip
+func (bc *Blockchain) ValidateTip() error {
+	return bc.checkTip(bc.GetLatest())
+}
+
+// checkTip 校验将要在其上挖矿的链顶区块：哈希和Merkle根、按其声明难度的共识封装（PoW或PoA签名），
+// 以及与主链上父区块的链接；创世区块只检查哈希。失败时返回包装了ErrInvalidTip的错误
+func (bc *Blockchain) checkTip(tip Block) error {
+	if !tip.ValidateBasic() {
+		return fmt.Errorf("%w: block %d header invalid", ErrInvalidTip, tip.Index)
+	}
+	if tip.Index == 0 {
+		return nil
+	}
+	if rerr := bc.Consensus().ValidateBlock(&tip, tip.Difficulty); rerr != nil {
+		return fmt.Errorf("%w: block %d: %v", ErrInvalidTip, tip.Index, rerr)
+	}
+	parent, err := bc.GetBlockByIndex(tip.Index - 1)
+	if err != nil || parent.Hash != tip.PrevHash {
+		return fmt.Errorf("%w: block %d does not link to its parent", ErrInvalidTip, tip.Index)
+	}
 	return nil
 }
 
 // MinePending 挖取包含内存池交易的新区块的辅助函数:
-// - 收集内存池中的交易
+// - 按手续费从高到低收集内存池中的交易，最多maxBlockTxs笔
+// - coinbase输出为下一个区块的奖励（由共识参数决定，见SetBlockReward）加上所选交易的手续费总额
 // - 运行工作量证明算法
 // - 返回挖取的区块（调用者应存储并调用ValidateAndApplyBlock提交UTXO变更）
-func (bc *Blockchain) MinePending(minerAddress string, reward int) (Block, error) {
-	txids := ListMempool() // 获取当前内存池中的交易ID列表
+// - ctx到期或取消时停止挖矿并返回ErrMiningDeadline
+// - 链顶区块未通过校验时不挖矿，返回ErrInvalidTip
+func (bc *Blockchain) MinePending(ctx context.Context, minerAddress string) (Block, error) {
+	// 在区块应用锁下同时读取链顶和内存池，避免在新链顶上打包尚未从内存池清除的已确认交易
+	bc.applyLock.Lock()
+	prev := bc.GetLatest()               // 获取前一个区块
+	txids, fees := orderedMempoolTxids() // 按手续费和依赖排列的内存池交易
+	bc.applyLock.Unlock()
 
-	// 创建coinbase交易作为矿工奖励
-	coinbaseTx := CoinbaseTx("Mining Reward", minerAddress, reward)
-	coinbaseTxId, err := TxID(coinbaseTx)
+	// 不在无效的链顶上继续挖矿
+	if err := bc.checkTip(prev); err != nil {
+		return Block{}, err
+	}
+
+	bc.lock.RLock()
+	coinbaseData := bc.coinbaseData
+	maxTxs := bc.maxBlockTxs
+	reward := bc.params.BlockSubsidy(prev.Index + 1)
+	bc.lock.RUnlock()
+
+	// 按打包顺序截取前maxTxs笔交易；父交易总排在子交易之前，截断不会留下缺少父交易的子交易
+	if maxTxs > 0 && len(txids) > maxTxs {
+		txids = txids[:maxTxs]
+	}
+	// 区块内交易总大小不超过MaxBlockSize；按全部候选手续费估算coinbase大小，
+	// 实际coinbase金额不会更大，编码也不会更长
+	estimate, err := coinbaseAmount(reward, txids, fees)
 	if err != nil {
-		return Block{}, errors.New("failed to generate coinbase transaction")
+		return Block{}, fmt.Errorf("coinbase amount: %w", err)
+	}
+	cbSize, err := TxSize(CoinbaseTx(coinbaseData, minerAddress, estimate))
+	if err != nil {
+		return Block{}, err
+	}
+	txids = fitBlockSize(txids, bc.Params().MaxBlockSize-cbSize)
+	if len(txids) == 0 { // 只有coinbase交易
+		return Block{}, ErrNoTxsToMine
 	}
 
+	// 创建coinbase交易作为矿工奖励和所选交易的手续费，附带配置的coinbase数据
+	amount, err := coinbaseAmount(reward, txids, fees)
+	if err != nil {
+		return Block{}, fmt.Errorf("coinbase amount: %w", err)
+	}
+	coinbaseTxId, err := PutTransaction(CoinbaseTx(coinbaseData, minerAddress, amount))
+	if err != nil {
+		return Block{}, errors.New("failed to generate coinbase transaction")
+	}
 	// 将coinbase交易ID添加到交易列表开头
 	allTxIds := append([]string{coinbaseTxId}, txids...)
 
-	if len(allTxIds) <= 1 { // 只有coinbase交易
-		return Block{}, errors.New("no txs to mine")
+	// 由共识引擎封装新区块（PoW挖矿或PoA签名）；调用者：持久化b然后调用ValidateAndApplyBlock提交UTXO变更
+	// 使用按难度调整窗口自动更新的当前难度
+	tmpl := newBlockTemplate(prev, allTxIds, bc.Difficulty())
+	if mtp := bc.MedianTimePast(); tmpl.Timestamp <= mtp {
+		tmpl.Timestamp = mtp + 1
 	}
-
-	prev := bc.GetLatest()                        // 获取前一个区块
-	b := MineBlock(prev, allTxIds, bc.difficulty) // 挖取新区块
-	// 调用者：持久化b然后调用ValidateAndApplyBlock提交UTXO变更
+	b, err := bc.Consensus().SealBlock(ctx, tmpl)
+	if err != nil {
+		return Block{}, err
+	}
+	b.CoinbaseData = coinbaseData
 	return b, nil
 }
+
+// coinbaseAmount 返回区块奖励reward加上txids的手续费总额
+func coinbaseAmount(reward int, txids []string, fees map[string]int) (int, error) {
+	amounts := make([]int, 0, len(txids)+1)
+	amounts = append(amounts, reward)
+	for _, txid := range txids {
+		amounts = append(amounts, fees[txid])
+	}
+	return SumAmounts(amounts...)
+}
+
+// persist 把接入主链的区块及其交易体写入存储
+func (bc *Blockchain) persist(b Block) error {
+	return putBlockWithTxs(bc.store, b)
+}
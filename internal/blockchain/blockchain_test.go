@@ -0,0 +1,296 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"mini_chain/internal/wallet"
+)
+
+// mempoolContains 判断交易是否在内存池中
+func mempoolContains(txid string) bool {
+	for _, id := range ListMempool() {
+		if id == txid {
+			return true
+		}
+	}
+	return false
+}
+
+// testTxs 保存并返回可以依次打包进同一区块的n笔交易ID：第一笔是以tag为附加数据的coinbase，
+// 之后每笔花费前一笔的输出；相同的tag得到相同的交易
+func testTxs(t *testing.T, tag string, n int) []string {
+	t.Helper()
+	txid, err := PutTransaction(CoinbaseTx(tag, "test-miner", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txids := []string{txid}
+	for len(txids) < n {
+		txids = append(txids, spendTx(t, txids[len(txids)-1], 0, "test-miner", 1))
+	}
+	return txids
+}
+
+// spendTx 保存并返回花费prev第vout个输出、向to支付amount的交易ID
+// 区块校验不检查签名，交易不签名
+func spendTx(t *testing.T, prev string, vout int, to string, amount int) string {
+	t.Helper()
+	tx := UTXOTx{
+		Version: TxVersion,
+		Inputs:  []TxInput{{Txid: prev, Vout: vout}},
+		Outputs: []TxOutput{{Address: to, Amount: amount}},
+	}
+	txid, err := PutTransaction(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txid
+}
+
+func TestReplaceChainRestoresOrphanedTxs(t *testing.T) {
+	bc := NewBlockchain(1, nil, GenesisAlloc{Address: "reorg-a", Amount: 10}, GenesisAlloc{Address: "reorg-b", Amount: 10},
+		GenesisAlloc{Address: "reorg-shared", Amount: 10})
+	genesis := bc.GetLatest()
+	alloc := genesis.Transactions[0]
+	orphanA := spendTx(t, alloc, 0, "reorg-payee", 10)
+	orphanB := spendTx(t, alloc, 1, "reorg-payee", 10)
+	shared := spendTx(t, alloc, 2, "reorg-payee", 10)
+	remoteOnly := testTxs(t, "remote-only", 1)
+	defer RemoveFromMempool([]string{orphanA, orphanB, shared, remoteOnly[0]})
+
+	// 本地挖出一个区块，包含coinbase、两笔本地交易和一笔双方都有的交易
+	coinbase := testTxs(t, "reorg test", 1)[0]
+	localBlock := MineBlock(genesis, []string{coinbase, orphanA, orphanB, shared}, 1)
+	if err := bc.ValidateAndApplyBlock(localBlock); err != nil {
+		t.Fatal(err)
+	}
+
+	// 远端从创世区块分叉，挖出更长的链，其中包含共同交易
+	candidate := []Block{genesis}
+	for _, txids := range [][]string{{shared}, remoteOnly} {
+		candidate = append(candidate, MineBlock(candidate[len(candidate)-1], txids, 1))
+	}
+	if err := bc.ReplaceChain(candidate); err != nil {
+		t.Fatalf("replace chain: %v", err)
+	}
+
+	for _, txid := range []string{orphanA, orphanB} {
+		if !mempoolContains(txid) {
+			t.Errorf("被放弃区块中的交易 %s 应回到内存池", txid)
+		}
+	}
+	if mempoolContains(shared) {
+		t.Error("新链已包含的交易不应回到内存池")
+	}
+	if mempoolContains(coinbase) {
+		t.Error("coinbase交易不应回到内存池")
+	}
+}
+
+func TestReplaceChainRollsBackUTXOs(t *testing.T) {
+	alice, _ := wallet.NewAccount()
+	bob, _ := wallet.NewAccount()
+	bc := NewBlockchain(1, nil, GenesisAlloc{Address: alice.Address, Amount: 100})
+	genesis := bc.GetLatest()
+
+	// 本地分支：alice花费创世输出向bob转30
+	wtx, err := wallet.BuildTransaction(alice, bob.Address, 30, 1, []wallet.UTXO{{Txid: genesis.Transactions[0], Vout: 0, Amount: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pay, err := PutTransaction(TxFromWallet(wtx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveFromMempool([]string{pay})
+	if err := bc.ValidateAndApplyBlock(MineBlock(genesis, []string{pay}, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if GetBalance(bob.Address) != 30 {
+		t.Fatalf("bob balance before reorg = %d, want 30", GetBalance(bob.Address))
+	}
+
+	// 从创世区块分叉的更长空分支胜出，本地分支的花费和输出都应撤销
+	fork := []Block{genesis}
+	for i := 0; i < 2; i++ {
+		fork = append(fork, MineBlock(fork[len(fork)-1], nil, 1))
+	}
+	if err := bc.ReplaceChain(fork); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetUTXO(genesis.Transactions[0], 0); err != nil {
+		t.Errorf("genesis output should be unspent after reorg: %v", err)
+	}
+	if _, err := GetUTXO(pay, 0); err == nil {
+		t.Error("output created on the abandoned branch should be gone")
+	}
+	if got := GetBalance(alice.Address); got != 100 {
+		t.Errorf("alice balance after reorg = %d, want 100", got)
+	}
+	if got := GetBalance(bob.Address); got != 0 {
+		t.Errorf("bob balance after reorg = %d, want 0", got)
+	}
+	// 输入重新可用，被放弃的交易通过重新校验回到内存池
+	if !mempoolContains(pay) {
+		t.Error("abandoned spend should return to the mempool")
+	}
+}
+
+func TestOrphanedTxids(t *testing.T) {
+	abandoned := []Block{{Transactions: []string{"a", "b"}}, {Transactions: []string{"c", "a"}}}
+	adopted := []Block{{Transactions: []string{"b", "d"}}}
+	got := orphanedTxids(abandoned, adopted)
+	want := []string{"a", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("期望 %v，实际 %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("期望 %v，实际 %v", want, got)
+		}
+	}
+}
+
+// TestConcurrentMiningAndBlockDelivery 本地矿工和peer同时在同一链顶上出块并同时提交（用 -race 运行）
+// 每轮只能有一个区块扩展链顶：链保持连续，交易最多被打包一次，内存池中不留已确认交易
+func TestConcurrentMiningAndBlockDelivery(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	const rounds = 30
+
+	var submitted []string
+	defer func() { RemoveFromMempool(submitted) }()
+	for round := 0; round < rounds; round++ {
+		for i := 0; i < 10; i++ {
+			// 区块中除coinbase外的交易必须有输入，每笔交易花费一个单独的UTXO
+			fund := fmt.Sprintf("race-fund-%d-%d", round, i)
+			PutUTXO(fund, 0, UTXOEntry{Address: "race", Amount: 1})
+			t.Cleanup(func() { DeleteUTXO(fund, 0) })
+			tx := UTXOTx{Version: TxVersion, Inputs: []TxInput{{Txid: fund, Vout: 0}}, Outputs: []TxOutput{{Address: fmt.Sprintf("race-%d-%d", round, i), Amount: 1}}}
+			txid, err := PutTransaction(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			AddToMempool(txid)
+			submitted = append(submitted, txid)
+		}
+
+		// 本地矿工经ValidateAndApplyBlock提交，peer区块经AddBlock送达；两者在同一链顶上出块后同时提交
+		height := bc.Height()
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		accepted := 0
+		for _, m := range []struct {
+			address string
+			submit  func(Block) bool
+		}{
+			{"local-miner", func(b Block) bool { return bc.ValidateAndApplyBlock(b) == nil }},
+			{"peer-miner", bc.AddBlock},
+		} {
+			b, err := bc.MinePending(ctx, m.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wg.Add(1)
+			go func(submit func(Block) bool) {
+				defer wg.Done()
+				<-start
+				if submit(b) {
+					mu.Lock()
+					accepted++
+					mu.Unlock()
+				}
+			}(m.submit)
+		}
+		close(start)
+		wg.Wait()
+		if accepted != 1 || bc.Height() != height+1 {
+			t.Fatalf("round %d: %d blocks accepted, height %d -> %d; want exactly one", round, accepted, height, bc.Height())
+		}
+	}
+
+	bc.lock.RLock()
+	chain := append([]Block(nil), bc.chain...)
+	latest := bc.latest
+	bc.lock.RUnlock()
+	if latest.Hash != chain[len(chain)-1].Hash {
+		t.Fatalf("latest %s is not the last block %s", latest.Hash, chain[len(chain)-1].Hash)
+	}
+	confirmed := make(map[string]int)
+	for i := 1; i < len(chain); i++ {
+		if chain[i].Index != i || chain[i].PrevHash != chain[i-1].Hash {
+			t.Fatalf("block %d does not extend block %d", i, i-1)
+		}
+		for _, txid := range chain[i].Transactions {
+			if tx, err := GetTransaction(txid); err == nil && IsCoinbase(tx) {
+				continue
+			}
+			if prev, ok := confirmed[txid]; ok {
+				t.Fatalf("tx %s included in blocks %d and %d", txid, prev, i)
+			}
+			confirmed[txid] = i
+			if _, err := GetUTXO(txid, 0); err != nil {
+				t.Errorf("output of confirmed tx %s missing from the UTXO set", txid)
+			}
+		}
+	}
+	for _, txid := range ListMempool() {
+		if b, ok := confirmed[txid]; ok {
+			t.Errorf("tx %s confirmed in block %d is still in the mempool", txid, b)
+		}
+	}
+}
+
+func TestMinePendingRejectsInvalidTip(t *testing.T) {
+	AddToMempool("tip-pending")
+	defer RemoveFromMempool([]string{"tip-pending"})
+
+	bc := NewBlockchain(1, nil)
+	valid := MineBlock(bc.GetLatest(), testTxs(t, "tip-valid", 1), 1)
+	if err := bc.ValidateAndApplyBlock(valid); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.ValidateTip(); err != nil {
+		t.Fatalf("valid tip rejected: %v", err)
+	}
+
+	tampered := valid
+	tampered.Nonce++ // 哈希不再与内容一致
+	unlinked := MineBlock(Block{Index: 1, Hash: "not-the-parent"}, []string{"tip-unlinked"}, 1)
+	for name, tip := range map[string]Block{"bad hash": tampered, "bad link": unlinked} {
+		bc.SetLatest(tip)
+		if _, err := bc.MinePending(context.Background(), "tip-miner"); !errors.Is(err, ErrInvalidTip) {
+			t.Errorf("%s: MinePending err = %v, want %v", name, err, ErrInvalidTip)
+		}
+		if bc.Height() != tip.Index || bc.GetLatest().Hash != tip.Hash {
+			t.Errorf("%s: chain moved past the invalid tip", name)
+		}
+	}
+}
+
+func TestStatsCountersFollowReorg(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	mineChain(t, bc, 3, "stats-main")
+	if s := bc.Stats(); s.Blocks != 4 || s.Transactions != 3 {
+		t.Fatalf("stats before reorg %+v, want 4 blocks and 3 txs", s)
+	}
+
+	// 从创世区块分叉的更长分支，每个区块2笔交易
+	fork := []Block{bc.chain[0]}
+	for i := 0; i < 4; i++ {
+		fork = append(fork, MineBlock(fork[len(fork)-1], testTxs(t, fmt.Sprintf("stats-fork-%d", i), 2), 1))
+	}
+	if err := bc.ReplaceChain(fork); err != nil {
+		t.Fatal(err)
+	}
+	if s := bc.Stats(); s.Blocks != 5 || s.Transactions != 8 || s.AvgTxsPerBlock != 1.6 {
+		t.Fatalf("stats after reorg %+v, want 5 blocks, 8 txs, 1.6 per block", s)
+	}
+}
@@ -0,0 +1,38 @@
+package blockchain
+
+// internal/blockchain/chain.go
+// core.Chain接口的实现，使本节点与gossip节点的区块链可以用同一套一致性测试验证
+
+import "mini_chain/gossip/core"
+
+var _ core.Chain[Block, UTXOTx] = (*Blockchain)(nil)
+
+// Tip 返回当前链顶区块，等同于GetLatest
+func (bc *Blockchain) Tip() Block {
+	return bc.GetLatest()
+}
+
+// AddBlock 校验并应用扩展链顶的区块，被拒绝时返回false
+// 需要拒绝原因时使用ValidateAndApplyBlock
+func (bc *Blockchain) AddBlock(b Block) bool {
+	return bc.ValidateAndApplyBlock(b) == nil
+}
+
+// AddTransaction 按内存池规则（大小上限、默认最低手续费）校验交易并加入内存池
+// 交易无效、过大、已被打包或已在内存池中时返回false
+func (bc *Blockchain) AddTransaction(tx UTXOTx) bool {
+	_, added, err := bc.AcceptTx(tx, DefaultMinRelayFee)
+	return err == nil && added
+}
+
+// Mempool 按手续费从高到低返回内存池中的交易体（与ListMempool顺序相同），交易体缺失的条目被跳过
+func (bc *Blockchain) Mempool() []UTXOTx {
+	txids := ListMempool()
+	txs := make([]UTXOTx, 0, len(txids))
+	for _, txid := range txids {
+		if tx, err := GetTransaction(txid); err == nil {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
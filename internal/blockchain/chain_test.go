@@ -0,0 +1,53 @@
+package blockchain
+
+import (
+	"testing"
+
+	"mini_chain/gossip/core"
+	"mini_chain/gossip/core/chaintest"
+	"mini_chain/internal/wallet"
+)
+
+func TestChainConformance(t *testing.T) {
+	chaintest.Run(t, chaintest.Harness[Block, UTXOTx]{
+		New: func(t *testing.T) core.Chain[Block, UTXOTx] {
+			RemoveFromMempool(ListMempool())
+			return NewBlockchain(1, nil)
+		},
+		Mine: func(t *testing.T, prev Block, txs []UTXOTx) Block {
+			txids := make([]string, len(txs))
+			for i, tx := range txs {
+				txid, err := PutTransaction(tx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				txids[i] = txid
+			}
+			return MineBlock(prev, txids, 1)
+		},
+		NewTx: func(t *testing.T) UTXOTx {
+			acc, err := wallet.NewAccount()
+			if err != nil {
+				t.Fatal(err)
+			}
+			prev := "fund-" + acc.Address[2:18]
+			PutUTXO(prev, 0, UTXOEntry{Address: acc.Address, Amount: 100})
+			wtx, err := wallet.BuildTransaction(acc, acc.Address, 40, DefaultMinRelayFee, []wallet.UTXO{{Txid: prev, Vout: 0, Amount: 100}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return TxFromWallet(wtx)
+		},
+		Blocks: func(c core.Chain[Block, UTXOTx]) []Block {
+			bc := c.(*Blockchain)
+			blocks := make([]Block, 0, bc.Height()+1)
+			for i := 0; i <= bc.Height(); i++ {
+				b, _ := bc.GetBlockByIndex(i)
+				blocks = append(blocks, b)
+			}
+			return blocks
+		},
+		Hash:   func(b Block) string { return b.Hash },
+		Tamper: func(b Block) Block { b.Nonce++; return b },
+	})
+}
@@ -0,0 +1,199 @@
+package blockchain
+
+// internal/blockchain/coinbase.go
+// coinbase附加数据
+// 矿工可以在coinbase交易中写入一段标记（类似比特币coinbase的scriptSig），
+// 数据位于coinbase交易内，通过交易ID和Merkle根被区块哈希覆盖；
+// 区块JSON中的coinbase_data字段必须与coinbase交易中的数据一致；
+// coinbase输出总额不能超过区块奖励加上区块内交易的手续费总额
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxCoinbaseDataLen coinbase附加数据的最大字节数
+const MaxCoinbaseDataLen = 100
+
+// DefaultCoinbaseData 未配置时使用的coinbase附加数据
+const DefaultCoinbaseData = "Mining Reward"
+
+// DefaultMaxCoinbaseOutputs coinbase交易默认最多允许的输出数量（一个奖励输出）
+const DefaultMaxCoinbaseOutputs = 1
+
+// ErrTooManyCoinbaseOutputs coinbase交易的输出数量超过上限
+var ErrTooManyCoinbaseOutputs = errors.New("coinbase tx has too many outputs")
+
+// ErrCoinbaseOverclaim coinbase输出总额超过区块奖励加手续费总额
+var ErrCoinbaseOverclaim = errors.New("coinbase claims more than subsidy plus fees")
+
+// 区块内coinbase交易位置和无输入交易的错误
+var (
+	ErrMisplacedCoinbase = errors.New("coinbase tx must be the first and only coinbase in the block")
+	ErrInputlessTx       = errors.New("non-coinbase tx has no inputs")
+)
+
+// ErrCoinbaseDataTooLong coinbase附加数据超过MaxCoinbaseDataLen
+var ErrCoinbaseDataTooLong = fmt.Errorf("coinbase data exceeds %d bytes", MaxCoinbaseDataLen)
+
+// NewCoinbaseTx 创建把reward支付给minerAddr的coinbase交易：没有真实输入，只有一个给矿工的输出，
+// 附加数据为DefaultCoinbaseData
+func NewCoinbaseTx(minerAddr string, reward int) UTXOTx {
+	return CoinbaseTx(DefaultCoinbaseData, minerAddr, reward)
+}
+
+// NewCoinbaseTx 按共识参数计算下一个区块的奖励（见SetBlockReward），用NewCoinbaseTx创建支付给minerAddr的coinbase交易，
+// 附加数据为本节点配置的coinbase数据
+func (bc *Blockchain) NewCoinbaseTx(minerAddr string) UTXOTx {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	tx := NewCoinbaseTx(minerAddr, bc.params.BlockSubsidy(bc.latest.Index+1))
+	tx.Inputs[0].Signature = bc.coinbaseData // coinbase附加数据保存在唯一输入的签名字段中，见CoinbaseData
+	return tx
+}
+
+// CoinbaseData 返回coinbase交易中的附加数据，非coinbase交易返回空字符串
+func CoinbaseData(tx UTXOTx) string {
+	if !IsCoinbase(tx) {
+		return ""
+	}
+	return tx.Inputs[0].Signature
+}
+
+// checkCoinbaseDataLen 检查coinbase附加数据的长度
+func checkCoinbaseDataLen(data string) error {
+	if len(data) > MaxCoinbaseDataLen {
+		return ErrCoinbaseDataTooLong
+	}
+	return nil
+}
+
+// SetCoinbaseData 设置本节点挖矿时写入coinbase交易的附加数据
+func (bc *Blockchain) SetCoinbaseData(data string) error {
+	if err := checkCoinbaseDataLen(data); err != nil {
+		return err
+	}
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	bc.coinbaseData = data
+	return nil
+}
+
+// checkBlockCoinbaseData 检查区块声明的coinbase_data与其coinbase交易一致
+// 区块未声明附加数据时不做检查（旧区块和不含coinbase的区块）
+func checkBlockCoinbaseData(b *Block) error {
+	if b.CoinbaseData == "" {
+		return nil
+	}
+	if err := checkCoinbaseDataLen(b.CoinbaseData); err != nil {
+		return err
+	}
+	if len(b.Transactions) == 0 {
+		return errors.New("coinbase data declared without a coinbase tx")
+	}
+	tx, err := GetTransaction(b.Transactions[0])
+	if err != nil {
+		return fmt.Errorf("coinbase tx: %w: %s", ErrTxNotFound, b.Transactions[0])
+	}
+	if !IsCoinbase(tx) || CoinbaseData(tx) != b.CoinbaseData {
+		return errors.New("coinbase data does not match the coinbase tx")
+	}
+	return nil
+}
+
+// SetMaxCoinbaseOutputs 设置coinbase交易最多允许的输出数量，n < 1 时恢复默认值
+func (bc *Blockchain) SetMaxCoinbaseOutputs(n int) {
+	if n < 1 {
+		n = DefaultMaxCoinbaseOutputs
+	}
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	bc.maxCoinbaseOutputs = n
+}
+
+// MaxCoinbaseOutputs 返回coinbase交易最多允许的输出数量
+func (bc *Blockchain) MaxCoinbaseOutputs() int {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	return bc.maxCoinbaseOutputs
+}
+
+// checkBlockCoinbaseOutputs 检查区块中的coinbase交易输出数量不超过max，防止用大量输出膨胀UTXO集合
+// 本地没有交易体的交易无法检查，返回ErrTxNotFound
+func checkBlockCoinbaseOutputs(b *Block, max int) error {
+	for _, txid := range b.Transactions {
+		tx, err := GetTransaction(txid)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrTxNotFound, txid)
+		}
+		if !IsCoinbase(tx) {
+			continue
+		}
+		if len(tx.Outputs) > max {
+			return fmt.Errorf("%w: %d > %d", ErrTooManyCoinbaseOutputs, len(tx.Outputs), max)
+		}
+	}
+	return nil
+}
+
+// checkBlockValue 检查区块整体的价值守恒：coinbase输出总额不超过subsidy加上区块内交易的手续费总额fees
+// 非coinbase交易的输入总额不小于输出总额已由checkBlockTxs逐笔检查；本地没有交易体的交易返回ErrTxNotFound
+func checkBlockValue(b *Block, subsidy, fees int) error {
+	allowed, err := CheckedAdd(subsidy, fees)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCoinbaseOverclaim, err)
+	}
+	claimed := 0
+	for _, txid := range b.Transactions {
+		tx, err := GetTransaction(txid)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrTxNotFound, txid)
+		}
+		if !IsCoinbase(tx) {
+			continue
+		}
+		out, err := SumOutputs(tx)
+		if err == nil {
+			claimed, err = CheckedAdd(claimed, out)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrCoinbaseOverclaim, err)
+		}
+	}
+	if claimed > allowed {
+		return fmt.Errorf("%w: claimed %d, subsidy %d + fees %d", ErrCoinbaseOverclaim, claimed, subsidy, fees)
+	}
+	return nil
+}
+
+// checkBlockCoinbase 检查区块最多包含一笔coinbase交易且位于第一位，其他交易必须有输入，
+// 否则没有输入的交易可以凭空产生货币；本地没有交易体的交易返回ErrTxNotFound
+func checkBlockCoinbase(b *Block) error {
+	for i, txid := range b.Transactions {
+		tx, err := GetTransaction(txid)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrTxNotFound, txid)
+		}
+		switch {
+		case IsCoinbase(tx) && i != 0:
+			return fmt.Errorf("%w: tx %s at position %d", ErrMisplacedCoinbase, txid, i)
+		case len(tx.Inputs) == 0:
+			return fmt.Errorf("%w: tx %s", ErrInputlessTx, txid)
+		}
+	}
+	return nil
+}
+
+// SetBlockReward 设置区块奖励（按HalvingInterval减半前的初始值），与其他共识参数一起校验，
+// 无效或与总量上限矛盾时返回ErrInvalidConsensusParams且不做修改
+func (bc *Blockchain) SetBlockReward(reward int) error {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	p := bc.params
+	p.BlockReward = reward
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	bc.params = p
+	return nil
+}
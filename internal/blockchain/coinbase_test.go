@@ -0,0 +1,217 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCoinbaseDataInMinedBlock(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	if err := bc.SetCoinbaseData("mini-pool/v1"); err != nil {
+		t.Fatal(err)
+	}
+	funding := MineBlock(bc.GetLatest(), testTxs(t, "coinbase-data-funds", 1), 1)
+	if err := bc.ValidateAndApplyBlock(funding); err != nil {
+		t.Fatal(err)
+	}
+	pending := spendTx(t, funding.Transactions[0], 0, "coinbase-data-payee", 1)
+	AddToMempool(pending)
+	defer RemoveFromMempool([]string{pending})
+
+	b, err := bc.MinePending(context.Background(), "miner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.CoinbaseData != "mini-pool/v1" {
+		t.Fatalf("block coinbase data = %q", b.CoinbaseData)
+	}
+	coinbase, err := GetTransaction(b.Transactions[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CoinbaseData(coinbase) != "mini-pool/v1" {
+		t.Fatalf("coinbase tx data = %q", CoinbaseData(coinbase))
+	}
+
+	// coinbase_data与coinbase交易不一致的区块被拒绝
+	forged := b
+	forged.CoinbaseData = "someone-else"
+	if err := bc.ValidateAndApplyBlock(forged); err == nil {
+		t.Fatal("expected block with mismatched coinbase data to be rejected")
+	}
+	if err := bc.ValidateAndApplyBlock(b); err != nil {
+		t.Fatalf("apply mined block: %v", err)
+	}
+}
+
+func TestCoinbaseDataTooLong(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	long := strings.Repeat("x", MaxCoinbaseDataLen+1)
+	if err := bc.SetCoinbaseData(long); err != ErrCoinbaseDataTooLong {
+		t.Fatalf("SetCoinbaseData over-length = %v, want ErrCoinbaseDataTooLong", err)
+	}
+	if err := ValidateTxStructure(CoinbaseTx(long, "miner", 10)); err == nil {
+		t.Fatal("expected over-length coinbase tx to be rejected")
+	}
+}
+
+func TestCoinbaseOutputsLimit(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	padded := CoinbaseTx("padded", "miner", 10)
+	padded.Outputs = append(padded.Outputs, TxOutput{Address: "dust-1", Amount: 0}, TxOutput{Address: "dust-2", Amount: 0})
+	txid, err := PutTransaction(padded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := MineBlock(bc.GetLatest(), []string{txid}, 1)
+
+	err = bc.ValidateAndApplyBlock(b)
+	if rerr, ok := err.(*BlockRejectError); !ok || rerr.Reason != RejectBadTx || !errors.Is(rerr.Err, ErrTooManyCoinbaseOutputs) {
+		t.Fatalf("expected too-many-coinbase-outputs rejection, got %v", err)
+	}
+
+	// 提高上限后同一区块被接受
+	bc.SetMaxCoinbaseOutputs(3)
+	if err := bc.ValidateAndApplyBlock(b); err != nil {
+		t.Fatalf("block within the raised limit rejected: %v", err)
+	}
+}
+
+func TestBlockValueConservation(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	subsidy := bc.Params().BlockSubsidy(1)
+	PutUTXO("value-fund", 0, UTXOEntry{Address: "alice", Amount: 100})
+	t.Cleanup(func() { DeleteUTXO("value-fund", 0) })
+	spend, err := PutTransaction(UTXOTx{
+		Version: TxVersion,
+		Inputs:  []TxInput{{Txid: "value-fund", Vout: 0}},
+		Outputs: []TxOutput{{Address: "bob", Amount: 95}}, // 手续费5
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := func(claim int) Block {
+		cb, err := PutTransaction(CoinbaseTx("value test", "miner", claim))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return MineBlock(bc.GetLatest(), []string{cb, spend}, 1)
+	}
+
+	// coinbase领取的金额超过区块奖励加手续费
+	err = bc.ValidateAndApplyBlock(block(subsidy + 6))
+	var rerr *BlockRejectError
+	if !errors.As(err, &rerr) || rerr.Reason != RejectBadTx || !errors.Is(err, ErrCoinbaseOverclaim) {
+		t.Fatalf("over-claiming coinbase: expected bad_tx %v, got %v", ErrCoinbaseOverclaim, err)
+	}
+	if bc.Height() != 0 {
+		t.Fatal("over-claiming block must not be applied")
+	}
+
+	// 恰好领取区块奖励加全部手续费
+	if err := bc.ValidateAndApplyBlock(block(subsidy + 5)); err != nil {
+		t.Fatalf("coinbase claiming subsidy plus fees rejected: %v", err)
+	}
+	if e, err := GetUTXO(spend, 0); err != nil || e.Amount != 95 {
+		t.Errorf("spend output = %+v, %v", e, err)
+	}
+}
+
+func TestNewCoinbaseTx(t *testing.T) {
+	tx := NewCoinbaseTx("cb-miner", 7)
+	if !IsCoinbase(tx) || len(tx.Outputs) != 1 || tx.Outputs[0] != (TxOutput{Address: "cb-miner", Amount: 7}) {
+		t.Fatalf("NewCoinbaseTx = %+v, want a coinbase paying 7 to cb-miner", tx)
+	}
+	if err := ValidateTxStructure(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 方法按下一个区块的奖励和配置的附加数据创建coinbase交易
+	bc := NewBlockchain(1, nil)
+	if err := bc.SetBlockReward(25); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.SetCoinbaseData("cb-pool"); err != nil {
+		t.Fatal(err)
+	}
+	tx = bc.NewCoinbaseTx("cb-miner")
+	if !IsCoinbase(tx) || tx.Outputs[0].Amount != 25 || CoinbaseData(tx) != "cb-pool" {
+		t.Fatalf("bc.NewCoinbaseTx = %+v, want a coinbase paying the reward 25 with data cb-pool", tx)
+	}
+}
+
+func TestMiningRewardBalance(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	if err := bc.SetBlockReward(25); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.SetBlockReward(DefaultMaxSupply + 1); !errors.Is(err, ErrInvalidConsensusParams) || bc.Params().BlockReward != 25 {
+		t.Fatalf("reward above max supply: err %v, reward %d", err, bc.Params().BlockReward)
+	}
+
+	PutUTXO("reward-fund", 0, UTXOEntry{Address: "reward-alice", Amount: 5})
+	t.Cleanup(func() { DeleteUTXO("reward-fund", 0) })
+	spend, err := PutTransaction(UTXOTx{
+		Version: TxVersion,
+		Inputs:  []TxInput{{Txid: "reward-fund", Vout: 0}},
+		Outputs: []TxOutput{{Address: "reward-bob", Amount: 5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddToMempool(spend)
+	t.Cleanup(func() { RemoveFromMempool([]string{spend}) })
+
+	const miner = "reward-miner"
+	before := GetBalance(miner)
+	b, err := bc.MinePending(context.Background(), miner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb, err := GetTransaction(b.Transactions[0]); err != nil || !IsCoinbase(cb) {
+		t.Fatalf("first tx of the mined block is not a coinbase: %+v, %v", cb, err)
+	}
+	if err := bc.ValidateAndApplyBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetBalance(miner) - before; got != 25 {
+		t.Fatalf("miner balance increased by %d, want the block reward 25", got)
+	}
+}
+
+func TestBlockCoinbasePlacement(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	PutUTXO("placement-fund", 0, UTXOEntry{Address: "alice", Amount: 5})
+	t.Cleanup(func() { DeleteUTXO("placement-fund", 0) })
+	put := func(tx UTXOTx) string {
+		txid, err := PutTransaction(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txid
+	}
+	coinbase := put(bc.NewCoinbaseTx("placement-miner"))
+	second := put(CoinbaseTx(DefaultCoinbaseData, "placement-other", 0))
+	spend := put(UTXOTx{Version: TxVersion, Inputs: []TxInput{{Txid: "placement-fund", Vout: 0}}, Outputs: []TxOutput{{Address: "bob", Amount: 5}}})
+	inputless := put(UTXOTx{Version: TxVersion, Outputs: []TxOutput{{Address: "bob", Amount: 0}}})
+
+	for _, c := range []struct {
+		name string
+		txs  []string
+		want error
+	}{
+		{"coinbase after another tx", []string{spend, coinbase}, ErrMisplacedCoinbase},
+		{"two coinbase txs", []string{coinbase, second}, ErrMisplacedCoinbase},
+		{"inputless non-coinbase tx", []string{coinbase, inputless}, ErrInputlessTx},
+	} {
+		err := bc.ValidateAndApplyBlock(MineBlock(bc.GetLatest(), c.txs, 1))
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+	if err := bc.ValidateAndApplyBlock(MineBlock(bc.GetLatest(), []string{coinbase, spend}, 1)); err != nil {
+		t.Fatalf("coinbase followed by a spend rejected: %v", err)
+	}
+}
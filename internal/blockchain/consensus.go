@@ -0,0 +1,206 @@
+package blockchain
+
+// internal/blockchain/consensus.go
+// 可插拔的共识引擎
+// 共识引擎决定区块如何被封装（PoW求解或授权签名者签名）以及如何校验封装。
+// 链接、Merkle根、版本、交易等与共识无关的校验仍由Blockchain完成；
+// 两种引擎下区块都携带本链难度，累计工作量比较方式保持不变
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"mini_chain/internal/wallet"
+)
+
+// Consensus 共识引擎接口
+type Consensus interface {
+	// Name 返回共识引擎名称（如 "pow"、"poa"）
+	Name() string
+	// ValidateBlock 按共识规则校验区块：难度与本链一致且封装有效
+	ValidateBlock(b *Block, difficulty int) *BlockRejectError
+	// VerifySeal 只凭区块头校验封装（先同步区块头时使用）
+	VerifySeal(h *BlockHeader) error
+	// SealBlock 封装已填好头部字段的区块模板，设置Nonce/签名和Hash
+	SealBlock(ctx context.Context, b Block) (Block, error)
+}
+
+// 共识引擎名称
+const (
+	ConsensusPoW = "pow"
+	ConsensusPoA = "poa"
+)
+
+// ErrNotSigner 本节点没有授权签名者私钥，无法出块
+var ErrNotSigner = errors.New("node is not an authorized signer")
+
+// NewConsensus 按名称创建共识引擎
+// name: "pow"（默认）或 "poa"
+// signers: PoA授权签名者公钥（十六进制）
+// key: PoA本节点的签名私钥，可为nil（只校验不出块）
+func NewConsensus(name string, signers []string, key *ecdsa.PrivateKey) (Consensus, error) {
+	switch name {
+	case "", ConsensusPoW:
+		return PoWConsensus{}, nil
+	case ConsensusPoA:
+		return NewPoAConsensus(signers, key)
+	default:
+		return nil, fmt.Errorf("unknown consensus %q", name)
+	}
+}
+
+// newBlockTemplate 构造待封装的区块模板
+// 时间戳取当前时间，且至少比父区块晚1秒，使快速连续出块时仍满足median-time-past规则
+func newBlockTemplate(prev Block, txids []string, difficulty int) Block {
+	ts := time.Now().Unix()
+	if ts <= prev.Timestamp {
+		ts = prev.Timestamp + 1
+	}
+	return Block{
+		Version:      BlockVersion,
+		Index:        prev.Index + 1,
+		Timestamp:    ts,
+		Transactions: txids,
+		PrevHash:     prev.Hash,
+		MerkleRoot:   MerkleRoot(txids),
+		Difficulty:   difficulty,
+	}
+}
+
+// PoWConsensus 工作量证明共识：区块哈希必须小于难度对应的目标值
+type PoWConsensus struct{}
+
+// Name 返回 "pow"
+func (PoWConsensus) Name() string { return ConsensusPoW }
+
+// ValidateBlock 检查难度与本链一致，且区块自身的Nonce产生声明的哈希并满足PoW
+func (PoWConsensus) ValidateBlock(b *Block, difficulty int) *BlockRejectError {
+	if b.Difficulty != difficulty {
+		return rejectBlock(b, RejectBadDifficulty, errors.New("block difficulty mismatch"))
+	}
+	if err := checkDifficulty(difficulty); err != nil {
+		return rejectBlock(b, RejectBadDifficulty, err)
+	}
+	if err := ValidateSeal(b, difficulty); err != nil {
+		return rejectBlock(b, RejectBadPoW, fmt.Errorf("block PoW invalid: %w", err))
+	}
+	return nil
+}
+
+// VerifySeal 检查区块头声明的难度有效且哈希满足该难度
+func (PoWConsensus) VerifySeal(h *BlockHeader) error {
+	if err := checkDifficulty(h.Difficulty); err != nil {
+		return err
+	}
+	if !hashMeetsTarget(h.Hash, h.Difficulty) {
+		return errors.New("PoW invalid")
+	}
+	return nil
+}
+
+// SealBlock 求解PoW，ctx到期或取消时返回ErrMiningDeadline
+func (PoWConsensus) SealBlock(ctx context.Context, b Block) (Block, error) {
+	pow := NewProofOfWork(&b, b.Difficulty)
+	nonce, hash, err := pow.RunContext(ctx)
+	if err != nil {
+		return Block{}, err
+	}
+	b.Nonce = nonce
+	b.Hash = hex.EncodeToString(hash)
+	return b, nil
+}
+
+// PoAConsensus 权威证明共识：区块由授权签名者对区块哈希签名，不需要挖矿
+type PoAConsensus struct {
+	signers map[string]bool   // 授权签名者公钥（十六进制）
+	key     *ecdsa.PrivateKey // 本节点的签名私钥，可为nil
+	pub     string            // 本节点的签名公钥
+}
+
+// NewPoAConsensus 创建PoA共识引擎
+// key 不为nil时必须属于授权签名者
+func NewPoAConsensus(signers []string, key *ecdsa.PrivateKey) (*PoAConsensus, error) {
+	if len(signers) == 0 {
+		return nil, errors.New("poa: no authorized signers")
+	}
+	c := &PoAConsensus{signers: make(map[string]bool, len(signers)), key: key}
+	for _, s := range signers {
+		c.signers[s] = true
+	}
+	if key != nil {
+		c.pub = hex.EncodeToString(crypto.FromECDSAPub(&key.PublicKey))
+		if !c.signers[c.pub] {
+			return nil, ErrNotSigner
+		}
+	}
+	return c, nil
+}
+
+// Name 返回 "poa"
+func (c *PoAConsensus) Name() string { return ConsensusPoA }
+
+// ValidateBlock 检查难度与本链一致且区块由授权签名者签名
+func (c *PoAConsensus) ValidateBlock(b *Block, difficulty int) *BlockRejectError {
+	if b.Difficulty != difficulty {
+		return rejectBlock(b, RejectBadDifficulty, errors.New("block difficulty mismatch"))
+	}
+	h := b.Header()
+	if err := c.VerifySeal(&h); err != nil {
+		return rejectBlock(b, RejectBadSeal, err)
+	}
+	return nil
+}
+
+// VerifySeal 检查签名者已授权，且签名是该签名者对区块哈希的签名
+func (c *PoAConsensus) VerifySeal(h *BlockHeader) error {
+	if !c.signers[h.Signer] {
+		return fmt.Errorf("signer %.16s is not authorized", h.Signer)
+	}
+	digest, err := hex.DecodeString(h.Hash)
+	if err != nil {
+		return fmt.Errorf("bad block hash: %v", err)
+	}
+	if err := wallet.VerifyRaw(h.Signer, h.Signature, digest); err != nil {
+		return fmt.Errorf("bad block signature: %v", err)
+	}
+	return nil
+}
+
+// SealBlock 计算区块哈希并用本节点私钥签名；本节点不是签名者时返回ErrNotSigner
+func (c *PoAConsensus) SealBlock(ctx context.Context, b Block) (Block, error) {
+	if c.key == nil {
+		return Block{}, ErrNotSigner
+	}
+	if err := ctx.Err(); err != nil {
+		return Block{}, ErrMiningDeadline
+	}
+	b.Nonce = 0
+	b.Hash = calcHash(&b)
+	digest, _ := hex.DecodeString(b.Hash)
+	sig, err := wallet.SignData(c.key, digest)
+	if err != nil {
+		return Block{}, err
+	}
+	b.Signer = c.pub
+	b.Signature = sig
+	return b, nil
+}
+
+// Consensus 返回本链使用的共识引擎
+func (bc *Blockchain) Consensus() Consensus {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	return bc.consensus
+}
+
+// SetConsensus 设置本链使用的共识引擎（应在启动时、接收区块之前设置）
+func (bc *Blockchain) SetConsensus(c Consensus) {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	bc.consensus = c
+}
@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"mini_chain/internal/wallet"
+)
+
+// newPoAChain 创建以authorized为唯一签名者的PoA链，返回签名者私钥
+func newPoAChain(t *testing.T) (*Blockchain, *PoAConsensus) {
+	t.Helper()
+	priv, pub, err := wallet.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cons, err := NewPoAConsensus([]string{pub}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := NewBlockchain(1, nil)
+	bc.SetConsensus(cons)
+	return bc, cons
+}
+
+func TestPoAAuthorizedSigner(t *testing.T) {
+	bc, cons := newPoAChain(t)
+	b, err := cons.SealBlock(context.Background(), newBlockTemplate(bc.GetLatest(), testTxs(t, "poa-tx", 1), 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Nonce != 0 || b.Signature == "" {
+		t.Fatalf("PoA block should be signed, not mined: %+v", b)
+	}
+	if err := bc.ValidateAndApplyBlock(b); err != nil {
+		t.Fatalf("authorized block rejected: %v", err)
+	}
+
+	// MinePending 在PoA下由签名者直接出块
+	pending := spendTx(t, b.Transactions[0], 0, "poa-payee", 1)
+	AddToMempool(pending)
+	defer RemoveFromMempool([]string{pending})
+	next, err := bc.MinePending(context.Background(), "poa-miner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.ValidateAndApplyBlock(next); err != nil {
+		t.Fatalf("MinePending block rejected: %v", err)
+	}
+}
+
+func TestPoAUnauthorizedSigner(t *testing.T) {
+	bc, _ := newPoAChain(t)
+
+	// 未授权的私钥签名的区块
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := newBlockTemplate(bc.GetLatest(), []string{"poa-rogue"}, 1)
+	b.Hash = calcHash(&b)
+	digest, _ := hex.DecodeString(b.Hash)
+	b.Signer = hex.EncodeToString(crypto.FromECDSAPub(&other.PublicKey))
+	if b.Signature, err = wallet.SignData(other, digest); err != nil {
+		t.Fatal(err)
+	}
+	err = bc.ValidateAndApplyBlock(b)
+	rerr, ok := err.(*BlockRejectError)
+	if !ok || rerr.Reason != RejectBadSeal {
+		t.Fatalf("expected bad_seal rejection, got %v", err)
+	}
+
+	// 冒用授权签名者公钥但签名不是其私钥生成的
+	authorized := bc.Consensus().(*PoAConsensus).pub
+	b.Signer = authorized
+	err = bc.ValidateAndApplyBlock(b)
+	if rerr, ok := err.(*BlockRejectError); !ok || rerr.Reason != RejectBadSeal {
+		t.Fatalf("expected bad_seal rejection for forged signer, got %v", err)
+	}
+	if bc.Height() != 0 {
+		t.Fatalf("rejected blocks changed the chain height to %d", bc.Height())
+	}
+
+	// PoW挖出的区块没有签名，同样被拒绝
+	mined := MineBlock(bc.GetLatest(), testTxs(t, "poa-pow", 1), 1)
+	if err := bc.ValidateAndApplyBlock(mined); err == nil {
+		t.Fatal("PoW block accepted by PoA chain")
+	}
+}
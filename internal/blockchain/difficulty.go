@@ -0,0 +1,127 @@
+package blockchain
+
+// internal/blockchain/difficulty.go
+// 难度调整（retarget）
+// 根据最近一个窗口内区块的实际出块间隔与目标间隔之比调整难度。
+// 难度以十六进制前导零个数表示，每加1工作量变为16倍，
+// 因此按 log16(期望用时/实际用时) 四舍五入得到调整量，并限制单次调整幅度和难度上下限
+
+import (
+	"math"
+)
+
+// 难度调整参数
+const (
+	MinDifficulty         = 1  // 最小难度
+	MaxDifficulty         = 64 // 最大难度（256位哈希的全部十六进制位）
+	MaxRetargetStep       = 1  // 单次调整的最大幅度
+	DefaultTargetSpacing  = 10 // 默认目标出块间隔（秒）
+	DefaultRetargetWindow = 10 // 默认调整窗口（区块数）
+)
+
+// AdjustDifficulty 根据blocks中最近window个区块的时间戳计算新难度
+// 当前难度取blocks中最后一个区块的Difficulty
+// targetSpacing: 目标出块间隔（秒）
+// 区块不足两个时保持当前难度；结果限制在当前难度±MaxRetargetStep以及[MinDifficulty, MaxDifficulty]之内
+func AdjustDifficulty(blocks []Block, targetSpacing int64, window int) int {
+	if len(blocks) == 0 {
+		return MinDifficulty
+	}
+	return retarget(blocks, blocks[len(blocks)-1].Difficulty, targetSpacing, window)
+}
+
+// retarget 按AdjustDifficulty的规则从current出发调整难度
+// 节点自身的难度状态不一定等于最后一个区块记录的难度（如PoA区块），共识路径直接传入current
+func retarget(blocks []Block, current int, targetSpacing int64, window int) int {
+	if window > len(blocks) {
+		window = len(blocks)
+	}
+	if window < 2 || targetSpacing <= 0 {
+		return clampDifficulty(current)
+	}
+	recent := blocks[len(blocks)-window:]
+	expected := float64(targetSpacing) * float64(window-1)
+	actual := float64(recent[len(recent)-1].Timestamp - recent[0].Timestamp)
+	if actual < 1 {
+		actual = 1 // 时间戳相同或倒退时按最快出块处理
+	}
+
+	delta := int(math.Round(math.Log(expected/actual) / math.Log(16)))
+	if delta > MaxRetargetStep {
+		delta = MaxRetargetStep
+	}
+	if delta < -MaxRetargetStep {
+		delta = -MaxRetargetStep
+	}
+	return clampDifficulty(current + delta)
+}
+
+// difficultyAfter 返回在chain之后出块使用的难度
+// 链顶高度是RetargetWindow的整数倍时按最近窗口的出块时间调整current，否则沿用current；
+// 所有节点对同一条链得到相同的结果，因此自动调整不会让节点之间的难度分叉
+func difficultyAfter(chain []Block, current int, p ConsensusParams) int {
+	tip := chain[len(chain)-1]
+	if tip.Index == 0 || p.RetargetWindow < 1 || tip.Index%p.RetargetWindow != 0 {
+		return current
+	}
+	return retarget(chain[1:], current, p.TargetSpacing, p.RetargetWindow)
+}
+
+// nextDifficulty 从chain的最新区块的难度出发，按共识规则得出下一个区块使用的难度
+// 只有创世区块或最新区块难度无效时使用参数中的初始难度
+func nextDifficulty(chain []Block, p ConsensusParams) int {
+	latest := chain[len(chain)-1]
+	if latest.Index == 0 || latest.Difficulty < MinDifficulty || latest.Difficulty > MaxDifficulty {
+		return p.Difficulty
+	}
+	return difficultyAfter(chain, latest.Difficulty, p)
+}
+
+// clampDifficulty 将难度限制在[MinDifficulty, MaxDifficulty]之内
+func clampDifficulty(d int) int {
+	if d < MinDifficulty {
+		return MinDifficulty
+	}
+	if d > MaxDifficulty {
+		return MaxDifficulty
+	}
+	return d
+}
+
+// Difficulty 返回当前挖矿和校验新区块使用的难度
+func (bc *Blockchain) Difficulty() int {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	return bc.difficulty
+}
+
+// RetargetResult 一次手动难度重算的结果
+type RetargetResult struct {
+	OldDifficulty int   `json:"old_difficulty"` // 重算前本节点使用的难度
+	NewDifficulty int   `json:"new_difficulty"` // 按共识规则从链重新推导并应用的难度
+	Projected     int   `json:"projected"`      // 按最近一个窗口的出块时间计算的调整结果（已限制幅度）
+	NextRetarget  int   `json:"next_retarget"`  // 下一个自动调整点的高度，不调整时为0
+	TargetSpacing int64 `json:"target_spacing"`
+	Window        int   `json:"window"`
+}
+
+// Retarget 立即按共识参数（TargetSpacing、RetargetWindow）重新计算难度
+// 应用的难度始终从链按共识规则推导，与其他节点一致，不会造成分叉；
+// 窗口中途按最近区块计算的调整结果只在Projected中报告，到下一个调整点才会生效
+func (bc *Blockchain) Retarget() RetargetResult {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	res := RetargetResult{
+		OldDifficulty: bc.difficulty,
+		TargetSpacing: bc.params.TargetSpacing,
+		Window:        bc.params.RetargetWindow,
+	}
+	bc.difficulty = nextDifficulty(bc.chain, bc.params)
+	res.NewDifficulty = bc.difficulty
+	// 创世区块的时间戳是固定值，不参与计算
+	res.Projected = retarget(bc.chain[1:], bc.difficulty, bc.params.TargetSpacing, bc.params.RetargetWindow)
+	if w := bc.params.RetargetWindow; w > 0 {
+		res.NextRetarget = (bc.latest.Index/w + 1) * w
+	}
+	return res
+}
@@ -0,0 +1,101 @@
+package blockchain
+
+import "testing"
+
+// blocksAt 构造具有指定时间戳的区块列表
+func blocksAt(timestamps ...int64) []Block {
+	blocks := make([]Block, len(timestamps))
+	for i, ts := range timestamps {
+		blocks[i] = Block{Index: i + 1, Timestamp: ts}
+	}
+	return blocks
+}
+
+func TestAdjustDifficulty(t *testing.T) {
+	cases := []struct {
+		name    string
+		blocks  []Block
+		current int
+		want    int
+	}{
+		{"按目标间隔出块保持不变", blocksAt(0, 10, 20, 30), 3, 3},
+		{"出块过快提高难度", blocksAt(0, 1, 2, 3), 3, 4},
+		{"出块过慢降低难度", blocksAt(0, 100, 200, 300), 3, 2},
+		{"极快出块单次最多提高MaxRetargetStep", blocksAt(0, 0, 0, 0), 3, 3 + MaxRetargetStep},
+		{"不低于最小难度", blocksAt(0, 1000, 2000, 3000), MinDifficulty, MinDifficulty},
+		{"不高于最大难度", blocksAt(0, 0, 0, 0), MaxDifficulty, MaxDifficulty},
+		{"区块不足保持不变", blocksAt(0), 3, 3},
+	}
+	for _, c := range cases {
+		c.blocks[len(c.blocks)-1].Difficulty = c.current // 当前难度取最后一个区块的难度
+		if got := AdjustDifficulty(c.blocks, 10, 10); got != c.want {
+			t.Errorf("%s: 期望 %d，实际 %d", c.name, c.want, got)
+		}
+	}
+	if got := AdjustDifficulty(nil, 10, 10); got != MinDifficulty {
+		t.Errorf("没有区块: 期望 %d，实际 %d", MinDifficulty, got)
+	}
+}
+
+func TestDifficultyAfterWindow(t *testing.T) {
+	p := DefaultConsensusParams()
+	genesis := Block{}
+	fast := append([]Block{genesis}, blocksAt(0, 1, 2, 3, 4, 5, 6, 7, 8, 9)...)
+	slow := append([]Block{genesis}, blocksAt(0, 100, 200, 300, 400, 500, 600, 700, 800, 900)...)
+	if got := difficultyAfter(fast, 3, p); got != 4 {
+		t.Errorf("fast window: difficulty %d, want 4", got)
+	}
+	if got := difficultyAfter(slow, 3, p); got != 2 {
+		t.Errorf("slow window: difficulty %d, want 2", got)
+	}
+	if got := difficultyAfter(fast[:len(fast)-1], 3, p); got != 3 {
+		t.Errorf("inside window: difficulty %d, want unchanged 3", got)
+	}
+	if got := difficultyAfter(slow, MinDifficulty, p); got != MinDifficulty {
+		t.Errorf("slow window at minimum: difficulty %d, want %d", got, MinDifficulty)
+	}
+}
+
+func TestAutomaticRetarget(t *testing.T) {
+	remote := NewBlockchain(2, nil)
+	mineChain(t, remote, DefaultRetargetWindow, "retarget")
+	// 测试中的区块间隔为1秒，快于默认目标间隔，窗口结束后难度提高
+	if got := remote.Difficulty(); got != 3 {
+		t.Fatalf("difficulty after a fast window = %d, want 3", got)
+	}
+	mineChain(t, remote, 2, "retarget-next")
+	if b := remote.GetLatest(); b.Difficulty != 3 {
+		t.Fatalf("block after the window mined at difficulty %d, want 3", b.Difficulty)
+	}
+
+	// 其他节点按同样的规则得出每个高度的难度，可以同步跨过调整窗口的链
+	local := NewBlockchain(2, nil)
+	if err := local.SyncHeadersFirst(&chainSource{bc: remote}); err != nil {
+		t.Fatalf("sync across a retarget: %v", err)
+	}
+	if local.Height() != remote.Height() || local.Difficulty() != 3 {
+		t.Fatalf("local height %d difficulty %d, want %d and 3", local.Height(), local.Difficulty(), remote.Height())
+	}
+}
+
+func TestRestartAtRetargetBoundary(t *testing.T) {
+	store := NewMemoryBlockStore()
+	bc := NewBlockchain(2, store)
+	mineChain(t, bc, DefaultRetargetWindow, "restart-retarget")
+	if bc.GetLatest().Difficulty != 2 || bc.Difficulty() != 3 {
+		t.Fatalf("tip difficulty %d, next difficulty %d; want 2 and 3", bc.GetLatest().Difficulty, bc.Difficulty())
+	}
+
+	// 链顶位于调整窗口边界时重启，下一个区块仍使用调整后的难度
+	restarted := NewBlockchain(2, store)
+	if restarted.Height() != DefaultRetargetWindow {
+		t.Fatalf("restarted height %d, want %d", restarted.Height(), DefaultRetargetWindow)
+	}
+	if got := restarted.Difficulty(); got != 3 {
+		t.Fatalf("difficulty after restart = %d, want 3", got)
+	}
+	mineChain(t, restarted, 1, "restart-retarget-next")
+	if b := restarted.GetLatest(); b.Difficulty != 3 {
+		t.Errorf("block after restart mined at difficulty %d, want 3", b.Difficulty)
+	}
+}
@@ -0,0 +1,57 @@
+package blockchain
+
+// internal/blockchain/genesis.go
+// 创世分配（预挖）
+// 创世区块可以包含一笔分配交易，在链开始时为指定地址创建UTXO。
+// 分配交易的ID进入创世区块的Merkle根，因此创世区块哈希覆盖分配内容，
+// 只有使用相同分配的节点才能互相同步
+
+// GenesisAlloc 创世分配项：地址及其初始金额
+type GenesisAlloc struct {
+	Address string `json:"address"`
+	Amount  int    `json:"amount"`
+}
+
+// GenesisAllocTx 返回创世分配交易，输出顺序与alloc一致
+// 分配交易使用coinbase形式的输入，不消费任何UTXO
+func GenesisAllocTx(alloc []GenesisAlloc) UTXOTx {
+	tx := CoinbaseTx("genesis", "", 0)
+	tx.Outputs = make([]TxOutput, len(alloc))
+	for i, a := range alloc {
+		tx.Outputs[i] = TxOutput{Address: a.Address, Amount: a.Amount}
+	}
+	return tx
+}
+
+// NewGenesisWithAlloc 创建包含创世分配交易的创世区块
+// alloc为空时与NewGenesis相同
+func NewGenesisWithAlloc(alloc []GenesisAlloc) Block {
+	g := NewGenesis()
+	if len(alloc) == 0 {
+		return g
+	}
+	txid, _ := TxID(GenesisAllocTx(alloc))
+	g.Transactions = []string{txid}
+	g.MerkleRoot = MerkleRoot(g.Transactions)
+	g.Hash = calcHash(&g)
+	return g
+}
+
+// applyGenesisAlloc 保存创世分配交易并把其输出写入UTXO集合
+func applyGenesisAlloc(alloc []GenesisAlloc) error {
+	if len(alloc) == 0 {
+		return nil
+	}
+	tx := GenesisAllocTx(alloc)
+	if err := ValidateTxStructure(tx); err != nil {
+		return err
+	}
+	txid, err := PutTransaction(tx)
+	if err != nil {
+		return err
+	}
+	for i, out := range tx.Outputs {
+		PutUTXO(txid, i, UTXOEntry{Address: out.Address, Amount: out.Amount})
+	}
+	return nil
+}
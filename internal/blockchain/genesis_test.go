@@ -0,0 +1,66 @@
+package blockchain
+
+import "testing"
+
+// balanceOf 汇总地址在UTXO集合中的余额
+func balanceOf(address string) int {
+	total := 0
+	for _, u := range FindUTXOsForAddress(address) {
+		total += u.Amount
+	}
+	return total
+}
+
+func TestGenesisAlloc(t *testing.T) {
+	alloc := []GenesisAlloc{
+		{Address: "premine-alice", Amount: 1000},
+		{Address: "premine-bob", Amount: 250},
+	}
+	bc := NewBlockchain(1, nil, alloc...)
+
+	if got := balanceOf("premine-alice"); got != 1000 {
+		t.Errorf("alice 余额期望 1000，实际 %d", got)
+	}
+	if got := balanceOf("premine-bob"); got != 250 {
+		t.Errorf("bob 余额期望 250，实际 %d", got)
+	}
+
+	// 创世区块哈希覆盖分配：不同分配得到不同的创世区块
+	genesis, _ := bc.GetBlockByIndex(0)
+	if !genesis.ValidateBasic() {
+		t.Fatal("带分配的创世区块应通过基本校验")
+	}
+	if genesis.Hash == NewGenesis().Hash {
+		t.Error("带分配的创世区块哈希应不同于默认创世区块")
+	}
+	other := NewGenesisWithAlloc([]GenesisAlloc{{Address: "premine-alice", Amount: 1001}})
+	if other.Hash == genesis.Hash {
+		t.Error("分配金额不同时创世区块哈希应不同")
+	}
+	if again := NewGenesisWithAlloc(alloc); again.Hash != genesis.Hash {
+		t.Error("相同分配应得到相同的创世区块")
+	}
+}
+
+func TestGenesisAllocInvalidIgnored(t *testing.T) {
+	bc := NewBlockchain(1, nil, GenesisAlloc{Address: "premine-negative", Amount: -5})
+	genesis, _ := bc.GetBlockByIndex(0)
+	if genesis.Hash != NewGenesis().Hash {
+		t.Error("无效分配应被忽略")
+	}
+	if got := balanceOf("premine-negative"); got != 0 {
+		t.Errorf("无效分配不应产生余额，实际 %d", got)
+	}
+}
+
+func TestGenesisEmptyTransactionsHash(t *testing.T) {
+	g := NewGenesis()
+	nilTxs := g
+	nilTxs.Transactions = nil
+	if calcHash(&nilTxs) != g.Hash || MerkleRoot(nilTxs.Transactions) != g.MerkleRoot {
+		t.Error("nil与空交易列表应得到相同的创世区块哈希")
+	}
+	if !nilTxs.ValidateBasic() {
+		t.Error("交易列表为nil的创世区块应通过基本校验")
+	}
+}
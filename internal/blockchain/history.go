@@ -0,0 +1,95 @@
+package blockchain
+
+// internal/blockchain/history.go
+// 历史余额查询：从创世区块重放主链到指定高度，只跟踪目标地址的输出
+// 结果按（该高度的区块哈希, 地址）缓存，链重组后旧哈希自然失效
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultBalanceCacheSize 历史余额缓存的默认条目数
+const DefaultBalanceCacheSize = 256
+
+// ErrHeightOutOfRange 查询高度超出主链范围
+var ErrHeightOutOfRange = errors.New("height out of range")
+
+// BalanceAt 返回地址在主链指定高度（含该区块）时的余额
+func (bc *Blockchain) BalanceAt(address string, height int) (int, error) {
+	bc.lock.RLock()
+	if height < 0 || height >= len(bc.chain) {
+		tip := len(bc.chain) - 1
+		bc.lock.RUnlock()
+		return 0, fmt.Errorf("%w: %d (tip %d)", ErrHeightOutOfRange, height, tip)
+	}
+	blocks := append([]Block(nil), bc.chain[:height+1]...)
+	bc.lock.RUnlock()
+
+	key := balanceKey{hash: blocks[height].Hash, address: address}
+	if v, ok := bc.balances.get(key); ok {
+		return v, nil
+	}
+
+	owned := make(map[UTXOKey]int)
+	del := func(txid string, vout int) { delete(owned, UTXOKey{Txid: txid, Vout: vout}) }
+	put := func(txid string, vout int, e UTXOEntry) {
+		if e.Address == address {
+			owned[UTXOKey{Txid: txid, Vout: vout}] = e.Amount
+		}
+	}
+	for _, b := range blocks {
+		if err := applyTxs(b.Transactions, del, put); err != nil {
+			return 0, fmt.Errorf("replay block %d: %v", b.Index, err)
+		}
+	}
+	balance := 0
+	for _, amount := range owned {
+		balance += amount
+	}
+	bc.balances.put(key, balance)
+	return balance, nil
+}
+
+// balanceKey 历史余额缓存的键
+type balanceKey struct {
+	hash    string // 查询高度上的区块哈希
+	address string
+}
+
+// balanceCache 固定容量的历史余额缓存，满时淘汰最早写入的条目；零值不缓存
+type balanceCache struct {
+	mu    sync.Mutex
+	size  int
+	vals  map[balanceKey]int
+	order []balanceKey
+}
+
+// newBalanceCache 创建容量为size的缓存
+func newBalanceCache(size int) *balanceCache {
+	return &balanceCache{size: size, vals: make(map[balanceKey]int, size)}
+}
+
+func (c *balanceCache) get(k balanceKey) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.vals[k]
+	return v, ok
+}
+
+func (c *balanceCache) put(k balanceKey, v int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size <= 0 {
+		return
+	}
+	if _, ok := c.vals[k]; !ok {
+		if len(c.order) >= c.size {
+			delete(c.vals, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, k)
+	}
+	c.vals[k] = v
+}
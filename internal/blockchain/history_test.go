@@ -0,0 +1,94 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"mini_chain/internal/wallet"
+)
+
+// transferChain 创建一条创世分配给alice的链：高度1 alice向bob转30，高度2为空块，高度3 bob向carol转10
+func transferChain(t *testing.T) (bc *Blockchain, alice, bob, carol *wallet.Account) {
+	t.Helper()
+	alice, _ = wallet.NewAccount()
+	bob, _ = wallet.NewAccount()
+	carol, _ = wallet.NewAccount()
+	bc = NewBlockchain(1, nil, GenesisAlloc{Address: alice.Address, Amount: 100})
+	genesisTx := bc.GetLatest().Transactions[0]
+
+	mine := func(txids ...string) {
+		t.Helper()
+		if err := bc.ValidateAndApplyBlock(MineBlock(bc.GetLatest(), txids, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	send := func(from *wallet.Account, to string, amount int, in wallet.UTXO) string {
+		t.Helper()
+		wtx, err := wallet.BuildTransaction(from, to, amount, 1, []wallet.UTXO{in})
+		if err != nil {
+			t.Fatal(err)
+		}
+		txid, err := PutTransaction(TxFromWallet(wtx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txid
+	}
+
+	pay := send(alice, bob.Address, 30, wallet.UTXO{Txid: genesisTx, Vout: 0, Amount: 100})
+	mine(pay)
+	mine()
+	mine(send(bob, carol.Address, 10, wallet.UTXO{Txid: pay, Vout: 0, Amount: 30}))
+	return bc, alice, bob, carol
+}
+
+func TestBalanceAt(t *testing.T) {
+	bc, alice, bob, carol := transferChain(t)
+
+	cases := []struct {
+		who    *wallet.Account
+		height int
+		want   int
+	}{
+		{alice, 0, 100},
+		{bob, 0, 0},
+		{alice, 1, 69},
+		{bob, 1, 30},
+		{bob, 2, 30},
+		{carol, 2, 0},
+		{bob, 3, 19},
+		{carol, 3, 10},
+		{alice, 3, 69},
+	}
+	for _, c := range cases {
+		got, err := bc.BalanceAt(c.who.Address, c.height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("balance of %s at height %d = %d, want %d", c.who.Address[:10], c.height, got, c.want)
+		}
+	}
+
+	// 第二次查询命中缓存
+	key := balanceKey{hash: bc.chain[2].Hash, address: bob.Address}
+	if v, ok := bc.balances.get(key); !ok || v != 30 {
+		t.Errorf("cached balance = %d, %v; want 30 cached", v, ok)
+	}
+	if _, err := bc.BalanceAt(bob.Address, 4); !errors.Is(err, ErrHeightOutOfRange) {
+		t.Errorf("expected ErrHeightOutOfRange, got %v", err)
+	}
+}
+
+func TestBalanceCacheEviction(t *testing.T) {
+	c := newBalanceCache(2)
+	for i, k := range []balanceKey{{"h1", "a"}, {"h2", "a"}, {"h3", "a"}} {
+		c.put(k, i)
+	}
+	if _, ok := c.get(balanceKey{"h1", "a"}); ok {
+		t.Error("oldest entry not evicted")
+	}
+	if v, ok := c.get(balanceKey{"h3", "a"}); !ok || v != 2 {
+		t.Errorf("newest entry = %d, %v", v, ok)
+	}
+}
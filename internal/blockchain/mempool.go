@@ -2,29 +2,205 @@ package blockchain
 
 // internal/blockchain/mempool.go
 // 简单的内存池管理，用于UTXO交易ID（字符串）
-// 在生产级节点中，我们会实现优先级、手续费、过期清理等功能
+// 每笔交易记录进入时的手续费（输入总额减输出总额），ListMempool按手续费从高到低返回，供矿工优先打包
+// 内存池容量有上限，满时新交易替换手续费最低（同手续费时最早进入）的交易；等待过久的交易由PruneExpired清除
+// 被替换或清除的交易连同花费其输出的内存池后代交易一起移除，避免后代交易引用已不存在的父交易
 
 import (
+	"context"
+	"errors"
+	"log"
+	"sort"
 	"sync"
+	"time"
 )
 
+// 内存池的默认容量和过期参数
+const (
+	DefaultMempoolMaxSize       = 10000            // 内存池最多容纳的交易数
+	DefaultMempoolExpiry        = 72 * time.Hour   // 自首次收到起超过该时间仍未打包的交易被清除
+	DefaultMempoolPruneInterval = 10 * time.Minute // 周期清理的间隔
+)
+
+// ErrMempoolFull 内存池已满，且交易的手续费不高于池中手续费最低的交易
+var ErrMempoolFull = errors.New("mempool full")
+
+// mempoolEntry 内存池中的一笔交易及本节点首次收到它的时间
+// 重复收到（例如其他节点重新广播）不会更新首次收到时间
+type mempoolEntry struct {
+	txid  string
+	fee   int // 手续费，交易体未知或无法计算时为0
+	added time.Time
+}
+
+// MempoolEntry 内存池条目的公开视图
+type MempoolEntry struct {
+	Txid      string    `json:"txid"`
+	FirstSeen time.Time `json:"first_seen"` // 本节点首次收到该交易的时间
+}
+
 var (
-	mempoolLock sync.Mutex // 内存池互斥锁，保护并发访问
-	mempool     []string   // 内存池，存储待处理的交易ID
+	mempoolLock    sync.Mutex              // 内存池互斥锁，保护并发访问
+	mempool        []mempoolEntry          // 内存池，按进入顺序存储待处理的交易
+	mempoolMaxSize = DefaultMempoolMaxSize // 内存池最多容纳的交易数
 )
 
-// AddToMempool 将交易ID添加到内存池（如果不存在）
-func AddToMempool(txid string) {
+// SetMempoolMaxSize 设置内存池最多容纳的交易数，n < 1 时恢复默认值
+// 已超出新上限的交易不会立即移除，之后加入交易时逐个替换
+func SetMempoolMaxSize(n int) {
+	if n < 1 {
+		n = DefaultMempoolMaxSize
+	}
 	mempoolLock.Lock()
 	defer mempoolLock.Unlock()
+	mempoolMaxSize = n
+}
+
+// AddToMempool 将交易ID添加到内存池（如果不存在），返回是否为新加入的交易；
+// 交易已存在或因内存池已满被拒绝时返回false
+// 手续费由已存储的交易体计算，交易体未知或无法计算时记为0
+func AddToMempool(txid string) bool {
+	fee := 0
+	if tx, err := GetTransaction(txid); err == nil {
+		if f, err := TxFee(tx); err == nil {
+			fee = f
+		}
+	}
+	return AddToMempoolWithFee(txid, fee)
+}
+
+// AddToMempoolWithFee 将手续费为fee的交易ID添加到内存池（如果不存在），返回是否为新加入的交易
+// 已在内存池中的交易保留原来的手续费和首次收到时间；
+// 内存池已满时替换手续费最低的交易（连同其内存池后代交易），新交易的手续费不高于它、
+// 或新交易本身花费被替换交易的输出时拒绝加入并返回false
+func AddToMempoolWithFee(txid string, fee int) bool {
+	mempoolLock.Lock()
 	// 检查交易是否已存在于内存池中
-	for _, t := range mempool {
-		if t == txid {
-			return
+	for _, e := range mempool {
+		if e.txid == txid {
+			mempoolLock.Unlock()
+			return false
 		}
 	}
+	var evicted []string
+	if len(mempool) >= mempoolMaxSize {
+		i := lowestFeeEntry()
+		if mempool[i].fee >= fee {
+			mempoolLock.Unlock()
+			return false
+		}
+		drop := mempoolDescendants(mempool[i].txid)
+		if tx, err := GetTransaction(txid); err == nil && spendsAny(tx, drop) {
+			mempoolLock.Unlock()
+			return false
+		}
+		evicted = removeEntries(drop)
+	}
 	// 添加新交易到内存池
-	mempool = append(mempool, txid)
+	mempool = append(mempool, mempoolEntry{txid: txid, fee: fee, added: time.Now()})
+	mempoolLock.Unlock()
+	for _, id := range evicted {
+		DeleteTransaction(id)
+	}
+	return true
+}
+
+// mempoolDescendants 返回roots及内存池中直接或间接花费它们输出的交易ID集合，调用者需持有mempoolLock
+// 交易体未知的交易无法判断依赖关系，不计入后代
+func mempoolDescendants(roots ...string) map[string]bool {
+	set := make(map[string]bool, len(roots))
+	for _, id := range roots {
+		set[id] = true
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, e := range mempool {
+			if set[e.txid] {
+				continue
+			}
+			if tx, err := GetTransaction(e.txid); err == nil && spendsAny(tx, set) {
+				set[e.txid] = true
+				changed = true
+			}
+		}
+	}
+	return set
+}
+
+// spendsAny 判断交易是否花费了txids中任一交易的输出
+func spendsAny(tx UTXOTx, txids map[string]bool) bool {
+	for _, in := range tx.Inputs {
+		if txids[in.Txid] {
+			return true
+		}
+	}
+	return false
+}
+
+// removeEntries 从内存池中移除txids中的交易，按进入顺序返回实际移除的交易ID，调用者需持有mempoolLock
+func removeEntries(txids map[string]bool) []string {
+	kept := make([]mempoolEntry, 0, len(mempool))
+	var removed []string
+	for _, e := range mempool {
+		if txids[e.txid] {
+			removed = append(removed, e.txid)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	mempool = kept
+	return removed
+}
+
+// lowestFeeEntry 返回手续费最低的内存池条目下标，手续费相同时取最早进入的，调用者需持有mempoolLock且内存池非空
+func lowestFeeEntry() int {
+	low := 0
+	for i, e := range mempool {
+		if e.fee < mempool[low].fee {
+			low = i
+		}
+	}
+	return low
+}
+
+// PruneExpired 移除首次收到时间早于maxAge之前的内存池交易及其交易体，
+// 花费过期交易输出的后代交易即使未过期也一并移除；返回被移除的交易ID
+func PruneExpired(maxAge time.Duration) []string {
+	cutoff := time.Now().Add(-maxAge)
+	mempoolLock.Lock()
+	var roots []string
+	for _, e := range mempool {
+		if e.added.Before(cutoff) {
+			roots = append(roots, e.txid)
+		}
+	}
+	var expired []string
+	if len(roots) > 0 {
+		expired = removeEntries(mempoolDescendants(roots...))
+	}
+	mempoolLock.Unlock()
+	for _, txid := range expired {
+		DeleteTransaction(txid)
+	}
+	return expired
+}
+
+// StartPruner 启动后台协程，每隔interval调用一次PruneExpired(maxAge)，直到ctx取消
+func StartPruner(ctx context.Context, interval, maxAge time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if expired := PruneExpired(maxAge); len(expired) > 0 {
+					log.Printf("Pruned %d expired mempool txs", len(expired))
+				}
+			}
+		}
+	}()
 }
 
 // RemoveFromMempool 从内存池中移除已被包含在区块中的交易
@@ -32,13 +208,13 @@ func RemoveFromMempool(txids []string) {
 	mempoolLock.Lock()
 	defer mempoolLock.Unlock()
 	// 创建新的内存池，只保留未被包含在区块中的交易
-	newPool := make([]string, 0, len(mempool))
+	newPool := make([]mempoolEntry, 0, len(mempool))
 outer:
 	// 遍历当前内存池中的所有交易
 	for _, e := range mempool {
 		// 检查该交易是否在要移除的列表中
 		for _, r := range txids {
-			if e == r {
+			if e.txid == r {
 				continue outer // 如果在移除列表中，跳过该交易
 			}
 		}
@@ -49,12 +225,117 @@ outer:
 	mempool = newPool
 }
 
-// ListMempool 返回当前内存池交易ID的副本
+// EvictFromMempool 将交易从本地内存池和交易体存储中移除，交易不在内存池中时返回false
+// 只影响本节点，其他节点的内存池不受影响
+func EvictFromMempool(txid string) bool {
+	mempoolLock.Lock()
+	found := false
+	for i, e := range mempool {
+		if e.txid == txid {
+			mempool = append(mempool[:i:i], mempool[i+1:]...)
+			found = true
+			break
+		}
+	}
+	mempoolLock.Unlock()
+	if found {
+		DeleteTransaction(txid)
+	}
+	return found
+}
+
+// ListMempool 返回按手续费从高到低排列的内存池交易ID，手续费相同的交易保持进入顺序
 func ListMempool() []string {
+	entries := mempoolByFee()
+	cp := make([]string, len(entries))
+	for i, e := range entries {
+		cp[i] = e.txid
+	}
+	return cp
+}
+
+// mempoolByFee 返回按手续费从高到低稳定排序的内存池条目副本
+func mempoolByFee() []mempoolEntry {
+	entries := snapshotMempool()
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].fee > entries[j].fee })
+	return entries
+}
+
+// ListMempoolEntries 按进入顺序返回内存池条目及其首次收到时间
+func ListMempoolEntries() []MempoolEntry {
+	entries := snapshotMempool()
+	out := make([]MempoolEntry, len(entries))
+	for i, e := range entries {
+		out[i] = MempoolEntry{Txid: e.txid, FirstSeen: e.added}
+	}
+	return out
+}
+
+// MempoolFirstSeen 返回内存池中交易的首次收到时间，交易不在内存池中时返回false
+func MempoolFirstSeen(txid string) (time.Time, bool) {
 	mempoolLock.Lock()
 	defer mempoolLock.Unlock()
-	// 创建内存池的副本以避免外部修改
-	cp := make([]string, len(mempool))
+	for _, e := range mempool {
+		if e.txid == txid {
+			return e.added, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// snapshotMempool 返回当前内存池条目的副本
+func snapshotMempool() []mempoolEntry {
+	mempoolLock.Lock()
+	defer mempoolLock.Unlock()
+	cp := make([]mempoolEntry, len(mempool))
 	copy(cp, mempool)
 	return cp
-}
\ No newline at end of file
+}
+
+// RevalidateMempool 针对当前UTXO集合按进入顺序重新校验内存池中的交易，移除已失效的交易
+// 输入已被区块花费、在内存池中被更早的交易花费或引用不存在输出的交易都会被移除，
+// 依赖被移除交易输出的后续交易也随之移除；交易体未知的交易无法校验，保留在内存池中。
+// 返回被移除的交易ID
+func RevalidateMempool() []string {
+	entries := snapshotMempool()
+	spent := make(map[UTXOKey]bool)   // 内存池中已被花费的输出
+	created := make(map[UTXOKey]bool) // 内存池中交易创建的输出
+	var dropped []string
+	for _, e := range entries {
+		tx, err := GetTransaction(e.txid)
+		if err != nil {
+			continue
+		}
+		if IsCoinbase(tx) || !mempoolInputsAvailable(tx, spent, created) {
+			dropped = append(dropped, e.txid)
+			continue
+		}
+		for _, in := range tx.Inputs {
+			spent[UTXOKey{Txid: in.Txid, Vout: in.Vout}] = true
+		}
+		for i := range tx.Outputs {
+			created[UTXOKey{Txid: e.txid, Vout: i}] = true
+		}
+	}
+	if len(dropped) > 0 {
+		RemoveFromMempool(dropped)
+	}
+	return dropped
+}
+
+// mempoolInputsAvailable 判断交易的所有输入是否仍可花费
+func mempoolInputsAvailable(tx UTXOTx, spent, created map[UTXOKey]bool) bool {
+	for _, in := range tx.Inputs {
+		k := UTXOKey{Txid: in.Txid, Vout: in.Vout}
+		if spent[k] {
+			return false
+		}
+		if created[k] {
+			continue
+		}
+		if _, err := GetUTXO(in.Txid, in.Vout); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package blockchain
+
+// internal/blockchain/mempool_stats.go
+// 内存池健康指标
+// 手续费 = 输入总额 - 输出总额，输入金额通过交易体存储中被引用交易的输出取得；
+// 大小为交易JSON编码（即计算交易ID时的编码）的字节数
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TxSize 返回交易序列化后的字节数
+func TxSize(tx UTXOTx) (int, error) {
+	b, err := json.Marshal(tx)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// TxFee 返回交易的手续费（输入总额减输出总额），coinbase交易的手续费为0
+// 被引用的交易体不在存储中时返回错误
+func TxFee(tx UTXOTx) (int, error) {
+	if IsCoinbase(tx) {
+		return 0, nil
+	}
+	in := 0
+	for _, input := range tx.Inputs {
+		prev, err := GetTransaction(input.Txid)
+		if err != nil {
+			return 0, err
+		}
+		if input.Vout < 0 || input.Vout >= len(prev.Outputs) {
+			return 0, fmt.Errorf("input %s:%d references missing output", input.Txid, input.Vout)
+		}
+		if in, err = CheckedAdd(in, prev.Outputs[input.Vout].Amount); err != nil {
+			return 0, err
+		}
+	}
+	out, err := SumOutputs(tx)
+	if err != nil {
+		return 0, err
+	}
+	return in - out, nil
+}
+
+// MempoolStats 内存池指标快照
+// 手续费和大小只统计交易体已知且手续费可计算的交易（Priced个）
+type MempoolStats struct {
+	Count         int   `json:"count"`           // 内存池交易数
+	Priced        int   `json:"priced"`          // 参与手续费/大小统计的交易数
+	TotalSize     int   `json:"total_size"`      // 交易总字节数
+	MinFee        int   `json:"min_fee"`         // 最低手续费
+	MedianFee     int   `json:"median_fee"`      // 手续费中位数
+	MaxFee        int   `json:"max_fee"`         // 最高手续费
+	OldestAgeSecs int64 `json:"oldest_age_secs"` // 最早进入内存池的交易已等待的秒数
+}
+
+// GetMempoolStats 计算当前内存池的指标
+func GetMempoolStats() MempoolStats {
+	entries := snapshotMempool()
+	now := time.Now()
+	stats := MempoolStats{Count: len(entries)}
+	var fees []int
+	for _, e := range entries {
+		if age := int64(now.Sub(e.added) / time.Second); age > stats.OldestAgeSecs {
+			stats.OldestAgeSecs = age
+		}
+		tx, err := GetTransaction(e.txid)
+		if err != nil {
+			continue
+		}
+		fee, err := TxFee(tx)
+		if err != nil {
+			continue
+		}
+		size, err := TxSize(tx)
+		if err != nil {
+			continue
+		}
+		stats.TotalSize += size
+		fees = append(fees, fee)
+	}
+	stats.Priced = len(fees)
+	if len(fees) == 0 {
+		return stats
+	}
+	sort.Ints(fees)
+	stats.MinFee = fees[0]
+	stats.MaxFee = fees[len(fees)-1]
+	if mid := len(fees) / 2; len(fees)%2 == 1 {
+		stats.MedianFee = fees[mid]
+	} else {
+		stats.MedianFee = (fees[mid-1] + fees[mid]) / 2
+	}
+	return stats
+}
@@ -0,0 +1,366 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMempoolStats(t *testing.T) {
+	// 资金交易：三个输出，各100
+	funding := UTXOTx{Version: TxVersion, Outputs: []TxOutput{
+		{Address: "stats-a", Amount: 100},
+		{Address: "stats-b", Amount: 100},
+		{Address: "stats-c", Amount: 100},
+	}}
+	fundingID, err := PutTransaction(funding)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 手续费分别为 5、1、20，输出数量不同使大小不同
+	spends := []UTXOTx{
+		{Version: TxVersion, Inputs: []TxInput{{Txid: fundingID, Vout: 0}}, Outputs: []TxOutput{{Address: "x", Amount: 95}}},
+		{Version: TxVersion, Inputs: []TxInput{{Txid: fundingID, Vout: 1}}, Outputs: []TxOutput{{Address: "x", Amount: 50}, {Address: "y", Amount: 49}}},
+		{Version: TxVersion, Inputs: []TxInput{{Txid: fundingID, Vout: 2}}, Outputs: []TxOutput{{Address: "x", Amount: 40}, {Address: "y", Amount: 20}, {Address: "z", Amount: 20}}},
+	}
+	var txids []string
+	totalSize := 0
+	for _, tx := range spends {
+		txid, err := PutTransaction(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size, _ := TxSize(tx)
+		totalSize += size
+		txids = append(txids, txid)
+		AddToMempool(txid)
+	}
+	// 交易体未知的交易只计入数量
+	txids = append(txids, "stats-unknown")
+	AddToMempool("stats-unknown")
+	defer RemoveFromMempool(txids)
+
+	// 将第一笔交易的进入时间提前
+	mempoolLock.Lock()
+	for i := range mempool {
+		if mempool[i].txid == txids[0] {
+			mempool[i].added = time.Now().Add(-90 * time.Second)
+		}
+	}
+	mempoolLock.Unlock()
+
+	stats := GetMempoolStats()
+	want := MempoolStats{Count: 4, Priced: 3, TotalSize: totalSize, MinFee: 1, MedianFee: 5, MaxFee: 20, OldestAgeSecs: 90}
+	if stats != want {
+		t.Fatalf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestMempoolRevalidationAfterBlock(t *testing.T) {
+	bc := NewBlockchain(1, nil, GenesisAlloc{Address: "reval-alice", Amount: 100})
+	genesis, _ := bc.GetBlockByIndex(0)
+	allocTxid := genesis.Transactions[0]
+
+	spend := func(to string, amount int) string {
+		txid, err := PutTransaction(UTXOTx{
+			Version: TxVersion,
+			Inputs:  []TxInput{{Txid: allocTxid, Vout: 0}},
+			Outputs: []TxOutput{{Address: to, Amount: amount}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txid
+	}
+	// 内存池中的交易和区块中的交易花费同一个输出
+	pooled := spend("reval-bob", 100)
+	child, _ := PutTransaction(UTXOTx{
+		Version: TxVersion,
+		Inputs:  []TxInput{{Txid: pooled, Vout: 0}},
+		Outputs: []TxOutput{{Address: "reval-carol", Amount: 100}},
+	})
+	mined := spend("reval-dave", 100)
+	AddToMempool(pooled)
+	AddToMempool(child)
+	AddToMempool("reval-unknown")
+	defer RemoveFromMempool([]string{pooled, child, "reval-unknown"})
+
+	b := MineBlock(bc.GetLatest(), []string{mined}, 1)
+	if err := bc.ValidateAndApplyBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	if mempoolContains(pooled) {
+		t.Error("冲突交易应被移出内存池")
+	}
+	if mempoolContains(child) {
+		t.Error("依赖冲突交易输出的交易应被移出内存池")
+	}
+	if !mempoolContains("reval-unknown") {
+		t.Error("交易体未知的交易应保留")
+	}
+}
+
+func TestMempoolFirstSeen(t *testing.T) {
+	tx := UTXOTx{Version: TxVersion, Outputs: []TxOutput{{Address: "first-seen", Amount: 7}}}
+	txid, err := PutTransaction(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now()
+	if !AddToMempool(txid) {
+		t.Fatal("new tx not added")
+	}
+	defer RemoveFromMempool([]string{txid})
+
+	seen, ok := MempoolFirstSeen(txid)
+	if !ok || seen.Before(before) || seen.After(time.Now()) {
+		t.Fatalf("first-seen = %v, %v; want set at add time", seen, ok)
+	}
+	found := false
+	for _, e := range ListMempoolEntries() {
+		if e.Txid == txid {
+			found = true
+			if !e.FirstSeen.Equal(seen) {
+				t.Errorf("ListMempoolEntries first-seen %v, want %v", e.FirstSeen, seen)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("tx missing from ListMempoolEntries")
+	}
+
+	// 重新广播的交易再次到达本节点（或被其他节点重播）不会刷新首次收到时间
+	r := NewRebroadcaster(func(id string, _ UTXOTx) {
+		if AddToMempool(id) {
+			t.Errorf("re-announced tx %s added twice", id)
+		}
+	})
+	r.Interval = time.Minute
+	if got := r.Rebroadcast(seen.Add(time.Minute)); len(got) != 1 {
+		t.Fatalf("expected one re-announcement, got %v", got)
+	}
+	if again, _ := MempoolFirstSeen(txid); !again.Equal(seen) {
+		t.Fatalf("first-seen changed from %v to %v after rebroadcast", seen, again)
+	}
+	// 过期按首次收到时间计算，与最近一次公告无关
+	if got := r.Rebroadcast(seen.Add(r.MaxAge)); len(got) != 0 {
+		t.Fatalf("tx past max age since first seen re-announced: %v", got)
+	}
+}
+
+func TestMiningOrderByFee(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	bc.SetMaxBlockTxs(2)
+
+	// 四笔互不相关的交易，手续费分别为 30、70、10、50
+	fees := []int{30, 70, 10, 50}
+	txids := make([]string, len(fees))
+	for i, fee := range fees {
+		prev := fmt.Sprintf("feeorder-fund-%d", i)
+		PutUTXO(prev, 0, UTXOEntry{Address: "feeorder-alice", Amount: 100})
+		t.Cleanup(func() { DeleteUTXO(prev, 0) })
+		txid, err := PutTransaction(UTXOTx{
+			Version: TxVersion,
+			Inputs:  []TxInput{{Txid: prev, Vout: 0}},
+			Outputs: []TxOutput{{Address: "feeorder-bob", Amount: 100 - fee}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		txids[i] = txid
+		AddToMempoolWithFee(txid, fee)
+	}
+	t.Cleanup(func() { RemoveFromMempool(txids) })
+
+	var ours []string
+	for _, id := range ListMempool() {
+		for _, txid := range txids {
+			if id == txid {
+				ours = append(ours, id)
+			}
+		}
+	}
+	if want := []string{txids[1], txids[3], txids[0], txids[2]}; fmt.Sprint(ours) != fmt.Sprint(want) {
+		t.Fatalf("ListMempool order %v, want %v", ours, want)
+	}
+
+	const miner = "feeorder-miner"
+	before := GetBalance(miner)
+	reward := bc.Params().BlockSubsidy(bc.Height() + 1)
+	b, err := bc.MinePending(context.Background(), miner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{txids[1], txids[3]}; fmt.Sprint(b.Transactions[1:]) != fmt.Sprint(want) {
+		t.Fatalf("mined txs %v, want the two highest-fee txs %v", b.Transactions[1:], want)
+	}
+	cb, err := GetTransaction(b.Transactions[0])
+	if err != nil || !IsCoinbase(cb) {
+		t.Fatalf("first tx is not a coinbase: %+v, %v", cb, err)
+	}
+	if got := cb.Outputs[0].Amount; got != reward+70+50 {
+		t.Fatalf("coinbase amount %d, want reward %d plus fees 120", got, reward)
+	}
+	if err := bc.ValidateAndApplyBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetBalance(miner) - before; got != reward+120 {
+		t.Fatalf("miner balance increased by %d, want %d", got, reward+120)
+	}
+	if !mempoolContains(txids[0]) || !mempoolContains(txids[2]) || mempoolContains(txids[1]) {
+		t.Fatal("mined txs should leave the mempool and the lower-fee txs remain")
+	}
+}
+
+func TestMempoolExpiry(t *testing.T) {
+	RemoveFromMempool(ListMempool())
+	var txids []string
+	for i := 0; i < 3; i++ {
+		txid, err := PutTransaction(UTXOTx{Version: TxVersion, Outputs: []TxOutput{{Address: "expiry", Amount: i + 1}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		AddToMempool(txid)
+		txids = append(txids, txid)
+	}
+	defer RemoveFromMempool(txids)
+	backdate := func(txid string, age time.Duration) {
+		mempoolLock.Lock()
+		defer mempoolLock.Unlock()
+		for i := range mempool {
+			if mempool[i].txid == txid {
+				mempool[i].added = time.Now().Add(-age)
+			}
+		}
+	}
+
+	backdate(txids[0], 2*time.Hour)
+	if got := PruneExpired(time.Hour); len(got) != 1 || got[0] != txids[0] {
+		t.Fatalf("pruned %v, want only %s", got, txids[0])
+	}
+	if mempoolContains(txids[0]) {
+		t.Fatal("expired tx still in mempool")
+	}
+	if _, err := GetTransaction(txids[0]); err == nil {
+		t.Fatal("expired tx body not deleted")
+	}
+	if !mempoolContains(txids[1]) || !mempoolContains(txids[2]) {
+		t.Fatal("fresh txs were pruned")
+	}
+
+	// 后台清理协程周期性清除过期交易
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backdate(txids[1], 2*time.Hour)
+	StartPruner(ctx, 10*time.Millisecond, time.Hour)
+	deadline := time.Now().Add(5 * time.Second)
+	for mempoolContains(txids[1]) {
+		if time.Now().After(deadline) {
+			t.Fatal("pruner did not remove the expired tx")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !mempoolContains(txids[2]) {
+		t.Fatal("pruner removed a fresh tx")
+	}
+}
+
+func TestMempoolCapEviction(t *testing.T) {
+	RemoveFromMempool(ListMempool())
+	SetMempoolMaxSize(3)
+	defer SetMempoolMaxSize(0)
+	ids := map[string]string{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		txid, err := PutTransaction(UTXOTx{Version: TxVersion, Outputs: []TxOutput{{Address: "cap-" + name, Amount: 1}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = txid
+		defer RemoveFromMempool([]string{txid})
+	}
+
+	for _, c := range []struct {
+		name string
+		fee  int
+		ok   bool
+	}{
+		{"a", 5, true},
+		{"b", 1, true},
+		{"c", 3, true},
+		{"d", 1, false}, // 已满，手续费不高于最低的b
+		{"e", 4, true},  // 替换手续费最低的b
+		{"f", 3, false}, // 不高于现在最低的c
+	} {
+		if got := AddToMempoolWithFee(ids[c.name], c.fee); got != c.ok {
+			t.Fatalf("add %s (fee %d) = %v, want %v", c.name, c.fee, got, c.ok)
+		}
+	}
+	if got, want := ListMempool(), []string{ids["a"], ids["e"], ids["c"]}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("mempool %v, want %v", got, want)
+	}
+	if _, err := GetTransaction(ids["b"]); err == nil {
+		t.Fatal("evicted tx body not deleted")
+	}
+}
+
+func TestMempoolEvictsDescendants(t *testing.T) {
+	RemoveFromMempool(ListMempool())
+	SetMempoolMaxSize(3)
+	defer SetMempoolMaxSize(0)
+	put := func(tx UTXOTx) string {
+		txid, err := PutTransaction(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { RemoveFromMempool([]string{txid}); DeleteTransaction(txid) })
+		return txid
+	}
+	spend := func(prev string, vout int, to string) string {
+		return put(UTXOTx{Version: TxVersion, Inputs: []TxInput{{Txid: prev, Vout: vout}}, Outputs: []TxOutput{{Address: to, Amount: 1}}})
+	}
+	parent := put(UTXOTx{Version: TxVersion, Outputs: []TxOutput{{Address: "family", Amount: 1}, {Address: "family", Amount: 1}}})
+	child := spend(parent, 0, "family-child")
+	other := put(UTXOTx{Version: TxVersion, Outputs: []TxOutput{{Address: "family-other", Amount: 1}}})
+	AddToMempoolWithFee(parent, 1)
+	AddToMempoolWithFee(child, 5)
+	AddToMempoolWithFee(other, 3)
+
+	// 花费即将被替换的父交易输出的新交易不能替换父交易
+	sibling := spend(parent, 1, "family-sibling")
+	if AddToMempoolWithFee(sibling, 2) {
+		t.Fatal("tx spending the evicted parent was admitted")
+	}
+	// 替换手续费最低的父交易时，子交易一并移除
+	newcomer := put(UTXOTx{Version: TxVersion, Outputs: []TxOutput{{Address: "family-new", Amount: 1}}})
+	if !AddToMempoolWithFee(newcomer, 2) {
+		t.Fatal("newcomer rejected")
+	}
+	if got, want := ListMempool(), []string{other, newcomer}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("mempool %v, want %v", got, want)
+	}
+	for _, txid := range []string{parent, child} {
+		if _, err := GetTransaction(txid); err == nil {
+			t.Fatalf("evicted tx %s body not deleted", txid)
+		}
+	}
+
+	// 父交易过期时，未过期的子孙交易一并清除
+	RemoveFromMempool(ListMempool())
+	parent = put(UTXOTx{Version: TxVersion, Outputs: []TxOutput{{Address: "family-old", Amount: 1}}})
+	child = spend(parent, 0, "family-old-child")
+	grandchild := spend(child, 0, "family-old-grandchild")
+	for _, txid := range []string{parent, child, grandchild} {
+		AddToMempoolWithFee(txid, 1)
+	}
+	mempoolLock.Lock()
+	mempool[0].added = time.Now().Add(-2 * time.Hour)
+	mempoolLock.Unlock()
+	if got, want := PruneExpired(time.Hour), []string{parent, child, grandchild}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("pruned %v, want %v", got, want)
+	}
+	if n := len(ListMempool()); n != 0 {
+		t.Fatalf("%d txs left in mempool after pruning the family", n)
+	}
+}
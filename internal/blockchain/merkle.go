@@ -0,0 +1,128 @@
+package blockchain
+
+// internal/blockchain/merkle.go
+// 交易ID的Merkle树计算
+// 区块头只承诺Merkle根，使得仅凭区块头即可校验哈希与工作量证明
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTxNotInTree 要证明的交易不在交易ID列表中
+var ErrTxNotInTree = errors.New("tx not in merkle tree")
+
+// hashPair 计算两个子节点拼接后的SHA256哈希（十六进制）
+func hashPair(left, right string) string {
+	sum := sha256.Sum256([]byte(left + right))
+	return hex.EncodeToString(sum[:])
+}
+
+// MerkleRoot 计算交易ID列表的Merkle根
+// 叶子节点为交易ID本身，节点数为奇数时复制最后一个节点
+// 没有交易时返回空字符串
+func MerkleRoot(txids []string) string {
+	if len(txids) == 0 {
+		return ""
+	}
+	level := make([]string, len(txids))
+	copy(level, txids)
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([]string, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, hashPair(level[i], level[i+1]))
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// merkleProofStep Merkle证明中的一步：兄弟节点哈希及其所在的一侧
+type merkleProofStep struct {
+	sibling string // 兄弟节点哈希
+	left    bool   // 兄弟节点是否位于左侧
+}
+
+// Merkle证明中每一步编码为兄弟节点哈希加上表示其所在一侧的前缀
+const (
+	merkleLeftPrefix  = "L:" // 兄弟节点位于左侧
+	merkleRightPrefix = "R:" // 兄弟节点位于右侧
+)
+
+// buildMerkleProof 为txids中第index个交易构建从叶子到根的Merkle包含证明
+// 与MerkleRoot使用相同的规则：节点数为奇数时复制最后一个节点
+func buildMerkleProof(txids []string, index int) ([]merkleProofStep, error) {
+	if index < 0 || index >= len(txids) {
+		return nil, fmt.Errorf("tx index %d out of range", index)
+	}
+	level := make([]string, len(txids))
+	copy(level, txids)
+	proof := []merkleProofStep{}
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		if index%2 == 0 {
+			proof = append(proof, merkleProofStep{sibling: level[index+1], left: false})
+		} else {
+			proof = append(proof, merkleProofStep{sibling: level[index-1], left: true})
+		}
+		next := make([]string, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, hashPair(level[i], level[i+1]))
+		}
+		level = next
+		index /= 2
+	}
+	return proof, nil
+}
+
+// MerkleProof 为txids中的交易target构建Merkle包含证明，target不在列表中时返回ErrTxNotInTree
+// 证明从叶子到根排列，每一步为兄弟节点哈希，前缀"L:"或"R:"表示兄弟节点位于左侧或右侧；
+// 交易ID重复时使用第一次出现的位置
+func MerkleProof(txids []string, target string) ([]string, error) {
+	for i, id := range txids {
+		if id != target {
+			continue
+		}
+		steps, err := buildMerkleProof(txids, i)
+		if err != nil {
+			return nil, err
+		}
+		proof := make([]string, len(steps))
+		for j, step := range steps {
+			if step.left {
+				proof[j] = merkleLeftPrefix + step.sibling
+			} else {
+				proof[j] = merkleRightPrefix + step.sibling
+			}
+		}
+		return proof, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTxNotInTree, target)
+}
+
+// VerifyMerkleProof 校验leaf通过MerkleProof生成的proof能否得到给定的Merkle根，格式错误的证明返回false
+func VerifyMerkleProof(root, leaf string, proof []string) bool {
+	if root == "" || leaf == "" {
+		return false
+	}
+	cur := leaf
+	for _, step := range proof {
+		switch {
+		case strings.HasPrefix(step, merkleLeftPrefix):
+			cur = hashPair(strings.TrimPrefix(step, merkleLeftPrefix), cur)
+		case strings.HasPrefix(step, merkleRightPrefix):
+			cur = hashPair(cur, strings.TrimPrefix(step, merkleRightPrefix))
+		default:
+			return false
+		}
+	}
+	return cur == root
+}
@@ -0,0 +1,43 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMerkleProof(t *testing.T) {
+	txids := []string{"tx-a", "tx-b", "tx-c", "tx-d", "tx-e"} // 奇数个叶子
+	root := MerkleRoot(txids)
+
+	// 奇数层复制最后一个节点
+	ab, cd, ee := hashPair("tx-a", "tx-b"), hashPair("tx-c", "tx-d"), hashPair("tx-e", "tx-e")
+	abcd, eeee := hashPair(ab, cd), hashPair(ee, ee)
+	if want := hashPair(abcd, eeee); root != want {
+		t.Fatalf("MerkleRoot = %s, want %s", root, want)
+	}
+
+	for _, txid := range txids {
+		proof, err := MerkleProof(txids, txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyMerkleProof(root, txid, proof) {
+			t.Errorf("proof for %s does not verify", txid)
+		}
+	}
+
+	// 不在树中的交易无法构建证明，借用其他交易的证明也无法通过校验
+	if _, err := MerkleProof(txids, "tx-x"); !errors.Is(err, ErrTxNotInTree) {
+		t.Fatalf("expected %v, got %v", ErrTxNotInTree, err)
+	}
+	proof, _ := MerkleProof(txids, "tx-c")
+	if VerifyMerkleProof(root, "tx-x", proof) {
+		t.Error("proof verified for a tx not in the tree")
+	}
+	if VerifyMerkleProof(MerkleRoot(txids[:4]), "tx-c", proof) {
+		t.Error("proof verified against a different root")
+	}
+	if VerifyMerkleProof(root, "tx-c", []string{"x" + proof[0][1:], proof[1], proof[2]}) {
+		t.Error("proof with a malformed step verified")
+	}
+}
@@ -0,0 +1,175 @@
+package blockchain
+
+// internal/blockchain/params.go
+// 共识参数
+// 难度、区块奖励、减半间隔、区块大小、出块间隔和货币总量上限集中在ConsensusParams中，
+// 启动时整体校验，拒绝互相矛盾的组合（如区块奖励超过总量上限、按奖励计划发行的总量超过上限），
+// 之后共识代码只从Blockchain持有的这份已校验参数读取
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// 默认共识参数
+const (
+	DefaultDifficulty      = 3          // 默认初始难度
+	DefaultBlockReward     = 10         // 默认初始区块奖励
+	DefaultHalvingInterval = 210000     // 默认奖励减半间隔（区块数）
+	DefaultMaxBlockSize    = 1 << 20    // 默认区块内交易规范编码的总字节数上限
+	DefaultMaxSupply       = 21_000_000 // 默认货币总量上限
+)
+
+// ErrInvalidConsensusParams 共识参数无效或互相矛盾
+var ErrInvalidConsensusParams = errors.New("invalid consensus params")
+
+// ErrBlockTooLarge 区块内交易的总大小超过MaxBlockSize
+var ErrBlockTooLarge = errors.New("block too large")
+
+// ConsensusParams 共识参数
+type ConsensusParams struct {
+	Difficulty      int   `json:"difficulty"`       // 初始PoW难度（前导十六进制0的个数），之后由难度调整改变
+	BlockReward     int   `json:"block_reward"`     // 初始区块奖励
+	HalvingInterval int   `json:"halving_interval"` // 每隔多少个区块奖励减半，0表示不减半
+	MaxBlockSize    int   `json:"max_block_size"`   // 区块内交易规范编码的总字节数上限
+	TargetSpacing   int64 `json:"target_spacing"`   // 难度调整的目标出块间隔（秒）
+	RetargetWindow  int   `json:"retarget_window"`  // 难度调整窗口（区块数）
+	MaxSupply       int   `json:"max_supply"`       // 货币总量上限（含创世分配）
+}
+
+// DefaultConsensusParams 返回默认共识参数
+func DefaultConsensusParams() ConsensusParams {
+	return ConsensusParams{
+		Difficulty:      DefaultDifficulty,
+		BlockReward:     DefaultBlockReward,
+		HalvingInterval: DefaultHalvingInterval,
+		MaxBlockSize:    DefaultMaxBlockSize,
+		TargetSpacing:   DefaultTargetSpacing,
+		RetargetWindow:  DefaultRetargetWindow,
+		MaxSupply:       DefaultMaxSupply,
+	}
+}
+
+// LoadConsensusParams 从JSON文件读取共识参数，文件中未出现的字段使用默认值，读取后校验
+func LoadConsensusParams(path string) (ConsensusParams, error) {
+	p := DefaultConsensusParams()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("%w: %v", ErrInvalidConsensusParams, err)
+	}
+	return p, p.Validate()
+}
+
+// Validate 检查各参数的取值范围以及参数之间的一致性
+func (p ConsensusParams) Validate() error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidConsensusParams, fmt.Sprintf(format, args...))
+	}
+	switch {
+	case p.Difficulty < MinDifficulty || p.Difficulty > MaxDifficulty:
+		return invalid("difficulty %d outside [%d, %d]", p.Difficulty, MinDifficulty, MaxDifficulty)
+	case p.BlockReward < 0:
+		return invalid("negative block reward %d", p.BlockReward)
+	case p.HalvingInterval < 0:
+		return invalid("negative halving interval %d", p.HalvingInterval)
+	case p.MaxBlockSize < 1:
+		return invalid("max block size %d below 1", p.MaxBlockSize)
+	case p.TargetSpacing <= 0:
+		return invalid("target spacing %d must be positive", p.TargetSpacing)
+	case p.RetargetWindow < 2:
+		return invalid("retarget window %d below 2", p.RetargetWindow)
+	case p.MaxSupply < 1:
+		return invalid("max supply %d below 1", p.MaxSupply)
+	case p.BlockReward > p.MaxSupply:
+		return invalid("block reward %d exceeds max supply %d", p.BlockReward, p.MaxSupply)
+	case p.BlockReward > 0 && p.HalvingInterval == 0:
+		return invalid("block reward %d never halves, issuance would exceed max supply %d", p.BlockReward, p.MaxSupply)
+	}
+	if total, ok := p.scheduledIssuance(); !ok {
+		return invalid("scheduled issuance %d exceeds max supply %d", total, p.MaxSupply)
+	}
+	return nil
+}
+
+// scheduledIssuance 计算按奖励计划全部区块奖励的发行总量，超过MaxSupply时提前返回false
+func (p ConsensusParams) scheduledIssuance() (int, bool) {
+	total := 0
+	for reward := p.BlockReward; reward > 0; reward >>= 1 {
+		if p.HalvingInterval > (math.MaxInt-total)/reward {
+			return math.MaxInt, false
+		}
+		total += reward * p.HalvingInterval
+		if total > p.MaxSupply {
+			return total, false
+		}
+	}
+	return total, true
+}
+
+// BlockSubsidy 返回高度为height的区块的奖励：每HalvingInterval个区块减半，创世区块没有奖励
+func (p ConsensusParams) BlockSubsidy(height int) int {
+	if height < 1 {
+		return 0
+	}
+	if p.HalvingInterval == 0 {
+		return p.BlockReward
+	}
+	era := (height - 1) / p.HalvingInterval
+	if era >= 63 {
+		return 0
+	}
+	return p.BlockReward >> era
+}
+
+// Params 返回区块链使用的共识参数；Difficulty为初始难度，当前难度见Difficulty()
+func (bc *Blockchain) Params() ConsensusParams {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	return bc.params
+}
+
+// checkBlockSize 检查区块内交易规范编码的总大小不超过max
+// 本地没有交易体的交易无法检查，返回ErrTxNotFound
+func checkBlockSize(b *Block, max int) error {
+	total := 0
+	for _, txid := range b.Transactions {
+		tx, err := GetTransaction(txid)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrTxNotFound, txid)
+		}
+		size, err := TxSize(tx)
+		if err != nil {
+			return err
+		}
+		if total += size; total > max {
+			return fmt.Errorf("%w: more than %d bytes of transactions", ErrBlockTooLarge, max)
+		}
+	}
+	return nil
+}
+
+// fitBlockSize 返回txids中总大小不超过max的最长前缀，保持交易顺序（子交易不会脱离其父交易被打包）
+// 本地没有交易体的交易不计大小
+func fitBlockSize(txids []string, max int) []string {
+	total := 0
+	for i, txid := range txids {
+		tx, err := GetTransaction(txid)
+		if err != nil {
+			continue
+		}
+		size, err := TxSize(tx)
+		if err != nil {
+			continue
+		}
+		if total += size; total > max {
+			return txids[:i]
+		}
+	}
+	return txids
+}
@@ -0,0 +1,121 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"mini_chain/internal/wallet"
+)
+
+func TestConsensusParamsValidate(t *testing.T) {
+	if err := DefaultConsensusParams().Validate(); err != nil {
+		t.Fatalf("default params rejected: %v", err)
+	}
+	valid := ConsensusParams{Difficulty: 2, BlockReward: 50, HalvingInterval: 100, MaxBlockSize: 4096, TargetSpacing: 5, RetargetWindow: 4, MaxSupply: 10000}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid params rejected: %v", err)
+	}
+
+	cases := map[string]func(p *ConsensusParams){
+		"zero difficulty":         func(p *ConsensusParams) { p.Difficulty = 0 },
+		"difficulty too high":     func(p *ConsensusParams) { p.Difficulty = MaxDifficulty + 1 },
+		"negative reward":         func(p *ConsensusParams) { p.BlockReward = -1 },
+		"negative halving":        func(p *ConsensusParams) { p.HalvingInterval = -1 },
+		"zero block size":         func(p *ConsensusParams) { p.MaxBlockSize = 0 },
+		"zero spacing":            func(p *ConsensusParams) { p.TargetSpacing = 0 },
+		"window too small":        func(p *ConsensusParams) { p.RetargetWindow = 1 },
+		"zero max supply":         func(p *ConsensusParams) { p.MaxSupply = 0 },
+		"reward above max supply": func(p *ConsensusParams) { p.BlockReward = p.MaxSupply + 1 },
+		"reward never halves":     func(p *ConsensusParams) { p.HalvingInterval = 0 },
+		// 50*100 + 25*100 + 12*100 + ... = 9700 > 9000
+		"issuance above supply": func(p *ConsensusParams) { p.MaxSupply = 9000 },
+		"issuance overflows":    func(p *ConsensusParams) { p.HalvingInterval = 1 << 62 },
+	}
+	for name, mutate := range cases {
+		p := valid
+		mutate(&p)
+		if err := p.Validate(); !errors.Is(err, ErrInvalidConsensusParams) {
+			t.Errorf("%s: expected ErrInvalidConsensusParams, got %v", name, err)
+		}
+		if _, err := NewBlockchainWithParams(p, nil); !errors.Is(err, ErrInvalidConsensusParams) {
+			t.Errorf("%s: NewBlockchainWithParams returned %v", name, err)
+		}
+	}
+
+	// 没有奖励时不需要减半
+	noReward := valid
+	noReward.BlockReward, noReward.HalvingInterval = 0, 0
+	if err := noReward.Validate(); err != nil {
+		t.Errorf("zero reward without halving rejected: %v", err)
+	}
+}
+
+func TestNewBlockchainWithParams(t *testing.T) {
+	p := ConsensusParams{Difficulty: 2, BlockReward: 10, HalvingInterval: 10, MaxBlockSize: 4096, TargetSpacing: 5, RetargetWindow: 4, MaxSupply: 500}
+	acc, _ := wallet.NewAccount()
+
+	// 发行计划共10*10+5*10+2*10+1*10 = 180，创世分配最多320
+	if _, err := NewBlockchainWithParams(p, nil, GenesisAlloc{Address: acc.Address, Amount: 321}); !errors.Is(err, ErrInvalidConsensusParams) {
+		t.Fatalf("over-allocated genesis accepted: %v", err)
+	}
+	bc, err := NewBlockchainWithParams(p, nil, GenesisAlloc{Address: acc.Address, Amount: 320})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bc.Params() != p || bc.Difficulty() != p.Difficulty {
+		t.Fatalf("chain params %+v difficulty %d, want %+v", bc.Params(), bc.Difficulty(), p)
+	}
+}
+
+func TestBlockSubsidy(t *testing.T) {
+	p := ConsensusParams{BlockReward: 50, HalvingInterval: 10}
+	cases := map[int]int{0: 0, 1: 50, 10: 50, 11: 25, 20: 25, 21: 12, 60: 1, 61: 0}
+	for height, want := range cases {
+		if got := p.BlockSubsidy(height); got != want {
+			t.Errorf("subsidy at height %d = %d, want %d", height, got, want)
+		}
+	}
+}
+
+func TestMaxBlockSize(t *testing.T) {
+	acc, _ := wallet.NewAccount()
+	fund := func(id string) string {
+		t.Helper()
+		PutUTXO(id, 0, UTXOEntry{Address: acc.Address, Amount: 100})
+		t.Cleanup(func() { DeleteUTXO(id, 0) })
+		wtx, err := wallet.BuildTransaction(acc, acc.Address, 10, 1, []wallet.UTXO{{Txid: id, Vout: 0, Amount: 100}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		txid, err := PutTransaction(TxFromWallet(wtx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txid
+	}
+	tx1 := fund("blocksize-1-" + acc.Address[2:10])
+	tx2 := fund("blocksize-2-" + acc.Address[2:10])
+	body, _ := GetTransaction(tx1)
+	size, _ := TxSize(body)
+
+	// 上限只容得下一笔交易
+	p := DefaultConsensusParams()
+	p.Difficulty = 1
+	p.MaxBlockSize = size + size/2
+	bc, err := NewBlockchainWithParams(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fitBlockSize([]string{tx1, tx2}, p.MaxBlockSize); len(got) != 1 || got[0] != tx1 {
+		t.Fatalf("fitBlockSize kept %v, want only %s", got, tx1)
+	}
+
+	err = bc.ValidateAndApplyBlock(MineBlock(bc.GetLatest(), []string{tx1, tx2}, 1))
+	var rerr *BlockRejectError
+	if !errors.As(err, &rerr) || rerr.Reason != RejectTooLarge || !errors.Is(err, ErrBlockTooLarge) {
+		t.Fatalf("oversized block: got %v, want %s", err, RejectTooLarge)
+	}
+	if err := bc.ValidateAndApplyBlock(MineBlock(bc.GetLatest(), []string{tx1}, 1)); err != nil {
+		t.Fatalf("block within the size limit rejected: %v", err)
+	}
+}
@@ -1,44 +1,88 @@
 package blockchain
 
 import (
- 	"crypto/sha256"
+	"context"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
-	"strings"
-	"time"
 )
 
+// ErrMiningDeadline 在找到满足目标的nonce之前上下文已到期或被取消
+var ErrMiningDeadline = errors.New("mining deadline exceeded")
+
+// ErrInvalidDifficulty 难度不在[MinDifficulty, MaxDifficulty]之内
+// 难度小于1时目标值不小于2^256，任何哈希都满足，必须当作错误而不是放行
+var ErrInvalidDifficulty = errors.New("invalid difficulty")
+
+// checkDifficulty 检查难度在[MinDifficulty, MaxDifficulty]之内
+func checkDifficulty(difficulty int) error {
+	if difficulty < MinDifficulty || difficulty > MaxDifficulty {
+		return fmt.Errorf("%w: %d outside [%d, %d]", ErrInvalidDifficulty, difficulty, MinDifficulty, MaxDifficulty)
+	}
+	return nil
+}
+
+// ctxCheckInterval 挖矿时每尝试多少个nonce检查一次上下文
+const ctxCheckInterval = 1 << 12
+
 // ProofOfWork 结构体，封装区块和目标值
 type ProofOfWork struct {
 	block  *Block
 	target *big.Int
+	err    error // 难度无效时非nil，Validate一律失败，RunContext直接返回该错误
 }
 
 // NewProofOfWork 创建新的工作量证明实例
-// difficulty: 难度系数（十六进制前导零数量）
+// difficulty: 难度系数（十六进制前导零数量），不在[MinDifficulty, MaxDifficulty]之内时任何区块都不满足
 func NewProofOfWork(block *Block, difficulty int) *ProofOfWork {
+	if err := checkDifficulty(difficulty); err != nil {
+		return &ProofOfWork{block: block, target: new(big.Int), err: err}
+	}
+	return &ProofOfWork{block: block, target: targetForDifficulty(difficulty)}
+}
+
+// targetForDifficulty 将难度转换为big.Int目标值 2^(256 - difficulty*4)
+func targetForDifficulty(difficulty int) *big.Int {
 	// 将十六进制前导零数量转换为比特位数（1 hex char = 4 bits）
 	bits := difficulty * 4
 	target := big.NewInt(1)
 	target.Lsh(target, uint(256-bits))
-	return &ProofOfWork{block, target}
+	return target
+}
+
+// hashMeetsTarget 判断十六进制哈希是否严格小于难度对应的目标值，难度无效时返回false
+// 用于只有区块头（没有完整区块）时的PoW校验
+func hashMeetsTarget(hash string, difficulty int) bool {
+	if checkDifficulty(difficulty) != nil {
+		return false
+	}
+	raw, err := hex.DecodeString(hash)
+	if err != nil || len(raw) != sha256.Size {
+		return false
+	}
+	return new(big.Int).SetBytes(raw).Cmp(targetForDifficulty(difficulty)) == -1
+}
+
+// HeaderMeetsTarget 判断区块头哈希是否满足其声明难度的目标值
+func HeaderMeetsTarget(h BlockHeader) bool {
+	return hashMeetsTarget(h.Hash, h.Difficulty)
 }
 
 // prepareData 准备用于哈希计算的数据
+// 使用与calcHash相同的规范头部编码，只替换其中的nonce
 func (pow *ProofOfWork) prepareData(nonce int64) []byte {
-	transactions := strings.Join(pow.block.Transactions, "")
-	data := fmt.Sprintf("%d%d%s%s%d",
-		pow.block.Index,
-		pow.block.Timestamp,
-		pow.block.PrevHash,
-		transactions,
-		nonce)
-	return []byte(data)
+	header := *pow.block
+	header.Nonce = nonce
+	return headerBytes(&header)
 }
 
-// Run 执行挖矿过程，寻找满足条件的nonce
+// Run 执行挖矿过程，寻找满足条件的nonce；难度无效时不挖矿，返回nil哈希
 func (pow *ProofOfWork) Run() (int64, []byte) {
+	if pow.err != nil {
+		return 0, nil
+	}
 	var hashInt big.Int
 	nonce := int64(0)
 
@@ -54,8 +98,34 @@ func (pow *ProofOfWork) Run() (int64, []byte) {
 	}
 }
 
-// Validate 验证区块是否满足工作量证明
+// RunContext 与Run相同，但在ctx到期或取消时停止并返回ErrMiningDeadline
+func (pow *ProofOfWork) RunContext(ctx context.Context) (int64, []byte, error) {
+	if pow.err != nil {
+		return 0, nil, pow.err
+	}
+	var hashInt big.Int
+	nonce := int64(0)
+
+	for {
+		if nonce%ctxCheckInterval == 0 && ctx.Err() != nil {
+			return 0, nil, ErrMiningDeadline
+		}
+		data := pow.prepareData(nonce)
+		hash := sha256.Sum256(data)
+		hashInt.SetBytes(hash[:])
+
+		if hashInt.Cmp(pow.target) == -1 {
+			return nonce, hash[:], nil
+		}
+		nonce++
+	}
+}
+
+// Validate 验证区块是否满足工作量证明，难度无效时返回false
 func (pow *ProofOfWork) Validate() bool {
+	if pow.err != nil {
+		return false
+	}
 	var hashInt big.Int
 	data := pow.prepareData(pow.block.Nonce)
 	hash := sha256.Sum256(data)
@@ -65,22 +135,47 @@ func (pow *ProofOfWork) Validate() bool {
 
 // MineBlock 使用改进的PoW算法挖取新区块
 func MineBlock(prev Block, txids []string, difficulty int) Block {
-	b := Block{
-		Index:        prev.Index + 1,
-		Timestamp:    time.Now().Unix(),
-		Transactions: txids,
-		PrevHash:     prev.Hash,
-		Nonce:        0,
-	}
-
-	pow := NewProofOfWork(&b, difficulty)
-	nonce, hash := pow.Run()
-	b.Nonce = nonce
-	b.Hash = hex.EncodeToString(hash)
+	b, _ := MineBlockContext(context.Background(), prev, txids, difficulty)
 	return b
 }
 
-// CheckPoW 验证区块是否满足PoW要求
+// MineBlockContext 挖取新区块，ctx到期或取消时返回ErrMiningDeadline
+func MineBlockContext(ctx context.Context, prev Block, txids []string, difficulty int) (Block, error) {
+	return PoWConsensus{}.SealBlock(ctx, newBlockTemplate(prev, txids, difficulty))
+}
+
+// BlockWork 返回给定难度下单个区块代表的工作量：2^256 / target = 2^(difficulty*4)
+// 用于比较不同链的累计工作量
+func BlockWork(difficulty int) *big.Int {
+	work := big.NewInt(1)
+	return work.Lsh(work, uint(difficulty*4))
+}
+
+// ErrSealMismatch 区块自身的Nonce和字段计算出的PoW哈希与声明的哈希不一致
+var ErrSealMismatch = errors.New("block hash does not match its nonce")
+
+// ErrSealTarget 区块PoW哈希不满足难度目标
+var ErrSealTarget = errors.New("block hash does not meet difficulty target")
+
+// ValidateSeal 用区块自身的Nonce和头部字段重新计算PoW哈希，
+// 确认其与区块声明的Hash一致且满足difficulty对应的目标值
+// 难度无效时返回ErrInvalidDifficulty
+func ValidateSeal(b *Block, difficulty int) error {
+	pow := NewProofOfWork(b, difficulty)
+	if pow.err != nil {
+		return pow.err
+	}
+	hash := sha256.Sum256(pow.prepareData(b.Nonce))
+	if hex.EncodeToString(hash[:]) != b.Hash {
+		return ErrSealMismatch
+	}
+	if new(big.Int).SetBytes(hash[:]).Cmp(pow.target) != -1 {
+		return ErrSealTarget
+	}
+	return nil
+}
+
+// CheckPoW 验证区块是否满足PoW要求，难度无效时返回false
 func CheckPoW(b *Block, difficulty int) bool {
 	pow := NewProofOfWork(b, difficulty)
 	return pow.Validate()
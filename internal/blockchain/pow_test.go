@@ -1,8 +1,11 @@
 package blockchain
 
 import (
+	"context"
+	"errors"
 	"math/big"
 	"testing"
+	"time"
 )
 
 func TestProofOfWork_Basic(t *testing.T) {
@@ -76,3 +79,100 @@ func TestProofOfWork_HighDifficulty(t *testing.T) {
 		t.Errorf("目标值错误: 期望 %x, 实际 %x", expectedTarget.Bytes(), pow.target.Bytes())
 	}
 }
+
+func TestMineBlock_HashMatchesHeader(t *testing.T) {
+	// 挖出的区块哈希必须同时通过头部哈希检查和数值目标检查
+	prev := NewGenesis()
+	b := MineBlock(prev, []string{"tx1", "tx2"}, 2)
+
+	if !b.ValidateBasic() {
+		t.Errorf("挖出的区块哈希 %s 与头部编码不一致", b.Hash)
+	}
+	if !CheckPoW(&b, 2) {
+		t.Error("挖出的区块未满足PoW目标")
+	}
+
+	// 以十六进制前导零表示的难度与big.Int目标值一致
+	if b.Hash[:2] != "00" {
+		t.Errorf("难度2的区块哈希应以两个0开头，实际 %s", b.Hash)
+	}
+}
+
+func TestMinePending_Deadline(t *testing.T) {
+	// 高难度下短超时不可能找到解
+	bc := NewBlockchain(16, nil)
+	AddToMempool("deadline-tx")
+	defer RemoveFromMempool([]string{"deadline-tx"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := bc.MinePending(ctx, "miner")
+	if err != ErrMiningDeadline {
+		t.Fatalf("expected ErrMiningDeadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("mining did not stop promptly after deadline: %v", elapsed)
+	}
+}
+
+func TestValidateSealAlteredNonce(t *testing.T) {
+	bc := NewBlockchain(2, nil)
+	b := MineBlock(bc.GetLatest(), testTxs(t, "seal-tx", 1), 2)
+	if err := ValidateSeal(&b, 2); err != nil {
+		t.Fatalf("刚挖出的区块应通过封装校验: %v", err)
+	}
+
+	// 封装后修改Nonce：声明的哈希不再由该Nonce产生
+	altered := b
+	altered.Nonce++
+	if err := ValidateSeal(&altered, 2); err != ErrSealMismatch {
+		t.Errorf("期望ErrSealMismatch，实际 %v", err)
+	}
+	if err := bc.ValidateAndApplyBlock(altered); err == nil {
+		t.Error("Nonce被修改的区块应被拒绝")
+	}
+
+	// 修改Nonce后重新计算哈希：哈希一致但不满足难度目标
+	for altered.Hash = calcHash(&altered); CheckPoW(&altered, 2); altered.Hash = calcHash(&altered) {
+		altered.Nonce++
+	}
+	if err := ValidateSeal(&altered, 2); err != ErrSealTarget {
+		t.Errorf("期望ErrSealTarget，实际 %v", err)
+	}
+	err := bc.ValidateAndApplyBlock(altered)
+	if rerr, ok := err.(*BlockRejectError); !ok || rerr.Reason != RejectBadPoW {
+		t.Errorf("期望bad_pow拒绝，实际 %v", err)
+	}
+
+	if err := bc.ValidateAndApplyBlock(b); err != nil {
+		t.Fatalf("原区块应被接受: %v", err)
+	}
+}
+
+// TestInvalidDifficultyRejected 难度小于1时目标值不小于2^256，任何区块都会通过，必须作为错误拒绝
+func TestInvalidDifficultyRejected(t *testing.T) {
+	b := &Block{Index: 1, Timestamp: 123456, Transactions: []string{"tx1"}, PrevHash: "prev", Difficulty: 0}
+	b.Hash = calcHash(b)
+
+	for _, d := range []int{0, -1, MaxDifficulty + 1} {
+		if CheckPoW(b, d) {
+			t.Errorf("CheckPoW accepted an arbitrary block at difficulty %d", d)
+		}
+		if err := ValidateSeal(b, d); !errors.Is(err, ErrInvalidDifficulty) {
+			t.Errorf("ValidateSeal at difficulty %d: err = %v, want %v", d, err, ErrInvalidDifficulty)
+		}
+		if _, err := (PoWConsensus{}).SealBlock(context.Background(), Block{Difficulty: d}); !errors.Is(err, ErrInvalidDifficulty) {
+			t.Errorf("SealBlock at difficulty %d: err = %v, want %v", d, err, ErrInvalidDifficulty)
+		}
+	}
+
+	rerr := PoWConsensus{}.ValidateBlock(b, 0)
+	if rerr == nil || rerr.Reason != RejectBadDifficulty {
+		t.Fatalf("ValidateBlock with difficulty 0 = %v, want a %s rejection", rerr, RejectBadDifficulty)
+	}
+	h := b.Header()
+	if err := (PoWConsensus{}).VerifySeal(&h); !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("VerifySeal with difficulty 0: err = %v, want %v", err, ErrInvalidDifficulty)
+	}
+}
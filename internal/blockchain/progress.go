@@ -0,0 +1,96 @@
+package blockchain
+
+// internal/blockchain/progress.go
+// 初始同步进度：目标高度取对端报告的最高链高，当前高度为本地链高或正在下载的区块体进度
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// SyncLogInterval 追赶同步期间输出进度日志的最小间隔
+const SyncLogInterval = 5 * time.Second
+
+// SyncProgress 链同步进度
+type SyncProgress struct {
+	Syncing       bool    `json:"syncing"`        // 是否正在下载区块体
+	CurrentHeight int     `json:"current_height"` // 本地已有（或已下载）的最高区块
+	TargetHeight  int     `json:"target_height"`  // 已知对端的最高链高
+	Percent       float64 `json:"percent"`        // 同步完成百分比（0-100）
+}
+
+// syncTracker 记录同步目标高度和区块体下载进度，零值可用
+type syncTracker struct {
+	mu      sync.Mutex
+	target  int       // 见过的最高对端链高
+	syncing bool      // 是否正在下载区块体
+	base    int       // 本轮同步的分叉点高度
+	fetched int       // 本轮已下载的区块体数量
+	lastLog time.Time // 上次输出进度日志的时间
+}
+
+// ObserveTargetHeight 记录对端报告的链高（区块头链、心跳等），目标高度取见过的最大值
+func (bc *Blockchain) ObserveTargetHeight(height int) {
+	bc.progress.mu.Lock()
+	defer bc.progress.mu.Unlock()
+	if height > bc.progress.target {
+		bc.progress.target = height
+	}
+}
+
+// SyncProgress 返回当前同步进度；没有更高的已知链时进度为100%
+func (bc *Blockchain) SyncProgress() SyncProgress {
+	height := bc.Height()
+	t := &bc.progress
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshotLocked(height)
+}
+
+// snapshotLocked 根据本地链高计算进度，调用者需持有t.mu
+func (t *syncTracker) snapshotLocked(height int) SyncProgress {
+	p := SyncProgress{Syncing: t.syncing, CurrentHeight: height, TargetHeight: t.target}
+	if t.syncing && t.base+t.fetched > p.CurrentHeight {
+		p.CurrentHeight = t.base + t.fetched
+	}
+	if p.TargetHeight < p.CurrentHeight {
+		p.TargetHeight = p.CurrentHeight
+	}
+	p.Percent = 100
+	if p.TargetHeight > 0 {
+		p.Percent = float64(p.CurrentHeight) * 100 / float64(p.TargetHeight)
+	}
+	return p
+}
+
+// beginSync 开始从分叉点base下载区块体
+func (bc *Blockchain) beginSync(base int) {
+	bc.progress.mu.Lock()
+	defer bc.progress.mu.Unlock()
+	bc.progress.syncing = true
+	bc.progress.base = base
+	bc.progress.fetched = 0
+	bc.progress.lastLog = time.Now()
+}
+
+// blockFetched 记录一个区块体下载完成，距上次日志超过SyncLogInterval时输出进度
+func (bc *Blockchain) blockFetched() {
+	height := bc.Height()
+	t := &bc.progress
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.fetched++
+	if now := time.Now(); now.Sub(t.lastLog) >= SyncLogInterval {
+		t.lastLog = now
+		p := t.snapshotLocked(height)
+		log.Printf("Sync progress: %d/%d (%.1f%%)", p.CurrentHeight, p.TargetHeight, p.Percent)
+	}
+}
+
+// endSync 结束本轮区块体下载
+func (bc *Blockchain) endSync() {
+	bc.progress.mu.Lock()
+	defer bc.progress.mu.Unlock()
+	bc.progress.syncing = false
+}
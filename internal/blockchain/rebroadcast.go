@@ -0,0 +1,108 @@
+package blockchain
+
+// internal/blockchain/rebroadcast.go
+// 内存池交易的周期性重新广播
+// 错过原始广播的节点永远不会得知仍在等待打包的交易，因此定期重新公告这些交易，
+// 每轮数量受限并带随机抖动，避免所有节点同时重播；交易被打包（移出内存池）或超过最大等待时间后不再重播
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 重新广播的默认参数
+const (
+	DefaultRebroadcastInterval = 5 * time.Minute  // 两次公告同一交易的最短间隔
+	DefaultRebroadcastJitter   = 30 * time.Second // 每轮等待时间的随机抖动上限
+	DefaultRebroadcastPerRound = 50               // 每轮最多重播的交易数
+	DefaultRebroadcastMaxAge   = 24 * time.Hour   // 超过该等待时间的交易视为过期，不再重播
+)
+
+// Rebroadcaster 定期重新公告仍在内存池中的交易
+type Rebroadcaster struct {
+	Interval    time.Duration                // 两次公告同一交易的最短间隔
+	Jitter      time.Duration                // 每轮等待时间的随机抖动上限
+	MaxPerRound int                          // 每轮最多重播的交易数
+	MaxAge      time.Duration                // 自首次收到起超过该时间的交易不再重播
+	Announce    func(txid string, tx UTXOTx) // 公告交易（通常为P2P广播）
+
+	mu   sync.Mutex
+	last map[string]time.Time // 交易ID到最近一次公告时间
+}
+
+// NewRebroadcaster 使用默认参数创建重新广播器
+func NewRebroadcaster(announce func(txid string, tx UTXOTx)) *Rebroadcaster {
+	return &Rebroadcaster{
+		Interval:    DefaultRebroadcastInterval,
+		Jitter:      DefaultRebroadcastJitter,
+		MaxPerRound: DefaultRebroadcastPerRound,
+		MaxAge:      DefaultRebroadcastMaxAge,
+		Announce:    announce,
+		last:        make(map[string]time.Time),
+	}
+}
+
+// Run 按Interval加随机抖动周期执行重新广播，直到ctx取消
+func (r *Rebroadcaster) Run(ctx context.Context) {
+	for {
+		wait := r.Interval
+		if r.Jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(r.Jitter)))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+			r.Rebroadcast(time.Now())
+		}
+	}
+}
+
+// Rebroadcast 公告到期的待处理交易，返回本轮公告的交易ID
+// 交易进入内存池或上次公告后经过Interval即到期；已离开内存池的交易不再跟踪
+func (r *Rebroadcaster) Rebroadcast(now time.Time) []string {
+	entries := snapshotMempool()
+
+	r.mu.Lock()
+	pending := make(map[string]bool, len(entries))
+	var due []string
+	var txs []UTXOTx
+	for _, e := range entries {
+		pending[e.txid] = true
+		if r.MaxAge > 0 && now.Sub(e.added) >= r.MaxAge {
+			continue
+		}
+		since := e.added
+		if t, ok := r.last[e.txid]; ok {
+			since = t
+		}
+		if now.Sub(since) < r.Interval {
+			continue
+		}
+		if r.MaxPerRound > 0 && len(due) >= r.MaxPerRound {
+			continue
+		}
+		tx, err := GetTransaction(e.txid)
+		if err != nil {
+			continue // 交易体未知，无法公告
+		}
+		r.last[e.txid] = now
+		due = append(due, e.txid)
+		txs = append(txs, tx)
+	}
+	for txid := range r.last {
+		if !pending[txid] {
+			delete(r.last, txid)
+		}
+	}
+	r.mu.Unlock()
+
+	if r.Announce != nil {
+		for i, txid := range due {
+			r.Announce(txid, txs[i])
+		}
+	}
+	return due
+}
@@ -0,0 +1,49 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRebroadcastPendingTx(t *testing.T) {
+	tx := UTXOTx{Version: TxVersion, Outputs: []TxOutput{{Address: "rebroadcast", Amount: 3}}}
+	txid, err := PutTransaction(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddToMempool(txid)
+	defer RemoveFromMempool([]string{txid})
+
+	var announced []string
+	r := NewRebroadcaster(func(id string, _ UTXOTx) { announced = append(announced, id) })
+	r.Interval = time.Minute
+	start := time.Now()
+
+	// 未到间隔不重播
+	if got := r.Rebroadcast(start); len(got) != 0 {
+		t.Fatalf("announced %v before the interval elapsed", got)
+	}
+	// 经过间隔后重播
+	if got := r.Rebroadcast(start.Add(time.Minute)); len(got) != 1 || got[0] != txid {
+		t.Fatalf("expected %s to be re-announced, got %v", txid, got)
+	}
+	// 刚公告过，下一个间隔前不再重播
+	if got := r.Rebroadcast(start.Add(90 * time.Second)); len(got) != 0 {
+		t.Fatalf("announced %v again within the interval", got)
+	}
+	if got := r.Rebroadcast(start.Add(2 * time.Minute)); len(got) != 1 {
+		t.Fatalf("expected a second re-announcement, got %v", got)
+	}
+	// 超过最大等待时间视为过期
+	if got := r.Rebroadcast(start.Add(DefaultRebroadcastMaxAge)); len(got) != 0 {
+		t.Fatalf("expired tx re-announced: %v", got)
+	}
+	// 被打包后不再重播
+	RemoveFromMempool([]string{txid})
+	if got := r.Rebroadcast(start.Add(4 * time.Minute)); len(got) != 0 {
+		t.Fatalf("mined tx re-announced: %v", got)
+	}
+	if len(announced) != 2 {
+		t.Errorf("Announce called %d times, want 2", len(announced))
+	}
+}
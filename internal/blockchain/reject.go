@@ -0,0 +1,126 @@
+package blockchain
+
+// internal/blockchain/reject.go
+// 区块拒绝原因的分类与记录
+// 每个拒绝路径都返回带分类原因的BlockRejectError，并计入计数器和最近拒绝环形缓冲区，
+// 便于通过调试接口观察节点为何拒绝区块
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RejectReason 区块被拒绝的分类原因
+type RejectReason string
+
+const (
+	RejectBadHeader     RejectReason = "bad_header"     // 头部哈希或Merkle根不匹配
+	RejectBadVersion    RejectReason = "bad_version"    // 未知版本或违反该版本的规则
+	RejectBadDifficulty RejectReason = "bad_difficulty" // 声明的难度与本链不一致
+	RejectBadPoW        RejectReason = "bad_pow"        // 工作量证明不满足目标
+	RejectBadSeal       RejectReason = "bad_seal"       // PoA签名者未授权或签名无效
+	RejectBadLink       RejectReason = "bad_link"       // 未链接到父区块
+	RejectBadTimestamp  RejectReason = "bad_timestamp"  // 时间戳不晚于median-time-past或过于超前
+	RejectBadTx         RejectReason = "bad_tx"         // 包含无效交易
+	RejectTooLarge      RejectReason = "too_large"      // 交易总大小超过MaxBlockSize
+	RejectApplyFailed   RejectReason = "apply_failed"   // 应用UTXO变更失败
+	RejectStorage       RejectReason = "storage_failed" // 区块持久化失败
+)
+
+// RejectionLogSize 最近拒绝环形缓冲区的容量
+const RejectionLogSize = 64
+
+// BlockRejectError 区块被拒绝时返回的错误，携带分类原因
+type BlockRejectError struct {
+	Reason RejectReason // 分类原因
+	Index  int          // 区块高度
+	Hash   string       // 区块哈希
+	Err    error        // 具体错误
+}
+
+func (e *BlockRejectError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Reason, e.Err)
+}
+
+func (e *BlockRejectError) Unwrap() error {
+	return e.Err
+}
+
+// rejectBlock 构造区块拒绝错误
+func rejectBlock(b *Block, reason RejectReason, err error) *BlockRejectError {
+	return &BlockRejectError{Reason: reason, Index: b.Index, Hash: b.Hash, Err: err}
+}
+
+// Rejection 一条区块拒绝记录
+type Rejection struct {
+	Time    int64        `json:"time"`    // 拒绝时间（Unix秒）
+	Index   int          `json:"index"`   // 区块高度
+	Hash    string       `json:"hash"`    // 区块哈希
+	Reason  RejectReason `json:"reason"`  // 分类原因
+	Message string       `json:"message"` // 具体错误描述
+}
+
+// RejectionStats 区块拒绝统计：按原因的计数和最近的拒绝记录（从旧到新）
+type RejectionStats struct {
+	Counts map[RejectReason]uint64 `json:"counts"`
+	Recent []Rejection             `json:"recent"`
+}
+
+// rejectionLog 区块拒绝计数器和最近拒绝环形缓冲区
+type rejectionLog struct {
+	mu     sync.Mutex
+	counts map[RejectReason]uint64
+	recent []Rejection // 环形缓冲区
+	next   int         // 下一个写入位置
+	full   bool        // 缓冲区是否已写满一轮
+}
+
+func newRejectionLog() *rejectionLog {
+	return &rejectionLog{
+		counts: make(map[RejectReason]uint64),
+		recent: make([]Rejection, RejectionLogSize),
+	}
+}
+
+// record 记录一次区块拒绝
+func (l *rejectionLog) record(e *BlockRejectError) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.counts[e.Reason]++
+	l.recent[l.next] = Rejection{
+		Time:    time.Now().Unix(),
+		Index:   e.Index,
+		Hash:    e.Hash,
+		Reason:  e.Reason,
+		Message: e.Err.Error(),
+	}
+	l.next = (l.next + 1) % len(l.recent)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// stats 返回计数和最近拒绝记录的副本
+func (l *rejectionLog) stats() RejectionStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	counts := make(map[RejectReason]uint64, len(l.counts))
+	for k, v := range l.counts {
+		counts[k] = v
+	}
+	var recent []Rejection
+	if l.full {
+		recent = append(recent, l.recent[l.next:]...)
+	}
+	recent = append(recent, l.recent[:l.next]...)
+	if recent == nil {
+		recent = []Rejection{}
+	}
+	return RejectionStats{Counts: counts, Recent: recent}
+}
+
+// Rejections 返回本链的区块拒绝统计
+func (bc *Blockchain) Rejections() RejectionStats {
+	return bc.rejections.stats()
+}
@@ -0,0 +1,47 @@
+package blockchain
+
+// internal/blockchain/reorg.go
+// 链重组事件
+// ReplaceChain 断开本地区块并接入另一分支时生成事件，通知订阅者（如WebSocket客户端）更新视图
+
+// ReorgEvent 一次链重组：分叉高度、被断开的区块哈希和新接入的区块哈希（均按高度升序）
+type ReorgEvent struct {
+	ForkHeight int      `json:"fork_height"` // 两条分支最后一个共同区块的高度
+	Removed    []string `json:"removed"`     // 被断开的区块哈希
+	Added      []string `json:"added"`       // 新接入的区块哈希
+}
+
+// newReorgEvent 根据分叉点和两条分支构造重组事件
+func newReorgEvent(fork int, removed, added []Block) ReorgEvent {
+	ev := ReorgEvent{
+		ForkHeight: fork,
+		Removed:    make([]string, len(removed)),
+		Added:      make([]string, len(added)),
+	}
+	for i, b := range removed {
+		ev.Removed[i] = b.Hash
+	}
+	for i, b := range added {
+		ev.Added[i] = b.Hash
+	}
+	return ev
+}
+
+// OnReorg 注册链重组回调
+// 回调在ReplaceChain释放锁之后同步调用，可以安全地读取区块链
+func (bc *Blockchain) OnReorg(fn func(ReorgEvent)) {
+	bc.reorgMu.Lock()
+	defer bc.reorgMu.Unlock()
+	bc.reorgHooks = append(bc.reorgHooks, fn)
+}
+
+// notifyReorg 依次调用所有重组回调
+func (bc *Blockchain) notifyReorg(ev ReorgEvent) {
+	bc.reorgMu.Lock()
+	hooks := make([]func(ReorgEvent), len(bc.reorgHooks))
+	copy(hooks, bc.reorgHooks)
+	bc.reorgMu.Unlock()
+	for _, fn := range hooks {
+		fn(ev)
+	}
+}
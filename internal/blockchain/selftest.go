@@ -0,0 +1,75 @@
+package blockchain
+
+// internal/blockchain/selftest.go
+// 启动自检
+// 在开始服务前端到端地运行一次密钥生成、交易签名/验签和挖矿/区块校验，
+// 尽早发现加密库或运行环境的问题。自检不读写全局的UTXO集合、内存池和交易存储
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"mini_chain/internal/wallet"
+)
+
+// selfTestTimeout 自检挖矿的最长时间
+const selfTestTimeout = 10 * time.Second
+
+// SelfTest 运行启动自检，任一步骤失败时返回说明失败步骤的错误
+func SelfTest() error {
+	// 1. 生成密钥
+	priv, pub, err := wallet.NewKey()
+	if err != nil {
+		return fmt.Errorf("self-test: key generation: %w", err)
+	}
+
+	// 2. 对样例交易签名并验签
+	tx := UTXOTx{
+		Version: TxVersion,
+		Inputs:  []TxInput{{Txid: "selftest", Vout: 0, PubKey: pub}},
+		Outputs: []TxOutput{{Address: pub, Amount: 1}},
+	}
+	if err := ValidateTxStructure(tx); err != nil {
+		return fmt.Errorf("self-test: tx structure: %w", err)
+	}
+	txid, err := TxID(tx)
+	if err != nil {
+		return fmt.Errorf("self-test: tx id: %w", err)
+	}
+	digest := sha256.Sum256([]byte(txid))
+	sig, err := wallet.SignData(priv, digest[:])
+	if err != nil {
+		return fmt.Errorf("self-test: signing: %w", err)
+	}
+	if err := wallet.VerifyRaw(pub, sig, digest[:]); err != nil {
+		return fmt.Errorf("self-test: signature verification: %w", err)
+	}
+	tampered := sha256.Sum256([]byte(txid + "x"))
+	if wallet.VerifyRaw(pub, sig, tampered[:]) == nil {
+		return fmt.Errorf("self-test: signature verified over wrong data")
+	}
+
+	// 3. 在创世区块上挖出一次性区块并校验
+	ctx, cancel := context.WithTimeout(context.Background(), selfTestTimeout)
+	defer cancel()
+	gen := NewGenesis()
+	b, err := MineBlockContext(ctx, gen, []string{txid}, 1)
+	if err != nil {
+		return fmt.Errorf("self-test: mining: %w", err)
+	}
+	if b.Index != gen.Index+1 || b.PrevHash != gen.Hash {
+		return fmt.Errorf("self-test: mined block does not link to genesis")
+	}
+	if !b.ValidateBasic() {
+		return fmt.Errorf("self-test: mined block header invalid (hash %s)", b.Hash)
+	}
+	if err := CheckBlockVersion(&b); err != nil {
+		return fmt.Errorf("self-test: block version: %w", err)
+	}
+	if rerr := (PoWConsensus{}).ValidateBlock(&b, 1); rerr != nil {
+		return fmt.Errorf("self-test: block validation: %v", rerr.Err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package blockchain
+
+import "testing"
+
+func TestSelfTest(t *testing.T) {
+	before := len(ListMempool())
+	if err := SelfTest(); err != nil {
+		t.Fatalf("self-test failed on a healthy build: %v", err)
+	}
+	if got := len(ListMempool()); got != before {
+		t.Fatalf("self-test changed the mempool: %d -> %d", before, got)
+	}
+}
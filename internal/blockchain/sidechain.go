@@ -0,0 +1,213 @@
+package blockchain
+
+// internal/blockchain/sidechain.go
+// 侧链区块存储
+// 不扩展主链顶的区块不再直接丢弃：父区块已知的（stale）和父区块未知的（orphan）都保存在侧链存储中，
+// 某条侧链分支的累计工作量超过主链时，用已保存的区块直接重组，不必重新下载；
+// 重组断开的主链区块也放入侧链存储，便于之后切换回来
+
+import "sync"
+
+// DefaultMaxSideChainBlocks 侧链存储默认最多保存的区块数，超出时淘汰最早保存的区块
+const DefaultMaxSideChainBlocks = 1000
+
+// SideChainStats 侧链存储指标
+type SideChainStats struct {
+	Stale   uint64 `json:"stale"`   // 收到的父区块已知但不扩展主链顶的区块数
+	Orphans uint64 `json:"orphans"` // 收到的父区块未知的区块数
+	Reorgs  uint64 `json:"reorgs"`  // 使用侧链存储中的区块完成的重组次数
+	Stored  int    `json:"stored"`  // 当前保存的区块数
+}
+
+// sideChainStore 按哈希保存不在主链上的区块
+type sideChainStore struct {
+	mu      sync.Mutex
+	max     int
+	blocks  map[string]Block
+	order   []string // 按保存顺序排列的哈希，用于淘汰
+	stale   uint64
+	orphans uint64
+	reorgs  uint64
+}
+
+func newSideChainStore(max int) *sideChainStore {
+	if max < 1 {
+		max = DefaultMaxSideChainBlocks
+	}
+	return &sideChainStore{max: max, blocks: make(map[string]Block)}
+}
+
+// add 保存区块，已保存的区块忽略；返回是否为新保存的区块
+func (s *sideChainStore) add(b Block) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addLocked(b)
+}
+
+func (s *sideChainStore) addLocked(b Block) bool {
+	if _, ok := s.blocks[b.Hash]; ok {
+		return false
+	}
+	for len(s.order) >= s.max {
+		delete(s.blocks, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.blocks[b.Hash] = b
+	s.order = append(s.order, b.Hash)
+	return true
+}
+
+// get 按哈希返回保存的区块
+func (s *sideChainStore) get(hash string) (Block, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.blocks[hash]
+	return b, ok
+}
+
+// children 返回父区块为hash的已保存区块
+func (s *sideChainStore) children(hash string) []Block {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Block
+	for _, b := range s.blocks {
+		if b.PrevHash == hash {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// swap 重组后更新存储：接入主链的区块移出，被断开的区块放入
+func (s *sideChainStore) swap(removed, added []Block) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range added {
+		delete(s.blocks, b.Hash)
+	}
+	kept := s.order[:0]
+	for _, h := range s.order {
+		if _, ok := s.blocks[h]; ok {
+			kept = append(kept, h)
+		}
+	}
+	s.order = kept
+	for _, b := range removed {
+		s.addLocked(b)
+	}
+}
+
+// count 计入一个stale或orphan区块
+func (s *sideChainStore) count(orphan bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if orphan {
+		s.orphans++
+	} else {
+		s.stale++
+	}
+}
+
+func (s *sideChainStore) countReorg() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reorgs++
+}
+
+func (s *sideChainStore) stats() SideChainStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SideChainStats{Stale: s.stale, Orphans: s.orphans, Reorgs: s.reorgs, Stored: len(s.blocks)}
+}
+
+// SideChainStats 返回侧链存储指标
+func (bc *Blockchain) SideChainStats() SideChainStats {
+	return bc.side.stats()
+}
+
+// SideChainBlock 按哈希返回侧链存储中的区块
+func (bc *Blockchain) SideChainBlock(hash string) (Block, bool) {
+	return bc.side.get(hash)
+}
+
+// storeSideBlock 保存不扩展主链顶的区块并计入stale/orphan指标
+// 包含该区块的侧链分支累计工作量超过主链时，返回从创世区块开始的候选链，否则返回nil
+func (bc *Blockchain) storeSideBlock(b Block) []Block {
+	if _, err := bc.GetBlockByHash(b.Hash); err == nil {
+		return nil // 已在主链上
+	}
+	_, parentInMain := bc.mainChainBlock(b.PrevHash)
+	_, parentInSide := bc.side.get(b.PrevHash)
+	if !bc.side.add(b) {
+		return nil
+	}
+	bc.side.count(!parentInMain && !parentInSide)
+	return bc.bestSideBranch(b)
+}
+
+// mainChainBlock 返回主链上哈希为hash的区块的高度
+func (bc *Blockchain) mainChainBlock(hash string) (int, bool) {
+	b, err := bc.GetBlockByHash(hash)
+	if err != nil {
+		return 0, false
+	}
+	return b.Index, true
+}
+
+// bestSideBranch 从b向前沿已保存的区块回溯到主链，再向后接上以b为祖先的已保存区块（取最长的一支），
+// 得到的候选链累计工作量超过主链时返回它
+func (bc *Blockchain) bestSideBranch(b Block) []Block {
+	branch := []Block{b}
+	fork, ok := bc.mainChainBlock(b.PrevHash)
+	for !ok {
+		parent, found := bc.side.get(branch[0].PrevHash)
+		if !found || len(branch) > bc.side.max {
+			return nil // 父区块未知，暂时无法连接
+		}
+		branch = append([]Block{parent}, branch...)
+		fork, ok = bc.mainChainBlock(parent.PrevHash)
+	}
+	branch = append(branch, bc.longestDescendants(b.Hash, 0)...)
+
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	if fork >= len(bc.chain) {
+		return nil
+	}
+	candidate := append(append([]Block(nil), bc.chain[:fork+1]...), branch...)
+	if chainWork(candidate).Cmp(chainWork(bc.chain)) <= 0 {
+		return nil
+	}
+	return candidate
+}
+
+// longestDescendants 返回以hash为父区块的已保存区块中最长的一条后代链
+func (bc *Blockchain) longestDescendants(hash string, depth int) []Block {
+	if depth > bc.side.max {
+		return nil
+	}
+	var best []Block
+	for _, c := range bc.side.children(hash) {
+		if path := append([]Block{c}, bc.longestDescendants(c.Hash, depth+1)...); len(path) > len(best) {
+			best = path
+		}
+	}
+	return best
+}
+
+// connectSideChildren 依次接入侧链存储中以新链顶为父区块的区块（先于父区块到达的orphan）
+func (bc *Blockchain) connectSideChildren(tip Block) {
+	for connected := true; connected; {
+		connected = false
+		for _, c := range bc.side.children(tip.Hash) {
+			bc.applyLock.Lock()
+			rerr := bc.validateAndApplyBlock(c)
+			bc.applyLock.Unlock()
+			if rerr == nil {
+				bc.side.swap(nil, []Block{c})
+				tip, connected = c, true
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSideChainReorg(t *testing.T) {
+	local := NewBlockchain(1, nil)
+	mainBlock := MineBlock(local.GetLatest(), testTxs(t, "side-main", 1), 1)
+	if err := local.ValidateAndApplyBlock(mainBlock); err != nil {
+		t.Fatal(err)
+	}
+
+	// 远端从创世区块分叉挖出四个区块
+	remote := NewBlockchain(1, nil)
+	var branch []Block
+	for _, tag := range []string{"side-1", "side-2", "side-3", "side-4"} {
+		b := MineBlock(remote.GetLatest(), testTxs(t, tag, 1), 1)
+		if err := remote.ValidateAndApplyBlock(b); err != nil {
+			t.Fatal(err)
+		}
+		branch = append(branch, b)
+	}
+
+	// 与主链等长的分支只保存，不重组
+	var rerr *BlockRejectError
+	if err := local.ValidateAndApplyBlock(branch[0]); !errors.As(err, &rerr) || rerr.Reason != RejectBadLink {
+		t.Fatalf("stale block: err = %v, want %s", err, RejectBadLink)
+	}
+	if st := local.SideChainStats(); st.Stale != 1 || st.Stored != 1 || st.Reorgs != 0 {
+		t.Fatalf("after stale block: %+v", st)
+	}
+	if local.GetLatest().Hash != mainBlock.Hash {
+		t.Fatal("equal-work side branch must not replace the main chain")
+	}
+
+	// 父区块未知的区块作为orphan保存
+	if err := local.ValidateAndApplyBlock(branch[3]); err == nil {
+		t.Fatal("orphan block accepted")
+	}
+	if st := local.SideChainStats(); st.Orphans != 1 || st.Stored != 2 {
+		t.Fatalf("after orphan block: %+v", st)
+	}
+
+	// 扩展侧链分支使其工作量超过主链，用已保存的区块重组
+	if err := local.ValidateAndApplyBlock(branch[1]); err != nil {
+		t.Fatalf("extending side branch: %v", err)
+	}
+	if local.GetLatest().Hash != branch[1].Hash {
+		t.Fatalf("tip = %s, want side branch %s", local.GetLatest().Hash, branch[1].Hash)
+	}
+	if st := local.SideChainStats(); st.Stale != 2 || st.Reorgs != 1 {
+		t.Fatalf("after side-chain reorg: %+v", st)
+	}
+	if _, ok := local.SideChainBlock(mainBlock.Hash); !ok {
+		t.Error("disconnected main chain block should be kept in the side-chain store")
+	}
+
+	// 补上缺失的父区块后，之前保存的orphan也一起接入
+	if err := local.ValidateAndApplyBlock(branch[2]); err != nil {
+		t.Fatal(err)
+	}
+	if local.GetLatest().Hash != branch[3].Hash {
+		t.Fatalf("tip = %s, want %s", local.GetLatest().Hash, branch[3].Hash)
+	}
+	st := local.SideChainStats()
+	if st.Stale != 2 || st.Orphans != 1 || st.Reorgs != 1 || st.Stored != 1 {
+		t.Errorf("final stats: %+v", st)
+	}
+	for _, b := range branch {
+		if _, ok := local.SideChainBlock(b.Hash); ok {
+			t.Errorf("block %d still in side-chain store after joining the main chain", b.Index)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package blockchain
+
+// internal/blockchain/stats.go
+// 链的汇总统计，供区块浏览器使用
+// 交易数和流通总量由区块接入、重组和UTXO变更时维护的计数器提供，查询时不扫描全链
+
+// ChainStats 链的汇总统计
+type ChainStats struct {
+	Blocks         int     `json:"blocks"`            // 主链区块数（含创世区块）
+	Transactions   int     `json:"transactions"`      // 主链区块包含的交易数（含coinbase）
+	Circulation    int     `json:"circulation"`       // 流通中的货币总量（UTXO金额之和）
+	AvgTxsPerBlock float64 `json:"avg_txs_per_block"` // 平均每个区块包含的交易数
+	Difficulty     int     `json:"difficulty"`        // 当前难度
+}
+
+// countTxs 返回blocks包含的交易总数
+func countTxs(blocks []Block) int {
+	n := 0
+	for _, b := range blocks {
+		n += len(b.Transactions)
+	}
+	return n
+}
+
+// Stats 返回链的汇总统计
+func (bc *Blockchain) Stats() ChainStats {
+	bc.lock.RLock()
+	s := ChainStats{
+		Blocks:       len(bc.chain),
+		Transactions: bc.txCount,
+		Difficulty:   bc.difficulty,
+	}
+	bc.lock.RUnlock()
+	s.Circulation = UTXOTotal()
+	if s.Blocks > 0 {
+		s.AvgTxsPerBlock = float64(s.Transactions) / float64(s.Blocks)
+	}
+	return s
+}
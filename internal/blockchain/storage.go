@@ -0,0 +1,364 @@
+package blockchain
+
+// internal/blockchain/storage.go
+// 区块持久化存储
+// BlockStore 保存主链区块：按哈希存区块体、按高度存主链索引，并记录最新区块；
+// 节点启动时从存储恢复整条主链，之后每个接入主链的区块都先写入存储再更新链顶。
+// 配置了数据目录时使用BoltBlockStore持久化，否则使用MemoryBlockStore。
+// 区块引用的交易体在区块之前写入存储；UTXO集合只保存在内存中，启动时从恢复的主链重放推导
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrBlockNotFound 存储中没有请求的区块
+var ErrBlockNotFound = errors.New("block not found")
+
+// BlockStore 区块存储
+// PutBlock 保存区块、把它记为所在高度的主链区块并设为最新区块；
+// 重组到更短的分支后，高于最新区块的旧索引不再可见
+// PutTxs 按交易ID保存交易体，GetTx 在交易体不存在时返回ErrTxNotFound
+type BlockStore interface {
+	PutBlock(b Block) error
+	GetBlockByHash(hash string) (Block, error)
+	GetBlockByIndex(index int) (Block, error)
+	Latest() (Block, error) // 存储为空时返回ErrBlockNotFound
+	PutTxs(txs []UTXOTx) error
+	GetTx(txid string) (UTXOTx, error)
+}
+
+// MemoryBlockStore 内存中的BlockStore实现，未配置数据目录时使用，进程退出后数据丢失
+type MemoryBlockStore struct {
+	mu     sync.RWMutex
+	blocks map[string]Block  // 区块哈希 -> 区块
+	index  map[int]string    // 高度 -> 区块哈希
+	latest string            // 最新区块的哈希
+	txs    map[string]UTXOTx // 交易ID -> 交易体
+}
+
+var _ BlockStore = (*MemoryBlockStore)(nil)
+
+// NewMemoryBlockStore 创建空的内存区块存储
+func NewMemoryBlockStore() *MemoryBlockStore {
+	return &MemoryBlockStore{blocks: make(map[string]Block), index: make(map[int]string), txs: make(map[string]UTXOTx)}
+}
+
+// PutBlock 保存区块、更新高度索引和最新区块
+func (s *MemoryBlockStore) PutBlock(b Block) error {
+	if b.Index < 0 {
+		return fmt.Errorf("put block: negative index %d", b.Index)
+	}
+	b.Transactions = append([]string(nil), b.Transactions...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocks[b.Hash] = b
+	s.index[b.Index] = b.Hash
+	s.latest = b.Hash
+	return nil
+}
+
+// GetBlockByHash 按哈希返回区块（包括已不在主链上的区块）
+func (s *MemoryBlockStore) GetBlockByHash(hash string) (Block, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.blocks[hash]
+	if !ok {
+		return Block{}, fmt.Errorf("%w: %s", ErrBlockNotFound, hash)
+	}
+	return b, nil
+}
+
+// GetBlockByIndex 按高度返回主链区块，高度超过最新区块时返回ErrBlockNotFound
+func (s *MemoryBlockStore) GetBlockByIndex(index int) (Block, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	latest, ok := s.blocks[s.latest]
+	if !ok || index < 0 || index > latest.Index {
+		return Block{}, fmt.Errorf("%w: index %d", ErrBlockNotFound, index)
+	}
+	hash, ok := s.index[index]
+	if !ok {
+		return Block{}, fmt.Errorf("%w: index %d", ErrBlockNotFound, index)
+	}
+	return s.blocks[hash], nil
+}
+
+// Latest 返回最新区块
+func (s *MemoryBlockStore) Latest() (Block, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.blocks[s.latest]
+	if !ok {
+		return Block{}, ErrBlockNotFound
+	}
+	return b, nil
+}
+
+// PutTxs 按交易ID保存交易体
+func (s *MemoryBlockStore) PutTxs(txs []UTXOTx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tx := range txs {
+		txid, err := TxID(tx)
+		if err != nil {
+			return err
+		}
+		s.txs[txid] = tx
+	}
+	return nil
+}
+
+// GetTx 按交易ID返回交易体
+func (s *MemoryBlockStore) GetTx(txid string) (UTXOTx, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tx, ok := s.txs[txid]
+	if !ok {
+		return UTXOTx{}, fmt.Errorf("%w: %s", ErrTxNotFound, txid)
+	}
+	return tx, nil
+}
+
+// BoltDB中的bucket和键
+var (
+	bucketBlocks = []byte("blocks") // 区块哈希 -> 区块JSON
+	bucketIndex  = []byte("index")  // 高度（8字节大端） -> 区块哈希
+	bucketTxs    = []byte("txs")    // 交易ID -> 交易体JSON
+	bucketMeta   = []byte("meta")
+	keyLatest    = []byte("latest") // meta中最新区块的哈希
+)
+
+// BoltBlockStore 基于BoltDB的BlockStore实现
+type BoltBlockStore struct {
+	db *bolt.DB
+}
+
+var _ BlockStore = (*BoltBlockStore)(nil)
+
+// OpenBoltBlockStore 打开（不存在时创建）path处的BoltDB区块存储
+func OpenBoltBlockStore(path string) (*BoltBlockStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open block store: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketBlocks, bucketIndex, bucketTxs, bucketMeta} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open block store: %w", err)
+	}
+	return &BoltBlockStore{db: db}, nil
+}
+
+// Close 关闭数据库
+func (s *BoltBlockStore) Close() error {
+	return s.db.Close()
+}
+
+// indexKey 返回高度对应的索引键
+func indexKey(index int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(index))
+	return key
+}
+
+// PutBlock 在一个事务中保存区块、更新高度索引和最新区块
+func (s *BoltBlockStore) PutBlock(b Block) error {
+	if b.Index < 0 {
+		return fmt.Errorf("put block: negative index %d", b.Index)
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(bucketBlocks).Put([]byte(b.Hash), data); err != nil {
+			return err
+		}
+		if err := tx.Bucket(bucketIndex).Put(indexKey(b.Index), []byte(b.Hash)); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketMeta).Put(keyLatest, []byte(b.Hash))
+	})
+}
+
+// GetBlockByHash 按哈希返回区块（包括已不在主链上的区块）
+func (s *BoltBlockStore) GetBlockByHash(hash string) (Block, error) {
+	var b Block
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		b, err = getBlock(tx, []byte(hash))
+		return err
+	})
+	return b, err
+}
+
+// GetBlockByIndex 按高度返回主链区块，高度超过最新区块时返回ErrBlockNotFound
+func (s *BoltBlockStore) GetBlockByIndex(index int) (Block, error) {
+	var b Block
+	err := s.db.View(func(tx *bolt.Tx) error {
+		latest, err := getLatest(tx)
+		if err != nil {
+			return err
+		}
+		if index < 0 || index > latest.Index {
+			return fmt.Errorf("%w: index %d", ErrBlockNotFound, index)
+		}
+		hash := tx.Bucket(bucketIndex).Get(indexKey(index))
+		if hash == nil {
+			return fmt.Errorf("%w: index %d", ErrBlockNotFound, index)
+		}
+		b, err = getBlock(tx, hash)
+		return err
+	})
+	return b, err
+}
+
+// Latest 返回最新区块
+func (s *BoltBlockStore) Latest() (Block, error) {
+	var b Block
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		b, err = getLatest(tx)
+		return err
+	})
+	return b, err
+}
+
+// PutTxs 在一个事务中按交易ID保存交易体
+func (s *BoltBlockStore) PutTxs(txs []UTXOTx) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, t := range txs {
+			txid, err := TxID(t)
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(t)
+			if err != nil {
+				return err
+			}
+			if err := tx.Bucket(bucketTxs).Put([]byte(txid), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetTx 按交易ID返回交易体
+func (s *BoltBlockStore) GetTx(txid string) (UTXOTx, error) {
+	var t UTXOTx
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketTxs).Get([]byte(txid))
+		if data == nil {
+			return fmt.Errorf("%w: %s", ErrTxNotFound, txid)
+		}
+		if err := json.Unmarshal(data, &t); err != nil {
+			return fmt.Errorf("decode tx %s: %w", txid, err)
+		}
+		return nil
+	})
+	return t, err
+}
+
+func getLatest(tx *bolt.Tx) (Block, error) {
+	hash := tx.Bucket(bucketMeta).Get(keyLatest)
+	if hash == nil {
+		return Block{}, ErrBlockNotFound
+	}
+	return getBlock(tx, hash)
+}
+
+func getBlock(tx *bolt.Tx, hash []byte) (Block, error) {
+	data := tx.Bucket(bucketBlocks).Get(hash)
+	if data == nil {
+		return Block{}, fmt.Errorf("%w: %s", ErrBlockNotFound, hash)
+	}
+	var b Block
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Block{}, fmt.Errorf("decode block %s: %w", hash, err)
+	}
+	return b, nil
+}
+
+// loadChain 从存储读取从创世区块到最新区块的主链，并检查高度和哈希链接
+// 存储为空时返回nil
+func loadChain(store BlockStore) ([]Block, error) {
+	latest, err := store.Latest()
+	if errors.Is(err, ErrBlockNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	chain := make([]Block, latest.Index+1)
+	for i := range chain {
+		b, err := store.GetBlockByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		if b.Index != i || (i > 0 && b.PrevHash != chain[i-1].Hash) {
+			return nil, fmt.Errorf("stored block %d does not link to its parent", i)
+		}
+		chain[i] = b
+	}
+	if chain[latest.Index].Hash != latest.Hash {
+		return nil, fmt.Errorf("stored index does not end at latest block %s", latest.Hash)
+	}
+	return chain, nil
+}
+
+// putBlockWithTxs 先从内存交易存储取出区块引用的交易体写入store，再写入区块
+// 区块引用了本地没有交易体的交易时返回ErrTxNotFound，不写入区块
+func putBlockWithTxs(store BlockStore, b Block) error {
+	txs := make([]UTXOTx, 0, len(b.Transactions))
+	for _, txid := range b.Transactions {
+		tx, err := GetTransaction(txid)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrTxNotFound, txid)
+		}
+		txs = append(txs, tx)
+	}
+	if err := store.PutTxs(txs); err != nil {
+		return fmt.Errorf("store txs of block %d: %w", b.Index, err)
+	}
+	return store.PutBlock(b)
+}
+
+// restoreChainState 把从store恢复的主链上的交易体读回内存交易存储，
+// 再从创世区块重放主链重建UTXO集合，替换当前集合
+func restoreChainState(store BlockStore, chain []Block) error {
+	view := newUTXOView(nil)
+	for _, b := range chain {
+		for _, txid := range b.Transactions {
+			if _, err := GetTransaction(txid); err == nil {
+				continue
+			}
+			tx, err := store.GetTx(txid)
+			if err != nil {
+				return fmt.Errorf("block %d: %w", b.Index, err)
+			}
+			if _, err := PutTransaction(tx); err != nil {
+				return err
+			}
+		}
+		if err := view.apply(b.Transactions); err != nil {
+			return fmt.Errorf("replay block %d: %v", b.Index, err)
+		}
+	}
+	replaceUTXOSet(view.added)
+	return nil
+}
@@ -0,0 +1,287 @@
+package blockchain
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"mini_chain/internal/wallet"
+)
+
+// openTestStore 在临时目录中打开BoltDB区块存储，测试结束时关闭
+func openTestStore(t *testing.T, path string) *BoltBlockStore {
+	t.Helper()
+	store, err := OpenBoltBlockStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// testBlockStore 对BlockStore实现运行一致性测试，newStore每次返回一个空存储
+func testBlockStore(t *testing.T, newStore func(t *testing.T) BlockStore) {
+	genesis := NewGenesisWithAlloc(nil)
+	mainChain := []Block{genesis}
+	for _, txid := range []string{"conf-1", "conf-2", "conf-3"} {
+		mainChain = append(mainChain, MineBlock(mainChain[len(mainChain)-1], []string{txid}, 1))
+	}
+	fork := MineBlock(mainChain[1], []string{"conf-fork"}, 1) // 高度2上的另一分支
+
+	t.Run("empty", func(t *testing.T) {
+		store := newStore(t)
+		if _, err := store.Latest(); !errors.Is(err, ErrBlockNotFound) {
+			t.Errorf("Latest: err = %v, want %v", err, ErrBlockNotFound)
+		}
+		if _, err := store.GetBlockByIndex(0); !errors.Is(err, ErrBlockNotFound) {
+			t.Errorf("GetBlockByIndex(0): err = %v, want %v", err, ErrBlockNotFound)
+		}
+	})
+
+	t.Run("put and get", func(t *testing.T) {
+		store := newStore(t)
+		for _, b := range mainChain {
+			if err := store.PutBlock(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		latest, err := store.Latest()
+		if err != nil || latest.Hash != mainChain[3].Hash {
+			t.Fatalf("Latest = %s (%v), want %s", latest.Hash, err, mainChain[3].Hash)
+		}
+		for i, want := range mainChain {
+			if b, err := store.GetBlockByIndex(i); err != nil || b.Hash != want.Hash {
+				t.Errorf("GetBlockByIndex(%d) = %s (%v), want %s", i, b.Hash, err, want.Hash)
+			}
+			b, err := store.GetBlockByHash(want.Hash)
+			if err != nil || b.Index != i || b.PrevHash != want.PrevHash || len(b.Transactions) != len(want.Transactions) {
+				t.Errorf("GetBlockByHash(%d) = %+v (%v)", i, b, err)
+			}
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		store := newStore(t)
+		for _, b := range mainChain[:2] {
+			if err := store.PutBlock(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := store.GetBlockByHash(mainChain[2].Hash); !errors.Is(err, ErrBlockNotFound) {
+			t.Errorf("GetBlockByHash(unknown): err = %v, want %v", err, ErrBlockNotFound)
+		}
+		for _, index := range []int{-1, 2, 100} {
+			if _, err := store.GetBlockByIndex(index); !errors.Is(err, ErrBlockNotFound) {
+				t.Errorf("GetBlockByIndex(%d): err = %v, want %v", index, err, ErrBlockNotFound)
+			}
+		}
+		if err := store.PutBlock(Block{Index: -1, Hash: "negative"}); err == nil {
+			t.Error("PutBlock accepted a negative index")
+		}
+	})
+
+	t.Run("txs", func(t *testing.T) {
+		store := newStore(t)
+		tx := CoinbaseTx("conf-tx", "miner", 10)
+		txid, _ := TxID(tx)
+		if _, err := store.GetTx(txid); !errors.Is(err, ErrTxNotFound) {
+			t.Errorf("GetTx(unknown): err = %v, want %v", err, ErrTxNotFound)
+		}
+		if err := store.PutTxs([]UTXOTx{tx}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.GetTx(txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id, _ := TxID(got); id != txid {
+			t.Errorf("GetTx returned a different tx %s, want %s", id, txid)
+		}
+	})
+
+	t.Run("reorg to shorter branch", func(t *testing.T) {
+		store := newStore(t)
+		for _, b := range append(mainChain, fork) {
+			if err := store.PutBlock(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if latest, _ := store.Latest(); latest.Hash != fork.Hash {
+			t.Fatalf("Latest = %s, want fork %s", latest.Hash, fork.Hash)
+		}
+		if b, err := store.GetBlockByIndex(2); err != nil || b.Hash != fork.Hash {
+			t.Errorf("GetBlockByIndex(2) = %s (%v), want fork %s", b.Hash, err, fork.Hash)
+		}
+		if _, err := store.GetBlockByIndex(3); !errors.Is(err, ErrBlockNotFound) {
+			t.Errorf("index above the new tip: err = %v, want %v", err, ErrBlockNotFound)
+		}
+		if _, err := store.GetBlockByHash(mainChain[3].Hash); err != nil {
+			t.Errorf("disconnected block no longer retrievable by hash: %v", err)
+		}
+	})
+}
+
+func TestMemoryBlockStore(t *testing.T) {
+	testBlockStore(t, func(t *testing.T) BlockStore { return NewMemoryBlockStore() })
+}
+
+func TestBoltBlockStore(t *testing.T) {
+	testBlockStore(t, func(t *testing.T) BlockStore {
+		return openTestStore(t, filepath.Join(t.TempDir(), "chain.db"))
+	})
+}
+
+func TestBoltBlockStoreRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chain.db")
+	store := openTestStore(t, path)
+	if _, err := store.Latest(); !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf("empty store: err = %v, want %v", err, ErrBlockNotFound)
+	}
+
+	bc := NewBlockchain(1, store)
+	txids := []string{"store-1", "store-2", "store-3"}
+	for _, tag := range txids {
+		if err := bc.ValidateAndApplyBlock(MineBlock(bc.GetLatest(), testTxs(t, tag, 1), 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := make([]string, bc.Height()+1)
+	for i := range want {
+		b, _ := bc.GetBlockByIndex(i)
+		want[i] = b.Hash
+	}
+	store.Close()
+
+	// 重新打开存储后恢复整条主链
+	reopened := openTestStore(t, path)
+	restored, err := NewBlockchainWithParams(DefaultConsensusParams(), reopened)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Height() != len(txids) || restored.GetLatest().Hash != want[len(want)-1] {
+		t.Fatalf("restored height %d tip %s, want height %d tip %s",
+			restored.Height(), restored.GetLatest().Hash, len(txids), want[len(want)-1])
+	}
+	for i, hash := range want {
+		b, err := restored.GetBlockByIndex(i)
+		if err != nil || b.Hash != hash {
+			t.Errorf("block %d = %s (%v), want %s", i, b.Hash, err, hash)
+		}
+		if stored, err := reopened.GetBlockByHash(hash); err != nil || stored.Index != i {
+			t.Errorf("store lookup %s: index %d, err %v", hash, stored.Index, err)
+		}
+	}
+	if blocks, err := restored.GetBlocks(1, 2); err != nil || len(blocks) != 2 || blocks[0].Hash != want[1] || blocks[1].Hash != want[2] {
+		t.Errorf("GetBlocks(1, 2) from store = %v, %v", blocks, err)
+	}
+	if restored.Difficulty() != 1 {
+		t.Errorf("restored difficulty = %d, want the latest block's difficulty 1", restored.Difficulty())
+	}
+
+	// 恢复的链继续出块并持久化
+	if err := restored.ValidateAndApplyBlock(MineBlock(restored.GetLatest(), testTxs(t, "store-4", 1), 1)); err != nil {
+		t.Fatal(err)
+	}
+	if latest, _ := reopened.Latest(); latest.Index != len(txids)+1 {
+		t.Errorf("store latest index = %d, want %d", latest.Index, len(txids)+1)
+	}
+	if _, err := reopened.GetBlockByIndex(len(txids) + 2); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("index beyond tip: err = %v, want %v", err, ErrBlockNotFound)
+	}
+
+	// 创世区块不一致（不同的创世分配）时拒绝恢复
+	if _, err := NewBlockchainWithParams(DefaultConsensusParams(), reopened, GenesisAlloc{Address: "store-other", Amount: 5}); err == nil {
+		t.Error("restoring with a different genesis should fail")
+	}
+}
+
+func TestBoltBlockStoreReorg(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chain.db")
+	store := openTestStore(t, path)
+	local := NewBlockchain(1, store)
+	if err := local.ValidateAndApplyBlock(MineBlock(local.GetLatest(), testTxs(t, "store-local", 1), 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 从创世区块分叉的更长分支替换本地链后，存储中的主链随之更新
+	genesis, _ := local.GetBlockByIndex(0)
+	candidate := []Block{genesis}
+	for _, tag := range []string{"store-remote-1", "store-remote-2"} {
+		candidate = append(candidate, MineBlock(candidate[len(candidate)-1], testTxs(t, tag, 1), 1))
+	}
+	if err := local.ReplaceChain(candidate); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	restored := NewBlockchain(1, openTestStore(t, path))
+	for i, b := range candidate {
+		got, err := restored.GetBlockByIndex(i)
+		if err != nil || got.Hash != b.Hash {
+			t.Errorf("block %d = %s (%v), want %s", i, got.Hash, err, b.Hash)
+		}
+	}
+}
+
+func TestBoltBlockStoreRestartSpend(t *testing.T) {
+	alice, _ := wallet.NewAccount()
+	bob, _ := wallet.NewAccount()
+	carol, _ := wallet.NewAccount()
+	alloc := GenesisAlloc{Address: alice.Address, Amount: 100}
+	path := filepath.Join(t.TempDir(), "chain.db")
+	store := openTestStore(t, path)
+	bc, err := NewBlockchainWithParams(DefaultConsensusParams(), store, alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesisTx := bc.GetLatest().Transactions[0]
+	wtx, err := wallet.BuildTransaction(alice, bob.Address, 30, 1, []wallet.UTXO{{Txid: genesisTx, Vout: 0, Amount: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pay, err := PutTransaction(TxFromWallet(wtx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.ValidateAndApplyBlock(MineBlock(bc.GetLatest(), []string{pay}, bc.Difficulty())); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// 模拟进程重启：内存中的交易体和UTXO集合全部丢失
+	txStoreLock.Lock()
+	txStore = make(map[string]UTXOTx)
+	txStoreLock.Unlock()
+	replaceUTXOSet(make(map[UTXOKey]UTXOEntry))
+
+	restored, err := NewBlockchainWithParams(DefaultConsensusParams(), openTestStore(t, path), alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetTransaction(pay); err != nil {
+		t.Fatalf("tx body not restored from the store: %v", err)
+	}
+	// UTXO集合由重放恢复：创世输出已被花费，bob收到的输出可用
+	if _, err := GetUTXO(genesisTx, 0); err == nil {
+		t.Error("genesis output spent before the restart is unspent again")
+	}
+	if got := GetBalance(bob.Address); got != 30 {
+		t.Fatalf("bob balance after restart = %d, want 30", got)
+	}
+
+	// 重启后bob花费收到的输出
+	wtx, err = wallet.BuildTransaction(bob, carol.Address, 10, 1, []wallet.UTXO{{Txid: pay, Vout: 0, Amount: 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spend, err := PutTransaction(TxFromWallet(wtx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.ValidateAndApplyBlock(MineBlock(restored.GetLatest(), []string{spend}, restored.Difficulty())); err != nil {
+		t.Fatalf("spend after restart rejected: %v", err)
+	}
+	if got := GetBalance(carol.Address); got != 10 {
+		t.Errorf("carol balance = %d, want 10", got)
+	}
+}
@@ -0,0 +1,264 @@
+package blockchain
+
+// internal/blockchain/sync.go
+// 先同步区块头（headers-first）的链同步实现：
+// 1. 分批拉取对端的区块头链，总数不超过对端公布的链高度
+// 2. 校验区块头链接、哈希和工作量证明，并比较累计工作量
+// 3. 只有对端链工作量更大时，才并行下载分叉点之后的区块体，以及本地没有的交易体
+// 4. 区块体和交易体全部到齐并校验通过后，整体替换本地链
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+const (
+	// HeaderBatchSize 每次请求的区块头数量
+	HeaderBatchSize = 500
+	// SyncWorkers 并行下载区块体的协程数量
+	SyncWorkers = 4
+)
+
+// ErrChainNotBetter 候选链的累计工作量不大于本地链
+var ErrChainNotBetter = errors.New("candidate chain does not have more work")
+
+// SyncSource 同步数据源（通常是一个远端节点）
+type SyncSource interface {
+	// Height 返回对端当前链高度（最新区块的索引）
+	Height() (int, error)
+	// GetHeaders 返回从from开始的至多count个区块头
+	GetHeaders(from, count int) ([]BlockHeader, error)
+	// GetBlock 按哈希返回完整区块
+	GetBlock(hash string) (Block, error)
+	// GetTx 按交易ID返回交易体
+	GetTx(txid string) (UTXOTx, error)
+}
+
+// SyncHeadersFirst 从数据源执行先同步区块头的链同步
+// 对端链不比本地链更好时返回ErrChainNotBetter，本地链保持不变
+func (bc *Blockchain) SyncHeadersFirst(src SyncSource) error {
+	headers, err := fetchHeaderChain(src)
+	if err != nil {
+		return err
+	}
+	if err := bc.ValidateHeaderChain(headers); err != nil {
+		return err
+	}
+	bc.ObserveTargetHeight(len(headers) - 1)
+	if HeadersWork(headers).Cmp(bc.ChainWork()) <= 0 {
+		return ErrChainNotBetter
+	}
+
+	// 找到与本地链的分叉点，只下载其后的区块体
+	fork := bc.commonAncestor(headers)
+	bc.beginSync(fork)
+	defer bc.endSync()
+	bodies, txs, err := fetchBodies(src, headers[fork+1:], bc.blockFetched)
+	if err != nil {
+		return err
+	}
+
+	// 区块校验按交易ID查找交易体，先保存下载的交易体；候选链被拒绝时再删除
+	for _, tx := range txs {
+		if _, err := PutTransaction(tx); err != nil {
+			return err
+		}
+	}
+	bc.lock.RLock()
+	candidate := make([]Block, 0, len(headers))
+	candidate = append(candidate, bc.chain[:fork+1]...)
+	bc.lock.RUnlock()
+	candidate = append(candidate, bodies...)
+	if err := bc.ReplaceChain(candidate); err != nil {
+		for txid := range txs {
+			DeleteTransaction(txid)
+		}
+		return err
+	}
+	return nil
+}
+
+// fetchHeaderChain 从创世区块开始分批拉取区块头链，总数不超过对端公布的高度加一
+// 对端返回多于请求数量的区块头时同步失败
+func fetchHeaderChain(src SyncSource) ([]BlockHeader, error) {
+	height, err := src.Height()
+	if err != nil {
+		return nil, fmt.Errorf("fetch peer height: %v", err)
+	}
+	if height < 0 {
+		return nil, fmt.Errorf("peer advertised invalid height %d", height)
+	}
+	var headers []BlockHeader
+	for len(headers) <= height {
+		count := height + 1 - len(headers)
+		if count > HeaderBatchSize {
+			count = HeaderBatchSize
+		}
+		batch, err := src.GetHeaders(len(headers), count)
+		if err != nil {
+			return nil, fmt.Errorf("fetch headers from %d: %v", len(headers), err)
+		}
+		if len(batch) > count {
+			return nil, fmt.Errorf("peer returned %d headers from %d, requested %d", len(batch), len(headers), count)
+		}
+		headers = append(headers, batch...)
+		if len(batch) < count {
+			break
+		}
+	}
+	if len(headers) == 0 {
+		return nil, errors.New("peer returned no headers")
+	}
+	return headers, nil
+}
+
+// ValidateHeaderChain 校验区块头链：创世区块一致、高度连续、哈希链接、哈希可由头部重算且封装有效（PoW或PoA签名）
+func (bc *Blockchain) ValidateHeaderChain(headers []BlockHeader) error {
+	if len(headers) == 0 {
+		return errors.New("empty header chain")
+	}
+	genesis, _ := bc.GetBlockByIndex(0)
+	if headers[0].Hash != genesis.Hash {
+		return errors.New("genesis mismatch")
+	}
+	// 按区块头重建只含难度调整所需字段的链，得到每个高度应使用的难度
+	stubs := []Block{{Index: 0, Timestamp: headers[0].Timestamp}}
+	params := bc.Params()
+	for i := 1; i < len(headers); i++ {
+		h := headers[i]
+		difficulty := params.Difficulty
+		if i > 1 {
+			difficulty = headers[i-1].Difficulty
+		}
+		want := difficultyAfter(stubs, difficulty, params)
+		stubs = append(stubs, Block{Index: h.Index, Timestamp: h.Timestamp, Difficulty: h.Difficulty})
+		if h.Index != i {
+			return fmt.Errorf("header %d: unexpected index %d", i, h.Index)
+		}
+		if h.PrevHash != headers[i-1].Hash {
+			return fmt.Errorf("header %d: broken link", i)
+		}
+		if HeaderHash(h) != h.Hash {
+			return fmt.Errorf("header %d: hash mismatch", i)
+		}
+		if _, ok := blockVersionRules[h.Version]; !ok {
+			return fmt.Errorf("header %d: unknown version %d", i, h.Version)
+		}
+		if local, err := bc.GetBlockByIndex(i); err == nil && local.Hash == h.Hash {
+			continue // 本地已有的区块难度可能早于最近一次难度调整
+		}
+		if h.Difficulty != want {
+			return fmt.Errorf("header %d: difficulty mismatch", i)
+		}
+		if err := bc.Consensus().VerifySeal(&h); err != nil {
+			return fmt.Errorf("header %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// HeadersWork 返回区块头链的累计工作量（不含创世区块）
+func HeadersWork(headers []BlockHeader) *big.Int {
+	total := new(big.Int)
+	for _, h := range headers {
+		if h.Index == 0 {
+			continue
+		}
+		total.Add(total, BlockWork(h.Difficulty))
+	}
+	return total
+}
+
+// commonAncestor 返回本地链与区块头链最后一个相同区块的高度
+func (bc *Blockchain) commonAncestor(headers []BlockHeader) int {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	fork := 0
+	for i := 0; i < len(headers) && i < len(bc.chain); i++ {
+		if bc.chain[i].Hash != headers[i].Hash {
+			break
+		}
+		fork = i
+	}
+	return fork
+}
+
+// fetchBodies 使用SyncWorkers个协程并行下载区块体，并确认每个区块体与对应区块头一致；
+// 区块引用的交易体本地没有时一并下载，并确认交易ID与区块中的一致且结构有效
+// 区块按区块头顺序返回，交易体按交易ID返回，任何一个区块体或交易体无效都会导致整体失败
+// fetched在每个区块体下载并校验通过后调用，用于报告进度
+func fetchBodies(src SyncSource, headers []BlockHeader, fetched func()) ([]Block, map[string]UTXOTx, error) {
+	bodies := make([]Block, len(headers))
+	errs := make([]error, len(headers))
+	jobs := make(chan int)
+	var txsMu sync.Mutex
+	txs := make(map[string]UTXOTx)
+
+	var wg sync.WaitGroup
+	for w := 0; w < SyncWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				h := headers[i]
+				b, err := src.GetBlock(h.Hash)
+				if err != nil {
+					errs[i] = fmt.Errorf("fetch block %d: %v", h.Index, err)
+					continue
+				}
+				if b.Header() != h || !b.ValidateBasic() {
+					errs[i] = fmt.Errorf("block %d body does not match header", h.Index)
+					continue
+				}
+				fetchedTxs, err := fetchTxs(src, b.Transactions)
+				if err != nil {
+					errs[i] = fmt.Errorf("block %d: %v", h.Index, err)
+					continue
+				}
+				txsMu.Lock()
+				for txid, tx := range fetchedTxs {
+					txs[txid] = tx
+				}
+				txsMu.Unlock()
+				bodies[i] = b
+				fetched()
+			}
+		}()
+	}
+	for i := range headers {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return bodies, txs, nil
+}
+
+// fetchTxs 下载txids中本地没有交易体的交易，确认交易体的交易ID与请求的一致且结构有效
+func fetchTxs(src SyncSource, txids []string) (map[string]UTXOTx, error) {
+	txs := make(map[string]UTXOTx)
+	for _, txid := range txids {
+		if _, err := GetTransaction(txid); err == nil {
+			continue
+		}
+		tx, err := src.GetTx(txid)
+		if err != nil {
+			return nil, fmt.Errorf("fetch tx %s: %v", txid, err)
+		}
+		if got, err := TxID(tx); err != nil || got != txid {
+			return nil, fmt.Errorf("tx %s body does not match its id", txid)
+		}
+		if err := ValidateTxStructure(tx); err != nil {
+			return nil, fmt.Errorf("tx %s: %v", txid, err)
+		}
+		txs[txid] = tx
+	}
+	return txs, nil
+}
@@ -0,0 +1,265 @@
+package blockchain
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chainSource 以本地Blockchain实例作为同步数据源，模拟远端节点
+type chainSource struct {
+	bc     *Blockchain
+	tamper func(*Block)      // 可选：篡改返回的区块体
+	txs    map[string]UTXOTx // 可选：对端持有的交易体，为nil时从本地交易体存储查找
+}
+
+func (s *chainSource) Height() (int, error) {
+	return s.bc.Height(), nil
+}
+
+func (s *chainSource) GetTx(txid string) (UTXOTx, error) {
+	if s.txs == nil {
+		return GetTransaction(txid)
+	}
+	tx, ok := s.txs[txid]
+	if !ok {
+		return UTXOTx{}, fmt.Errorf("transaction not found: %s", txid)
+	}
+	return tx, nil
+}
+
+func (s *chainSource) GetHeaders(from, count int) ([]BlockHeader, error) {
+	return s.bc.GetHeaders(from, count), nil
+}
+
+func (s *chainSource) GetBlock(hash string) (Block, error) {
+	b, err := s.bc.GetBlockByHash(hash)
+	if err != nil {
+		return Block{}, err
+	}
+	if s.tamper != nil {
+		s.tamper(&b)
+	}
+	return b, nil
+}
+
+// mineChain 在区块链上挖出n个低难度区块
+func mineChain(t *testing.T, bc *Blockchain, n int, tag string) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		b := MineBlock(bc.GetLatest(), testTxs(t, fmt.Sprintf("%s-tx-%d", tag, i), 1), bc.difficulty)
+		if err := bc.ValidateAndApplyBlock(b); err != nil {
+			t.Fatalf("apply block %d: %v", i, err)
+		}
+	}
+}
+
+func TestSyncHeadersFirst(t *testing.T) {
+	// 测试区块间隔1秒，两端共识参数的目标间隔都设为1秒使50个区块保持低难度
+	params := DefaultConsensusParams()
+	params.Difficulty = 1
+	params.TargetSpacing = 1
+	remote, err := NewBlockchainWithParams(params, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mineChain(t, remote, 50, "remote")
+
+	local, err := NewBlockchainWithParams(params, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := local.SyncHeadersFirst(&chainSource{bc: remote}); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	if local.Height() != 50 {
+		t.Fatalf("expected height 50, got %d", local.Height())
+	}
+	for i := 0; i <= 50; i++ {
+		want, _ := remote.GetBlockByIndex(i)
+		got, err := local.GetBlockByIndex(i)
+		if err != nil || got.Hash != want.Hash {
+			t.Fatalf("block %d mismatch after sync", i)
+		}
+	}
+	if local.ChainWork().Cmp(remote.ChainWork()) != 0 {
+		t.Error("chain work differs after sync")
+	}
+}
+
+func TestSyncHeadersFirstRejectsWorseChain(t *testing.T) {
+	remote := NewBlockchain(1, nil)
+	mineChain(t, remote, 2, "remote")
+
+	local := NewBlockchain(1, nil)
+	mineChain(t, local, 3, "local")
+	tip := local.GetLatest().Hash
+
+	if err := local.SyncHeadersFirst(&chainSource{bc: remote}); err != ErrChainNotBetter {
+		t.Fatalf("expected ErrChainNotBetter, got %v", err)
+	}
+	if local.GetLatest().Hash != tip {
+		t.Error("local chain should be unchanged")
+	}
+}
+
+func TestSyncHeadersFirstRejectsMismatchedBody(t *testing.T) {
+	remote := NewBlockchain(1, nil)
+	mineChain(t, remote, 5, "remote")
+
+	// 对端返回的区块体与区块头不一致（交易被替换）
+	src := &chainSource{bc: remote, tamper: func(b *Block) {
+		b.Transactions = []string{"forged"}
+	}}
+	local := NewBlockchain(1, nil)
+	if err := local.SyncHeadersFirst(src); err == nil {
+		t.Fatal("sync with tampered bodies should fail")
+	}
+	if local.Height() != 0 {
+		t.Errorf("local chain should stay at genesis, got height %d", local.Height())
+	}
+}
+
+// gatedSource 前allowed个区块体立即返回，其余的等待release关闭后才返回
+type gatedSource struct {
+	chainSource
+	allowed int
+	release chan struct{}
+	mu      sync.Mutex
+	served  int
+}
+
+func (s *gatedSource) GetBlock(hash string) (Block, error) {
+	s.mu.Lock()
+	s.served++
+	wait := s.served > s.allowed
+	s.mu.Unlock()
+	if wait {
+		<-s.release
+	}
+	return s.chainSource.GetBlock(hash)
+}
+
+func TestSyncProgress(t *testing.T) {
+	remote := NewBlockchain(1, nil)
+	mineChain(t, remote, 10, "progress")
+	local := NewBlockchain(1, nil)
+
+	if p := local.SyncProgress(); p.Syncing || p.Percent != 100 || p.TargetHeight != 0 {
+		t.Fatalf("idle progress = %+v, want 100%% with no target", p)
+	}
+
+	src := &gatedSource{chainSource: chainSource{bc: remote}, allowed: 4, release: make(chan struct{})}
+	done := make(chan error, 1)
+	go func() { done <- local.SyncHeadersFirst(src) }()
+
+	// 下载了4个区块体后同步停住：进度为4/10
+	deadline := time.Now().Add(5 * time.Second)
+	var p SyncProgress
+	for time.Now().Before(deadline) {
+		if p = local.SyncProgress(); p.CurrentHeight == 4 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !p.Syncing || p.CurrentHeight != 4 || p.TargetHeight != 10 || p.Percent != 40 {
+		t.Fatalf("mid-sync progress = %+v, want syncing 4/10 (40%%)", p)
+	}
+
+	close(src.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if p := local.SyncProgress(); p.Syncing || p.CurrentHeight != 10 || p.TargetHeight != 10 || p.Percent != 100 {
+		t.Fatalf("final progress = %+v, want 10/10 (100%%)", p)
+	}
+
+	// 对端报告更高的链高时进度回落
+	local.ObserveTargetHeight(20)
+	if p := local.SyncProgress(); p.Percent != 50 {
+		t.Fatalf("progress with higher target = %+v, want 50%%", p)
+	}
+}
+
+// takeBodies 把链上全部交易体从本地交易体存储移到返回的映射中，模拟只有对端持有这些交易体
+func takeBodies(t *testing.T, bc *Blockchain) map[string]UTXOTx {
+	t.Helper()
+	txs := make(map[string]UTXOTx)
+	for i := 1; i <= bc.Height(); i++ {
+		b, _ := bc.GetBlockByIndex(i)
+		for _, txid := range b.Transactions {
+			tx, err := GetTransaction(txid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txs[txid] = tx
+			DeleteTransaction(txid)
+		}
+	}
+	return txs
+}
+
+func TestSyncFetchesTxBodies(t *testing.T) {
+	remote := NewBlockchain(1, nil)
+	mineChain(t, remote, 5, "bodies")
+	txs := takeBodies(t, remote)
+
+	// 对端返回的交易体与交易ID不一致时同步失败，下载的交易体不会留在本地
+	forged := make(map[string]UTXOTx, len(txs))
+	for txid, tx := range txs {
+		tx.Outputs = append([]TxOutput(nil), tx.Outputs...)
+		tx.Outputs[0].Amount++
+		forged[txid] = tx
+	}
+	local := NewBlockchain(1, nil)
+	if err := local.SyncHeadersFirst(&chainSource{bc: remote, txs: forged}); err == nil {
+		t.Fatal("sync with forged tx bodies should fail")
+	}
+	if local.Height() != 0 {
+		t.Fatalf("local chain should stay at genesis, got height %d", local.Height())
+	}
+
+	if err := local.SyncHeadersFirst(&chainSource{bc: remote, txs: txs}); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if local.Height() != 5 {
+		t.Fatalf("expected height 5, got %d", local.Height())
+	}
+	for txid := range txs {
+		if _, err := GetTransaction(txid); err != nil {
+			t.Fatalf("tx body %s not stored after sync", txid)
+		}
+	}
+}
+
+// lyingSource 公布的高度低于实际链高度，且忽略请求的数量返回全部区块头
+type lyingSource struct {
+	chainSource
+	height int
+}
+
+func (s *lyingSource) Height() (int, error) {
+	return s.height, nil
+}
+
+func (s *lyingSource) GetHeaders(from, count int) ([]BlockHeader, error) {
+	return s.bc.GetHeaders(from, HeaderBatchSize), nil
+}
+
+func TestSyncHeaderCountBounded(t *testing.T) {
+	remote := NewBlockchain(1, nil)
+	mineChain(t, remote, 5, "bounded")
+
+	local := NewBlockchain(1, nil)
+	if err := local.SyncHeadersFirst(&lyingSource{chainSource: chainSource{bc: remote}, height: 2}); err == nil {
+		t.Fatal("sync should fail when the peer returns more headers than requested")
+	}
+	if local.Height() != 0 {
+		t.Fatalf("local chain should stay at genesis, got height %d", local.Height())
+	}
+	if err := local.SyncHeadersFirst(&lyingSource{chainSource: chainSource{bc: remote}, height: -1}); err == nil {
+		t.Fatal("sync should fail on a negative advertised height")
+	}
+}
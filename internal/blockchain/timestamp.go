@@ -0,0 +1,71 @@
+package blockchain
+
+// internal/blockchain/timestamp.go
+// 区块时间戳规则
+// 只与父区块比较时间戳容易被操纵，因此与比特币一样要求新区块时间戳严格大于
+// 最近若干个区块时间戳的中位数（median-time-past），同时不能超过本地时间太多
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 时间戳规则参数
+const (
+	DefaultMedianTimeWindow = 11          // 默认计算中位数的区块数
+	MaxFutureBlockTime      = 2 * 60 * 60 // 区块时间戳最多领先本地时间的秒数
+)
+
+// MedianTimePast 返回blocks中最近window个区块时间戳的中位数
+// 区块不足window个时使用全部区块；blocks为空时返回0
+func MedianTimePast(blocks []Block, window int) int64 {
+	if window < 1 {
+		window = 1
+	}
+	if window > len(blocks) {
+		window = len(blocks)
+	}
+	if window == 0 {
+		return 0
+	}
+	ts := make([]int64, window)
+	for i, b := range blocks[len(blocks)-window:] {
+		ts[i] = b.Timestamp
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
+	return ts[window/2]
+}
+
+// checkBlockTimestamp 检查区块时间戳大于祖先区块的median-time-past且不超过未来漂移上限
+// ancestors: 按高度升序排列、以父区块结尾的祖先区块
+func checkBlockTimestamp(b *Block, ancestors []Block, window int, now int64) error {
+	if mtp := MedianTimePast(ancestors, window); b.Timestamp <= mtp {
+		return fmt.Errorf("timestamp %d not after median time past %d", b.Timestamp, mtp)
+	}
+	if b.Timestamp > now+MaxFutureBlockTime {
+		return fmt.Errorf("timestamp %d too far in the future", b.Timestamp)
+	}
+	return nil
+}
+
+// SetMedianTimeWindow 设置计算median-time-past的区块数
+func (bc *Blockchain) SetMedianTimeWindow(n int) {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	bc.mtpWindow = n
+}
+
+// MedianTimePast 返回主链当前的median-time-past，新区块时间戳必须大于该值
+func (bc *Blockchain) MedianTimePast() int64 {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	return MedianTimePast(bc.chain, bc.mtpWindow)
+}
+
+// checkTimestamp 针对主链检查新区块的时间戳
+func (bc *Blockchain) checkTimestamp(b *Block) error {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	return checkBlockTimestamp(b, bc.chain, bc.mtpWindow, time.Now().Unix())
+}
@@ -0,0 +1,49 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// mineAt 在prev之后挖出指定时间戳的区块
+func mineAt(t *testing.T, prev Block, ts int64) Block {
+	b := MineBlock(prev, testTxs(t, fmt.Sprintf("mtp-%d", ts), 1), 1)
+	b.Timestamp = ts
+	nonce, hash := NewProofOfWork(&b, 1).Run()
+	b.Nonce = nonce
+	b.Hash = hex.EncodeToString(hash)
+	return b
+}
+
+func TestMedianTimePastRule(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	bc.SetMedianTimeWindow(5)
+	base := time.Now().Unix() - 1000
+
+	// 早于父区块但晚于median-time-past的区块是允许的
+	for _, off := range []int64{10, 20, 30, 40, 25, 28} {
+		if err := bc.ValidateAndApplyBlock(mineAt(t, bc.GetLatest(), base+off)); err != nil {
+			t.Fatalf("block at +%d rejected: %v", off, err)
+		}
+	}
+	// 最近5个时间戳 20,30,40,25,28 的中位数为28
+	if got := bc.MedianTimePast(); got != base+28 {
+		t.Fatalf("median time past = +%d, want +28", got-base)
+	}
+
+	for name, ts := range map[string]int64{
+		"below median":   base + 25,
+		"equal median":   base + 28,
+		"too far future": time.Now().Unix() + MaxFutureBlockTime + 60,
+	} {
+		err := bc.ValidateAndApplyBlock(mineAt(t, bc.GetLatest(), ts))
+		if rerr, ok := err.(*BlockRejectError); !ok || rerr.Reason != RejectBadTimestamp {
+			t.Errorf("%s: expected bad_timestamp rejection, got %v", name, err)
+		}
+	}
+	if err := bc.ValidateAndApplyBlock(mineAt(t, bc.GetLatest(), base+29)); err != nil {
+		t.Fatalf("block just after median rejected: %v", err)
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+
+	"mini_chain/internal/wallet"
 )
 
 // TxInput 交易输入，通过txid:vout引用前一个UTXO，并携带签名和公钥
@@ -28,6 +31,7 @@ type TxOutput struct {
 
 // UTXOTx UTXO模型中的可序列化原始交易
 type UTXOTx struct {
+	Version int        `json:"version"` // 交易版本，参与交易ID计算
 	Inputs  []TxInput  `json:"inputs"`  // 交易输入列表
 	Outputs []TxOutput `json:"outputs"` // 交易输出列表
 }
@@ -55,7 +59,7 @@ func CoinbaseTx(coinbaseData, to string, amount int) UTXOTx {
 		},
 	}
 
-	return UTXOTx{Inputs: inputs, Outputs: outputs}
+	return UTXOTx{Version: TxVersion, Inputs: inputs, Outputs: outputs}
 }
 
 // IsCoinbase 判断交易是否为Coinbase交易
@@ -81,61 +85,154 @@ func TxID(raw UTXOTx) (string, error) {
 	return hex.EncodeToString(sum[:]), nil // 返回十六进制编码的哈希值
 }
 
+// TxFromWallet 将钱包构造的交易转换为UTXOTx（两者字段和JSON编码相同）
+func TxFromWallet(wtx wallet.Transaction) UTXOTx {
+	tx := UTXOTx{Version: wtx.Version}
+	for _, in := range wtx.Inputs {
+		tx.Inputs = append(tx.Inputs, TxInput{Txid: in.Txid, Vout: in.Vout, Signature: in.Signature, PubKey: in.PubKey})
+	}
+	for _, out := range wtx.Outputs {
+		tx.Outputs = append(tx.Outputs, TxOutput{Address: out.Address, Amount: out.Amount})
+	}
+	return tx
+}
+
+// toWallet 将UTXOTx转换为钱包交易结构
+func (tx UTXOTx) toWallet() wallet.Transaction {
+	wtx := wallet.Transaction{Version: tx.Version}
+	for _, in := range tx.Inputs {
+		wtx.Inputs = append(wtx.Inputs, wallet.TxInput{Txid: in.Txid, Vout: in.Vout, Signature: in.Signature, PubKey: in.PubKey})
+	}
+	for _, out := range tx.Outputs {
+		wtx.Outputs = append(wtx.Outputs, wallet.TxOutput{Address: out.Address, Amount: out.Amount})
+	}
+	return wtx
+}
+
+// TxSigHash 返回交易输入签名的摘要，与wallet.TxSigHash相同
+func TxSigHash(tx UTXOTx) []byte {
+	return wallet.TxSigHash(tx.toWallet())
+}
+
 // ValidateTxStructure 基本健全性检查（结构）
-// 验证交易的基本结构是否合法
+// 验证交易版本已知，并按该版本的规则检查交易结构
 func ValidateTxStructure(raw UTXOTx) error {
-	// 检查交易是否既没有输入也没有输出
-	if len(raw.Inputs) == 0 && len(raw.Outputs) == 0 {
-		return fmt.Errorf("tx has no inputs and no outputs")
+	return checkTxVersion(raw)
+}
+
+// 区块交易校验错误
+var (
+	ErrTxNotFound          = errors.New("transaction not found") // 本地没有该交易ID对应的交易体
+	ErrOutputsExceedInputs = errors.New("outputs exceed inputs") // 交易输出总额大于输入总额
+)
+
+// validateRawTx 按交易ID取回交易体，检查其结构、每个输入引用的UTXO都存在于当前UTXO集合中，
+// 且输入总额不小于输出总额；同一交易重复引用同一输出由结构检查拒绝（ErrDuplicateInput）
+// 签名在交易进入内存池时校验（ValidateTxForMempool），这里不重复检查
+func validateRawTx(txid string) error {
+	tx, err := GetTransaction(txid)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrTxNotFound, txid)
 	}
+	_, err = checkRawTx(txid, tx, GetUTXO)
+	return err
+}
 
-	// 检查输出金额是否为负数
-	for _, out := range raw.Outputs {
-		if out.Amount < 0 {
-			return fmt.Errorf("negative amount")
+// checkRawTx 用lookup查找输入引用的UTXO，执行validateRawTx的检查，返回交易的手续费
+// coinbase交易没有可查找的输入，手续费为0
+func checkRawTx(txid string, tx UTXOTx, lookup func(txid string, vout int) (UTXOEntry, error)) (int, error) {
+	if err := ValidateTxStructure(tx); err != nil {
+		return 0, fmt.Errorf("%w: tx %s: %w", ErrBadTxStructure, txid, err)
+	}
+	if IsCoinbase(tx) {
+		return 0, nil
+	}
+	amounts := make([]int, 0, len(tx.Inputs))
+	for _, in := range tx.Inputs {
+		entry, err := lookup(in.Txid, in.Vout)
+		if err != nil {
+			return 0, fmt.Errorf("%w: tx %s spends %s:%d", ErrMissingInput, txid, in.Txid, in.Vout)
 		}
+		amounts = append(amounts, entry.Amount)
+	}
+	in, err := SumAmounts(amounts...)
+	if err != nil {
+		return 0, fmt.Errorf("%w: tx %s input total: %v", ErrBadTxStructure, txid, err)
+	}
+	out, _ := SumOutputs(tx) // 结构检查已保证不溢出
+	if out > in {
+		return 0, fmt.Errorf("%w: tx %s outputs %d, inputs %d", ErrOutputsExceedInputs, txid, out, in)
 	}
-	return nil
+	return in - out, nil
 }
 
-// validateRawTx 检查交易的所有输入是否存在于UTXO集合中
-// 区块链使用此函数验证区块中的交易
-func validateRawTx(txid string) error {
-	// TODO: 这是一个简化的实现
-	// 在实际实现中，我们会：
-	// 1. 通过txid从存储中检索交易
-	// 2. 验证交易结构
-	// 3. 检查所有输入UTXO是否存在
-	// 4. 验证签名
-	//
-	// 目前，我们只返回nil表示交易有效
-
-	// 实际实现应该如下所示：
-	/*
-		// 1. 通过txid从存储中检索交易
-		tx, err := getTransactionById(txid)
+// utxoView 在底层UTXO查找之上记录尚未写入全局集合的变更，
+// 使区块内（或候选链上）后面的交易可以花费前面交易的输出，且同一输出不能被花费两次
+type utxoView struct {
+	base  func(txid string, vout int) (UTXOEntry, error) // nil表示底层为空集合
+	spent map[UTXOKey]bool
+	added map[UTXOKey]UTXOEntry
+}
+
+func newUTXOView(base func(txid string, vout int) (UTXOEntry, error)) *utxoView {
+	return &utxoView{base: base, spent: make(map[UTXOKey]bool), added: make(map[UTXOKey]UTXOEntry)}
+}
+
+func (v *utxoView) get(txid string, vout int) (UTXOEntry, error) {
+	k := UTXOKey{Txid: txid, Vout: vout}
+	if v.spent[k] {
+		return UTXOEntry{}, fmt.Errorf("utxo already spent %s:%d", txid, vout)
+	}
+	if e, ok := v.added[k]; ok {
+		return e, nil
+	}
+	if v.base == nil {
+		return UTXOEntry{}, fmt.Errorf("utxo not found %s:%d", txid, vout)
+	}
+	return v.base(txid, vout)
+}
+
+func (v *utxoView) del(txid string, vout int) {
+	k := UTXOKey{Txid: txid, Vout: vout}
+	delete(v.added, k)
+	v.spent[k] = true
+}
+
+func (v *utxoView) put(txid string, vout int, e UTXOEntry) {
+	k := UTXOKey{Txid: txid, Vout: vout}
+	delete(v.spent, k)
+	v.added[k] = e
+}
+
+// apply 把区块交易的UTXO变更记入视图，不做校验（用于已校验过的区块）
+func (v *utxoView) apply(txids []string) error {
+	return applyTxs(txids, v.del, v.put)
+}
+
+// checkBlockTxs 按区块内依赖顺序用validateRawTx的规则逐笔校验交易，并把通过的交易记入视图，
+// 返回区块内交易的手续费总额
+// 本地没有交易体的交易无法校验，返回ErrTxNotFound
+func checkBlockTxs(txids []string, view *utxoView) (int, error) {
+	ordered, err := orderBlockTxs(txids)
+	if err != nil {
+		return 0, err
+	}
+	fees := 0
+	for _, txid := range ordered {
+		tx, err := GetTransaction(txid)
 		if err != nil {
-			return fmt.Errorf("transaction not found: %s", txid)
+			return 0, fmt.Errorf("%w: %s", ErrTxNotFound, txid)
 		}
-
-		// 2. 验证交易结构
-		if err := ValidateTxStructure(tx); err != nil {
-			return fmt.Errorf("invalid transaction structure: %v", err)
+		fee, err := checkRawTx(txid, tx, view.get)
+		if err != nil {
+			return 0, err
 		}
-
-		// 3. 检查所有输入UTXO是否存在
-		for _, input := range tx.Inputs {
-			_, err := GetUTXO(input.Txid, input.Vout)
-			if err != nil {
-				return fmt.Errorf("input UTXO not found: %s:%d", input.Txid, input.Vout)
-			}
+		if fees, err = CheckedAdd(fees, fee); err != nil {
+			return 0, fmt.Errorf("%w: block fee total: %v", ErrBadTxStructure, err)
 		}
-
-		// 4. 验证签名
-		if err := verifyTransactionSignatures(tx); err != nil {
-			return fmt.Errorf("invalid signatures: %v", err)
+		if err := view.apply([]string{txid}); err != nil {
+			return 0, err
 		}
-	*/
-
-	return nil
+	}
+	return fees, nil
 }
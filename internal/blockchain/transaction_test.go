@@ -0,0 +1,125 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateTxStructureDuplicateInput(t *testing.T) {
+	tx := UTXOTx{
+		Version: TxVersion,
+		Inputs: []TxInput{
+			{Txid: "prev", Vout: 0, PubKey: "alice"},
+			{Txid: "prev", Vout: 1, PubKey: "alice"},
+		},
+		Outputs: []TxOutput{{Address: "bob", Amount: 10}},
+	}
+	if err := ValidateTxStructure(tx); err != nil {
+		t.Fatalf("引用不同输出的交易应被接受: %v", err)
+	}
+
+	tx.Inputs = append(tx.Inputs, TxInput{Txid: "prev", Vout: 0, PubKey: "alice"})
+	if err := ValidateTxStructure(tx); !errors.Is(err, ErrDuplicateInput) {
+		t.Fatalf("重复引用同一输出的交易应被拒绝，实际 %v", err)
+	}
+}
+
+func TestValidateRawTx(t *testing.T) {
+	PutUTXO("raw-fund", 0, UTXOEntry{Address: "alice", Amount: 30})
+	PutUTXO("raw-fund", 1, UTXOEntry{Address: "alice", Amount: 20})
+	t.Cleanup(func() {
+		DeleteUTXO("raw-fund", 0)
+		DeleteUTXO("raw-fund", 1)
+	})
+	put := func(tx UTXOTx) string {
+		t.Helper()
+		txid, err := PutTransaction(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { DeleteTransaction(txid) })
+		return txid
+	}
+	spend := func(outAmount int, inputs ...TxInput) UTXOTx {
+		return UTXOTx{Version: TxVersion, Inputs: inputs, Outputs: []TxOutput{{Address: "bob", Amount: outAmount}}}
+	}
+
+	cases := []struct {
+		name string
+		txid string
+		want error
+	}{
+		{"valid", put(spend(50, TxInput{Txid: "raw-fund", Vout: 0}, TxInput{Txid: "raw-fund", Vout: 1})), nil},
+		{"coinbase", put(CoinbaseTx("raw test", "miner", 10)), nil},
+		{"not found", "raw-no-such-tx", ErrTxNotFound},
+		{"bad structure", put(UTXOTx{Version: TxVersion + 100, Inputs: []TxInput{{Txid: "raw-fund", Vout: 0}}}), ErrBadTxStructure},
+		{"duplicate input", put(spend(10, TxInput{Txid: "raw-fund", Vout: 0}, TxInput{Txid: "raw-fund", Vout: 0})), ErrDuplicateInput},
+		{"missing input", put(spend(10, TxInput{Txid: "raw-fund", Vout: 2})), ErrMissingInput},
+		{"outputs exceed inputs", put(spend(31, TxInput{Txid: "raw-fund", Vout: 0})), ErrOutputsExceedInputs},
+	}
+	for _, c := range cases {
+		err := validateRawTx(c.txid)
+		if c.want == nil && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if c.want != nil && !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+}
+
+func TestBlockRejectsDoubleSpendAcrossTxs(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	PutUTXO("block-fund", 0, UTXOEntry{Address: "alice", Amount: 10})
+	t.Cleanup(func() { DeleteUTXO("block-fund", 0) })
+	var txids []string
+	for _, to := range []string{"bob", "carol"} {
+		txid, err := PutTransaction(UTXOTx{Version: TxVersion, Inputs: []TxInput{{Txid: "block-fund", Vout: 0}}, Outputs: []TxOutput{{Address: to, Amount: 10}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		txids = append(txids, txid)
+	}
+
+	// 两笔交易各自有效，但在同一区块中花费了同一个输出
+	b := MineBlock(bc.GetLatest(), txids, 1)
+	err := bc.ValidateAndApplyBlock(b)
+	var rerr *BlockRejectError
+	if !errors.As(err, &rerr) || rerr.Reason != RejectBadTx || !errors.Is(err, ErrMissingInput) {
+		t.Fatalf("expected bad_tx rejection for the double spend, got %v", err)
+	}
+	if _, err := GetUTXO("block-fund", 0); err != nil {
+		t.Error("rejected block must not spend the funding output")
+	}
+}
+
+func TestBlockRejectsUnknownTx(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	txids := append(testTxs(t, "unknown-tx-coinbase", 1), "no-such-tx")
+	b := MineBlock(bc.GetLatest(), txids, 1)
+
+	// 本地没有交易体的交易无法校验，区块被拒绝
+	err := bc.ValidateAndApplyBlock(b)
+	var rerr *BlockRejectError
+	if !errors.As(err, &rerr) || rerr.Reason != RejectBadTx || !errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("expected bad_tx rejection for the unknown tx, got %v", err)
+	}
+	if bc.Height() != 0 {
+		t.Fatalf("rejected block changed the height to %d", bc.Height())
+	}
+	if err := bc.ReplaceChain([]Block{bc.GetLatest(), b}); !errors.Is(err, ErrTxNotFound) {
+		t.Errorf("candidate chain with an unknown tx: err = %v, want %v", err, ErrTxNotFound)
+	}
+
+	// 其余区块级检查同样不跳过未知交易
+	for name, check := range map[string]func(*Block) error{
+		"coinbase":         checkBlockCoinbase,
+		"value":            func(b *Block) error { return checkBlockValue(b, 10, 0) },
+		"coinbase outputs": func(b *Block) error { return checkBlockCoinbaseOutputs(b, DefaultMaxCoinbaseOutputs) },
+		"size":             func(b *Block) error { return checkBlockSize(b, DefaultMaxBlockSize) },
+	} {
+		if err := check(&b); !errors.Is(err, ErrTxNotFound) {
+			t.Errorf("%s check: err = %v, want %v", name, err, ErrTxNotFound)
+		}
+	}
+}
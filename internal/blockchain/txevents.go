@@ -0,0 +1,66 @@
+package blockchain
+
+// internal/blockchain/txevents.go
+// 交易接受事件
+// 无论交易来自API还是gossip，都经AcceptTx校验并进入内存池，新进入内存池时通知订阅者（如WebSocket客户端）
+
+import (
+	"fmt"
+	"time"
+)
+
+// TxEvent 一笔交易进入内存池
+type TxEvent struct {
+	Txid      string    `json:"txid"`
+	Tx        UTXOTx    `json:"tx"`
+	FirstSeen time.Time `json:"first_seen"` // 进入内存池的时间
+}
+
+// AcceptTx 按内存池规则（大小上限、未被打包、完整校验、最低手续费minFee）校验交易并加入内存池
+// 内存池已满且手续费不高于池中最低手续费时返回ErrMempoolFull
+// 返回交易ID以及交易是否为新加入；已在内存池中的交易不报错，但不再通知OnTx回调
+func (bc *Blockchain) AcceptTx(tx UTXOTx, minFee int) (txid string, added bool, err error) {
+	if err := bc.CheckTxSize(tx); err != nil {
+		return "", false, err
+	}
+	if err := bc.CheckNotConfirmed(tx); err != nil {
+		return "", false, err
+	}
+	fee, err := ValidateTxForMempool(tx, minFee)
+	if err != nil {
+		return "", false, err
+	}
+	if txid, err = PutTransaction(tx); err != nil {
+		return "", false, err
+	}
+	if !AddToMempoolWithFee(txid, fee) {
+		if _, pooled := MempoolFirstSeen(txid); !pooled {
+			// 内存池已满且手续费不足以替换池中的交易
+			DeleteTransaction(txid)
+			return "", false, fmt.Errorf("%w: fee %d too low to replace pooled txs", ErrMempoolFull, fee)
+		}
+		return txid, false, nil
+	}
+	firstSeen, _ := MempoolFirstSeen(txid)
+	bc.notifyTx(TxEvent{Txid: txid, Tx: tx, FirstSeen: firstSeen})
+	return txid, true, nil
+}
+
+// OnTx 注册交易进入内存池的回调
+// 回调在AcceptTx中同步调用，调用时不持有区块链的锁
+func (bc *Blockchain) OnTx(fn func(TxEvent)) {
+	bc.txHooksMu.Lock()
+	defer bc.txHooksMu.Unlock()
+	bc.txHooks = append(bc.txHooks, fn)
+}
+
+// notifyTx 依次调用所有交易回调
+func (bc *Blockchain) notifyTx(ev TxEvent) {
+	bc.txHooksMu.Lock()
+	hooks := make([]func(TxEvent), len(bc.txHooks))
+	copy(hooks, bc.txHooks)
+	bc.txHooksMu.Unlock()
+	for _, fn := range hooks {
+		fn(ev)
+	}
+}
@@ -0,0 +1,112 @@
+package blockchain
+
+// internal/blockchain/txorder.go
+// 区块内交易的依赖排序：子交易花费同一区块中父交易的输出时，父交易必须先被应用
+// 打包区块时内存池交易先按手续费从高到低排列，再按依赖排序，父交易总在子交易之前，
+// 本节点挖矿时最多打包前maxBlockTxs笔
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTxDependencyCycle 区块内交易的输入引用构成环，无法确定应用顺序
+var ErrTxDependencyCycle = errors.New("block transactions have a dependency cycle")
+
+// orderBlockTxs 按区块内依赖对交易ID拓扑排序，父交易排在花费其输出的子交易之前
+// 没有依赖关系的交易保持区块中的原有顺序；本地没有交易体的交易视为没有依赖
+// 依赖构成环时返回ErrTxDependencyCycle
+func orderBlockTxs(txids []string) ([]string, error) {
+	pos := make(map[string]int, len(txids))
+	for i, txid := range txids {
+		if _, dup := pos[txid]; !dup {
+			pos[txid] = i
+		}
+	}
+
+	// children[i]为花费第i笔交易输出的交易，pending[i]为第i笔交易尚未应用的父交易数量
+	children := make([][]int, len(txids))
+	pending := make([]int, len(txids))
+	for i, txid := range txids {
+		tx, err := GetTransaction(txid)
+		if err != nil || IsCoinbase(tx) {
+			continue
+		}
+		parents := make(map[int]bool)
+		for _, in := range tx.Inputs {
+			if p, ok := pos[in.Txid]; ok && !parents[p] {
+				parents[p] = true
+				children[p] = append(children[p], i)
+				pending[i]++
+			}
+		}
+	}
+
+	// 每次取位置最靠前的就绪交易，使无依赖的交易保持原顺序
+	ordered := make([]string, 0, len(txids))
+	done := make([]bool, len(txids))
+	for len(ordered) < len(txids) {
+		next := -1
+		for i := range txids {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("%w (%d of %d txs ordered)", ErrTxDependencyCycle, len(ordered), len(txids))
+		}
+		done[next] = true
+		ordered = append(ordered, txids[next])
+		for _, c := range children[next] {
+			pending[c]--
+		}
+	}
+	return ordered, nil
+}
+
+// OrderedMempoolTx 按打包顺序排列的一笔内存池交易
+type OrderedMempoolTx struct {
+	Txid string  `json:"txid"`
+	Fee  int     `json:"fee"`          // 手续费，无法计算时为0
+	Tx   *UTXOTx `json:"tx,omitempty"` // 交易体，本地没有时为空
+}
+
+// OrderedMempool 按打包区块时使用的顺序返回内存池交易：手续费从高到低，父交易在花费其输出的子交易之前
+func OrderedMempool() []OrderedMempoolTx {
+	txids, fees := orderedMempoolTxids()
+	out := make([]OrderedMempoolTx, len(txids))
+	for i, txid := range txids {
+		out[i] = OrderedMempoolTx{Txid: txid, Fee: fees[txid]}
+		if tx, err := GetTransaction(txid); err == nil {
+			out[i].Tx = &tx
+		}
+	}
+	return out
+}
+
+// orderedMempoolTxids 返回按打包顺序排列的内存池交易ID及其进入内存池时记录的手续费
+// 手续费相同的交易保持进入内存池的顺序；依赖构成环时（不会出现在有效交易中）只按手续费排列
+func orderedMempoolTxids() ([]string, map[string]int) {
+	entries := mempoolByFee()
+	txids := make([]string, len(entries))
+	fees := make(map[string]int, len(entries))
+	for i, e := range entries {
+		txids[i] = e.txid
+		fees[e.txid] = e.fee
+	}
+	if ordered, err := orderBlockTxs(txids); err == nil {
+		txids = ordered
+	}
+	return txids, fees
+}
+
+// SetMaxBlockTxs 设置本节点挖矿时每个区块最多打包的内存池交易数（不含coinbase），n < 1 时不限制
+func (bc *Blockchain) SetMaxBlockTxs(n int) {
+	if n < 1 {
+		n = 0
+	}
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	bc.maxBlockTxs = n
+}
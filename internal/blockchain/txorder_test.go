@@ -0,0 +1,76 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"mini_chain/internal/wallet"
+)
+
+func TestChainedTxsAppliedInDependencyOrder(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	alice, _ := wallet.NewAccount()
+	bob, _ := wallet.NewAccount()
+	carol, _ := wallet.NewAccount()
+
+	fund := "fund-" + alice.Address[2:18]
+	PutUTXO(fund, 0, UTXOEntry{Address: alice.Address, Amount: 100})
+	parentW, err := wallet.BuildTransaction(alice, bob.Address, 60, 1, []wallet.UTXO{{Txid: fund, Vout: 0, Amount: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentID, _ := PutTransaction(TxFromWallet(parentW))
+	childW, err := wallet.BuildTransaction(bob, carol.Address, 50, 1, []wallet.UTXO{{Txid: parentID, Vout: 0, Amount: 60}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	childID, _ := PutTransaction(TxFromWallet(childW))
+
+	// 子交易排在父交易之前
+	b := MineBlock(bc.GetLatest(), []string{childID, parentID}, 1)
+	if err := bc.ValidateAndApplyBlock(b); err != nil {
+		t.Fatalf("block with reversed parent/child rejected: %v", err)
+	}
+	if _, err := GetUTXO(fund, 0); err == nil {
+		t.Error("funding output should be spent by the parent")
+	}
+	if _, err := GetUTXO(parentID, 0); err == nil {
+		t.Error("parent output should be spent by the child")
+	}
+	if e, err := GetUTXO(childID, 0); err != nil || e.Address != carol.Address || e.Amount != 50 {
+		t.Errorf("child output = %+v, %v; want 50 to carol", e, err)
+	}
+	if e, err := GetUTXO(parentID, 1); err != nil || e.Address != alice.Address {
+		t.Errorf("parent change output = %+v, %v; want change to alice", e, err)
+	}
+}
+
+func TestOrderBlockTxs(t *testing.T) {
+	// 互相花费对方输出的两笔交易构成环（直接写入交易体存储）
+	txStoreLock.Lock()
+	txStore["cycle-a"] = UTXOTx{Version: TxVersion, Inputs: []TxInput{{Txid: "cycle-b", Vout: 0}}, Outputs: []TxOutput{{Address: "x", Amount: 1}}}
+	txStore["cycle-b"] = UTXOTx{Version: TxVersion, Inputs: []TxInput{{Txid: "cycle-a", Vout: 0}}, Outputs: []TxOutput{{Address: "y", Amount: 1}}}
+	txStoreLock.Unlock()
+	t.Cleanup(func() {
+		DeleteTransaction("cycle-a")
+		DeleteTransaction("cycle-b")
+	})
+
+	if _, err := orderBlockTxs([]string{"unrelated", "cycle-a", "cycle-b"}); !errors.Is(err, ErrTxDependencyCycle) {
+		t.Fatalf("expected ErrTxDependencyCycle, got %v", err)
+	}
+	bc := NewBlockchain(1, nil)
+	b := MineBlock(bc.GetLatest(), []string{"cycle-a", "cycle-b"}, 1)
+	if err := bc.ValidateAndApplyBlock(b); !errors.Is(err, ErrTxDependencyCycle) {
+		t.Fatalf("cyclic block: expected ErrTxDependencyCycle, got %v", err)
+	}
+	if bc.Height() != 0 {
+		t.Fatal("cyclic block must not be applied")
+	}
+
+	// 没有依赖的交易保持原顺序
+	got, err := orderBlockTxs([]string{"z", "a", "m"})
+	if err != nil || len(got) != 3 || got[0] != "z" || got[1] != "a" || got[2] != "m" {
+		t.Fatalf("independent txs reordered: %v, %v", got, err)
+	}
+}
@@ -0,0 +1,46 @@
+package blockchain
+
+// internal/blockchain/txsize.go
+// 单笔交易的大小上限
+// 输入输出数量极多的交易可能单独超过区块大小，也可被用来消耗节点资源，
+// 因此在进入内存池前按规范编码（TxSize）的字节数拒绝过大的交易
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxTxSize 单笔交易规范编码的默认最大字节数
+const DefaultMaxTxSize = 100 * 1024
+
+// ErrTxTooLarge 交易规范编码超过大小上限
+var ErrTxTooLarge = errors.New("tx too large")
+
+// SetMaxTxSize 设置单笔交易规范编码的最大字节数，n < 1 时恢复默认值
+func (bc *Blockchain) SetMaxTxSize(n int) {
+	if n < 1 {
+		n = DefaultMaxTxSize
+	}
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	bc.maxTxSize = n
+}
+
+// MaxTxSize 返回单笔交易规范编码的最大字节数
+func (bc *Blockchain) MaxTxSize() int {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	return bc.maxTxSize
+}
+
+// CheckTxSize 交易规范编码超过MaxTxSize时返回ErrTxTooLarge
+func (bc *Blockchain) CheckTxSize(tx UTXOTx) error {
+	size, err := TxSize(tx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadTxStructure, err)
+	}
+	if max := bc.MaxTxSize(); size > max {
+		return fmt.Errorf("%w: %d bytes exceeds limit %d", ErrTxTooLarge, size, max)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"mini_chain/internal/wallet"
+)
+
+func TestMaxTxSize(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	acc, _ := wallet.NewAccount()
+	fund := func(id string) UTXOTx {
+		t.Helper()
+		PutUTXO(id, 0, UTXOEntry{Address: acc.Address, Amount: 1000})
+		t.Cleanup(func() { DeleteUTXO(id, 0) })
+		wtx, err := wallet.BuildTransaction(acc, acc.Address, 10, 1, []wallet.UTXO{{Txid: id, Vout: 0, Amount: 1000}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return TxFromWallet(wtx)
+	}
+
+	// 大量输出使交易超过默认上限，签名无效也先因大小被拒绝
+	huge := fund("size-huge-" + acc.Address[2:10])
+	for size, _ := TxSize(huge); size <= DefaultMaxTxSize; size, _ = TxSize(huge) {
+		for i := 0; i < 100; i++ {
+			huge.Outputs = append(huge.Outputs, TxOutput{Address: "dust", Amount: 0})
+		}
+	}
+	if err := bc.CheckTxSize(huge); !errors.Is(err, ErrTxTooLarge) {
+		t.Fatalf("expected ErrTxTooLarge, got %v", err)
+	}
+	if bc.AddTransaction(huge) {
+		t.Fatal("oversized tx entered the mempool")
+	}
+
+	normal := fund("size-normal-" + acc.Address[2:10])
+	if !bc.AddTransaction(normal) {
+		t.Fatal("normal tx rejected")
+	}
+	txid, _ := TxID(normal)
+	defer EvictFromMempool(txid)
+
+	// 上限可配置，小于1时恢复默认值
+	size, _ := TxSize(normal)
+	bc.SetMaxTxSize(size - 1)
+	if err := bc.CheckTxSize(normal); !errors.Is(err, ErrTxTooLarge) {
+		t.Fatalf("tx above configured limit: expected ErrTxTooLarge, got %v", err)
+	}
+	bc.SetMaxTxSize(0)
+	if bc.MaxTxSize() != DefaultMaxTxSize {
+		t.Fatalf("MaxTxSize = %d after reset, want %d", bc.MaxTxSize(), DefaultMaxTxSize)
+	}
+}
@@ -0,0 +1,46 @@
+package blockchain
+
+// internal/blockchain/txstore.go
+// 交易体存储
+// 区块只保存交易ID，重放UTXO变更时需要通过交易ID取回完整交易
+// 与UTXO集合一样使用受互斥锁保护的内存映射，后续由持久化存储替代
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	txStoreLock sync.RWMutex              // 交易体存储读写锁
+	txStore     = make(map[string]UTXOTx) // 交易ID到交易体的映射
+)
+
+// PutTransaction 保存交易体，返回其交易ID
+func PutTransaction(tx UTXOTx) (string, error) {
+	txid, err := TxID(tx)
+	if err != nil {
+		return "", err
+	}
+	txStoreLock.Lock()
+	defer txStoreLock.Unlock()
+	txStore[txid] = tx
+	return txid, nil
+}
+
+// GetTransaction 按交易ID返回交易体
+func GetTransaction(txid string) (UTXOTx, error) {
+	txStoreLock.RLock()
+	defer txStoreLock.RUnlock()
+	tx, ok := txStore[txid]
+	if !ok {
+		return UTXOTx{}, fmt.Errorf("transaction not found: %s", txid)
+	}
+	return tx, nil
+}
+
+// DeleteTransaction 删除交易体（交易被逐出内存池时调用）
+func DeleteTransaction(txid string) {
+	txStoreLock.Lock()
+	defer txStoreLock.Unlock()
+	delete(txStore, txid)
+}
@@ -0,0 +1,82 @@
+package blockchain
+
+// internal/blockchain/txvalidate.go
+// 交易进入内存池前的完整校验
+// 在结构检查之外，要求每个输出地址格式有效、每个输入引用当前UTXO集合中存在的输出、由该输出的所有者签名，
+// 且手续费（输入总额减输出总额）不低于最低转发手续费。
+// 每类失败返回可用errors.Is区分的错误，API据此返回不同的错误码
+
+import (
+	"errors"
+	"fmt"
+
+	"mini_chain/internal/wallet"
+)
+
+// DefaultMinRelayFee 交易进入内存池所需的默认最低手续费
+const DefaultMinRelayFee = 1
+
+// 交易校验错误
+var (
+	ErrBadTxStructure  = errors.New("bad tx structure")        // 结构检查失败（ValidateTxStructure）
+	ErrMissingInput    = errors.New("input utxo not found")    // 输入引用的输出不存在或已花费
+	ErrBadSignature    = errors.New("invalid input signature") // 输入签名无效或签名者不是输出所有者
+	ErrInsufficientFee = errors.New("insufficient fee")        // 手续费低于最低转发手续费
+
+	ErrTxAlreadyConfirmed = errors.New("tx already confirmed") // 交易已被主链区块打包
+	ErrBadAddress         = errors.New("malformed address")    // 输出地址不是有效的公钥地址
+)
+
+// CheckNotConfirmed 交易已被主链区块打包时返回ErrTxAlreadyConfirmed，防止已确认的交易重新进入内存池并被再次广播
+func (bc *Blockchain) CheckNotConfirmed(tx UTXOTx) error {
+	txid, err := TxID(tx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadTxStructure, err)
+	}
+	if b, ok := bc.FindTxBlock(txid); ok {
+		return fmt.Errorf("%w: %s in block %d", ErrTxAlreadyConfirmed, txid, b.Index)
+	}
+	return nil
+}
+
+// ValidateTxForMempool 完整校验待进入内存池的交易，返回其手续费
+// minFee: 最低手续费
+func ValidateTxForMempool(tx UTXOTx, minFee int) (int, error) {
+	if err := ValidateTxStructure(tx); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrBadTxStructure, err)
+	}
+	if len(tx.Inputs) == 0 || IsCoinbase(tx) {
+		return 0, fmt.Errorf("%w: tx has no spendable inputs", ErrBadTxStructure)
+	}
+	for i, out := range tx.Outputs {
+		if !wallet.IsValidAddress(out.Address) {
+			return 0, fmt.Errorf("%w: output %d: %q", ErrBadAddress, i, out.Address)
+		}
+	}
+
+	digest := TxSigHash(tx)
+	amounts := make([]int, 0, len(tx.Inputs))
+	for _, in := range tx.Inputs {
+		entry, err := GetUTXO(in.Txid, in.Vout)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s:%d", ErrMissingInput, in.Txid, in.Vout)
+		}
+		if entry.Address != in.PubKey {
+			return 0, fmt.Errorf("%w: %s:%d not owned by signer", ErrBadSignature, in.Txid, in.Vout)
+		}
+		if err := wallet.VerifyRaw(in.PubKey, in.Signature, digest); err != nil {
+			return 0, fmt.Errorf("%w: %s:%d: %v", ErrBadSignature, in.Txid, in.Vout, err)
+		}
+		amounts = append(amounts, entry.Amount)
+	}
+
+	in, err := SumAmounts(amounts...)
+	if err != nil {
+		return 0, fmt.Errorf("%w: input total: %v", ErrBadTxStructure, err)
+	}
+	out, _ := SumOutputs(tx) // 结构检查已保证不溢出
+	if fee := in - out; fee < minFee {
+		return 0, fmt.Errorf("%w: fee %d below minimum %d", ErrInsufficientFee, fee, minFee)
+	}
+	return in - out, nil
+}
@@ -7,7 +7,11 @@ package blockchain
 // 后续集成存储时，将由Badger/LevelDB支持
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -26,6 +30,7 @@ type UTXOEntry struct {
 var (
 	utxoLock sync.RWMutex        // UTXO读写锁，保护并发访问
 	utxos    = make(map[UTXOKey]UTXOEntry) // 内存中的UTXO集合
+	utxoTotal int // UTXO集合中的金额总和，随插入和删除更新
 )
 
 // UTXO管理接口
@@ -54,6 +59,7 @@ func PutUTXO(txid string, vout int, entry UTXOEntry) {
 	
 	// 构造UTXO键值并插入
 	k := UTXOKey{Txid: txid, Vout: vout}
+	utxoTotal += entry.Amount - utxos[k].Amount
 	utxos[k] = entry
 }
 
@@ -64,9 +70,28 @@ func DeleteUTXO(txid string, vout int) {
 	
 	// 构造UTXO键值并删除
 	k := UTXOKey{Txid: txid, Vout: vout}
+	utxoTotal -= utxos[k].Amount
 	delete(utxos, k)
 }
 
+// replaceUTXOSet 用给定集合整体替换当前UTXO集合（导入链时使用）
+func replaceUTXOSet(set map[UTXOKey]UTXOEntry) {
+	utxoLock.Lock()
+	defer utxoLock.Unlock()
+	utxos = set
+	utxoTotal = 0
+	for _, e := range set {
+		utxoTotal += e.Amount
+	}
+}
+
+// UTXOTotal 返回UTXO集合中的金额总和，即流通中的货币总量
+func UTXOTotal() int {
+	utxoLock.RLock()
+	defer utxoLock.RUnlock()
+	return utxoTotal
+}
+
 // FindUTXOsForAddress 返回指定地址拥有的所有UTXO
 func FindUTXOsForAddress(address string) []struct {
 	Txid string
@@ -97,43 +122,156 @@ func FindUTXOsForAddress(address string) []struct {
 	return res
 }
 
+// GetBalance 返回地址当前拥有的全部UTXO的金额总和，没有UTXO的地址余额为0
+func GetBalance(address string) int {
+	utxoLock.RLock()
+	defer utxoLock.RUnlock()
+	balance := 0
+	for _, v := range utxos {
+		if v.Address == address {
+			balance += v.Amount
+		}
+	}
+	return balance
+}
+
+// SpendableUTXO 地址可花费的一个输出，钱包据此选择交易输入
+type SpendableUTXO struct {
+	Txid   string `json:"txid"`   // 交易ID
+	Vout   int    `json:"vout"`   // 输出索引
+	Amount int    `json:"amount"` // 金额
+}
+
+// SpendableUTXOs 返回地址拥有的、金额不小于min的UTXO，按txid和vout排序
+// min <= 0 时返回全部
+func SpendableUTXOs(address string, min int) []SpendableUTXO {
+	res := []SpendableUTXO{}
+	for _, u := range FindUTXOsForAddress(address) {
+		if u.Amount < min {
+			continue // 过滤粉尘输出
+		}
+		res = append(res, SpendableUTXO{Txid: u.Txid, Vout: u.Vout, Amount: u.Amount})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Txid != res[j].Txid {
+			return res[i].Txid < res[j].Txid
+		}
+		return res[i].Vout < res[j].Vout
+	})
+	return res
+}
+
 // applyTxsInBlock 应用区块中所有交易的UTXO变更
 // 对于每笔交易：
-// 1. 删除被消费的UTXO（来自输入）
+// 1. 删除被消费的UTXO（来自输入，coinbase交易没有真实输入）
 // 2. 添加新的UTXO（来自输出）
+// 本地没有交易体的交易无法推导UTXO变更，跳过（接入主链的区块已由checkBlockTxs确认交易体存在）
+// 交易按区块内依赖排序后应用，父交易的输出总是先于花费它的子交易加入集合；依赖成环时不做任何变更并返回错误
 func applyTxsInBlock(txids []string) error {
-	// TODO: 这是一个简化的实现
-	// 在实际实现中，我们会：
-	// 1. 对于每笔交易：
-	//    a. 从集合中删除所有输入UTXO
-	//    b. 将所有输出UTXO添加到集合中
-	// 
-	// 目前，我们只返回nil表示成功
-	
-	// 实际实现应该如下所示：
-	/*
-	for _, txid := range txids {
+	return applyTxs(txids, DeleteUTXO, PutUTXO)
+}
+
+// applyTxs 按区块内依赖顺序把交易的UTXO变更交给del/put，全局集合和历史重放共用
+func applyTxs(txids []string, del func(txid string, vout int), put func(txid string, vout int, e UTXOEntry)) error {
+	ordered, err := orderBlockTxs(txids)
+	if err != nil {
+		return err
+	}
+	for _, txid := range ordered {
 		// 获取交易详情
-		tx, err := getTransactionById(txid)
+		tx, err := GetTransaction(txid)
 		if err != nil {
-			return fmt.Errorf("failed to get transaction %s: %v", txid, err)
+			continue
 		}
-		
+
 		// 删除被消费的UTXO（来自输入）
-		for _, input := range tx.Inputs {
-			DeleteUTXO(input.Txid, input.Vout)
+		if !IsCoinbase(tx) {
+			for _, input := range tx.Inputs {
+				del(input.Txid, input.Vout)
+			}
 		}
-		
+
 		// 添加新的UTXO（来自输出）
 		for i, output := range tx.Outputs {
 			entry := UTXOEntry{
 				Address: output.Address,
 				Amount:  output.Amount,
 			}
-			PutUTXO(txid, i, entry)
+			put(txid, i, entry)
 		}
 	}
-	*/
-	
 	return nil
-}
\ No newline at end of file
+}
+
+// SnapshotUTXOs 返回当前UTXO集合的副本
+func SnapshotUTXOs() map[UTXOKey]UTXOEntry {
+	utxoLock.RLock()
+	defer utxoLock.RUnlock()
+	cp := make(map[UTXOKey]UTXOEntry, len(utxos))
+	for k, v := range utxos {
+		cp[k] = v
+	}
+	return cp
+}
+
+// UTXORoot 返回当前UTXO集合的承诺哈希
+// 两个节点在相同高度上的UTXORoot不同，说明双方的UTXO状态已经分叉
+func UTXORoot() string {
+	return UTXOSetRoot(SnapshotUTXOs())
+}
+
+// UTXOSetRoot 计算UTXO集合的确定性承诺哈希
+// 条目按txid、vout排序后依次编码（字符串带长度前缀，整数为8字节大端），再整体做SHA256
+// 与map遍历顺序无关；任一条目的键、地址或金额变化都会改变结果
+func UTXOSetRoot(set map[UTXOKey]UTXOEntry) string {
+	keys := make([]UTXOKey, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Txid != keys[j].Txid {
+			return keys[i].Txid < keys[j].Txid
+		}
+		return keys[i].Vout < keys[j].Vout
+	})
+
+	h := sha256.New()
+	var num [8]byte
+	writeInt := func(n int) {
+		binary.BigEndian.PutUint64(num[:], uint64(n))
+		h.Write(num[:])
+	}
+	writeString := func(s string) {
+		writeInt(len(s))
+		h.Write([]byte(s))
+	}
+	writeInt(len(keys))
+	for _, k := range keys {
+		e := set[k]
+		writeString(k.Txid)
+		writeInt(k.Vout)
+		writeString(e.Address)
+		writeInt(e.Amount)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RebuildUTXOSet 清空UTXO集合并从创世区块开始重放主链上的每个区块重新推导
+// 用于UTXO集合不一致时的恢复，返回重建后的UTXO数量
+func (bc *Blockchain) RebuildUTXOSet() (int, error) {
+	bc.applyLock.Lock()
+	defer bc.applyLock.Unlock()
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+
+	replaceUTXOSet(make(map[UTXOKey]UTXOEntry))
+	for _, b := range bc.chain {
+		if err := applyTxsInBlock(b.Transactions); err != nil {
+			return 0, fmt.Errorf("replay block %d: %v", b.Index, err)
+		}
+	}
+
+	utxoLock.RLock()
+	defer utxoLock.RUnlock()
+	return len(utxos), nil
+}
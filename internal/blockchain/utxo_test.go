@@ -0,0 +1,84 @@
+package blockchain
+
+import "testing"
+
+func TestUTXOSetRoot(t *testing.T) {
+	set := map[UTXOKey]UTXOEntry{
+		{Txid: "aa", Vout: 0}: {Address: "alice", Amount: 50},
+		{Txid: "aa", Vout: 1}: {Address: "bob", Amount: 25},
+		{Txid: "bb", Vout: 0}: {Address: "carol", Amount: 10},
+	}
+	root := UTXOSetRoot(set)
+
+	// 同一集合（与插入顺序无关）得到相同的承诺
+	same := map[UTXOKey]UTXOEntry{}
+	for _, k := range []UTXOKey{{"bb", 0}, {"aa", 1}, {"aa", 0}} {
+		same[k] = set[k]
+	}
+	for i := 0; i < 10; i++ {
+		if got := UTXOSetRoot(same); got != root {
+			t.Fatalf("root not stable: %s != %s", got, root)
+		}
+	}
+
+	// 任一条目变化都会改变承诺
+	changes := map[string]func(m map[UTXOKey]UTXOEntry){
+		"amount":  func(m map[UTXOKey]UTXOEntry) { m[UTXOKey{"aa", 1}] = UTXOEntry{Address: "bob", Amount: 26} },
+		"address": func(m map[UTXOKey]UTXOEntry) { m[UTXOKey{"aa", 1}] = UTXOEntry{Address: "bobby", Amount: 25} },
+		"removed": func(m map[UTXOKey]UTXOEntry) { delete(m, UTXOKey{"bb", 0}) },
+		"added":   func(m map[UTXOKey]UTXOEntry) { m[UTXOKey{"cc", 0}] = UTXOEntry{Address: "dave", Amount: 1} },
+		"vout":    func(m map[UTXOKey]UTXOEntry) { m[UTXOKey{"bb", 1}] = m[UTXOKey{"bb", 0}]; delete(m, UTXOKey{"bb", 0}) },
+	}
+	for name, change := range changes {
+		m := map[UTXOKey]UTXOEntry{}
+		for k, v := range set {
+			m[k] = v
+		}
+		change(m)
+		if UTXOSetRoot(m) == root {
+			t.Errorf("%s change did not alter the root", name)
+		}
+	}
+
+	// 字段边界不会混淆："a"+"b" 与 "ab"+"" 不同
+	x := UTXOSetRoot(map[UTXOKey]UTXOEntry{{Txid: "a", Vout: 0}: {Address: "b", Amount: 1}})
+	y := UTXOSetRoot(map[UTXOKey]UTXOEntry{{Txid: "ab", Vout: 0}: {Address: "", Amount: 1}})
+	if x == y {
+		t.Error("length prefixes must separate adjacent fields")
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	seed := []struct {
+		txid    string
+		vout    int
+		address string
+		amount  int
+	}{
+		{"balance-a", 0, "balance-alice", 30},
+		{"balance-a", 1, "balance-bob", 5},
+		{"balance-b", 0, "balance-alice", 12},
+		{"balance-c", 2, "balance-bob", 7},
+		{"balance-c", 3, "balance-alice", 1},
+	}
+	for _, u := range seed {
+		PutUTXO(u.txid, u.vout, UTXOEntry{Address: u.address, Amount: u.amount})
+	}
+	t.Cleanup(func() {
+		for _, u := range seed {
+			DeleteUTXO(u.txid, u.vout)
+		}
+	})
+
+	for address, want := range map[string]int{"balance-alice": 43, "balance-bob": 12, "balance-nobody": 0} {
+		if got := GetBalance(address); got != want {
+			t.Errorf("GetBalance(%s) = %d, want %d", address, got, want)
+		}
+	}
+
+	// 花费后的输出不再计入余额
+	DeleteUTXO("balance-a", 0)
+	if got := GetBalance("balance-alice"); got != 13 {
+		t.Errorf("after spend GetBalance = %d, want 13", got)
+	}
+}
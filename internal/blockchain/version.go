@@ -0,0 +1,96 @@
+package blockchain
+
+// internal/blockchain/version.go
+// 区块和交易的版本号
+// 版本号参与哈希计算，协议升级（新增字段或规则）通过新版本号启用，
+// 校验时拒绝未知版本，并按版本应用对应的规则
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 当前版本
+const (
+	BlockVersion = 1 // 新挖出区块使用的版本
+	TxVersion    = 1 // 新建交易使用的版本
+)
+
+// blockVersionRules 已知区块版本及其附加规则
+var blockVersionRules = map[int]func(b *Block) error{
+	1: blockRulesV1,
+}
+
+// txVersionRules 已知交易版本及其附加规则
+var txVersionRules = map[int]func(tx UTXOTx) error{
+	1: txRulesV1,
+}
+
+// CheckBlockVersion 检查区块版本是否已知，并应用该版本的规则
+func CheckBlockVersion(b *Block) error {
+	rules, ok := blockVersionRules[b.Version]
+	if !ok {
+		return fmt.Errorf("unknown block version %d", b.Version)
+	}
+	return rules(b)
+}
+
+// checkTxVersion 检查交易版本是否已知，并应用该版本的规则
+func checkTxVersion(tx UTXOTx) error {
+	rules, ok := txVersionRules[tx.Version]
+	if !ok {
+		return fmt.Errorf("unknown tx version %d", tx.Version)
+	}
+	return rules(tx)
+}
+
+// blockRulesV1 版本1区块规则：非创世区块的难度至少为1
+func blockRulesV1(b *Block) error {
+	if b.Index > 0 && b.Difficulty < 1 {
+		return fmt.Errorf("block difficulty %d below minimum", b.Difficulty)
+	}
+	return nil
+}
+
+// 交易结构错误
+var (
+	ErrDuplicateInput = errors.New("tx spends the same outpoint twice") // 同一交易的多个输入引用了同一个输出
+	ErrNegativeAmount = errors.New("negative amount")                   // 输出金额为负数
+)
+
+// txRulesV1 版本1交易规则：至少有一个输入或输出，输入引用的输出互不相同，
+// 输出金额非负且总额不溢出，coinbase附加数据不超长
+func txRulesV1(tx UTXOTx) error {
+	// 检查交易是否既没有输入也没有输出
+	if len(tx.Inputs) == 0 && len(tx.Outputs) == 0 {
+		return errors.New("tx has no inputs and no outputs")
+	}
+
+	// 检查输入是否重复引用同一个输出（txid:vout）
+	seen := make(map[UTXOKey]bool, len(tx.Inputs))
+	for _, in := range tx.Inputs {
+		k := UTXOKey{Txid: in.Txid, Vout: in.Vout}
+		if seen[k] {
+			return fmt.Errorf("%w: %s:%d", ErrDuplicateInput, in.Txid, in.Vout)
+		}
+		seen[k] = true
+	}
+
+	// 检查coinbase附加数据长度
+	if err := checkCoinbaseDataLen(CoinbaseData(tx)); err != nil {
+		return err
+	}
+
+	// 检查输出金额是否为负数
+	for _, out := range tx.Outputs {
+		if out.Amount < 0 {
+			return ErrNegativeAmount
+		}
+	}
+
+	// 检查输出总额是否溢出
+	if _, err := SumOutputs(tx); err != nil {
+		return fmt.Errorf("output total: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// mineWithVersion 以指定版本挖出链接到prev的区块
+func mineWithVersion(t *testing.T, prev Block, version int) Block {
+	b := MineBlock(prev, testTxs(t, "version-tx", 1), 1)
+	b.Version = version
+	nonce, hash := NewProofOfWork(&b, 1).Run()
+	b.Nonce = nonce
+	b.Hash = hex.EncodeToString(hash)
+	return b
+}
+
+func TestBlockVersion(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+
+	unknown := mineWithVersion(t, bc.GetLatest(), 99)
+	err := bc.ValidateAndApplyBlock(unknown)
+	rerr, ok := err.(*BlockRejectError)
+	if !ok || rerr.Reason != RejectBadVersion {
+		t.Fatalf("期望未知版本被拒绝，实际 %v", err)
+	}
+
+	known := mineWithVersion(t, bc.GetLatest(), BlockVersion)
+	if err := bc.ValidateAndApplyBlock(known); err != nil {
+		t.Fatalf("已知版本的区块应被接受: %v", err)
+	}
+}
+
+func TestBlockVersionInHash(t *testing.T) {
+	b := MineBlock(NewGenesis(), []string{"tx"}, 1)
+	b.Version = BlockVersion + 1
+	if b.ValidateBasic() {
+		t.Error("修改版本后哈希应不再匹配")
+	}
+}
+
+func TestTxVersion(t *testing.T) {
+	tx := UTXOTx{Version: TxVersion, Outputs: []TxOutput{{Address: "a", Amount: 1}}}
+	if err := ValidateTxStructure(tx); err != nil {
+		t.Fatalf("已知版本的交易应被接受: %v", err)
+	}
+	id1, _ := TxID(tx)
+
+	tx.Version = 99
+	if err := ValidateTxStructure(tx); err == nil {
+		t.Error("未知版本的交易应被拒绝")
+	}
+	if id2, _ := TxID(tx); id1 == id2 {
+		t.Error("版本应参与交易ID计算")
+	}
+}
@@ -0,0 +1,93 @@
+package blockchain
+
+// internal/blockchain/watchdog.go
+// 挖矿停滞检测
+// 挖矿已启用且内存池非空时，如果超过Timeout仍没有新区块（自己挖出或从网络收到），
+// 认为挖矿已停滞：记录警告并将节点标记为不健康（反映在 /readyz）。链头再次前进后恢复健康
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// 停滞检测的默认参数
+const (
+	DefaultStallTimeout     = 10 * time.Minute // 没有新区块的最长时间
+	DefaultWatchdogInterval = 30 * time.Second // 检查间隔
+)
+
+// MiningWatchdog 挖矿停滞检测器
+type MiningWatchdog struct {
+	Timeout  time.Duration // 没有新区块的最长时间
+	Interval time.Duration // Run的检查间隔
+	// MiningEnabled 返回本节点当前是否在挖矿，为nil时视为始终挖矿
+	MiningEnabled func() bool
+
+	bc *Blockchain
+
+	mu           sync.Mutex
+	lastHash     string    // 上次检查时的链头哈希
+	lastProgress time.Time // 链头最近一次前进（或检测开始）的时间
+	stalled      bool
+}
+
+// NewMiningWatchdog 使用默认参数创建停滞检测器
+func NewMiningWatchdog(bc *Blockchain) *MiningWatchdog {
+	return &MiningWatchdog{
+		Timeout:  DefaultStallTimeout,
+		Interval: DefaultWatchdogInterval,
+		bc:       bc,
+	}
+}
+
+// Check 在now时刻检查一次，返回节点是否健康
+// 链头变化时重置计时；未挖矿或内存池为空时不算停滞
+func (w *MiningWatchdog) Check(now time.Time) bool {
+	hash := w.bc.GetLatest().Hash
+	mining := w.MiningEnabled == nil || w.MiningEnabled()
+	pending := len(ListMempool())
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if hash != w.lastHash || w.lastProgress.IsZero() {
+		w.lastHash = hash
+		w.lastProgress = now
+	}
+	idle := now.Sub(w.lastProgress)
+	stalled := mining && pending > 0 && idle > w.Timeout
+	if stalled && !w.stalled {
+		log.Printf("WARNING: mining stalled: no block for %s with %d pending txs", idle.Round(time.Second), pending)
+	} else if !stalled && w.stalled {
+		log.Printf("mining resumed at block %.16s", hash)
+	}
+	w.stalled = stalled
+	return !stalled
+}
+
+// Healthy 返回最近一次检查的结果
+func (w *MiningWatchdog) Healthy() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.stalled
+}
+
+// Run 每隔Interval检查一次，直到ctx取消
+func (w *MiningWatchdog) Run(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchdogInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	w.Check(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.Check(now)
+		}
+	}
+}
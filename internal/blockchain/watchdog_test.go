@@ -0,0 +1,51 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMiningWatchdogStall(t *testing.T) {
+	bc := NewBlockchain(1, nil)
+	w := NewMiningWatchdog(bc)
+	w.Timeout = time.Minute
+	mining := true
+	w.MiningEnabled = func() bool { return mining }
+
+	AddToMempool("watchdog-tx")
+	defer RemoveFromMempool([]string{"watchdog-tx"})
+
+	t0 := time.Now()
+	if !w.Check(t0) || !w.Healthy() {
+		t.Fatal("watchdog unhealthy right after start")
+	}
+	if !w.Check(t0.Add(30 * time.Second)) {
+		t.Fatal("watchdog unhealthy before the timeout")
+	}
+
+	// 超时仍没有新区块：停滞
+	if w.Check(t0.Add(2*time.Minute)) || w.Healthy() {
+		t.Fatal("expected stall to mark the node unhealthy")
+	}
+
+	// 未挖矿时不算停滞
+	mining = false
+	if !w.Check(t0.Add(3 * time.Minute)) {
+		t.Fatal("watchdog should be healthy when mining is disabled")
+	}
+	mining = true
+	if w.Check(t0.Add(4 * time.Minute)) {
+		t.Fatal("expected stall again once mining is re-enabled")
+	}
+
+	// 链头前进后恢复健康
+	if err := bc.ValidateAndApplyBlock(MineBlock(bc.GetLatest(), testTxs(t, "watchdog-block", 1), 1)); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Check(t0.Add(5*time.Minute)) || !w.Healthy() {
+		t.Fatal("watchdog still unhealthy after a new block")
+	}
+	if w.Check(t0.Add(7 * time.Minute)) {
+		t.Fatal("expected stall after another timeout without blocks")
+	}
+}
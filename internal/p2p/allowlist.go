@@ -0,0 +1,80 @@
+package p2p
+
+// internal/p2p/allowlist.go
+// 私有网络模式：配置节点ID白名单后，节点只与名单中的peer建立连接，
+// 名单外peer的入站连接在安全握手得知对端ID后被拒绝，mDNS发现的名单外peer不会被拨号
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/connmgr"
+	"github.com/libp2p/go-libp2p/core/control"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// ParseAllowlist 解析逗号分隔的节点ID列表（如 "12D3KooW...,12D3KooW..."），空字符串返回nil
+func ParseAllowlist(s string) ([]peer.ID, error) {
+	var out []peer.ID
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := peer.Decode(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist peer ID %q: %v", part, err)
+		}
+		out = append(out, id)
+	}
+	return out, nil
+}
+
+// AllowlistGater 只放行白名单中peer的ConnectionGater
+type AllowlistGater struct {
+	allowed map[peer.ID]struct{}
+}
+
+var _ connmgr.ConnectionGater = (*AllowlistGater)(nil)
+
+// NewAllowlistGater 创建只放行ids中peer的连接过滤器
+func NewAllowlistGater(ids []peer.ID) *AllowlistGater {
+	g := &AllowlistGater{allowed: make(map[peer.ID]struct{}, len(ids))}
+	for _, id := range ids {
+		g.allowed[id] = struct{}{}
+	}
+	return g
+}
+
+// Allowed 判断peer是否在白名单中
+func (g *AllowlistGater) Allowed(p peer.ID) bool {
+	_, ok := g.allowed[p]
+	return ok
+}
+
+// InterceptPeerDial 只拨号白名单中的peer
+func (g *AllowlistGater) InterceptPeerDial(p peer.ID) bool {
+	return g.Allowed(p)
+}
+
+// InterceptAddrDial 只拨号白名单中peer的地址
+func (g *AllowlistGater) InterceptAddrDial(p peer.ID, _ ma.Multiaddr) bool {
+	return g.Allowed(p)
+}
+
+// InterceptAccept 入站连接此时还不知道对端ID，留到InterceptSecured检查
+func (g *AllowlistGater) InterceptAccept(network.ConnMultiaddrs) bool {
+	return true
+}
+
+// InterceptSecured 安全握手完成后按对端ID过滤入站和出站连接
+func (g *AllowlistGater) InterceptSecured(_ network.Direction, p peer.ID, _ network.ConnMultiaddrs) bool {
+	return g.Allowed(p)
+}
+
+// InterceptUpgraded 连接在InterceptSecured中已经过滤
+func (g *AllowlistGater) InterceptUpgraded(network.Conn) (bool, control.DisconnectReason) {
+	return true, 0
+}
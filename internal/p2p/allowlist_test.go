@@ -0,0 +1,78 @@
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestParseAllowlist(t *testing.T) {
+	h, err := libp2p.New(libp2p.NoListenAddrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ids, err := ParseAllowlist(" " + h.ID().String() + ", ")
+	if err != nil || len(ids) != 1 || ids[0] != h.ID() {
+		t.Fatalf("ParseAllowlist = %v, %v", ids, err)
+	}
+	if _, err := ParseAllowlist("not-a-peer-id"); err == nil {
+		t.Fatal("expected error for invalid peer ID")
+	}
+}
+
+func TestAllowlistRejectsUnknownPeer(t *testing.T) {
+	allowed, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer allowed.Close()
+	stranger, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stranger.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n, err := NewNodeWithConfig(ctx, NodeConfig{Allowlist: []peer.ID{allowed.ID()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	var local ma.Multiaddr
+	for _, a := range n.Host.Network().ListenAddresses() {
+		if port, err := a.ValueForProtocol(ma.P_TCP); err == nil {
+			local = ma.StringCast("/ip4/127.0.0.1/tcp/" + port)
+		}
+	}
+	if local == nil {
+		t.Fatalf("no TCP listen address in %v", n.Host.Network().ListenAddresses())
+	}
+	target := peer.AddrInfo{ID: n.Host.ID(), Addrs: []ma.Multiaddr{local}}
+
+	dctx, dcancel := context.WithTimeout(ctx, 10*time.Second)
+	defer dcancel()
+	if err := stranger.Connect(dctx, target); err == nil {
+		// 拨号方可能在对端拒绝前就完成了握手，以节点一侧的连接为准
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(n.Host.Network().ConnsToPeer(stranger.ID())) != 0 {
+		t.Fatal("node accepted a connection from a peer not on the allowlist")
+	}
+	if err := allowed.Connect(dctx, target); err != nil {
+		t.Fatalf("allowlisted peer could not connect: %v", err)
+	}
+	if len(n.Host.Network().ConnsToPeer(allowed.ID())) == 0 {
+		t.Fatal("node has no connection to the allowlisted peer")
+	}
+
+	// 节点也不会主动拨号名单外的peer
+	if err := n.Host.Connect(dctx, peer.AddrInfo{ID: stranger.ID(), Addrs: stranger.Addrs()}); err == nil {
+		t.Fatal("node dialed a peer not on the allowlist")
+	}
+}
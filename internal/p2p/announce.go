@@ -0,0 +1,41 @@
+package p2p
+
+// internal/p2p/announce.go
+// 对外通告地址：NAT后的节点监听0.0.0.0或内网地址，对端无法直接拨号，
+// 配置通告地址后节点只向外宣布这些可达的公网地址
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/libp2p/go-libp2p"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// ParseAnnounceAddrs 解析逗号分隔的通告multiaddr列表（如 "/ip4/203.0.113.7/tcp/4001"），空字符串返回nil
+// 地址不能带 /p2p/<节点ID> 后缀，节点ID由连接方自行附加
+func ParseAnnounceAddrs(s string) ([]ma.Multiaddr, error) {
+	var out []ma.Multiaddr
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		a, err := ma.NewMultiaddr(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid announce address %q: %v", part, err)
+		}
+		if _, err := a.ValueForProtocol(ma.P_P2P); err == nil {
+			return nil, fmt.Errorf("announce address %q must not include /p2p/", part)
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// announceOption 返回让主机只通告给定地址的libp2p选项
+func announceOption(announce []ma.Multiaddr) libp2p.Option {
+	return libp2p.AddrsFactory(func([]ma.Multiaddr) []ma.Multiaddr {
+		return announce
+	})
+}
@@ -0,0 +1,72 @@
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestAnnounceAddrs(t *testing.T) {
+	announce, err := ParseAnnounceAddrs(" /ip4/203.0.113.7/tcp/4001 ")
+	if err != nil || len(announce) != 1 {
+		t.Fatalf("ParseAnnounceAddrs = %v, %v", announce, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n, err := NewNodeWithConfig(ctx, NodeConfig{AnnounceAddrs: announce})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	addrs := n.Host.Addrs()
+	if len(addrs) != 1 || !addrs[0].Equal(announce[0]) {
+		t.Fatalf("host addrs = %v, want only %v", addrs, announce[0])
+	}
+
+	// 对端通过identify得知的是通告地址；拨号仍使用实际监听的回环地址
+	dialer, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dialer.Close()
+	var local ma.Multiaddr
+	for _, a := range n.Host.Network().ListenAddresses() {
+		if port, err := a.ValueForProtocol(ma.P_TCP); err == nil {
+			local = ma.StringCast("/ip4/127.0.0.1/tcp/" + port)
+		}
+	}
+	if local == nil {
+		t.Fatalf("no TCP listen address in %v", n.Host.Network().ListenAddresses())
+	}
+	dctx, dcancel := context.WithTimeout(ctx, 10*time.Second)
+	defer dcancel()
+	if err := dialer.Connect(dctx, peer.AddrInfo{ID: n.Host.ID(), Addrs: []ma.Multiaddr{local}}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, a := range dialer.Peerstore().Addrs(n.Host.ID()) {
+			if a.Equal(announce[0]) {
+				return
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("peer sees addrs %v, want %v among them", dialer.Peerstore().Addrs(n.Host.ID()), announce[0])
+}
+
+func TestParseAnnounceAddrsInvalid(t *testing.T) {
+	if addrs, err := ParseAnnounceAddrs(""); err != nil || addrs != nil {
+		t.Fatalf("empty input = %v, %v; want nil", addrs, err)
+	}
+	for _, s := range []string{"203.0.113.7:4001", "/ip4/203.0.113.7/tcp/4001/p2p/12D3KooWDpJ7As7BWAwRMfu1VU2WCqNjvq387JEYKDBj4kx6nXTN"} {
+		if _, err := ParseAnnounceAddrs(s); err == nil {
+			t.Errorf("ParseAnnounceAddrs(%q) accepted an invalid address", s)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package p2p
+
+// internal/p2p/bandwidth.go
+// 带宽计量
+// 通过libp2p的带宽报告器统计所有流上收发的字节数，按协议分类。
+// gossip主题的流量计入gossipsub协议（/meshsub/...），同步流量计入同步协议。
+// 累计值由计量器周期性（约每秒）汇总，刚发生的流量可能稍后才反映出来
+
+// ProtocolBandwidth 单个协议的累计流量
+type ProtocolBandwidth struct {
+	BytesIn  int64 `json:"bytes_in"`  // 接收字节数
+	BytesOut int64 `json:"bytes_out"` // 发送字节数
+}
+
+// BandwidthStats 节点的流量统计
+type BandwidthStats struct {
+	BytesIn   int64                        `json:"bytes_in"`  // 累计接收字节数
+	BytesOut  int64                        `json:"bytes_out"` // 累计发送字节数
+	RateIn    float64                      `json:"rate_in"`   // 当前接收速率（字节/秒）
+	RateOut   float64                      `json:"rate_out"`  // 当前发送速率（字节/秒）
+	Protocols map[string]ProtocolBandwidth `json:"protocols"` // 按协议分类的累计流量
+}
+
+// Bandwidth 返回节点的流量统计；节点未启用带宽计量时返回零值
+func (n *Node) Bandwidth() BandwidthStats {
+	stats := BandwidthStats{Protocols: map[string]ProtocolBandwidth{}}
+	if n.bw == nil {
+		return stats
+	}
+	totals := n.bw.GetBandwidthTotals()
+	stats.BytesIn, stats.BytesOut = totals.TotalIn, totals.TotalOut
+	stats.RateIn, stats.RateOut = totals.RateIn, totals.RateOut
+	for proto, s := range n.bw.GetBandwidthByProtocol() {
+		stats.Protocols[string(proto)] = ProtocolBandwidth{BytesIn: s.TotalIn, BytesOut: s.TotalOut}
+	}
+	return stats
+}
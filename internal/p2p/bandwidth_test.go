@@ -0,0 +1,50 @@
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+)
+
+func TestBandwidthCounters(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	a, err := NewNode(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Host.Close()
+	b, err := NewNode(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Host.Close()
+
+	before := a.Bandwidth()
+	if err := a.Host.Connect(ctx, peer.AddrInfo{ID: b.Host.ID(), Addrs: b.Host.Addrs()}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := a.ping(ctx, b.Host.ID()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 计量器约每秒汇总一次累计值
+	for {
+		st := a.Bandwidth()
+		sent := st.Protocols[string(ping.ID)]
+		recv := b.Bandwidth().Protocols[string(ping.ID)]
+		if st.BytesOut > before.BytesOut && st.BytesIn > before.BytesIn && sent.BytesOut > 0 && recv.BytesIn > 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("byte counters did not increase: %+v", st)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
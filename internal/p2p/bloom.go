@@ -0,0 +1,90 @@
+package p2p
+
+// internal/p2p/bloom.go
+// 交易公告使用的布隆过滤器：节点把内存池交易ID加入过滤器发给peer，
+// peer公告交易时跳过过滤器命中的交易；误判只会让对方少收到一次公告，不会漏掉过滤器外的交易
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// DefaultBloomFPRate 未指定时布隆过滤器的目标误判率
+const DefaultBloomFPRate = 0.01
+
+// 接受peer发来的过滤器的上限，避免过大的过滤器占用内存或拖慢查询
+const (
+	MaxBloomFilterBytes = 1 << 20
+	MaxBloomHashes      = 32
+)
+
+// BloomFilter 布隆过滤器：Test返回false时元素一定没有加入过，返回true时可能误判
+type BloomFilter struct {
+	Bits   []byte `json:"bits"`   // 位数组，位数为len(Bits)*8
+	Hashes int    `json:"hashes"` // 每个元素设置的位数
+}
+
+// NewBloomFilter 创建容纳n个元素、误判率约为fpRate的过滤器
+// fpRate不在(0,1)内时使用DefaultBloomFPRate
+func NewBloomFilter(n int, fpRate float64) *BloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	if !(fpRate > 0 && fpRate < 1) {
+		fpRate = DefaultBloomFPRate
+	}
+	// 最优位数 m = -n·ln(p)/(ln2)²，最优哈希数 k = (m/n)·ln2
+	bits := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	size := int(math.Min(math.Ceil(bits/8), MaxBloomFilterBytes))
+	if size < 1 {
+		size = 1
+	}
+	k := int(math.Round(float64(size*8) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	if k > MaxBloomHashes {
+		k = MaxBloomHashes
+	}
+	return &BloomFilter{Bits: make([]byte, size), Hashes: k}
+}
+
+// Add 把元素加入过滤器
+func (f *BloomFilter) Add(item string) {
+	f.each(item, func(bit uint64) { f.Bits[bit/8] |= 1 << (bit % 8) })
+}
+
+// Test 判断元素是否可能已加入过滤器
+func (f *BloomFilter) Test(item string) bool {
+	found := true
+	f.each(item, func(bit uint64) {
+		if f.Bits[bit/8]&(1<<(bit%8)) == 0 {
+			found = false
+		}
+	})
+	return found
+}
+
+// each 对元素的每个哈希位置调用fn；用SHA256的两段做双重哈希 h1 + i·h2
+func (f *BloomFilter) each(item string, fn func(bit uint64)) {
+	sum := sha256.Sum256([]byte(item))
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+	m := uint64(len(f.Bits)) * 8
+	for i := 0; i < f.Hashes; i++ {
+		fn((h1 + uint64(i)*h2) % m)
+	}
+}
+
+// validate 检查peer发来的过滤器大小和哈希数在允许范围内
+func (f *BloomFilter) validate() error {
+	if len(f.Bits) == 0 || len(f.Bits) > MaxBloomFilterBytes {
+		return fmt.Errorf("bloom filter size %d bytes out of range (1-%d)", len(f.Bits), MaxBloomFilterBytes)
+	}
+	if f.Hashes < 1 || f.Hashes > MaxBloomHashes {
+		return fmt.Errorf("bloom filter hash count %d out of range (1-%d)", f.Hashes, MaxBloomHashes)
+	}
+	return nil
+}
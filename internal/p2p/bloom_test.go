@@ -0,0 +1,37 @@
+package p2p
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilter(t *testing.T) {
+	const n = 1000
+	f := NewBloomFilter(n, 0.01)
+	for i := 0; i < n; i++ {
+		f.Add(fmt.Sprintf("tx-%d", i))
+	}
+	for i := 0; i < n; i++ {
+		if !f.Test(fmt.Sprintf("tx-%d", i)) {
+			t.Fatalf("false negative for tx-%d", i)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.Test(fmt.Sprintf("other-%d", i)) {
+			falsePositives++
+		}
+	}
+	// 目标误判率1%，允许统计波动
+	if falsePositives > 300 {
+		t.Fatalf("%d false positives in 10000 tests, want about 100", falsePositives)
+	}
+
+	// 误判率越低过滤器越大
+	if small, large := NewBloomFilter(n, 0.1), NewBloomFilter(n, 0.0001); len(small.Bits) >= len(large.Bits) {
+		t.Fatalf("filter for 0.1%% is %d bytes, for 0.0001 is %d bytes", len(small.Bits), len(large.Bits))
+	}
+	if err := (&BloomFilter{Bits: make([]byte, MaxBloomFilterBytes+1), Hashes: 3}).validate(); err == nil {
+		t.Fatal("expected error for oversized filter")
+	}
+}
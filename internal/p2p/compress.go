@@ -0,0 +1,74 @@
+package p2p
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EncodingGzip 消息数据使用gzip压缩
+const EncodingGzip = "gzip"
+
+// CompressMinSize 数据不少于该字节数时才压缩，小消息保持未压缩
+const CompressMinSize = 1024
+
+// MaxDecompressedSize 解压后数据的上限，防止压缩炸弹
+const MaxDecompressedSize = 64 << 20
+
+// ErrDecompressedTooLarge 解压后的数据超过MaxDecompressedSize
+var ErrDecompressedTooLarge = errors.New("decompressed message exceeds size limit")
+
+// Compress 数据不少于CompressMinSize时用gzip压缩Data，压缩后的字节以base64字符串存放，Encoding置为gzip
+func (m *Message) Compress() error {
+	if m.Encoding != "" || len(m.Data) < CompressMinSize {
+		return nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(m.Data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	m.Data = data
+	m.Encoding = EncodingGzip
+	return nil
+}
+
+// Decompress 还原压缩过的Data，未压缩的消息保持不变
+func (m *Message) Decompress() error {
+	switch m.Encoding {
+	case "":
+		return nil
+	case EncodingGzip:
+	default:
+		return fmt.Errorf("unsupported message encoding %q", m.Encoding)
+	}
+	var compressed []byte
+	if err := json.Unmarshal(m.Data, &compressed); err != nil {
+		return err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(io.LimitReader(zr, MaxDecompressedSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxDecompressedSize {
+		return ErrDecompressedTooLarge
+	}
+	m.Data = data
+	m.Encoding = ""
+	return nil
+}
@@ -0,0 +1,55 @@
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"mini_chain/internal/blockchain"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	bc := blockchain.NewBlockchain(1, nil)
+	blocks := []blockchain.Block{bc.GetLatest()}
+	for i := 0; i < 20; i++ {
+		blocks = append(blocks, blockchain.MineBlock(blocks[i], []string{fmt.Sprintf("gz-tx-%d", i)}, 1))
+	}
+	msg := &Message{Type: MsgChain, Data: mustMarshal(blocks)}
+	plain := len(msg.Data)
+	if err := msg.Compress(); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Encoding != EncodingGzip || len(msg.Data) >= plain {
+		t.Fatalf("chain not compressed: encoding %q, %d -> %d bytes", msg.Encoding, plain, len(msg.Data))
+	}
+
+	// 经过线上编码后解压，得到与原链完全相同的区块
+	raw, err := msg.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := got.Decompress(); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []blockchain.Block
+	if err := json.Unmarshal(got.Data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, blocks) {
+		t.Fatal("decompressed chain differs from the original")
+	}
+
+	// 小消息保持未压缩
+	small := &Message{Type: MsgGetBlock, Data: mustMarshal("abc")}
+	if err := small.Compress(); err != nil || small.Encoding != "" {
+		t.Fatalf("small message compressed: encoding %q, err %v", small.Encoding, err)
+	}
+	if err := (&Message{Encoding: "br", Data: mustMarshal("x")}).Decompress(); err == nil {
+		t.Error("expected error for unsupported encoding")
+	}
+}
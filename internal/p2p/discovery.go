@@ -14,12 +14,16 @@ const rendezvous = "mini-chain"
 
 // Notifee 处理新发现节点的结构体
 type Notifee struct {
-	h host.Host // 主机实例
+	h     host.Host       // 主机实例
+	gater *AllowlistGater // 节点ID白名单，nil表示不限制
 }
 
 // HandlePeerFound 当发现新节点时调用的处理函数
 // pi: 新发现的节点地址信息
 func (n *Notifee) HandlePeerFound(pi peer.AddrInfo) {
+	if n.gater != nil && !n.gater.Allowed(pi.ID) {
+		return // 私有网络模式下忽略白名单外的peer
+	}
 	log.Println("Discovered new peer:", pi.ID.String(), pi.Addrs)
 	// 可以直接连接到新发现的节点
 	n.h.Connect(context.Background(), pi)
@@ -29,8 +33,13 @@ func (n *Notifee) HandlePeerFound(pi peer.AddrInfo) {
 // ctx: 上下文
 // h: 主机实例
 func SetupMdns(ctx context.Context, h host.Host) error {
+	return setupMdns(ctx, h, nil)
+}
+
+// setupMdns 启动mDNS服务，gater非nil时只连接白名单中的peer
+func setupMdns(ctx context.Context, h host.Host, gater *AllowlistGater) error {
 	// 创建Notifee实例
-	n := &Notifee{h: h}
+	n := &Notifee{h: h, gater: gater}
 	// 创建mDNS服务实例
 	service := mdns.NewMdnsService(h, rendezvous, n)
 	// 服务会在后台运行
@@ -0,0 +1,88 @@
+package p2p
+
+// internal/p2p/identity.go
+// 节点身份与签名地址记录
+// 签名地址记录（signed peer record）是节点私钥对其peer ID和监听地址的签名，
+// 运维人员可将其分发给其他节点用于安全引导，对方无需信任传递渠道即可校验地址归属
+
+import (
+	"fmt"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/core/record"
+)
+
+// Identity 节点的libp2p身份
+type Identity struct {
+	PeerID           string   `json:"peer_id"`            // 节点ID
+	PublicKey        []byte   `json:"public_key"`         // protobuf编码的节点公钥（JSON中为base64）
+	ListenAddrs      []string `json:"listen_addrs"`       // 监听地址（附带 /p2p/<节点ID>）
+	SignedPeerRecord []byte   `json:"signed_peer_record"` // 序列化的签名地址记录信封（JSON中为base64）
+}
+
+// Identity 返回节点ID、公钥、监听地址和签名地址记录
+// 签名地址记录优先取自主机peerstore；主机尚未生成时用节点私钥即时签名
+func (n *Node) Identity() (*Identity, error) {
+	id := n.Host.ID()
+	pub, err := crypto.MarshalPublicKey(n.Host.Peerstore().PubKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("marshal public key: %w", err)
+	}
+	env, err := n.signedPeerRecord()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := env.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("marshal peer record: %w", err)
+	}
+	return &Identity{
+		PeerID:           id.String(),
+		PublicKey:        pub,
+		ListenAddrs:      n.ListenAddrs(),
+		SignedPeerRecord: raw,
+	}, nil
+}
+
+// signedPeerRecord 返回本节点的签名地址记录信封
+func (n *Node) signedPeerRecord() (*record.Envelope, error) {
+	id := n.Host.ID()
+	if cab, ok := peerstore.GetCertifiedAddrBook(n.Host.Peerstore()); ok {
+		if env := cab.GetPeerRecord(id); env != nil {
+			return env, nil
+		}
+	}
+	key := n.Host.Peerstore().PrivKey(id)
+	if key == nil {
+		return nil, fmt.Errorf("no private key for %s", id)
+	}
+	rec := peer.PeerRecordFromAddrInfo(peer.AddrInfo{ID: id, Addrs: n.Host.Addrs()})
+	env, err := record.Seal(rec, key)
+	if err != nil {
+		return nil, fmt.Errorf("sign peer record: %w", err)
+	}
+	return env, nil
+}
+
+// VerifyPeerRecord 校验序列化的签名地址记录信封，返回其中的地址记录
+// 签名无效、记录类型不符或签名者与记录中的peer ID不一致时返回错误
+func VerifyPeerRecord(data []byte) (*peer.PeerRecord, error) {
+	env, rec, err := record.ConsumeEnvelope(data, peer.PeerRecordEnvelopeDomain)
+	if err != nil {
+		return nil, err
+	}
+	pr, ok := rec.(*peer.PeerRecord)
+	if !ok {
+		return nil, fmt.Errorf("unexpected record type %T", rec)
+	}
+	signer, err := peer.IDFromPublicKey(env.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if signer != pr.PeerID {
+		return nil, fmt.Errorf("record for %s signed by %s", pr.PeerID, signer)
+	}
+	return pr, nil
+}
@@ -11,12 +11,36 @@ const (
 	MsgBlock    MsgType = "BLOCK"    // 区块消息
 	MsgGetChain MsgType = "GETCHAIN" // 请求区块链
 	MsgChain    MsgType = "CHAIN"    // 返回区块链
+
+	// 先同步区块头：先请求区块头链，校验通过后再请求区块体
+	MsgGetHeaders MsgType = "GETHEADERS" // 请求区块头
+	MsgHeaders    MsgType = "HEADERS"    // 返回区块头
+	MsgGetBlock   MsgType = "GETBLOCK"   // 按哈希请求区块体（响应使用MsgBlock）
+	MsgError      MsgType = "ERROR"      // 请求失败时的响应
+	MsgGetTx      MsgType = "GETTX"      // 按交易ID请求交易体（响应使用MsgTx）
+	MsgGetHeight  MsgType = "GETHEIGHT"  // 请求对方当前链高度
+	MsgHeight     MsgType = "HEIGHT"     // 返回链高度
+
+	// 基于布隆过滤器的交易公告，见txannounce.go
+	MsgFilterLoad MsgType = "FILTERLOAD" // 设置对方向本节点公告交易时使用的过滤器
+	MsgInv        MsgType = "INV"        // 公告交易ID列表
 )
 
+// GetHeadersRequest GETHEADERS消息的数据：从From高度开始请求至多Count个区块头
+type GetHeadersRequest struct {
+	From  int `json:"from"`  // 起始高度
+	Count int `json:"count"` // 请求数量
+}
+
 // Message 节点间传输的数据结构
 type Message struct {
 	Type MsgType         `json:"type"` // 消息类型
 	Data json.RawMessage `json:"data"` // 消息数据
+
+	// 可选压缩：请求方在AcceptEncoding中声明可以解压，响应方据此压缩较大的Data并在Encoding中标明
+	// 旧节点忽略这两个字段，仍按未压缩格式通信
+	Encoding       string `json:"encoding,omitempty"`        // Data的编码，空表示未压缩
+	AcceptEncoding string `json:"accept_encoding,omitempty"` // 请求方可以解压的编码
 }
 
 // Encode 将消息序列化为JSON
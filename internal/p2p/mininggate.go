@@ -0,0 +1,142 @@
+package p2p
+
+// internal/p2p/mininggate.go
+// 挖矿启动门槛
+// 孤立节点从创世区块开始挖矿会产生与网络冲突的链，连上网络后被迫重组。
+// 门槛要求先连上指定数量的peer（可选：并完成初始同步）再开始挖矿。
+// 门槛一旦打开就保持打开，之后peer数量下降不会暂停挖矿；
+// 运维需要时可通过Pause/Resume在运行时暂停和恢复挖矿，与门槛条件无关
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMiningGatePoll 等待门槛打开时检查peer数量的间隔
+const DefaultMiningGatePoll = time.Second
+
+// MiningGateState 挖矿门槛状态（GET /status 中返回）
+type MiningGateState struct {
+	MinPeers    int  `json:"min_peers"`    // 开始挖矿所需的最少peer数量
+	Peers       int  `json:"peers"`        // 当前已连接peer数量
+	RequireSync bool `json:"require_sync"` // 是否要求先完成初始同步
+	Synced      bool `json:"synced"`       // 初始同步是否已完成
+	Open        bool `json:"open"`         // 是否已允许挖矿
+	Paused      bool `json:"paused"`       // 是否被运维暂停
+}
+
+// MiningGate 挖矿启动门槛，可并发使用
+type MiningGate struct {
+	MinPeers     int           // 最少peer数量，<=0 表示不要求
+	RequireSync  bool          // 是否要求先完成初始同步
+	PollInterval time.Duration // Wait检查门槛的间隔
+
+	peers func() int // 返回当前已连接peer数量
+
+	mu     sync.Mutex
+	synced bool
+	open   bool
+	resume chan struct{} // 暂停期间非nil，恢复时关闭
+}
+
+// NewMiningGate 基于节点的连接数创建挖矿门槛
+func NewMiningGate(n *Node, minPeers int, requireSync bool) *MiningGate {
+	return &MiningGate{
+		MinPeers:     minPeers,
+		RequireSync:  requireSync,
+		PollInterval: DefaultMiningGatePoll,
+		peers:        func() int { return len(n.Host.Network().Peers()) },
+	}
+}
+
+// MarkSynced 标记初始同步已完成
+func (g *MiningGate) MarkSynced() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.synced = true
+}
+
+// Ready 检查门槛条件，满足时打开门槛并返回true
+func (g *MiningGate) Ready() bool {
+	return g.State().Open
+}
+
+// State 返回门槛当前状态；条件满足时会打开门槛
+func (g *MiningGate) State() MiningGateState {
+	peers := g.peers()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.open && peers >= g.MinPeers && (!g.RequireSync || g.synced) {
+		g.open = true
+	}
+	return MiningGateState{
+		MinPeers:    g.MinPeers,
+		Peers:       peers,
+		RequireSync: g.RequireSync,
+		Synced:      g.synced,
+		Open:        g.open,
+		Paused:      g.resume != nil,
+	}
+}
+
+// Pause 暂停挖矿，已暂停时不做任何事
+// 正在挖的区块不受影响，矿工在下一轮开始前停下
+func (g *MiningGate) Pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resume == nil {
+		g.resume = make(chan struct{})
+	}
+}
+
+// Resume 恢复挖矿并唤醒等待中的矿工，未暂停时不做任何事
+func (g *MiningGate) Resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resume != nil {
+		close(g.resume)
+		g.resume = nil
+	}
+}
+
+// Paused 返回挖矿是否被暂停
+func (g *MiningGate) Paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resume != nil
+}
+
+// WaitResumed 挖矿被暂停时阻塞直到恢复，ctx取消时返回ctx的错误
+func (g *MiningGate) WaitResumed(ctx context.Context) error {
+	g.mu.Lock()
+	resume := g.resume
+	g.mu.Unlock()
+	if resume == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resume:
+		return nil
+	}
+}
+
+// Wait 阻塞直到门槛打开，ctx取消时返回ctx的错误
+func (g *MiningGate) Wait(ctx context.Context) error {
+	interval := g.PollInterval
+	if interval <= 0 {
+		interval = DefaultMiningGatePoll
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for !g.Ready() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestMiningGateWaitsForPeers(t *testing.T) {
+	h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	other, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	gate := NewMiningGate(&Node{Host: h}, 1, false)
+	gate.PollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	started := make(chan struct{})
+	go func() {
+		if gate.Wait(ctx) == nil {
+			close(started) // 模拟挖矿协程开始挖矿
+		}
+	}()
+
+	select {
+	case <-started:
+		t.Fatal("mining started before reaching the peer threshold")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if st := gate.State(); st.Open || st.Peers != 0 {
+		t.Fatalf("unexpected gate state before connecting: %+v", st)
+	}
+
+	if err := h.Connect(ctx, peer.AddrInfo{ID: other.ID(), Addrs: other.Addrs()}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-ctx.Done():
+		t.Fatal("mining did not start after reaching the peer threshold")
+	}
+	if st := gate.State(); !st.Open || st.Peers != 1 {
+		t.Fatalf("unexpected gate state after connecting: %+v", st)
+	}
+}
+
+func TestMiningGateRequiresSync(t *testing.T) {
+	peers := 3
+	gate := &MiningGate{MinPeers: 2, RequireSync: true, peers: func() int { return peers }}
+	if gate.Ready() {
+		t.Fatal("gate opened before initial sync finished")
+	}
+	gate.MarkSynced()
+	if !gate.Ready() {
+		t.Fatal("gate should open once synced with enough peers")
+	}
+	// 门槛打开后peer减少不会重新关闭
+	peers = 0
+	if st := gate.State(); !st.Open {
+		t.Fatalf("gate closed after peers dropped: %+v", st)
+	}
+}
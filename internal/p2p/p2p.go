@@ -2,13 +2,17 @@ package p2p
 
 import (
 	"context"
-	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/libp2p/go-libp2p"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/metrics"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/pnet"
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 // Node 表示一个libp2p节点，包含主机、发布订阅和主题相关信息
@@ -17,20 +21,58 @@ type Node struct {
 	PubSub *pubsub.PubSub // 发布订阅实例
 	Topic  *pubsub.Topic  // 主题实例
 	Sub    *pubsub.Subscription // 订阅实例
+
+	bw *metrics.BandwidthCounter // 带宽计量器
+
+	// 按消息类型注册的处理函数
+	handlersMu sync.RWMutex
+	handlers   map[MsgType]func(from peer.ID, m *Message)
+
+	// peer发来的交易过滤器，公告交易时跳过命中的交易
+	filtersMu   sync.Mutex
+	peerFilters map[peer.ID]*BloomFilter
+}
+
+// NodeConfig 节点配置
+type NodeConfig struct {
+	ListenPort    int            // 监听端口
+	Transports    []Transport    // 监听的传输协议，为空时只监听TCP
+	AnnounceAddrs []ma.Multiaddr // 对外通告地址，为空时通告实际监听地址
+	Allowlist     []peer.ID      // 节点ID白名单，非空时只与名单中的peer连接（私有网络模式）
+	PSK           pnet.PSK       // 私有网络预共享密钥，非空时只能与持有相同密钥的节点通信
 }
 
 // NewNode 创建libp2p节点并初始化gossipsub
 // ctx: 上下文
 // listenPort: 监听端口
-func NewNode(ctx context.Context, listenPort int) (*Node, error) {
-	// 创建libp2p主机实例
-	h, err := libp2p.New(
-		libp2p.ListenAddrStrings(
-			// 支持TCP + 随机端口
-			// "/ip4/0.0.0.0/tcp/<port>"
-			fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", listenPort),
-		),
-	)
+// transports: 监听的传输协议，未指定时只监听TCP
+func NewNode(ctx context.Context, listenPort int, transports ...Transport) (*Node, error) {
+	return NewNodeWithConfig(ctx, NodeConfig{ListenPort: listenPort, Transports: transports})
+}
+
+// NewNodeWithConfig 按配置创建libp2p节点并初始化gossipsub
+func NewNodeWithConfig(ctx context.Context, cfg NodeConfig) (*Node, error) {
+	opts, err := transportOptions(cfg.ListenPort, cfg.Transports)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.AnnounceAddrs) > 0 {
+		opts = append(opts, announceOption(cfg.AnnounceAddrs))
+	}
+	if len(cfg.PSK) > 0 {
+		if err := checkPSK(cfg.PSK, cfg.Transports); err != nil {
+			return nil, err
+		}
+		opts = append(opts, libp2p.PrivateNetwork(cfg.PSK))
+	}
+	var gater *AllowlistGater
+	if len(cfg.Allowlist) > 0 {
+		gater = NewAllowlistGater(cfg.Allowlist)
+		opts = append(opts, libp2p.ConnectionGater(gater))
+	}
+	// 创建libp2p主机实例，在每种传输协议上监听，并统计收发流量
+	bw := metrics.NewBandwidthCounter()
+	h, err := libp2p.New(append(opts, libp2p.BandwidthReporter(bw))...)
 	if err != nil {
 		return nil, err
 	}
@@ -59,10 +101,14 @@ func NewNode(ctx context.Context, listenPort int) (*Node, error) {
 		PubSub: ps,
 		Topic:  topic,
 		Sub:    sub,
+		bw:     bw,
 	}
 
+	// 接受peer的交易过滤器和交易公告
+	node.serveTxAnnounce()
+
 	// 启动mDNS服务用于局域网节点发现
-	if err := SetupMdns(ctx, h); err != nil {
+	if err := setupMdns(ctx, h, gater); err != nil {
 		log.Println("mDNS warning:", err)
 	}
 
@@ -82,6 +128,16 @@ func (n *Node) Broadcast(msg *Message) {
 	}
 }
 
+// Close 退订主题并关闭libp2p主机，节点的后台协程随之退出
+// 调用者还应取消传给NewNode的ctx，以停止GossipSub的内部协程
+func (n *Node) Close() error {
+	n.Sub.Cancel()
+	if err := n.Topic.Close(); err != nil {
+		log.Println("Failed to close topic:", err)
+	}
+	return n.Host.Close()
+}
+
 // handleMessages 循环接收gossipsub消息
 // ctx: 上下文
 func (n *Node) handleMessages(ctx context.Context) {
@@ -101,19 +157,39 @@ func (n *Node) handleMessages(ctx context.Context) {
 			log.Println("invalid message:", err)
 			continue
 		}
-		// TODO: 根据消息类型调用blockchain/txpool等处理函数
-		log.Println("Received msg from", msg.ReceivedFrom, "type:", m.Type)
+		// 交给该类型注册的处理函数，未注册时只记录日志
+		n.handlersMu.RLock()
+		fn := n.handlers[m.Type]
+		n.handlersMu.RUnlock()
+		if fn == nil {
+			log.Println("Received msg from", msg.ReceivedFrom, "type:", m.Type)
+			continue
+		}
+		fn(msg.ReceivedFrom, m)
+	}
+}
+
+// Handle 注册类型为t的gossip消息的处理函数，重复注册时覆盖之前的函数
+// 处理函数在消息接收循环中同步调用，不应长时间阻塞
+func (n *Node) Handle(t MsgType, fn func(from peer.ID, m *Message)) {
+	n.handlersMu.Lock()
+	defer n.handlersMu.Unlock()
+	if n.handlers == nil {
+		n.handlers = make(map[MsgType]func(from peer.ID, m *Message))
 	}
+	n.handlers[t] = fn
 }
 
 // ConnectPeer 手动连接到指定的peer
-// addr: peer地址字符串
+// addr: peer地址字符串，支持IP地址以及 /dns4、/dns6、/dnsaddr 主机名地址
 func (n *Node) ConnectPeer(addr string) error {
-	// 从地址字符串解析peer地址信息
-	pi, err := peer.AddrInfoFromString(addr)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// 从地址字符串解析peer地址信息，并将主机名解析为IP
+	pi, err := ResolvePeerAddr(ctx, addr)
 	if err != nil {
 		return err
 	}
 	// 连接到peer
-	return n.Host.Connect(context.Background(), *pi)
+	return n.Host.Connect(ctx, *pi)
 }
\ No newline at end of file
@@ -0,0 +1,76 @@
+package p2p
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+)
+
+// PingTimeout 测量单个peer延迟的超时时间
+const PingTimeout = 2 * time.Second
+
+// PeerInfo 已连接peer的连接详情
+type PeerInfo struct {
+	ID        string  `json:"id"`                   // peer ID
+	Addr      string  `json:"addr"`                 // 连接的远端multiaddr
+	Direction string  `json:"direction"`            // 连接方向：inbound 或 outbound
+	Streams   int     `json:"streams"`              // 当前打开的流数量
+	LatencyMs float64 `json:"latency_ms"`           // 通过libp2p ping测得的往返延迟（毫秒）
+	PingError string  `json:"ping_error,omitempty"` // ping失败的原因
+}
+
+// PeerDetails 返回所有已连接peer的连接详情，并行ping测量往返延迟
+// 结果按peer ID排序
+func (n *Node) PeerDetails(ctx context.Context) []PeerInfo {
+	peers := n.Host.Network().Peers()
+	infos := make([]PeerInfo, len(peers))
+
+	var wg sync.WaitGroup
+	for i, pid := range peers {
+		infos[i] = n.connInfo(pid)
+		wg.Add(1)
+		go func(info *PeerInfo, pid peer.ID) {
+			defer wg.Done()
+			rtt, err := n.ping(ctx, pid)
+			if err != nil {
+				info.PingError = err.Error()
+				return
+			}
+			info.LatencyMs = float64(rtt) / float64(time.Millisecond)
+		}(&infos[i], pid)
+	}
+	wg.Wait()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// connInfo 汇总到peer的连接信息，存在多个连接时地址和方向取第一个连接
+func (n *Node) connInfo(pid peer.ID) PeerInfo {
+	info := PeerInfo{ID: pid.String()}
+	conns := n.Host.Network().ConnsToPeer(pid)
+	for i, c := range conns {
+		if i == 0 {
+			info.Addr = c.RemoteMultiaddr().String()
+			info.Direction = strings.ToLower(c.Stat().Direction.String())
+		}
+		info.Streams += len(c.GetStreams())
+	}
+	return info
+}
+
+// ping 使用libp2p ping协议测量到peer的单次往返延迟
+func (n *Node) ping(ctx context.Context, pid peer.ID) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, PingTimeout)
+	defer cancel()
+	res, ok := <-ping.Ping(ctx, n.Host, pid)
+	if !ok {
+		return 0, ctx.Err()
+	}
+	return res.RTT, res.Error
+}
@@ -0,0 +1,71 @@
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestPeerDetails(t *testing.T) {
+	target, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	dialer, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dialer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := dialer.Connect(ctx, peer.AddrInfo{ID: target.ID(), Addrs: target.Addrs()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 保持一个打开的流，以便统计流数量
+	done := make(chan struct{})
+	defer close(done)
+	target.SetStreamHandler("/mini-chain/test-hold/1.0.0", func(s network.Stream) {
+		<-done
+		s.Close()
+	})
+	s, err := dialer.NewStream(ctx, target.ID(), "/mini-chain/test-hold/1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	n := &Node{Host: dialer}
+	infos := n.PeerDetails(ctx)
+	if len(infos) != 1 {
+		t.Fatalf("expected 1 peer, got %d", len(infos))
+	}
+	info := infos[0]
+	if info.ID != target.ID().String() {
+		t.Errorf("peer id = %s, want %s", info.ID, target.ID())
+	}
+	if info.Addr == "" {
+		t.Error("expected connected multiaddr")
+	}
+	if info.Direction != "outbound" {
+		t.Errorf("direction = %q, want outbound", info.Direction)
+	}
+	if info.Streams < 1 {
+		t.Errorf("expected at least 1 open stream, got %d", info.Streams)
+	}
+	if info.PingError != "" || info.LatencyMs <= 0 {
+		t.Errorf("expected measured latency, got %.3fms (error %q)", info.LatencyMs, info.PingError)
+	}
+
+	// 对端看到的是入站连接
+	back := (&Node{Host: target}).PeerDetails(ctx)
+	if len(back) != 1 || back[0].Direction != "inbound" {
+		t.Errorf("expected one inbound peer on target, got %+v", back)
+	}
+}
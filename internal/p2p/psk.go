@@ -0,0 +1,46 @@
+package p2p
+
+// internal/p2p/psk.go
+// 私有网络预共享密钥（PSK）：配置后所有连接在传输层用该密钥加密，
+// 只有持有相同密钥的节点能完成握手；libp2p的私有网络不支持QUIC，只能使用TCP
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/libp2p/go-libp2p/core/pnet"
+)
+
+// SwarmKeyLen 预共享密钥的字节数
+const SwarmKeyLen = 32
+
+// ErrPSKWithQUIC 配置了预共享密钥的节点启用了QUIC
+var ErrPSKWithQUIC = errors.New("private network (PSK) does not support the QUIC transport")
+
+// LoadSwarmKey 读取swarm.key格式（/key/swarm/psk/1.0.0/）的预共享密钥文件
+func LoadSwarmKey(path string) (pnet.PSK, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	psk, err := pnet.DecodeV1PSK(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid swarm key %s: %v", path, err)
+	}
+	return psk, nil
+}
+
+// checkPSK 检查预共享密钥的长度以及监听的传输协议是否与私有网络兼容
+func checkPSK(psk pnet.PSK, transports []Transport) error {
+	if len(psk) != SwarmKeyLen {
+		return fmt.Errorf("swarm key must be %d bytes, got %d", SwarmKeyLen, len(psk))
+	}
+	for _, t := range transports {
+		if t == TransportQUIC {
+			return ErrPSKWithQUIC
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package p2p
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/pnet"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func newPSK(t *testing.T) pnet.PSK {
+	t.Helper()
+	psk := make(pnet.PSK, SwarmKeyLen)
+	if _, err := rand.Read(psk); err != nil {
+		t.Fatal(err)
+	}
+	return psk
+}
+
+// newPSKNode 创建只监听回环TCP地址的私有网络节点，返回节点和可拨号的地址
+func newPSKNode(t *testing.T, ctx context.Context, psk pnet.PSK) (*Node, peer.AddrInfo) {
+	t.Helper()
+	return newLoopbackNode(t, ctx, NodeConfig{PSK: psk})
+}
+
+// newLoopbackNode 按配置创建节点，返回节点和它在回环地址上可拨号的TCP地址
+func newLoopbackNode(t *testing.T, ctx context.Context, cfg NodeConfig) (*Node, peer.AddrInfo) {
+	t.Helper()
+	n, err := NewNodeWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { n.Close() })
+	for _, a := range n.Host.Network().ListenAddresses() {
+		if port, err := a.ValueForProtocol(ma.P_TCP); err == nil {
+			return n, peer.AddrInfo{ID: n.Host.ID(), Addrs: []ma.Multiaddr{ma.StringCast("/ip4/127.0.0.1/tcp/" + port)}}
+		}
+	}
+	t.Fatalf("no TCP listen address in %v", n.Host.Network().ListenAddresses())
+	return nil, peer.AddrInfo{}
+}
+
+func TestPrivateNetworkPSK(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	key, other := newPSK(t), newPSK(t)
+	a, aInfo := newPSKNode(t, ctx, key)
+	b, _ := newPSKNode(t, ctx, key)
+	c, _ := newPSKNode(t, ctx, other)
+
+	dctx, dcancel := context.WithTimeout(ctx, 10*time.Second)
+	defer dcancel()
+	if err := b.Host.Connect(dctx, aInfo); err != nil {
+		t.Fatalf("nodes with the same PSK could not connect: %v", err)
+	}
+	if err := c.Host.Connect(dctx, aInfo); err == nil {
+		t.Fatal("node with a different PSK connected")
+	}
+	if len(a.Host.Network().ConnsToPeer(c.Host.ID())) != 0 {
+		t.Fatal("node accepted a connection from a different private network")
+	}
+}
+
+func TestPSKConfig(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := NewNodeWithConfig(ctx, NodeConfig{PSK: newPSK(t), Transports: []Transport{TransportTCP, TransportQUIC}}); !errors.Is(err, ErrPSKWithQUIC) {
+		t.Fatalf("expected %v, got %v", ErrPSKWithQUIC, err)
+	}
+	if _, err := NewNodeWithConfig(ctx, NodeConfig{PSK: pnet.PSK("short")}); err == nil {
+		t.Fatal("expected error for a short PSK")
+	}
+
+	psk := newPSK(t)
+	path := filepath.Join(t.TempDir(), "swarm.key")
+	if err := os.WriteFile(path, []byte("/key/swarm/psk/1.0.0/\n/base16/\n"+hex.EncodeToString(psk)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadSwarmKey(path)
+	if err != nil || string(got) != string(psk) {
+		t.Fatalf("LoadSwarmKey = %x, %v; want %x", got, err, psk)
+	}
+}
@@ -0,0 +1,69 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
+)
+
+// maxDNSResolveDepth /dnsaddr 记录可以指向另一个 /dnsaddr，限制递归解析的深度
+const maxDNSResolveDepth = 4
+
+// ResolvePeerAddr 解析peer地址字符串，将 /dns、/dns4、/dns6、/dnsaddr 组件解析为IP地址
+// addr: 形如 /dns4/host/tcp/port/p2p/id 的multiaddr
+// 返回只包含IP传输地址的AddrInfo
+func ResolvePeerAddr(ctx context.Context, addr string) (*peer.AddrInfo, error) {
+	maddr, err := ma.NewMultiaddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	pi, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved []ma.Multiaddr
+	for _, a := range pi.Addrs {
+		addrs, err := resolveDNS(ctx, a, pi.ID, 0)
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %v", a, err)
+		}
+		resolved = append(resolved, addrs...)
+	}
+	if len(resolved) == 0 {
+		return nil, fmt.Errorf("no addresses resolved for %s", addr)
+	}
+	return &peer.AddrInfo{ID: pi.ID, Addrs: resolved}, nil
+}
+
+// resolveDNS 递归解析单个传输地址，丢弃指向其他peer的 /dnsaddr 结果
+func resolveDNS(ctx context.Context, a ma.Multiaddr, id peer.ID, depth int) ([]ma.Multiaddr, error) {
+	if !madns.Matches(a) {
+		return []ma.Multiaddr{a}, nil
+	}
+	if depth >= maxDNSResolveDepth {
+		return nil, fmt.Errorf("dns resolution too deep")
+	}
+	addrs, err := madns.Resolve(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ma.Multiaddr
+	for _, r := range addrs {
+		// /dnsaddr 解析结果带有 /p2p/<id> 后缀，只保留属于目标peer的地址
+		transport, rid := peer.SplitAddr(r)
+		if transport == nil || (rid != "" && rid != id) {
+			continue
+		}
+		sub, err := resolveDNS(ctx, transport, id, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sub...)
+	}
+	return out, nil
+}
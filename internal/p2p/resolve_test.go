@@ -0,0 +1,70 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/network"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestResolvePeerAddrDNS4(t *testing.T) {
+	h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	port, err := h.Addrs()[0].ValueForProtocol(ma.P_TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addr := fmt.Sprintf("/dns4/localhost/tcp/%s/p2p/%s", port, h.ID())
+	pi, err := ResolvePeerAddr(ctx, addr)
+	if err != nil {
+		t.Fatalf("resolve %s: %v", addr, err)
+	}
+	if pi.ID != h.ID() {
+		t.Errorf("peer id = %s, want %s", pi.ID, h.ID())
+	}
+	want := "/ip4/127.0.0.1/tcp/" + port
+	found := false
+	for _, a := range pi.Addrs {
+		if a.String() == want {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("resolved addrs %v do not contain %s", pi.Addrs, want)
+	}
+}
+
+func TestConnectPeerDNS4(t *testing.T) {
+	target, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	dialer, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dialer.Close()
+
+	port, err := target.Addrs()[0].ValueForProtocol(ma.P_TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &Node{Host: dialer}
+	if err := n.ConnectPeer(fmt.Sprintf("/dns4/localhost/tcp/%s/p2p/%s", port, target.ID())); err != nil {
+		t.Fatalf("connect via dns4: %v", err)
+	}
+	if dialer.Network().Connectedness(target.ID()) != network.Connected {
+		t.Fatal("expected dialer to be connected to target")
+	}
+}
@@ -0,0 +1,192 @@
+package p2p
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"mini_chain/internal/blockchain"
+)
+
+// SyncProtocolID 链同步请求/响应使用的流协议
+const SyncProtocolID = "/mini-chain/sync/1.0.0"
+
+// syncTimeout 单次同步请求的超时时间
+const syncTimeout = 10 * time.Second
+
+// SyncProvider 响应同步请求的本地数据提供方（通常是*blockchain.Blockchain）
+type SyncProvider interface {
+	Height() int
+	GetHeaders(from, count int) []blockchain.BlockHeader
+	GetBlockByHash(hash string) (blockchain.Block, error)
+}
+
+// ServeSync 注册同步协议处理器，对外提供区块头和区块体
+// p: 本地数据提供方
+func (n *Node) ServeSync(p SyncProvider) {
+	n.Host.SetStreamHandler(SyncProtocolID, func(s network.Stream) {
+		defer s.Close()
+		raw, err := bufio.NewReader(s).ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		req, err := Decode(raw)
+		if err != nil {
+			log.Println("invalid sync request:", err)
+			return
+		}
+		resp := handleSyncRequest(p, req)
+		if req.AcceptEncoding == EncodingGzip {
+			if err := resp.Compress(); err != nil {
+				log.Println("compress sync response:", err)
+			}
+		}
+		out, _ := resp.Encode()
+		s.Write(append(out, '\n'))
+	})
+}
+
+// handleSyncRequest 根据请求类型生成响应消息
+func handleSyncRequest(p SyncProvider, req *Message) *Message {
+	switch req.Type {
+	case MsgGetHeight:
+		return &Message{Type: MsgHeight, Data: mustMarshal(p.Height())}
+	case MsgGetHeaders:
+		var r GetHeadersRequest
+		if err := json.Unmarshal(req.Data, &r); err != nil {
+			return errorMessage(err)
+		}
+		return &Message{Type: MsgHeaders, Data: mustMarshal(p.GetHeaders(r.From, r.Count))}
+	case MsgGetBlock:
+		var hash string
+		if err := json.Unmarshal(req.Data, &hash); err != nil {
+			return errorMessage(err)
+		}
+		b, err := p.GetBlockByHash(hash)
+		if err != nil {
+			return errorMessage(err)
+		}
+		return &Message{Type: MsgBlock, Data: mustMarshal(b)}
+	case MsgGetTx:
+		var txid string
+		if err := json.Unmarshal(req.Data, &txid); err != nil {
+			return errorMessage(err)
+		}
+		tx, err := blockchain.GetTransaction(txid)
+		if err != nil {
+			return errorMessage(err)
+		}
+		return &Message{Type: MsgTx, Data: mustMarshal(tx)}
+	default:
+		return errorMessage(fmt.Errorf("unsupported sync request %s", req.Type))
+	}
+}
+
+// errorMessage 构造错误响应
+func errorMessage(err error) *Message {
+	return &Message{Type: MsgError, Data: mustMarshal(err.Error())}
+}
+
+// PeerSource 通过同步协议从远端节点获取数据，实现blockchain.SyncSource
+type PeerSource struct {
+	node *Node
+	peer peer.ID
+}
+
+// PeerSource 返回指定节点的同步数据源
+// pid: 远端节点ID
+func (n *Node) PeerSource(pid peer.ID) *PeerSource {
+	return &PeerSource{node: n, peer: pid}
+}
+
+// Height 向远端请求其当前链高度
+func (s *PeerSource) Height() (int, error) {
+	var height int
+	if err := s.request(&Message{Type: MsgGetHeight}, MsgHeight, &height); err != nil {
+		return 0, err
+	}
+	return height, nil
+}
+
+// GetHeaders 向远端请求从from开始的至多count个区块头
+func (s *PeerSource) GetHeaders(from, count int) ([]blockchain.BlockHeader, error) {
+	req := &Message{Type: MsgGetHeaders, Data: mustMarshal(GetHeadersRequest{From: from, Count: count})}
+	var headers []blockchain.BlockHeader
+	if err := s.request(req, MsgHeaders, &headers); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+// GetBlock 向远端按哈希请求区块体
+func (s *PeerSource) GetBlock(hash string) (blockchain.Block, error) {
+	req := &Message{Type: MsgGetBlock, Data: mustMarshal(hash)}
+	var b blockchain.Block
+	if err := s.request(req, MsgBlock, &b); err != nil {
+		return blockchain.Block{}, err
+	}
+	return b, nil
+}
+
+// GetTx 向远端按交易ID请求交易体（对方INV公告的交易，或同步时本地缺少的区块交易）
+func (s *PeerSource) GetTx(txid string) (blockchain.UTXOTx, error) {
+	req := &Message{Type: MsgGetTx, Data: mustMarshal(txid)}
+	var tx blockchain.UTXOTx
+	if err := s.request(req, MsgTx, &tx); err != nil {
+		return blockchain.UTXOTx{}, err
+	}
+	return tx, nil
+}
+
+// request 打开新流发送一条请求并读取一条响应，响应数据解析到out
+func (s *PeerSource) request(req *Message, want MsgType, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	st, err := s.node.Host.NewStream(ctx, s.peer, SyncProtocolID)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	st.SetDeadline(time.Now().Add(syncTimeout))
+
+	req.AcceptEncoding = EncodingGzip
+	data, _ := req.Encode()
+	if _, err := st.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	raw, err := bufio.NewReader(st).ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	resp, err := Decode(raw)
+	if err != nil {
+		return err
+	}
+	if err := resp.Decompress(); err != nil {
+		return err
+	}
+	if resp.Type == MsgError {
+		var msg string
+		json.Unmarshal(resp.Data, &msg)
+		return errors.New(msg)
+	}
+	if resp.Type != want {
+		return fmt.Errorf("unexpected response %s", resp.Type)
+	}
+	return json.Unmarshal(resp.Data, out)
+}
+
+// mustMarshal 将对象序列化为JSON（序列化失败时返回null）
+func mustMarshal(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return b
+}
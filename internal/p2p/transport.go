@@ -0,0 +1,95 @@
+package p2p
+
+// internal/p2p/transport.go
+// 监听传输协议配置
+// 节点默认只监听TCP，可额外启用QUIC；每种传输使用相同的端口号（TCP端口与UDP端口互不冲突）
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/libp2p/go-libp2p"
+	libp2pquic "github.com/libp2p/go-libp2p/p2p/transport/quic"
+	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
+)
+
+// Transport 节点监听使用的传输协议
+type Transport string
+
+const (
+	TransportTCP  Transport = "tcp"  // TCP（默认）
+	TransportQUIC Transport = "quic" // QUIC v1（基于UDP）
+)
+
+// DefaultTransports 未指定传输协议时使用的默认值
+var DefaultTransports = []Transport{TransportTCP}
+
+// ParseTransports 解析逗号分隔的传输协议列表（如 "tcp,quic"），空字符串返回默认值
+func ParseTransports(s string) ([]Transport, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultTransports, nil
+	}
+	var out []Transport
+	for _, part := range strings.Split(s, ",") {
+		t := Transport(strings.ToLower(strings.TrimSpace(part)))
+		if _, err := t.listenAddr(0); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// listenAddr 返回该传输协议在指定端口上的监听multiaddr
+func (t Transport) listenAddr(port int) (string, error) {
+	switch t {
+	case TransportTCP:
+		return fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", port), nil
+	case TransportQUIC:
+		return fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic-v1", port), nil
+	default:
+		return "", fmt.Errorf("unknown transport %q", string(t))
+	}
+}
+
+// option 返回启用该传输协议的libp2p选项
+func (t Transport) option() libp2p.Option {
+	if t == TransportQUIC {
+		return libp2p.Transport(libp2pquic.NewTransport)
+	}
+	return libp2p.Transport(tcp.NewTCPTransport)
+}
+
+// transportOptions 返回启用给定传输协议并在其上监听的libp2p选项
+func transportOptions(port int, transports []Transport) ([]libp2p.Option, error) {
+	if len(transports) == 0 {
+		transports = DefaultTransports
+	}
+	var opts []libp2p.Option
+	var addrs []string
+	seen := make(map[Transport]bool)
+	for _, t := range transports {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		addr, err := t.listenAddr(port)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+		opts = append(opts, t.option())
+	}
+	return append(opts, libp2p.ListenAddrStrings(addrs...)), nil
+}
+
+// ListenAddrs 返回节点所有监听地址，附带 /p2p/<节点ID>，可直接用于连接
+func (n *Node) ListenAddrs() []string {
+	id := n.Host.ID().String()
+	addrs := n.Host.Addrs()
+	out := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		out = append(out, a.String()+"/p2p/"+id)
+	}
+	return out
+}
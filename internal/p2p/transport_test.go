@@ -0,0 +1,39 @@
+package p2p
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNewNodeTCPAndQUIC(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n, err := NewNode(ctx, 0, TransportTCP, TransportQUIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Host.Close()
+
+	var tcp, quic bool
+	for _, a := range n.ListenAddrs() {
+		if !strings.HasSuffix(a, "/p2p/"+n.Host.ID().String()) {
+			t.Errorf("address %s does not end with the node ID", a)
+		}
+		tcp = tcp || strings.Contains(a, "/tcp/")
+		quic = quic || strings.Contains(a, "/udp/") && strings.Contains(a, "/quic-v1/")
+	}
+	if !tcp || !quic {
+		t.Fatalf("expected TCP and QUIC listen addresses, got %v", n.ListenAddrs())
+	}
+}
+
+func TestParseTransports(t *testing.T) {
+	ts, err := ParseTransports(" TCP, quic ")
+	if err != nil || len(ts) != 2 || ts[0] != TransportTCP || ts[1] != TransportQUIC {
+		t.Fatalf("ParseTransports = %v, %v", ts, err)
+	}
+	if _, err := ParseTransports("tcp,carrier-pigeon"); err == nil {
+		t.Fatal("expected error for unknown transport")
+	}
+}
@@ -0,0 +1,145 @@
+package p2p
+
+// internal/p2p/txannounce.go
+// 基于布隆过滤器的交易公告（可选）：节点用FILTERLOAD把内存池交易ID的过滤器发给peer，
+// peer用INV逐个公告交易ID时跳过过滤器已命中的交易，收到INV的节点再用GETTX取回缺少的交易体
+// 没有发过过滤器的peer收到全部公告；过滤器在peer断开时丢弃
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// TxAnnounceProtocolID 过滤器交换和交易公告使用的流协议，每个流只发送一条消息
+const TxAnnounceProtocolID = "/mini-chain/txannounce/1.0.0"
+
+// announceTimeout 发送一条过滤器或公告消息的超时时间
+const announceTimeout = 5 * time.Second
+
+// serveTxAnnounce 注册交易公告协议处理器，并在peer断开时丢弃它的过滤器
+func (n *Node) serveTxAnnounce() {
+	n.Host.SetStreamHandler(TxAnnounceProtocolID, n.handleAnnounceStream)
+	n.Host.Network().Notify(&network.NotifyBundle{
+		DisconnectedF: func(_ network.Network, c network.Conn) {
+			if len(n.Host.Network().ConnsToPeer(c.RemotePeer())) == 0 {
+				n.filtersMu.Lock()
+				delete(n.peerFilters, c.RemotePeer())
+				n.filtersMu.Unlock()
+			}
+		},
+	})
+}
+
+// handleAnnounceStream 读取一条FILTERLOAD或INV消息
+// FILTERLOAD替换该peer的过滤器；INV交给通过Handle注册的MsgInv处理函数
+func (n *Node) handleAnnounceStream(s network.Stream) {
+	defer s.Close()
+	s.SetReadDeadline(time.Now().Add(announceTimeout))
+	from := s.Conn().RemotePeer()
+	raw, err := bufio.NewReader(s).ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	m, err := Decode(raw)
+	if err != nil {
+		log.Println("invalid announce message from", from, ":", err)
+		return
+	}
+	switch m.Type {
+	case MsgFilterLoad:
+		var f BloomFilter
+		if err := json.Unmarshal(m.Data, &f); err != nil {
+			log.Println("invalid tx filter from", from, ":", err)
+			return
+		}
+		if err := f.validate(); err != nil {
+			log.Println("invalid tx filter from", from, ":", err)
+			return
+		}
+		n.filtersMu.Lock()
+		if n.peerFilters == nil {
+			n.peerFilters = make(map[peer.ID]*BloomFilter)
+		}
+		n.peerFilters[from] = &f
+		n.filtersMu.Unlock()
+	case MsgInv:
+		n.handlersMu.RLock()
+		fn := n.handlers[MsgInv]
+		n.handlersMu.RUnlock()
+		if fn != nil {
+			fn(from, m)
+		}
+	default:
+		log.Println("unexpected announce message from", from, "type:", m.Type)
+	}
+}
+
+// SendTxFilter 把txids（通常为本地内存池）的布隆过滤器发给peer，
+// 此后对方公告交易时跳过过滤器命中的交易
+// fpRate: 目标误判率，不在(0,1)内时使用DefaultBloomFPRate
+func (n *Node) SendTxFilter(ctx context.Context, pid peer.ID, txids []string, fpRate float64) error {
+	f := NewBloomFilter(len(txids), fpRate)
+	for _, txid := range txids {
+		f.Add(txid)
+	}
+	return n.sendAnnounce(ctx, pid, &Message{Type: MsgFilterLoad, Data: mustMarshal(f)})
+}
+
+// PeerTxFilter 返回peer最近发来的交易过滤器
+func (n *Node) PeerTxFilter(pid peer.ID) (*BloomFilter, bool) {
+	n.filtersMu.Lock()
+	defer n.filtersMu.Unlock()
+	f, ok := n.peerFilters[pid]
+	return f, ok
+}
+
+// AnnounceTxs 向每个已连接的peer公告txids中对方过滤器未命中的交易ID，
+// 没有需要公告的交易时不向该peer发送消息；返回实际向每个peer公告的交易ID
+func (n *Node) AnnounceTxs(ctx context.Context, txids []string) map[peer.ID][]string {
+	sent := make(map[peer.ID][]string)
+	for _, pid := range n.Host.Network().Peers() {
+		f, hasFilter := n.PeerTxFilter(pid)
+		var unknown []string
+		for _, txid := range txids {
+			if !hasFilter || !f.Test(txid) {
+				unknown = append(unknown, txid)
+			}
+		}
+		if len(unknown) == 0 {
+			continue
+		}
+		if err := n.sendAnnounce(ctx, pid, &Message{Type: MsgInv, Data: mustMarshal(unknown)}); err != nil {
+			log.Println("announce txs to", pid, ":", err)
+			continue
+		}
+		sent[pid] = unknown
+	}
+	return sent
+}
+
+// sendAnnounce 打开交易公告流向peer发送一条消息
+func (n *Node) sendAnnounce(ctx context.Context, pid peer.ID, m *Message) error {
+	ctx, cancel := context.WithTimeout(ctx, announceTimeout)
+	defer cancel()
+	s, err := n.Host.NewStream(ctx, pid, TxAnnounceProtocolID)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	s.SetWriteDeadline(time.Now().Add(announceTimeout))
+	data, err := m.Encode()
+	if err != nil {
+		return err
+	}
+	if _, err := s.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("send %s: %w", m.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestAnnounceSkipsFilteredTxs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, _ := newLoopbackNode(t, ctx, NodeConfig{})
+	b, bInfo := newLoopbackNode(t, ctx, NodeConfig{})
+	if err := a.Host.Connect(ctx, bInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	inv := make(chan []string, 1)
+	b.Handle(MsgInv, func(from peer.ID, m *Message) {
+		var txids []string
+		if from == a.Host.ID() && json.Unmarshal(m.Data, &txids) == nil {
+			inv <- txids
+		}
+	})
+
+	// b的内存池中已有known，把过滤器发给a
+	known := []string{"tx-1", "tx-2", "tx-3"}
+	if err := b.SendTxFilter(ctx, a.Host.ID(), known, 0.0001); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := a.PeerTxFilter(b.Host.ID()); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("a did not receive b's filter")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sent := a.AnnounceTxs(ctx, []string{"tx-1", "tx-4", "tx-2", "tx-3", "tx-5"})
+	want := []string{"tx-4", "tx-5"}
+	if fmt.Sprint(sent[b.Host.ID()]) != fmt.Sprint(want) {
+		t.Fatalf("announced %v to b, want only the unknown txs %v", sent[b.Host.ID()], want)
+	}
+	select {
+	case got := <-inv:
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("b received inv %v, want %v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("b did not receive the announcement")
+	}
+
+	// 全部已知时不发送公告
+	if sent := a.AnnounceTxs(ctx, known); len(sent[b.Host.ID()]) != 0 {
+		t.Fatalf("announced %v although b already has them", sent[b.Host.ID()])
+	}
+}
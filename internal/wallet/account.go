@@ -11,8 +11,9 @@ import (
 
 // Account 钱包账户结构体
 type Account struct {
-	Address string            // 公钥地址（十六进制）
-	Private *ecdsa.PrivateKey // 私钥；如果为只读账户则可能为nil
+	Address        string            // 公钥地址（十六进制）
+	Private        *ecdsa.PrivateKey // 私钥；如果为只读账户则可能为nil
+	DerivationPath string            // HD钱包派生路径（如m/44'/60'/0'/0/0）；不是由助记词派生的账户为空
 }
 
 // NewAccount 创建包含私钥的新账户
@@ -0,0 +1,114 @@
+package wallet
+
+// internal/wallet/hd.go
+// 助记词HD钱包：BIP-39助记词和种子，BIP-32/BIP-44子密钥派生
+// 同一助记词和口令总是派生出相同的账户序列，用户只需备份助记词即可恢复全部账户。
+// 账户按以太坊的BIP-44路径m/44'/60'/0'/0/index派生（同为secp256k1），可与其他钱包互相导入
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// HardenedOffset BIP-32强化派生的子索引偏移
+const HardenedOffset uint32 = 1 << 31
+
+// 账户派生路径m/44'/60'/0'/0中的固定部分
+var accountPathPrefix = []uint32{44 + HardenedOffset, 60 + HardenedOffset, HardenedOffset, 0}
+
+// ErrInvalidSeed 种子长度不在BIP-32规定的16~64字节之间
+var ErrInvalidSeed = errors.New("seed must be 16 to 64 bytes")
+
+// errInvalidChild 派生出的子密钥无效（概率约2^-127），按BIP-32应改用下一个索引
+var errInvalidChild = errors.New("derived key is invalid, use the next index")
+
+// NewMnemonic 生成12个单词的BIP-39助记词（128位熵）
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(128)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// SeedFromMnemonic 按BIP-39由助记词和可选口令计算64字节种子
+// 不同口令得到完全不同的种子（和账户）
+func SeedFromMnemonic(mnemonic, passphrase string) []byte {
+	return bip39.NewSeed(mnemonic, passphrase)
+}
+
+// DeriveAccount 从种子按路径m/44'/60'/0'/0/index派生账户
+// seed: SeedFromMnemonic返回的种子
+// index: 账户序号，须小于HardenedOffset
+func DeriveAccount(seed []byte, index uint32) (*Account, error) {
+	if index >= HardenedOffset {
+		return nil, fmt.Errorf("account index %d out of range", index)
+	}
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, ErrInvalidSeed
+	}
+	key, chain := hmacSHA512([]byte("Bitcoin seed"), seed)
+	if !validScalar(key) {
+		return nil, errInvalidChild
+	}
+	for _, i := range append(accountPathPrefix, index) {
+		var err error
+		if key, chain, err = deriveChild(key, chain, i); err != nil {
+			return nil, err
+		}
+	}
+	priv, err := crypto.ToECDSA(key)
+	if err != nil {
+		return nil, err
+	}
+	acc := FromPrivate(priv)
+	acc.DerivationPath = fmt.Sprintf("m/44'/60'/0'/0/%d", index)
+	return acc, nil
+}
+
+// deriveChild BIP-32私钥派生CKDpriv：返回第i个子私钥和子链码
+func deriveChild(key, chain []byte, i uint32) ([]byte, []byte, error) {
+	var data []byte
+	if i >= HardenedOffset {
+		data = append([]byte{0}, key...) // 0x00 || ser256(k)
+	} else {
+		priv, err := crypto.ToECDSA(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = crypto.CompressPubkey(&priv.PublicKey) // serP(point(k))
+	}
+	data = binary.BigEndian.AppendUint32(data, i)
+	il, ir := hmacSHA512(chain, data)
+	if !validScalar(il) {
+		return nil, nil, errInvalidChild
+	}
+	n := crypto.S256().Params().N
+	child := new(big.Int).Add(new(big.Int).SetBytes(il), new(big.Int).SetBytes(key))
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, nil, errInvalidChild
+	}
+	return child.FillBytes(make([]byte, 32)), ir, nil
+}
+
+// hmacSHA512 返回HMAC-SHA512结果的左右两半
+func hmacSHA512(key, data []byte) ([]byte, []byte) {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	return sum[:32], sum[32:]
+}
+
+// validScalar 判断32字节大端整数是否在[1, n-1]内
+func validScalar(b []byte) bool {
+	k := new(big.Int).SetBytes(b)
+	return k.Sign() > 0 && k.Cmp(crypto.S256().Params().N) < 0
+}
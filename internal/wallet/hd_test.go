@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testMnemonic BIP-39测试向量中的助记词，其m/44'/60'/0'/0/i地址在各以太坊钱包中广泛使用
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestDeriveAccountKnownAddresses(t *testing.T) {
+	seed := SeedFromMnemonic(testMnemonic, "")
+	want := []string{
+		"0x9858EfFD232B4033E47d90003D41EC34EcaEda94",
+		"0x6Fac4D18c912343BF86fa7049364Dd4E424Ab9C0",
+		"0xb6716976A3ebe8D39aCEB04372f22Ff8e6802D7A",
+	}
+	for i, addr := range want {
+		acc, err := DeriveAccount(seed, uint32(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := AddressFromPrivate(acc.Private); got != addr {
+			t.Errorf("index %d: address %s, want %s", i, got, addr)
+		}
+		if got := PubKeyToAddress(acc.Address); got != addr {
+			t.Errorf("index %d: account address maps to %s, want %s", i, got, addr)
+		}
+		if wantPath := fmt.Sprintf("m/44'/60'/0'/0/%d", i); acc.DerivationPath != wantPath {
+			t.Errorf("index %d: path %q, want %q", i, acc.DerivationPath, wantPath)
+		}
+	}
+
+	// 口令改变种子，派生出不同的账户
+	other, err := DeriveAccount(SeedFromMnemonic(testMnemonic, "TREZOR"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if AddressFromPrivate(other.Private) == want[0] {
+		t.Error("passphrase must change the derived accounts")
+	}
+}
+
+func TestDeriveAccountDeterministic(t *testing.T) {
+	mnemonic, err := NewMnemonic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(strings.Fields(mnemonic)); n != 12 {
+		t.Fatalf("mnemonic has %d words, want 12", n)
+	}
+	a, err := DeriveAccount(SeedFromMnemonic(mnemonic, ""), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := DeriveAccount(SeedFromMnemonic(mnemonic, ""), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Address != b.Address || !IsValidAddress(a.Address) {
+		t.Fatalf("index 0 derived twice: %s vs %s", a.Address, b.Address)
+	}
+	c, _ := DeriveAccount(SeedFromMnemonic(mnemonic, ""), 1)
+	if c.Address == a.Address {
+		t.Error("different indexes must derive different accounts")
+	}
+
+	if _, err := DeriveAccount(make([]byte, 8), 0); err != ErrInvalidSeed {
+		t.Errorf("short seed: err = %v, want %v", err, ErrInvalidSeed)
+	}
+	if _, err := DeriveAccount(SeedFromMnemonic(mnemonic, ""), HardenedOffset); err == nil {
+		t.Error("hardened index must be rejected")
+	}
+}
@@ -1,35 +1,102 @@
 package wallet
 
 // internal/wallet/keystore.go
-// 简单的密钥库：使用密码加密/解密私钥JSON（AES或PBKDF为简洁起见省略）
-// **仅供演示** —— 不要在生产中使用此密钥库（无强加密）
+// 密码加密的密钥库：用scrypt从密码和随机盐派生密钥，再用AES-256-GCM加密DER编码的私钥
+// 密码错误时GCM认证失败，返回ErrWrongPassword而不是错误的私钥字节
+// 兼容旧版本导出的明文十六进制密钥库（priv_hex），以及其他钱包导出的以太坊V3密钥库（scrypt/pbkdf2 + aes-128-ctr）
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/ecdsa"
+	"crypto/rand"
 	"crypto/x509"
+	"encoding/asn1"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 导出密钥库使用的scrypt参数
+const (
+	ScryptN      = 1 << 15
+	ScryptR      = 8
+	ScryptP      = 1
+	scryptKeyLen = 32 // AES-256密钥长度
+	saltLen      = 32
 )
 
-// KeyStore 密钥库存储结构
+// KeyStoreCipher 本包加密密钥库使用的加密算法
+const KeyStoreCipher = "aes-256-gcm"
+
+// 导入密钥库的错误
+var (
+	ErrPasswordRequired = errors.New("encrypted keystore requires a password")
+	ErrWrongPassword    = errors.New("could not decrypt keystore with given password")
+	ErrAddressMismatch  = errors.New("keystore address does not match decrypted key")
+)
+
+// KeyStore 旧版本的明文密钥库存储结构，仅用于导入
 type KeyStore struct {
 	PrivHex string `json:"priv_hex"` // 十六进制编码的私钥字节（DER格式）
 }
 
-// ExportKey 返回包含私钥的JSON编码密钥库字符串
+// ScryptParams 密钥库中记录的scrypt参数，导入时按这些参数重新派生密钥
+type ScryptParams struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt string `json:"salt"` // 十六进制编码的随机盐
+}
+
+// EncryptedKeyStore 密码加密的密钥库
+type EncryptedKeyStore struct {
+	Cipher     string       `json:"cipher"`     // 固定为KeyStoreCipher
+	KDF        string       `json:"kdf"`        // 固定为scrypt
+	KDFParams  ScryptParams `json:"kdfparams"`  // scrypt参数和盐
+	Nonce      string       `json:"nonce"`      // 十六进制编码的GCM nonce
+	Ciphertext string       `json:"ciphertext"` // 十六进制编码的密文（含GCM认证标签）
+}
+
+// ExportKey 用password加密私钥，返回JSON编码的密钥库字符串
 // priv: 私钥
-func ExportKey(priv *ecdsa.PrivateKey) (string, error) {
-	// 将私钥序列化为DER格式
-	der, err := x509.MarshalECPrivateKey(priv)
+// password: 加密密码，不能为空
+func ExportKey(priv *ecdsa.PrivateKey, password string) (string, error) {
+	if password == "" {
+		return "", ErrPasswordRequired
+	}
+	der, err := marshalPrivateKey(priv)
 	if err != nil {
-		return "", errors.New("could not marshal private key")
+		return "", err
+	}
+
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	params := ScryptParams{N: ScryptN, R: ScryptR, P: ScryptP, Salt: hex.EncodeToString(salt)}
+	aead, err := keyStoreAEAD(password, salt, params)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	ks := EncryptedKeyStore{
+		Cipher:     KeyStoreCipher,
+		KDF:        "scrypt",
+		KDFParams:  params,
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(aead.Seal(nil, nonce, der, nil)),
 	}
-	
-	// 创建密钥库实例
-	ks := KeyStore{PrivHex: hex.EncodeToString(der)}
-	
-	// 序列化为JSON
 	b, err := json.Marshal(ks)
 	if err != nil {
 		return "", err
@@ -37,26 +104,163 @@ func ExportKey(priv *ecdsa.PrivateKey) (string, error) {
 	return string(b), nil
 }
 
-// ImportKey 解析密钥库JSON并返回私钥
+// keyStoreAEAD 用scrypt从密码派生AES-256密钥，返回对应的GCM
+func keyStoreAEAD(password string, salt []byte, p ScryptParams) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(password), salt, p.N, p.R, p.P, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("derive keystore key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ImportKey 解析密钥库JSON并返回私钥，自动识别格式：
+// 本包的加密密钥库和以太坊V3密钥库用password解密，密码错误时返回ErrWrongPassword；
+// 旧版本的明文密钥库（priv_hex）直接导入，忽略password
 // jsonStr: 密钥库JSON字符串
-func ImportKey(jsonStr string) (*ecdsa.PrivateKey, error) {
-	var ks KeyStore
-	
-	// 反序列化JSON到密钥库结构体
+// password: 加密密钥库的密码
+func ImportKey(jsonStr, password string) (*ecdsa.PrivateKey, error) {
+	if IsV3Keystore(jsonStr) {
+		return importV3(jsonStr, password)
+	}
+	var ks struct {
+		EncryptedKeyStore
+		KeyStore
+	}
 	if err := json.Unmarshal([]byte(jsonStr), &ks); err != nil {
 		return nil, err
 	}
-	
+	if ks.Ciphertext == "" {
+		return importPlain(ks.KeyStore)
+	}
+	return importEncrypted(ks.EncryptedKeyStore, password)
+}
+
+// importEncrypted 解密本包导出的加密密钥库
+func importEncrypted(ks EncryptedKeyStore, password string) (*ecdsa.PrivateKey, error) {
+	if ks.Cipher != KeyStoreCipher || ks.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported keystore cipher %q / kdf %q", ks.Cipher, ks.KDF)
+	}
+	if password == "" {
+		return nil, ErrPasswordRequired
+	}
+	salt, err := hex.DecodeString(ks.KDFParams.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("keystore salt: %w", err)
+	}
+	nonce, err := hex.DecodeString(ks.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("keystore nonce: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(ks.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("keystore ciphertext: %w", err)
+	}
+	aead, err := keyStoreAEAD(password, salt, ks.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("keystore nonce length %d, want %d", len(nonce), aead.NonceSize())
+	}
+	der, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	return parsePrivateKey(der)
+}
+
+// importPlain 导入旧版本的明文密钥库
+func importPlain(ks KeyStore) (*ecdsa.PrivateKey, error) {
+	if ks.PrivHex == "" {
+		return nil, errors.New("unrecognized keystore format")
+	}
 	// 解码十六进制私钥
 	der, err := hex.DecodeString(ks.PrivHex)
 	if err != nil {
 		return nil, err
 	}
-	
 	// 解析DER格式的私钥
-	privKey, err := x509.ParseECPrivateKey(der)
+	return parsePrivateKey(der)
+}
+
+// oidSecp256k1 SEC 2中secp256k1曲线的OID，x509包不支持该曲线
+var oidSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+
+// sec1PrivateKey RFC 5915 / SEC 1中的ECPrivateKey结构
+type sec1PrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// marshalPrivateKey 把私钥编码为SEC 1 DER；钱包使用的secp256k1私钥自行编码，其他曲线交给x509
+func marshalPrivateKey(priv *ecdsa.PrivateKey) ([]byte, error) {
+	if priv.Curve != crypto.S256() {
+		der, err := x509.MarshalECPrivateKey(priv)
+		if err != nil {
+			return nil, errors.New("could not marshal private key")
+		}
+		return der, nil
+	}
+	return asn1.Marshal(sec1PrivateKey{
+		Version:       1,
+		PrivateKey:    crypto.FromECDSA(priv),
+		NamedCurveOID: oidSecp256k1,
+		PublicKey:     asn1.BitString{Bytes: crypto.FromECDSAPub(&priv.PublicKey), BitLength: 8 * 65},
+	})
+}
+
+// parsePrivateKey 解析marshalPrivateKey编码的私钥
+func parsePrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+	var key sec1PrivateKey
+	if _, err := asn1.Unmarshal(der, &key); err == nil && key.NamedCurveOID.Equal(oidSecp256k1) {
+		return crypto.ToECDSA(key.PrivateKey)
+	}
+	return x509.ParseECPrivateKey(der)
+}
+
+// v3Keystore 以太坊V3密钥库中识别格式和校验地址所需的字段
+type v3Keystore struct {
+	Version int             `json:"version"`
+	Address string          `json:"address"`
+	Crypto  json.RawMessage `json:"crypto"`
+}
+
+// IsV3Keystore 判断JSON是否为以太坊V3密钥库（version为3且包含crypto字段）
+func IsV3Keystore(jsonStr string) bool {
+	var v3 v3Keystore
+	if err := json.Unmarshal([]byte(jsonStr), &v3); err != nil {
+		return false
+	}
+	return v3.Version == 3 && len(v3.Crypto) > 0
+}
+
+// importV3 用password解密以太坊V3密钥库并校验MAC，包含address字段时还要求它与解密出的私钥一致
+func importV3(jsonStr, password string) (*ecdsa.PrivateKey, error) {
+	if password == "" {
+		return nil, ErrPasswordRequired
+	}
+	key, err := keystore.DecryptKey([]byte(jsonStr), password)
+	if errors.Is(err, keystore.ErrDecrypt) {
+		return nil, ErrWrongPassword
+	}
 	if err != nil {
 		return nil, err
 	}
-	return privKey, nil
-}
\ No newline at end of file
+
+	// 地址字段不参与MAC计算，可能被篡改，用私钥推导出的地址核对
+	var v3 v3Keystore
+	json.Unmarshal([]byte(jsonStr), &v3)
+	if v3.Address != "" {
+		want := strings.ToLower(strings.TrimPrefix(v3.Address, "0x"))
+		if want != strings.ToLower(strings.TrimPrefix(crypto.PubkeyToAddress(key.PrivateKey.PublicKey).Hex(), "0x")) {
+			return nil, ErrAddressMismatch
+		}
+	}
+	return key.PrivateKey, nil
+}
@@ -0,0 +1,136 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// v3Fixture Web3 Secret Storage规范中的pbkdf2测试向量（密码testpassword），补充了对应的address字段
+const v3Fixture = `{
+	"address": "008aeeda4d805471df9b2a5b0f38a0c3bcba786b",
+	"crypto": {
+		"cipher": "aes-128-ctr",
+		"cipherparams": {"iv": "6087dab2f9fdbbfaddc31a909735c1e6"},
+		"ciphertext": "5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46",
+		"kdf": "pbkdf2",
+		"kdfparams": {
+			"c": 262144,
+			"dklen": 32,
+			"prf": "hmac-sha256",
+			"salt": "ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"
+		},
+		"mac": "517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"
+	},
+	"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+	"version": 3
+}`
+
+// v3FixtureAddress 测试向量私钥7a28b5...fe9d对应的本链地址（未压缩公钥）
+const v3FixtureAddress = "0432d87c5cd4b31d81c5b010af42a2e413af253dc3a91bd3d53c6b2c45291c3de71633bf7793447a0d3ddde601f8d21668fca5b33324f14ebe7516eab0da8bab8f"
+
+func TestImportV3Keystore(t *testing.T) {
+	if !IsV3Keystore(v3Fixture) {
+		t.Fatal("fixture not detected as v3 keystore")
+	}
+	priv, err := ImportKey(v3Fixture, "testpassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FromPrivate(priv).Address; got != v3FixtureAddress {
+		t.Fatalf("address = %s, want %s", got, v3FixtureAddress)
+	}
+
+	if _, err := ImportKey(v3Fixture, "wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("wrong password: err = %v, want %v", err, ErrWrongPassword)
+	}
+	if _, err := ImportKey(v3Fixture, ""); !errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("no password: err = %v, want %v", err, ErrPasswordRequired)
+	}
+	tampered := strings.Replace(v3Fixture, "008aeeda", "108aeeda", 1)
+	if _, err := ImportKey(tampered, "testpassword"); !errors.Is(err, ErrAddressMismatch) {
+		t.Errorf("tampered address: err = %v, want %v", err, ErrAddressMismatch)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	acc, err := NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := ExportKey(acc.Private, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ks EncryptedKeyStore
+	if err := json.Unmarshal([]byte(js), &ks); err != nil {
+		t.Fatal(err)
+	}
+	if ks.Cipher != KeyStoreCipher || ks.KDF != "scrypt" || ks.KDFParams.N != ScryptN || ks.KDFParams.Salt == "" || ks.Nonce == "" {
+		t.Fatalf("unexpected keystore header %+v", ks)
+	}
+	der, _ := marshalPrivateKey(acc.Private)
+	if strings.Contains(js, hex.EncodeToString(der)) {
+		t.Fatal("keystore contains the plaintext key")
+	}
+
+	priv, err := ImportKey(js, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FromPrivate(priv).Address; got != acc.Address {
+		t.Fatalf("address = %s, want %s", got, acc.Address)
+	}
+
+	// 同一私钥再次导出使用新的盐和nonce
+	again, _ := ExportKey(acc.Private, "correct horse")
+	if again == js {
+		t.Error("exports of the same key must differ")
+	}
+	if _, err := ExportKey(acc.Private, ""); !errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("empty password: err = %v, want %v", err, ErrPasswordRequired)
+	}
+}
+
+func TestImportWrongPassword(t *testing.T) {
+	acc, _ := NewAccount()
+	js, err := ExportKey(acc.Private, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if priv, err := ImportKey(js, "Secret"); !errors.Is(err, ErrWrongPassword) || priv != nil {
+		t.Errorf("wrong password: key %v, err %v; want %v", priv, err, ErrWrongPassword)
+	}
+	if _, err := ImportKey(js, ""); !errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("no password: err = %v, want %v", err, ErrPasswordRequired)
+	}
+}
+
+func TestImportLegacyPlainKeystore(t *testing.T) {
+	// 旧版本的ExportKey用x509编码私钥，只能导出x509支持的曲线
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := `{"priv_hex":"` + hex.EncodeToString(der) + `"}`
+	priv, err := ImportKey(legacy, "ignored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.Equal(key) {
+		t.Fatal("imported legacy key differs from the exported one")
+	}
+	if _, err := ImportKey(`{"unknown":1}`, "x"); err == nil {
+		t.Error("unrecognized keystore must be rejected")
+	}
+}
@@ -0,0 +1,30 @@
+package wallet
+
+// internal/wallet/message.go
+// 任意消息的签名与验证，用于证明地址所有权
+// 签名对象是带域分隔前缀的消息哈希，签名不能被当作交易或区块签名重放
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"fmt"
+)
+
+// MessageDomain 消息签名的域分隔前缀
+const MessageDomain = "mini_chain signed message:\n"
+
+// MessageHash 返回消息的签名摘要：sha256(MessageDomain || 消息长度 || "\n" || 消息)
+func MessageHash(msg string) []byte {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s%d\n%s", MessageDomain, len(msg), msg)))
+	return h[:]
+}
+
+// SignMessage 用私钥对消息签名，返回十六进制编码的签名
+func SignMessage(priv *ecdsa.PrivateKey, msg string) (string, error) {
+	return SignData(priv, MessageHash(msg))
+}
+
+// VerifyMessage 验证签名是否为地址（十六进制公钥）对消息的签名
+func VerifyMessage(address, msg, sigHex string) (bool, error) {
+	return VerifySignature(address, sigHex, MessageHash(msg))
+}
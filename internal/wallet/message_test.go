@@ -0,0 +1,30 @@
+package wallet
+
+import "testing"
+
+func TestSignVerifyMessage(t *testing.T) {
+	acc, err := NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := "I own this address"
+	sig, err := SignMessage(acc.Private, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyMessage(acc.Address, msg, sig); err != nil || !ok {
+		t.Fatalf("valid signature rejected: %v, %v", ok, err)
+	}
+	if ok, _ := VerifyMessage(acc.Address, msg+"!", sig); ok {
+		t.Fatal("signature verified for a tampered message")
+	}
+
+	other, err := NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := VerifyMessage(other.Address, msg, sig); ok {
+		t.Fatal("signature verified for a different address")
+	}
+
+}
@@ -0,0 +1,105 @@
+package wallet
+
+// internal/wallet/tx.go
+// 构造并签名UTXO交易
+// 钱包不依赖区块链包（区块链包使用钱包的签名函数），因此这里的交易结构与
+// internal/blockchain的UTXOTx保持相同的字段和JSON编码，构造结果可直接提交到 POST /tx
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// TxVersion 新建交易使用的版本，与blockchain.TxVersion一致
+const TxVersion = 1
+
+// ErrInsufficientFunds 可用UTXO不足以支付金额和手续费
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// TxInput 交易输入，与blockchain.TxInput编码相同
+type TxInput struct {
+	Txid      string `json:"txid"`      // 引用的前一个交易ID
+	Vout      int    `json:"vout"`      // 引用的前一个交易的输出索引
+	Signature string `json:"signature"` // 十六进制编码的签名
+	PubKey    string `json:"pubkey"`    // 十六进制编码的公钥（地址）
+}
+
+// TxOutput 交易输出，与blockchain.TxOutput编码相同
+type TxOutput struct {
+	Address string `json:"address"` // 接收地址
+	Amount  int    `json:"amount"`  // 金额
+}
+
+// Transaction 待提交的UTXO交易，与blockchain.UTXOTx编码相同
+type Transaction struct {
+	Version int        `json:"version"` // 交易版本
+	Inputs  []TxInput  `json:"inputs"`  // 交易输入列表
+	Outputs []TxOutput `json:"outputs"` // 交易输出列表
+}
+
+// UTXO 账户可花费的一个输出（GET /addr/{address}/utxos 返回的条目）
+type UTXO struct {
+	Txid   string `json:"txid"`   // 交易ID
+	Vout   int    `json:"vout"`   // 输出索引
+	Amount int    `json:"amount"` // 金额
+}
+
+// TxSigHash 返回交易的签名摘要：清空所有输入签名后JSON编码的SHA256
+// 每个输入对同一摘要签名，签名不参与摘要计算
+func TxSigHash(tx Transaction) []byte {
+	unsigned := tx
+	unsigned.Inputs = make([]TxInput, len(tx.Inputs))
+	for i, in := range tx.Inputs {
+		in.Signature = ""
+		unsigned.Inputs[i] = in
+	}
+	b, _ := json.Marshal(unsigned)
+	h := sha256.Sum256(b)
+	return h[:]
+}
+
+// BuildTransaction 从available中按顺序贪心选择输入，直到覆盖amount+fee，
+// 创建给to的输出，多余部分作为找零输出返还给发送方，并用账户私钥签名每个输入
+// 手续费不单独出现在交易中，等于输入总额减去输出总额
+func BuildTransaction(acc *Account, to string, amount, fee int, available []UTXO) (Transaction, error) {
+	if acc == nil || acc.Private == nil {
+		return Transaction{}, errors.New("account has no private key")
+	}
+	if amount <= 0 {
+		return Transaction{}, fmt.Errorf("invalid amount %d", amount)
+	}
+	if fee < 0 {
+		return Transaction{}, fmt.Errorf("invalid fee %d", fee)
+	}
+
+	need := amount + fee
+	tx := Transaction{Version: TxVersion}
+	total := 0
+	for _, u := range available {
+		if total >= need {
+			break
+		}
+		tx.Inputs = append(tx.Inputs, TxInput{Txid: u.Txid, Vout: u.Vout, PubKey: acc.Address})
+		total += u.Amount
+	}
+	if total < need {
+		return Transaction{}, fmt.Errorf("%w: have %d, need %d", ErrInsufficientFunds, total, need)
+	}
+
+	tx.Outputs = []TxOutput{{Address: to, Amount: amount}}
+	if change := total - need; change > 0 {
+		tx.Outputs = append(tx.Outputs, TxOutput{Address: acc.Address, Amount: change})
+	}
+
+	digest := TxSigHash(tx)
+	for i := range tx.Inputs {
+		sig, err := SignData(acc.Private, digest)
+		if err != nil {
+			return Transaction{}, err
+		}
+		tx.Inputs[i].Signature = sig
+	}
+	return tx, nil
+}
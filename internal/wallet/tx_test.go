@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestAccount(t *testing.T) *Account {
+	t.Helper()
+	acc, err := NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acc
+}
+
+// checkSigned 校验每个输入都带有发送方公钥和对签名摘要的有效签名
+func checkSigned(t *testing.T, acc *Account, tx Transaction) {
+	t.Helper()
+	digest := TxSigHash(tx)
+	for i, in := range tx.Inputs {
+		if in.PubKey != acc.Address {
+			t.Fatalf("input %d pubkey = %.16s, want sender", i, in.PubKey)
+		}
+		if err := VerifyRaw(in.PubKey, in.Signature, digest); err != nil {
+			t.Fatalf("input %d signature: %v", i, err)
+		}
+	}
+}
+
+func TestBuildTransactionExactAmount(t *testing.T) {
+	acc := newTestAccount(t)
+	utxos := []UTXO{{Txid: "a", Vout: 0, Amount: 6}, {Txid: "b", Vout: 1, Amount: 5}, {Txid: "c", Vout: 0, Amount: 100}}
+	tx, err := BuildTransaction(acc, "bob", 10, 1, utxos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Inputs) != 2 || tx.Inputs[0].Txid != "a" || tx.Inputs[1].Txid != "b" || tx.Inputs[1].Vout != 1 {
+		t.Fatalf("unexpected inputs %+v", tx.Inputs)
+	}
+	if len(tx.Outputs) != 1 || tx.Outputs[0] != (TxOutput{Address: "bob", Amount: 10}) {
+		t.Fatalf("expected single recipient output without change, got %+v", tx.Outputs)
+	}
+	checkSigned(t, acc, tx)
+}
+
+func TestBuildTransactionWithChange(t *testing.T) {
+	acc := newTestAccount(t)
+	tx, err := BuildTransaction(acc, "bob", 30, 2, []UTXO{{Txid: "a", Vout: 0, Amount: 20}, {Txid: "b", Vout: 0, Amount: 25}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TxOutput{{Address: "bob", Amount: 30}, {Address: acc.Address, Amount: 13}}
+	if len(tx.Outputs) != 2 || tx.Outputs[0] != want[0] || tx.Outputs[1] != want[1] {
+		t.Fatalf("outputs = %+v, want %+v", tx.Outputs, want)
+	}
+	checkSigned(t, acc, tx)
+
+	// 篡改输出后签名失效
+	tx.Outputs[0].Amount = 43
+	if VerifyRaw(acc.Address, tx.Inputs[0].Signature, TxSigHash(tx)) == nil {
+		t.Fatal("signature still valid after changing outputs")
+	}
+}
+
+func TestBuildTransactionInsufficientFunds(t *testing.T) {
+	acc := newTestAccount(t)
+	_, err := BuildTransaction(acc, "bob", 10, 1, []UTXO{{Txid: "a", Vout: 0, Amount: 4}, {Txid: "b", Vout: 0, Amount: 6}})
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if _, err := BuildTransaction(acc, "bob", 1, 0, nil); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds with no utxos, got %v", err)
+	}
+}
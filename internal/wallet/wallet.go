@@ -7,7 +7,10 @@ import (
 	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
@@ -22,11 +25,67 @@ func NewKey() (*ecdsa.PrivateKey, string, error) {
 	return priv, pubHex, nil
 }
 
-// PubKeyToAddress 将公钥十六进制字符串转换为标准地址（十六进制）
-// 这里我们只返回公钥十六进制（无哈希/截断）— 用于演示
-// 在实际链中，您可能会像以太坊一样哈希并取最后20字节
+// ErrBadChecksum 大小写混合的地址不符合EIP-55校验和
+var ErrBadChecksum = errors.New("address checksum mismatch")
+
+// PubKeyToAddress 像以太坊一样由公钥推导短地址：对未压缩公钥（去掉0x04前缀）做Keccak-256，
+// 取最后20字节，返回带EIP-55校验和的0x前缀十六进制地址；公钥格式错误时返回空串
+// 注意：链上UTXO的所有者和Account.Address仍然是完整的公钥十六进制
 func PubKeyToAddress(pubHex string) string {
-	return pubHex
+	pubBytes, err := hex.DecodeString(pubHex)
+	if err != nil {
+		return ""
+	}
+	pub, err := crypto.UnmarshalPubkey(pubBytes)
+	if err != nil {
+		return ""
+	}
+	return crypto.PubkeyToAddress(*pub).Hex()
+}
+
+// AddressFromPrivate 返回私钥对应的带校验和的短地址
+func AddressFromPrivate(priv *ecdsa.PrivateKey) string {
+	return crypto.PubkeyToAddress(priv.PublicKey).Hex()
+}
+
+// VerifySignatureForAddress 从签名中恢复公钥，推导其短地址并与addr比较
+// addr: 0x前缀的短地址；大小写混合时必须符合EIP-55校验和，全小写或全大写时不检查
+// sigHex: SignData返回的十六进制签名
+// data: 被签名的数据（哈希）
+func VerifySignatureForAddress(addr, sigHex string, data []byte) (bool, error) {
+	if !common.IsHexAddress(addr) {
+		return false, fmt.Errorf("invalid address %q", addr)
+	}
+	want := common.HexToAddress(addr)
+	if body := strings.TrimPrefix(strings.TrimPrefix(addr, "0x"), "0X"); body != strings.ToLower(body) && body != strings.ToUpper(body) && addr != want.Hex() {
+		return false, fmt.Errorf("%w: %s", ErrBadChecksum, addr)
+	}
+	sigBytes, err := hex.DecodeString(sigHex)
+	if err != nil {
+		return false, err
+	}
+	pub, err := crypto.SigToPub(data, sigBytes)
+	if err != nil {
+		return false, err
+	}
+	return crypto.PubkeyToAddress(*pub) == want, nil
+}
+
+// AddressHexLen 地址（未压缩secp256k1公钥的十六进制编码）的长度
+const AddressHexLen = 2 * 65
+
+// IsValidAddress 检查地址格式：长度为AddressHexLen的十六进制串，且能解析为曲线上的未压缩公钥
+// 格式错误的地址没有对应私钥，发往它的币无法再被花费
+func IsValidAddress(addr string) bool {
+	if len(addr) != AddressHexLen {
+		return false
+	}
+	pubBytes, err := hex.DecodeString(addr)
+	if err != nil {
+		return false
+	}
+	_, err = crypto.UnmarshalPubkey(pubBytes)
+	return err == nil
 }
 
 // SignData 使用私钥对数据进行签名，返回十六进制编码的签名
@@ -0,0 +1,96 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestIsValidAddress(t *testing.T) {
+	acc, err := NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsValidAddress(acc.Address) {
+		t.Fatalf("generated address %s rejected", acc.Address)
+	}
+
+	offCurve, _ := hex.DecodeString(acc.Address)
+	offCurve[len(offCurve)-1] ^= 1 // 修改Y坐标，点不再在曲线上
+	malformed := map[string]string{
+		"empty":     "",
+		"name":      "bob",
+		"too short": acc.Address[:AddressHexLen-2],
+		"too long":  acc.Address + "00",
+		"not hex":   "zz" + acc.Address[2:],
+		"0x prefix": "0x" + acc.Address[2:],
+		"prefix":    "02" + acc.Address[2:],
+		"off curve": hex.EncodeToString(offCurve),
+		"zero":      "04" + strings.Repeat("0", AddressHexLen-2),
+	}
+	for name, addr := range malformed {
+		if IsValidAddress(addr) {
+			t.Errorf("%s: malformed address %q accepted", name, addr)
+		}
+	}
+}
+
+// 已知密钥对：go-ethereum crypto包测试使用的私钥及其以太坊地址
+const (
+	knownPrivHex = "289c2857d4598e37fb9647507e47a309d6133539bf21a8b9cb6df88fd5232032"
+	knownAddress = "0x970E8128AB834E8EAC17Ab8E3812F010678CF791"
+)
+
+func TestPubKeyToAddress(t *testing.T) {
+	priv, err := crypto.HexToECDSA(knownPrivHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc := FromPrivate(priv)
+	for i := 0; i < 2; i++ {
+		if got := PubKeyToAddress(acc.Address); got != knownAddress {
+			t.Fatalf("PubKeyToAddress = %s, want %s", got, knownAddress)
+		}
+	}
+	if got := AddressFromPrivate(priv); got != knownAddress {
+		t.Fatalf("AddressFromPrivate = %s, want %s", got, knownAddress)
+	}
+	for _, bad := range []string{"", "bob", acc.Address[:AddressHexLen-2]} {
+		if got := PubKeyToAddress(bad); got != "" {
+			t.Errorf("PubKeyToAddress(%q) = %s, want empty", bad, got)
+		}
+	}
+}
+
+func TestVerifySignatureForAddress(t *testing.T) {
+	priv, _ := crypto.HexToECDSA(knownPrivHex)
+	digest := sha256.Sum256([]byte("short address"))
+	sig, err := SignData(priv, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, addr := range []string{knownAddress, strings.ToLower(knownAddress)} {
+		if ok, err := VerifySignatureForAddress(addr, sig, digest[:]); !ok || err != nil {
+			t.Errorf("%s: ok=%v err=%v, want valid", addr, ok, err)
+		}
+	}
+	other, _ := NewAccount()
+	if ok, err := VerifySignatureForAddress(AddressFromPrivate(other.Private), sig, digest[:]); ok || err != nil {
+		t.Errorf("other address: ok=%v err=%v, want false without error", ok, err)
+	}
+	badChecksum := "0x970e8128AB834E8EAC17Ab8E3812F010678CF791"
+	if _, err := VerifySignatureForAddress(badChecksum, sig, digest[:]); !errors.Is(err, ErrBadChecksum) {
+		t.Errorf("bad checksum: err = %v, want %v", err, ErrBadChecksum)
+	}
+	if _, err := VerifySignatureForAddress("0x1234", sig, digest[:]); err == nil {
+		t.Error("short address must be rejected")
+	}
+	if _, err := VerifySignatureForAddress(knownAddress, "zz", digest[:]); err == nil {
+		t.Error("malformed signature must be rejected")
+	}
+}
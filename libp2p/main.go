@@ -8,15 +8,18 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/big"
 	"os"
 	"strconv"
 	"strings"
@@ -30,6 +33,8 @@ import (
 	peerstore "github.com/libp2p/go-libp2p/core/peerstore"
 	mdns "github.com/libp2p/go-libp2p/p2p/discovery/mdns"
 	ma "github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
+	"mini_chain/gossip/core"
 )
 
 // ProtocolID 定义了本地区块链网络使用的协议标识符
@@ -68,16 +73,17 @@ var (
 	txPool      []Transaction // 交易池（内存池）
 	chainMutex  sync.Mutex    // 区块链访问互斥锁
 	txPoolMutex sync.Mutex    // 交易池访问互斥锁
-	difficulty  = 3           // 挖矿难度（哈希值前缀0的个数）
+	difficulty  = 3           // 挖矿难度（目标值为2^(256-difficulty*4)）
 )
 
 // libp2p related libp2p相关变量
 var (
 	h           host.Host                    // libp2p主机实例
-	ctx         context.Context              // 上下文
-	cancel      context.CancelFunc           // 取消函数
+	rootCtx     context.Context              // 节点根上下文，关闭时取消
+	rootCancel  context.CancelFunc           // 根上下文的取消函数
 	knownPeers  = make(map[peer.ID]struct{}) // 已知节点集合
 	knownPeersM sync.Mutex                   // 已知节点集合访问互斥锁
+	peerStreams *streamPool                  // 向其他节点发送消息复用的出站流
 )
 
 // ===== Wallet & Signature Utils 钱包与签名工具函数 =====
@@ -99,6 +105,14 @@ func HashTransaction(tx Transaction) []byte {
 	return h[:]
 }
 
+// TxID 返回交易的唯一标识：签名内容和签名一起的SHA256（十六进制），规则与gossip/core.TxID一致
+// 交易池用它去重，收发方和金额相同的两笔转账因签名不同而得到不同的TxID
+func TxID(tx Transaction) string {
+	data := tx.From + "|" + tx.To + "|" + strconv.Itoa(tx.Amount) + "|" + tx.Signature
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
+
 // SignTransaction 对交易进行签名
 func SignTransaction(priv *ecdsa.PrivateKey, tx Transaction) (string, error) {
 	h := HashTransaction(tx)
@@ -109,30 +123,45 @@ func SignTransaction(priv *ecdsa.PrivateKey, tx Transaction) (string, error) {
 	return hex.EncodeToString(sig), nil
 }
 
+// parsePublicKey 把未压缩P256公钥的十六进制编码解析为ECDSA公钥，规则与gossip/core.ParsePublicKey一致
+// 长度、前缀不符或不是曲线上的点时返回错误，任何输入都不会panic
+func parsePublicKey(s string) (*ecdsa.PublicKey, error) {
+	pubBytes, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(pubBytes) != 65 || pubBytes[0] != 0x04 {
+		return nil, errors.New("public key is not an uncompressed P256 point")
+	}
+	if _, err := ecdh.P256().NewPublicKey(pubBytes); err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(pubBytes[1:33]),
+		Y:     new(big.Int).SetBytes(pubBytes[33:]),
+	}, nil
+}
+
 // VerifyTransaction 验证交易签名的有效性
 func VerifyTransaction(tx Transaction) bool {
-	pubBytes, err := hex.DecodeString(tx.From)
+	pub, err := parsePublicKey(tx.From)
 	if err != nil {
 		return false
 	}
-	x, y := elliptic.Unmarshal(elliptic.P256(), pubBytes)
-	if x == nil {
-		return false
-	}
-	pub := ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
 	sigBytes, err := hex.DecodeString(tx.Signature)
 	if err != nil {
 		return false
 	}
 	h := HashTransaction(tx)
-	return ecdsa.VerifyASN1(&pub, h, sigBytes)
+	return ecdsa.VerifyASN1(pub, h, sigBytes)
 }
 
 // ===== Block & PoW 区块与工作量证明相关函数 =====
 
 // CalculateHash 计算区块的哈希值
 func CalculateHash(b Block) string {
-	txBytes, _ := json.Marshal(b.Transactions)
+	txBytes := core.CanonicalTxBytes(b.Transactions)
 	record := strconv.Itoa(b.Index) + strconv.FormatInt(b.Timestamp, 10) + string(txBytes) + b.PrevHash + strconv.FormatInt(b.Nonce, 10)
 	h := sha256.Sum256([]byte(record))
 	return fmt.Sprintf("%x", h)
@@ -151,8 +180,8 @@ func MineBlock(transactions []Transaction, prev Block) Block {
 	// 不断尝试不同的nonce值直到找到满足难度要求的哈希值
 	for {
 		newBlock.Hash = CalculateHash(newBlock)
-		// 检查哈希值是否满足难度要求（以指定数量的0开头）
-		if strings.HasPrefix(newBlock.Hash, strings.Repeat("0", difficulty)) {
+		// 检查哈希值是否满足难度对应的数值目标
+		if core.HashMeetsTarget(newBlock.Hash, difficulty) {
 			break
 		}
 		newBlock.Nonce++
@@ -163,10 +192,11 @@ func MineBlock(transactions []Transaction, prev Block) Block {
 // ===== Chain operations 区块链操作函数 =====
 
 // InitGenesis 初始化创世区块
+// 创世区块使用固定时间戳，独立启动的节点得到相同的创世区块，才能接受彼此的链
 func InitGenesis() {
 	genesis := Block{
 		Index:        0,
-		Timestamp:    time.Now().Unix(),
+		Timestamp:    core.GenesisTimestamp,
 		Transactions: []Transaction{},
 		PrevHash:     "0",
 		Nonce:        0,
@@ -189,45 +219,130 @@ func AddBlock(b Block) bool {
 	if CalculateHash(b) != b.Hash {
 		return false
 	}
-	// 验证工作量证明是否有效
-	if !strings.HasPrefix(b.Hash, strings.Repeat("0", difficulty)) {
+	// 验证工作量证明是否有效（数值目标）
+	if !core.HashMeetsTarget(b.Hash, difficulty) {
 		return false
 	}
 	blockchain = append(blockchain, b)
 	return true
 }
 
+var (
+	// ErrChainNotLonger 候选链不比本地链长
+	ErrChainNotLonger = core.ErrChainNotLonger
+	// ErrGenesisMismatch 候选链的创世区块与本地链不同
+	ErrGenesisMismatch = core.ErrGenesisMismatch
+)
+
+// ValidateChain 校验候选链，规则由gossip/core.ValidateLinks实现，与gossip/core.ReplaceChain一致：
+// 创世区块与本地链相同，之后每个区块的索引连续、PrevHash等于前一区块的Hash、
+// Hash与CalculateHash的结果一致且满足PoW难度
+func ValidateChain(chain []Block) error {
+	chainMutex.Lock()
+	genesis := blockchain[0]
+	chainMutex.Unlock()
+	return core.ValidateLinks(genesis.Hash, chain, difficulty, chainLink)
+}
+
+// chainLink 返回区块参与链接校验的字段
+func chainLink(b Block) core.ChainLink {
+	return core.ChainLink{Index: b.Index, PrevHash: b.PrevHash, Hash: b.Hash, Computed: CalculateHash(b)}
+}
+
 // ReplaceChain 替换本地区块链（当收到更长的有效链时）
-func ReplaceChain(newChain []Block) {
+// 只有当新链比当前链更长且通过ValidateChain的校验时才替换，否则记录日志并返回拒绝原因
+func ReplaceChain(newChain []Block) error {
 	chainMutex.Lock()
 	defer chainMutex.Unlock()
-	// 只有当新链比当前链更长时才替换
-	if len(newChain) > len(blockchain) {
-		blockchain = newChain
-		log.Println("Replaced chain with longer chain length:", len(blockchain))
+	if len(newChain) <= len(blockchain) {
+		return ErrChainNotLonger
+	}
+	if err := core.ValidateLinks(blockchain[0].Hash, newChain, difficulty, chainLink); err != nil {
+		log.Println("Rejected invalid chain from peer:", err)
+		return err
 	}
+	blockchain = newChain
+	log.Println("Replaced chain with longer chain length:", len(blockchain))
+	return nil
 }
 
-// ===== tx pool handling 交易池处理函数 =====
+// ===== core.Chain implementation core.Chain接口实现 =====
 
-// handleTx 处理接收到的交易
-func handleTx(tx Transaction) {
+// nodeChain 基于全局区块链和交易池实现core.Chain，消息处理和挖矿都通过localChain操作区块链，
+// 与gossip节点和internal/blockchain遵循同一套接口语义（可用chaintest一致性测试验证）
+type nodeChain struct{}
+
+var _ core.Chain[Block, Transaction] = nodeChain{}
+
+// localChain 本节点使用的区块链
+var localChain core.Chain[Block, Transaction] = nodeChain{}
+
+// AddBlock 校验并追加扩展链顶的区块，区块中的交易随之离开交易池
+func (nodeChain) AddBlock(b Block) bool {
+	if !AddBlock(b) {
+		return false
+	}
+	// 移除已被包含在区块中的交易
+	removeTxs(b.Transactions)
+	return true
+}
+
+// Tip 返回当前链顶区块
+func (nodeChain) Tip() Block {
+	chainMutex.Lock()
+	defer chainMutex.Unlock()
+	return blockchain[len(blockchain)-1]
+}
+
+// Height 返回链顶区块的高度，只有创世区块时为0
+func (nodeChain) Height() int {
+	chainMutex.Lock()
+	defer chainMutex.Unlock()
+	return len(blockchain) - 1
+}
+
+// ReplaceChain 按ReplaceChain的规则用更长的有效链替换本地链
+func (nodeChain) ReplaceChain(newChain []Block) error {
+	return ReplaceChain(newChain)
+}
+
+// AddTransaction 校验交易签名并加入交易池，签名无效或已在池中时返回false
+func (nodeChain) AddTransaction(tx Transaction) bool {
 	// 首先验证交易签名
 	if !VerifyTransaction(tx) {
 		log.Println("Invalid tx signature")
-		return
+		return false
 	}
 	txPoolMutex.Lock()
 	defer txPoolMutex.Unlock()
-	// 检查交易是否已在交易池中（通过签名去重）
+	// 检查交易是否已在交易池中（按TxID去重）
+	id := TxID(tx)
 	for _, t := range txPool {
-		if t.Signature == tx.Signature {
-			return
+		if TxID(t) == id {
+			return false
 		}
 	}
 	// 将交易添加到交易池
 	txPool = append(txPool, tx)
 	log.Println("Accepted tx into pool. Pool size:", len(txPool))
+	return true
+}
+
+// Mempool 返回交易池的副本，按加入顺序排列
+func (nodeChain) Mempool() []Transaction {
+	txPoolMutex.Lock()
+	defer txPoolMutex.Unlock()
+	return append([]Transaction(nil), txPool...)
+}
+
+// ===== tx pool handling 交易池处理函数 =====
+
+// handleTx 处理接收到的交易
+func handleTx(tx Transaction) {
+	// 签名无效或已在交易池中的交易不再广播
+	if !localChain.AddTransaction(tx) {
+		return
+	}
 	// 广播该交易给其他节点
 	broadcastMessage(Message{Type: "TX", Data: mustMarshal(tx)})
 }
@@ -241,7 +356,7 @@ func removeTxs(txs []Transaction) {
 	for _, p := range txPool {
 		found := false
 		for _, t := range txs {
-			if p.Signature == t.Signature {
+			if TxID(p) == TxID(t) {
 				found = true
 				break
 			}
@@ -261,22 +376,15 @@ func mustMarshal(v interface{}) json.RawMessage {
 	return json.RawMessage(b)
 }
 
-// sendToPeer 向指定节点发送消息
-func sendToPeer(pid peer.ID, msg Message) error {
-	// 创建带超时的上下文
-	ctx, cancel := context.WithTimeout(ctxWithCancel(), 6*time.Second)
-	defer cancel()
-	// 创建到目标节点的新流
-	s, err := h.NewStream(ctx, pid, ProtocolID)
-	if err != nil {
-		return err
-	}
-	defer s.Close()
-	// 序列化消息并发送
-	out, _ := json.Marshal(msg)
-	out = append(out, '\n')
-	_, err = s.Write(out)
-	return err
+// SendTimeout 单次向节点发送消息的超时时间
+const SendTimeout = 6 * time.Second
+
+// sendToPeer 向指定节点发送消息，复用peerStreams中到该节点的流
+// parent 通常为节点根上下文；每次调用在其上派生独立的超时，parent取消（节点关闭）时发送随之中止
+func sendToPeer(parent context.Context, pid peer.ID, msg Message) error {
+	sendCtx, sendCancel := context.WithTimeout(parent, SendTimeout)
+	defer sendCancel()
+	return peerStreams.send(sendCtx, pid, msg)
 }
 
 // broadcastMessage 向所有已知节点广播消息
@@ -292,7 +400,7 @@ func broadcastMessage(msg Message) {
 	// 并发向每个节点发送消息
 	for _, pid := range peers {
 		go func(p peer.ID) {
-			if err := sendToPeer(p, msg); err != nil {
+			if err := sendToPeer(rootCtx, p, msg); err != nil {
 				// 如果某些节点发送失败可以接受，不影响整体
 			}
 		}(pid)
@@ -324,7 +432,7 @@ func requestChainsFromPeers() {
 	// 并发向每个节点发送GETCHAIN请求
 	for _, pid := range peers {
 		go func(p peer.ID) {
-			_ = sendToPeer(p, Message{Type: "GETCHAIN", Data: nil})
+			_ = sendToPeer(rootCtx, p, Message{Type: "GETCHAIN", Data: nil})
 		}(pid)
 	}
 }
@@ -333,62 +441,65 @@ func requestChainsFromPeers() {
 
 // setStreamHandler 设置协议流处理器
 func setStreamHandler() {
-	h.SetStreamHandler(ProtocolID, func(s network.Stream) {
-		defer s.Close()
-		// 获取远程节点ID并添加到已知节点列表
-		remote := s.Conn().RemotePeer()
-		addKnownPeer(remote)
-		// 创建读取器来读取流数据
-		r := bufio.NewReader(s)
-		// 循环读取消息
-		for {
-			raw, err := r.ReadBytes('\n')
-			if err != nil {
-				if err != io.EOF {
-					// log.Println("stream read err:", err)
-				}
-				return
+	h.SetStreamHandler(ProtocolID, serveStream)
+}
+
+// serveStream 循环读取流上的消息并处理，直到流关闭
+// 对端发起的入站流和peerStreams中的出站流共用此处理逻辑，GETCHAIN的响应写回同一条流
+func serveStream(s network.Stream) {
+	defer s.Close()
+	// 获取远程节点ID并添加到已知节点列表
+	remote := s.Conn().RemotePeer()
+	addKnownPeer(remote)
+	// 创建读取器来读取流数据
+	r := bufio.NewReader(s)
+	// 循环读取消息
+	for {
+		raw, err := r.ReadBytes('\n')
+		if err != nil {
+			if err != io.EOF {
+				// log.Println("stream read err:", err)
 			}
-			// 解析消息
-			var msg Message
-			if err := json.Unmarshal(raw, &msg); err != nil {
-				log.Println("invalid message:", err)
-				continue
+			return
+		}
+		// 解析消息
+		var msg Message
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			log.Println("invalid message:", err)
+			continue
+		}
+		// 根据消息类型处理不同逻辑
+		switch msg.Type {
+		case "TX":
+			// 处理交易消息
+			var tx Transaction
+			if err := json.Unmarshal(msg.Data, &tx); err == nil {
+				handleTx(tx)
 			}
-			// 根据消息类型处理不同逻辑
-			switch msg.Type {
-			case "TX":
-				// 处理交易消息
-				var tx Transaction
-				if err := json.Unmarshal(msg.Data, &tx); err == nil {
-					handleTx(tx)
+		case "BLOCK":
+			// 处理区块消息
+			var b Block
+			if err := json.Unmarshal(msg.Data, &b); err == nil {
+				// 已被包含在区块中的交易随之离开交易池
+				if localChain.AddBlock(b) {
+					log.Println("Added block from peer:", b.Index)
+				} else {
+					log.Println("Received invalid block")
 				}
-			case "BLOCK":
-				// 处理区块消息
-				var b Block
-				if err := json.Unmarshal(msg.Data, &b); err == nil {
-					if AddBlock(b) {
-						log.Println("Added block from peer:", b.Index)
-						// 移除已被包含在区块中的交易
-						removeTxs(b.Transactions)
-					} else {
-						log.Println("Received invalid block")
-					}
-				}
-			case "GETCHAIN":
-				// 处理获取区块链请求
-				sendChainToStreamWriter(s)
-			case "CHAIN":
-				// 处理区块链数据
-				var chain []Block
-				if err := json.Unmarshal(msg.Data, &chain); err == nil {
-					ReplaceChain(chain)
-				}
-			default:
-				// 忽略未知类型的消息
 			}
+		case "GETCHAIN":
+			// 处理获取区块链请求
+			sendChainToStreamWriter(s)
+		case "CHAIN":
+			// 处理区块链数据
+			var chain []Block
+			if err := json.Unmarshal(msg.Data, &chain); err == nil {
+				localChain.ReplaceChain(chain)
+			}
+		default:
+			// 忽略未知类型的消息
 		}
-	})
+	}
 }
 
 // ===== known peers helpers 已知节点辅助函数 =====
@@ -446,44 +557,28 @@ func setupMdns() {
 // mineRoutine 挖矿协程函数
 func mineRoutine(priv *ecdsa.PrivateKey) {
 	for {
-		txPoolMutex.Lock()
+		// 获取交易池中的所有交易副本
+		txs := localChain.Mempool()
 		// 如果交易池为空，则等待
-		if len(txPool) == 0 {
-			txPoolMutex.Unlock()
+		if len(txs) == 0 {
 			time.Sleep(2 * time.Second)
 			continue
 		}
-		// 获取交易池中的所有交易副本
-		txs := make([]Transaction, len(txPool))
-		copy(txs, txPool)
-		txPoolMutex.Unlock()
-
-		chainMutex.Lock()
 		// 获取最新的区块作为前一个区块
-		last := blockchain[len(blockchain)-1]
-		chainMutex.Unlock()
+		last := localChain.Tip()
 
 		log.Println("Start mining block with", len(txs), "txs...")
 		// 开始挖矿（工作量证明）
 		newB := MineBlock(txs, last)
-		// 如果成功添加新区块
-		if AddBlock(newB) {
+		// 如果成功添加新区块，已被包含在区块中的交易随之离开交易池
+		if localChain.AddBlock(newB) {
 			log.Println("Mined new block:", newB.Index, newB.Hash[:10])
-			// 从交易池中移除已被包含在区块中的交易
-			removeTxs(txs)
 			// 广播新区块给其他节点
 			broadcastMessage(Message{Type: "BLOCK", Data: mustMarshal(newB)})
 		}
 	}
 }
 
-// ===== helper to create a context accessible to sendToPeer 创建可被sendToPeer访问的上下文的辅助函数 =====
-
-// ctxWithCancel 返回全局上下文
-func ctxWithCancel() context.Context {
-	return ctx
-}
-
 // ===== CLI helpers CLI辅助函数 =====
 
 // printChain 打印当前区块链信息
@@ -527,9 +622,9 @@ func printPeers() {
 
 // ===== main 主函数 =====
 func main() {
-	// 初始化上下文
-	ctx, cancel = context.WithCancel(context.Background())
-	defer cancel()
+	// 初始化根上下文，退出时取消所有进行中的发送
+	rootCtx, rootCancel = context.WithCancel(context.Background())
+	defer rootCancel()
 
 	// 检查命令行参数
 	if len(os.Args) < 2 {
@@ -575,8 +670,10 @@ func main() {
 		fmt.Println("  ", a.String()+"/p2p/"+h.ID().String())
 	}
 
-	// 设置协议流处理器
+	// 设置协议流处理器和出站流池
 	setStreamHandler()
+	peerStreams = newStreamPool(h, ProtocolID, MaxStreamsPerPeer, StreamIdleTimeout, serveStream)
+	go peerStreams.run(rootCtx)
 
 	setupMdns()
 
@@ -608,7 +705,11 @@ func main() {
 				continue
 			}
 			to := parts[1]
-			amt, _ := strconv.Atoi(parts[2])
+			amt, err := core.ParseAmount(parts[2])
+			if err != nil {
+				fmt.Println("invalid amount:", err)
+				continue
+			}
 			tx := Transaction{From: pubAddr, To: to, Amount: amt}
 			sig, err := SignTransaction(priv, tx)
 			if err != nil {
@@ -623,12 +724,11 @@ func main() {
 			printChain()
 		case "pool":
 			// 显示交易池命令
-			txPoolMutex.Lock()
-			fmt.Println("Pending txs:", len(txPool))
-			for i, t := range txPool {
+			pool := localChain.Mempool()
+			fmt.Println("Pending txs:", len(pool))
+			for i, t := range pool {
 				fmt.Printf("%d: %s -> %s : %d sig:%s\n", i, shorten(t.From, 10), shorten(t.To, 10), t.Amount, shorten(t.Signature, 10))
 			}
-			txPoolMutex.Unlock()
 		case "peers":
 			// 显示节点信息命令
 			printPeers()
@@ -639,18 +739,14 @@ func main() {
 				continue
 			}
 			addrStr := parts[1]
-			maddr, err := ma.NewMultiaddr(addrStr)
-			if err != nil {
-				fmt.Println("invalid multiaddr:", err)
-				continue
-			}
-			info, err := peer.AddrInfoFromP2pAddr(maddr)
+			ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
+			info, err := resolvePeerAddr(ctx2, addrStr)
 			if err != nil {
-				fmt.Println("invalid peer addr info:", err)
+				fmt.Println("invalid peer addr:", err)
+				cancel2()
 				continue
 			}
 			h.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.TempAddrTTL)
-			ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
 			if err := h.Connect(ctx2, *info); err != nil {
 				fmt.Println("connect failed:", err)
 			} else {
@@ -666,3 +762,45 @@ func main() {
 		}
 	}
 }
+
+// resolvePeerAddr 解析multiaddr字符串为peer地址信息
+// 支持 /dns4、/dns6、/dnsaddr 主机名地址，解析后只保留属于该peer的IP地址
+func resolvePeerAddr(ctx context.Context, addrStr string) (*peer.AddrInfo, error) {
+	maddr, err := ma.NewMultiaddr(addrStr)
+	if err != nil {
+		return nil, err
+	}
+	info, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []ma.Multiaddr
+	for _, a := range info.Addrs {
+		// 最多解析几层（/dnsaddr 可能指向另一个 /dnsaddr）
+		pending := []ma.Multiaddr{a}
+		for depth := 0; len(pending) > 0 && depth < 4; depth++ {
+			var next []ma.Multiaddr
+			for _, p := range pending {
+				if !madns.Matches(p) {
+					addrs = append(addrs, p)
+					continue
+				}
+				resolved, err := madns.Resolve(ctx, p)
+				if err != nil {
+					return nil, err
+				}
+				for _, r := range resolved {
+					transport, id := peer.SplitAddr(r)
+					if transport != nil && (id == "" || id == info.ID) {
+						next = append(next, transport)
+					}
+				}
+			}
+			pending = next
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses resolved for %s", addrStr)
+	}
+	return &peer.AddrInfo{ID: info.ID, Addrs: addrs}, nil
+}
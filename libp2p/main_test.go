@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mini_chain/gossip/core"
+	"mini_chain/gossip/core/chaintest"
+
+	"github.com/libp2p/go-libp2p"
+	network "github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+)
+
+func TestSendToPeerRespectsCancelledParent(t *testing.T) {
+	var err error
+	if h, err = libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0")); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	target, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	h.Peerstore().AddAddrs(target.ID(), target.Addrs(), peerstore.TempAddrTTL)
+	peerStreams = newStreamPool(h, ProtocolID, 0, 0, serveStream)
+
+	parent, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = sendToPeer(parent, target.ID(), Message{Type: "GETCHAIN"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSendToPeerReusesStream(t *testing.T) {
+	var err error
+	if h, err = libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0")); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	target, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	h.Peerstore().AddAddrs(target.ID(), target.Addrs(), peerstore.TempAddrTTL)
+	peerStreams = newStreamPool(h, ProtocolID, 0, 0, serveStream)
+
+	// 目标节点统计入站流数量和收到的消息
+	var mu sync.Mutex
+	streams, received := 0, 0
+	target.SetStreamHandler(ProtocolID, func(s network.Stream) {
+		defer s.Close()
+		mu.Lock()
+		streams++
+		mu.Unlock()
+		r := bufio.NewReader(s)
+		for {
+			if _, err := r.ReadBytes('\n'); err != nil {
+				return
+			}
+			mu.Lock()
+			received++
+			mu.Unlock()
+		}
+	})
+
+	const sends = 5
+	for i := 0; i < sends; i++ {
+		if err := sendToPeer(context.Background(), target.ID(), Message{Type: "TX"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n, got := streams, received
+		mu.Unlock()
+		if got == sends {
+			if n != 1 {
+				t.Fatalf("%d messages arrived on %d streams, want 1 stream", got, n)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d of %d messages", got, sends)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := peerStreams.Opened(); n != 1 {
+		t.Fatalf("%d sends opened %d streams, want 1", sends, n)
+	}
+
+	// 空闲超时后流被关闭，下一次发送新建流
+	if n := peerStreams.closeIdle(time.Now().Add(StreamIdleTimeout)); n != 1 {
+		t.Fatalf("closeIdle closed %d streams, want 1", n)
+	}
+	if err := sendToPeer(context.Background(), target.ID(), Message{Type: "TX"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := peerStreams.Opened(); n != 2 {
+		t.Fatalf("send after idle close opened %d streams in total, want 2", n)
+	}
+}
+
+func buildChain(t *testing.T, n int) []Block {
+	t.Helper()
+	old := difficulty
+	difficulty = 1
+	t.Cleanup(func() { difficulty = old })
+	InitGenesis()
+	chain := []Block{blockchain[0]}
+	for i := 0; i < n; i++ {
+		chain = append(chain, MineBlock([]Transaction{{From: "a", To: "b", Amount: i + 1}}, chain[len(chain)-1]))
+	}
+	return chain
+}
+
+func TestReplaceChainValidLongerChain(t *testing.T) {
+	chain := buildChain(t, 3)
+	if err := ValidateChain(chain); err != nil {
+		t.Fatalf("ValidateChain: %v", err)
+	}
+	if err := ReplaceChain(chain); err != nil {
+		t.Fatalf("ReplaceChain: %v", err)
+	}
+	if len(blockchain) != 4 {
+		t.Fatalf("chain length %d after replace, want 4", len(blockchain))
+	}
+	if err := ReplaceChain(chain[:2]); !errors.Is(err, ErrChainNotLonger) {
+		t.Fatalf("shorter chain: expected %v, got %v", ErrChainNotLonger, err)
+	}
+}
+
+func TestReplaceChainRejectsTamperedBlock(t *testing.T) {
+	chain := buildChain(t, 3)
+	chain[2].Transactions[0].Amount = 1000 // 篡改中间区块的交易，哈希不再匹配
+	if err := ValidateChain(chain); err == nil {
+		t.Fatal("tampered chain passed validation")
+	}
+	if err := ReplaceChain(chain); err == nil || len(blockchain) != 1 {
+		t.Fatalf("tampered chain replaced local chain (err %v, length %d)", err, len(blockchain))
+	}
+
+	// 重新计算哈希但不重新挖矿，PoW不再成立（或后续区块的链接断开）
+	chain[2].Hash = CalculateHash(chain[2])
+	if err := ReplaceChain(chain); err == nil || len(blockchain) != 1 {
+		t.Fatalf("rehashed chain replaced local chain (err %v, length %d)", err, len(blockchain))
+	}
+}
+
+func TestReplaceChainRejectsBrokenLink(t *testing.T) {
+	chain := buildChain(t, 3)
+	// 第2个区块自身有效，但不指向第1个区块
+	chain[2] = MineBlock(chain[2].Transactions, Block{Index: 1, Hash: "deadbeef"})
+	if err := ReplaceChain(chain); err == nil || len(blockchain) != 1 {
+		t.Fatalf("chain with broken link replaced local chain (err %v, length %d)", err, len(blockchain))
+	}
+
+	other := buildChain(t, 3)
+	// 本地换成另一个创世区块
+	InitGenesis()
+	blockchain[0].Timestamp--
+	blockchain[0].Hash = CalculateHash(blockchain[0])
+	if err := ReplaceChain(other); !errors.Is(err, ErrGenesisMismatch) {
+		t.Fatalf("foreign genesis: expected %v, got %v", ErrGenesisMismatch, err)
+	}
+}
+
+func TestChainConformance(t *testing.T) {
+	old := difficulty
+	difficulty = 1
+	t.Cleanup(func() { difficulty = old })
+	chaintest.Run(t, chaintest.Harness[Block, Transaction]{
+		New: func(t *testing.T) core.Chain[Block, Transaction] {
+			InitGenesis()
+			txPool = nil
+			return localChain
+		},
+		Mine: func(t *testing.T, prev Block, txs []Transaction) Block {
+			return MineBlock(txs, prev)
+		},
+		NewTx: func(t *testing.T) Transaction {
+			priv, addr := NewKeyPair()
+			_, to := NewKeyPair()
+			tx := Transaction{From: addr, To: to, Amount: 1}
+			sig, err := SignTransaction(priv, tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx.Signature = sig
+			return tx
+		},
+		Blocks: func(c core.Chain[Block, Transaction]) []Block {
+			chainMutex.Lock()
+			defer chainMutex.Unlock()
+			return append([]Block(nil), blockchain...)
+		},
+		Hash:   func(b Block) string { return b.Hash },
+		Tamper: func(b Block) Block { b.Nonce++; return b },
+	})
+}
+
+// helperNodeEnv 设置时TestHelperNode作为独立进程中的远端节点运行，值为本地节点创建创世区块时的Unix秒数
+const helperNodeEnv = "MINICHAIN_TEST_HELPER_NODE"
+
+// TestHelperNode 不是独立的测试：由TestSyncIndependentNodes在子进程中启动，
+// 在晚于本地节点的另一秒创建自己的创世区块，挖出3个区块后通过libp2p主机提供链数据
+func TestHelperNode(t *testing.T) {
+	v := os.Getenv(helperNodeEnv)
+	if v == "" {
+		t.Skip("only runs as a helper process")
+	}
+	since, _ := strconv.ParseInt(v, 10, 64)
+	for time.Now().Unix() <= since {
+		time.Sleep(20 * time.Millisecond)
+	}
+	difficulty = 1
+	InitGenesis()
+	for i := 0; i < 3; i++ {
+		AddBlock(MineBlock([]Transaction{{From: "a", To: "b", Amount: i + 1}}, blockchain[len(blockchain)-1]))
+	}
+	var err error
+	if h, err = libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0")); err != nil {
+		t.Fatal(err)
+	}
+	setStreamHandler()
+	fmt.Printf("addr: %s/p2p/%s\n", h.Addrs()[0], h.ID())
+	select {}
+}
+
+func TestSyncIndependentNodes(t *testing.T) {
+	old := difficulty
+	difficulty = 1
+	t.Cleanup(func() { difficulty = old })
+	InitGenesis()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperNode$")
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", helperNodeEnv, time.Now().Unix()))
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill(); cmd.Wait() })
+	var addr string
+	for scanner := bufio.NewScanner(out); scanner.Scan(); {
+		if a, ok := strings.CutPrefix(scanner.Text(), "addr: "); ok {
+			addr = a
+			break
+		}
+	}
+	info, err := peer.AddrInfoFromString(addr)
+	if err != nil {
+		t.Fatalf("helper node address %q: %v", addr, err)
+	}
+
+	if h, err = libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0")); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if rootCtx == nil {
+		rootCtx = context.Background()
+	}
+	peerStreams = newStreamPool(h, ProtocolID, 0, 0, serveStream)
+	h.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.TempAddrTTL)
+	addKnownPeer(info.ID)
+	defer removeKnownPeer(info.ID)
+
+	requestChainsFromPeers()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		chainMutex.Lock()
+		n := len(blockchain)
+		chainMutex.Unlock()
+		if n == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("local chain length %d after sync, want 4", n)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
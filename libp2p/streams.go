@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	network "github.com/libp2p/go-libp2p/core/network"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// 出站流池参数
+const (
+	MaxStreamsPerPeer = 2                // 每个节点保留的空闲流数量上限
+	StreamIdleTimeout = 30 * time.Second // 空闲超过该时间的流被关闭
+)
+
+// outStream 池中的一条出站流
+type outStream struct {
+	s    network.Stream
+	idle time.Time     // 归还到池中的时间
+	done chan struct{} // 流上的读取协程退出（流已失效）时关闭
+}
+
+// alive 判断流的读取协程是否仍在运行
+func (o *outStream) alive() bool {
+	select {
+	case <-o.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// streamPool 按节点缓存到同一协议的出站流，发送消息时复用而不是每次新建
+// 消息以换行分隔的JSON编码，对端在同一条流上循环读取；
+// 每条新建的流都启动一个读取协程用serve处理对端写回的消息（如GETCHAIN的CHAIN响应）
+type streamPool struct {
+	host    host.Host
+	proto   protocol.ID
+	max     int
+	timeout time.Duration
+	serve   func(network.Stream)
+
+	mu     sync.Mutex
+	idle   map[peer.ID][]*outStream
+	opened atomic.Uint64 // 累计新建的流数量
+}
+
+// newStreamPool 创建出站流池，max和timeout小于1时使用默认值
+func newStreamPool(h host.Host, proto protocol.ID, max int, timeout time.Duration, serve func(network.Stream)) *streamPool {
+	if max < 1 {
+		max = MaxStreamsPerPeer
+	}
+	if timeout <= 0 {
+		timeout = StreamIdleTimeout
+	}
+	return &streamPool{host: h, proto: proto, max: max, timeout: timeout, serve: serve, idle: make(map[peer.ID][]*outStream)}
+}
+
+// send 在到pid的流上写入一条消息，写入受ctx的截止时间和取消约束
+// 复用的流可能已被对端关闭，此时丢弃它并换一条流重试；新建的流失败时直接返回错误
+func (p *streamPool) send(ctx context.Context, pid peer.ID, msg Message) error {
+	out, _ := json.Marshal(msg)
+	out = append(out, '\n')
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		o, reused, err := p.get(ctx, pid)
+		if err != nil {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			o.s.SetWriteDeadline(deadline)
+		}
+		if _, err = o.s.Write(out); err == nil {
+			o.s.SetWriteDeadline(time.Time{})
+			p.put(pid, o)
+			return nil
+		}
+		o.s.Reset()
+		if !reused {
+			return err
+		}
+	}
+}
+
+// get 取出到pid的一条可用空闲流（最近归还的优先），没有时新建；reused表示流来自池
+func (p *streamPool) get(ctx context.Context, pid peer.ID) (o *outStream, reused bool, err error) {
+	p.mu.Lock()
+	for list := p.idle[pid]; len(list) > 0; list = p.idle[pid] {
+		o = list[len(list)-1]
+		p.idle[pid] = list[:len(list)-1]
+		if o.alive() && time.Since(o.idle) < p.timeout {
+			p.mu.Unlock()
+			return o, true, nil
+		}
+		o.s.Close()
+	}
+	delete(p.idle, pid)
+	p.mu.Unlock()
+
+	s, err := p.host.NewStream(ctx, pid, p.proto)
+	if err != nil {
+		return nil, false, err
+	}
+	p.opened.Add(1)
+	o = &outStream{s: s, done: make(chan struct{})}
+	go func() {
+		defer close(o.done)
+		p.serve(s)
+	}()
+	return o, false, nil
+}
+
+// put 归还写入成功的流，池中该节点的空闲流已满时关闭它
+func (p *streamPool) put(pid peer.ID, o *outStream) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.idle[pid]) >= p.max {
+		o.s.Close()
+		return
+	}
+	o.idle = time.Now()
+	p.idle[pid] = append(p.idle[pid], o)
+}
+
+// closeIdle 关闭在now之前已空闲超过timeout或已失效的流，返回关闭的数量
+func (p *streamPool) closeIdle(now time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for pid, list := range p.idle {
+		kept := list[:0]
+		for _, o := range list {
+			if !o.alive() || now.Sub(o.idle) >= p.timeout {
+				o.s.Close()
+				n++
+				continue
+			}
+			kept = append(kept, o)
+		}
+		if len(kept) == 0 {
+			delete(p.idle, pid)
+		} else {
+			p.idle[pid] = kept
+		}
+	}
+	return n
+}
+
+// run 定期关闭空闲超时的流，ctx取消时关闭池中全部流
+func (p *streamPool) run(ctx context.Context) {
+	ticker := time.NewTicker(p.timeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			p.closeIdle(time.Now().Add(p.timeout))
+			return
+		case now := <-ticker.C:
+			p.closeIdle(now)
+		}
+	}
+}
+
+// Opened 返回累计新建的流数量
+func (p *streamPool) Opened() uint64 {
+	return p.opened.Load()
+}
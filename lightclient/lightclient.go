@@ -0,0 +1,58 @@
+// Package lightclient 提供不运行完整节点即可校验区块头链和交易包含证明的轻客户端函数
+// 只依赖规范的区块头哈希和Merkle计算，不访问UTXO集合、内存池或网络
+package lightclient
+
+import (
+	"errors"
+	"fmt"
+
+	"mini_chain/internal/blockchain"
+)
+
+// 类型别名，外部使用者无需直接引用内部包
+type BlockHeader = blockchain.BlockHeader
+
+// VerifyHeaderChain 校验一段连续的区块头链
+// 要求高度连续、哈希链接正确、哈希可由头部字段重算，且非创世区块满足其声明难度的PoW
+// 区块头链不必从创世区块开始
+func VerifyHeaderChain(headers []BlockHeader) error {
+	if len(headers) == 0 {
+		return errors.New("empty header chain")
+	}
+	for i, h := range headers {
+		if blockchain.HeaderHash(h) != h.Hash {
+			return fmt.Errorf("header %d: hash mismatch", h.Index)
+		}
+		if h.Index > 0 {
+			if h.Difficulty < 1 {
+				return fmt.Errorf("header %d: invalid difficulty %d", h.Index, h.Difficulty)
+			}
+			if !blockchain.HeaderMeetsTarget(h) {
+				return fmt.Errorf("header %d: PoW invalid", h.Index)
+			}
+		}
+		if i == 0 {
+			continue
+		}
+		prev := headers[i-1]
+		if h.Index != prev.Index+1 {
+			return fmt.Errorf("header %d: unexpected index after %d", h.Index, prev.Index)
+		}
+		if h.PrevHash != prev.Hash {
+			return fmt.Errorf("header %d: broken link", h.Index)
+		}
+	}
+	return nil
+}
+
+// VerifyTxInclusion 校验交易txid通过Merkle证明（由blockchain.MerkleProof生成）被包含在区块头对应的区块中
+// 调用者应先用VerifyHeaderChain确认区块头本身可信
+func VerifyTxInclusion(header BlockHeader, proof []string, txid string) error {
+	if blockchain.HeaderHash(header) != header.Hash {
+		return fmt.Errorf("header %d: hash mismatch", header.Index)
+	}
+	if !blockchain.VerifyMerkleProof(header.MerkleRoot, txid, proof) {
+		return fmt.Errorf("tx %s not included in block %d", txid, header.Index)
+	}
+	return nil
+}
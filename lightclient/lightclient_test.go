@@ -0,0 +1,112 @@
+package lightclient
+
+import (
+	"fmt"
+	"testing"
+
+	"mini_chain/internal/blockchain"
+)
+
+// buildChain 挖出n个区块（每个区块包含txsPerBlock笔交易），返回完整区块列表
+// 每个区块的第一笔交易为coinbase，之后每笔花费前一笔的输出
+func buildChain(t *testing.T, n, txsPerBlock int) []blockchain.Block {
+	t.Helper()
+	bc := blockchain.NewBlockchain(1, nil)
+	blocks := []blockchain.Block{bc.GetLatest()}
+	for i := 0; i < n; i++ {
+		txids := make([]string, txsPerBlock)
+		for j := range txids {
+			tx := blockchain.CoinbaseTx(fmt.Sprintf("lc-tx-%d", i), "lc-miner", 1)
+			if j > 0 {
+				tx = blockchain.UTXOTx{
+					Version: blockchain.TxVersion,
+					Inputs:  []blockchain.TxInput{{Txid: txids[j-1], Vout: 0}},
+					Outputs: []blockchain.TxOutput{{Address: "lc-miner", Amount: 1}},
+				}
+			}
+			txid, err := blockchain.PutTransaction(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txids[j] = txid
+		}
+		b := blockchain.MineBlock(bc.GetLatest(), txids, 1)
+		if err := bc.ValidateAndApplyBlock(b); err != nil {
+			t.Fatalf("apply block %d: %v", i, err)
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+func headersOf(blocks []blockchain.Block) []BlockHeader {
+	headers := make([]BlockHeader, len(blocks))
+	for i := range blocks {
+		headers[i] = blocks[i].Header()
+	}
+	return headers
+}
+
+func TestVerifyHeaderChain(t *testing.T) {
+	headers := headersOf(buildChain(t, 5, 1))
+	if err := VerifyHeaderChain(headers); err != nil {
+		t.Fatalf("valid chain rejected: %v", err)
+	}
+	// 不从创世区块开始的片段同样可以校验
+	if err := VerifyHeaderChain(headers[2:]); err != nil {
+		t.Fatalf("valid segment rejected: %v", err)
+	}
+}
+
+func TestVerifyHeaderChainRejectsTampered(t *testing.T) {
+	cases := map[string]func(h []BlockHeader){
+		"merkle root": func(h []BlockHeader) { h[3].MerkleRoot = "deadbeef" },
+		"broken link": func(h []BlockHeader) { h[3].PrevHash = h[1].Hash },
+		"gap":         func(h []BlockHeader) { copy(h[2:], h[3:]) },
+		"empty":       nil,
+	}
+	for name, tamper := range cases {
+		headers := headersOf(buildChain(t, 5, 1))
+		if tamper == nil {
+			headers = nil
+		} else {
+			tamper(headers)
+		}
+		if err := VerifyHeaderChain(headers); err == nil {
+			t.Errorf("%s: tampered chain accepted", name)
+		}
+	}
+}
+
+func TestVerifyTxInclusion(t *testing.T) {
+	blocks := buildChain(t, 1, 5)
+	b := blocks[1]
+	header := b.Header()
+
+	for i, txid := range b.Transactions {
+		proof, err := blockchain.MerkleProof(b.Transactions, txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyTxInclusion(header, proof, txid); err != nil {
+			t.Errorf("tx %d: valid proof rejected: %v", i, err)
+		}
+	}
+
+	proof, _ := blockchain.MerkleProof(b.Transactions, b.Transactions[2])
+	if err := VerifyTxInclusion(header, proof, "lc-tx-other"); err == nil {
+		t.Error("proof accepted for a txid not in the block")
+	}
+
+	tampered := append([]string(nil), proof...)
+	tampered[0] += "00"
+	if err := VerifyTxInclusion(header, tampered, b.Transactions[2]); err == nil {
+		t.Error("tampered proof accepted")
+	}
+
+	badHeader := header
+	badHeader.MerkleRoot = "00"
+	if err := VerifyTxInclusion(badHeader, proof, b.Transactions[2]); err == nil {
+		t.Error("proof accepted against a tampered header")
+	}
+}
@@ -2,99 +2,392 @@ package main
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"mini_chain/gossip/core"
 	"mini_chain/internal/api"
 	"mini_chain/internal/blockchain"
 	"mini_chain/internal/p2p"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/pnet"
 )
 
 func main() {
-	// 检查命令行参数
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run main.go <p2p_port> [api_port] [bootstrap_peers]")
-		fmt.Println("Example: go run main.go 3000 8080 /ip4/127.0.0.1/tcp/3001/p2p/QmPeerId")
-		os.Exit(1)
+	// 解析子命令；一次性命令（wallet / tx / signmsg / verifymsg / sync / demo）执行后直接退出
+	cmd, err := parseArgs(os.Args[1:], os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
 	}
+	if cmd.Run != nil {
+		if err := cmd.Run(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	p2pPort, apiPort, bootstrapPeers := cmd.Node.P2PPort, cmd.Node.APIPort, cmd.Node.BootstrapPeers
 
-	// 从命令行解析P2P端口
-	p2pPort, err := strconv.Atoi(os.Args[1])
+	// 启动自检：密钥、签名和挖矿有任何问题时立即退出
+	if err := blockchain.SelfTest(); err != nil {
+		log.Fatal(err)
+	}
+
+	// 收到SIGINT/SIGTERM时取消ctx，各后台协程随之退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// 1️⃣ 启动区块链：共识参数默认使用内置值（难度3），设置 MINICHAIN_CONSENSUS_PARAMS 时从该JSON文件读取，
+	// 参数无效或互相矛盾时拒绝启动
+	params := blockchain.DefaultConsensusParams()
+	if path := os.Getenv("MINICHAIN_CONSENSUS_PARAMS"); path != "" {
+		if params, err = blockchain.LoadConsensusParams(path); err != nil {
+			log.Fatal("Invalid MINICHAIN_CONSENSUS_PARAMS:", err)
+		}
+	}
+	// 设置 MINICHAIN_DATADIR 时主链区块及其交易体持久化到该目录下的BoltDB文件，
+	// 重启后从中恢复主链并重放重建UTXO集合；未设置时只保存在内存中
+	var store blockchain.BlockStore
+	if dir := os.Getenv("MINICHAIN_DATADIR"); dir != "" {
+		bolt, err := openBlockStore(dir)
+		if err != nil {
+			log.Fatal("Invalid MINICHAIN_DATADIR:", err)
+		}
+		defer bolt.Close()
+		store = bolt
+	}
+	bc, err := blockchain.NewBlockchainWithParams(params, store)
 	if err != nil {
-		log.Fatal("Invalid P2P port:", err)
+		log.Fatal("Cannot start blockchain:", err)
+	}
+	// 挖矿时写入coinbase交易的附加数据从环境变量读取（可选）
+	if data := os.Getenv("MINICHAIN_COINBASE_DATA"); data != "" {
+		if err := bc.SetCoinbaseData(data); err != nil {
+			log.Fatal("Invalid MINICHAIN_COINBASE_DATA:", err)
+		}
 	}
 
-	// 从命令行解析API端口（默认为8080）
-	apiPort := 8080
-	if len(os.Args) >= 3 {
-		apiPort, err = strconv.Atoi(os.Args[2])
+	// 单笔交易大小上限由 MINICHAIN_MAX_TX_SIZE 指定（字节，默认100KiB）
+	if v := os.Getenv("MINICHAIN_MAX_TX_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
 		if err != nil {
-			log.Fatal("Invalid API port:", err)
+			log.Fatal("Invalid MINICHAIN_MAX_TX_SIZE:", err)
 		}
+		bc.SetMaxTxSize(n)
 	}
 
-	// 解析引导节点（可选）
-	var bootstrapPeers []string
-	if len(os.Args) >= 4 {
-		bootstrapPeers = strings.Split(os.Args[3], ",")
+	// 内存池最多容纳的交易数由 MINICHAIN_MEMPOOL_MAX_SIZE 指定（默认10000），
+	// 等待超过 MINICHAIN_MEMPOOL_EXPIRY（如 "24h"，默认72小时）仍未打包的交易被定期清除
+	if v := os.Getenv("MINICHAIN_MEMPOOL_MAX_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatal("Invalid MINICHAIN_MEMPOOL_MAX_SIZE:", err)
+		}
+		blockchain.SetMempoolMaxSize(n)
 	}
+	mempoolExpiry := blockchain.DefaultMempoolExpiry
+	if v := os.Getenv("MINICHAIN_MEMPOOL_EXPIRY"); v != "" {
+		if mempoolExpiry, err = time.ParseDuration(v); err != nil || mempoolExpiry <= 0 {
+			log.Fatal("Invalid MINICHAIN_MEMPOOL_EXPIRY:", v)
+		}
+	}
+	blockchain.StartPruner(ctx, blockchain.DefaultMempoolPruneInterval, mempoolExpiry)
 
-	ctx := context.Background()
-	// 1️⃣ 启动区块链，默认难度为3
-	bc := blockchain.NewBlockchain(3)
+	// 每个区块最多打包的内存池交易数由 MINICHAIN_MAX_BLOCK_TXS 指定（默认不限制，只受区块大小约束）
+	if v := os.Getenv("MINICHAIN_MAX_BLOCK_TXS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatal("Invalid MINICHAIN_MAX_BLOCK_TXS:", err)
+		}
+		bc.SetMaxBlockTxs(n)
+	}
+
+	// 共识引擎从环境变量选择：MINICHAIN_CONSENSUS=pow（默认）或 poa
+	// PoA模式下 MINICHAIN_POA_SIGNERS 为逗号分隔的授权签名者公钥，MINICHAIN_POA_KEY 为本节点签名私钥（十六进制，可选）
+	signerKey, err := loadSignerKey(os.Getenv("MINICHAIN_POA_KEY"))
+	if err != nil {
+		log.Fatal("Invalid MINICHAIN_POA_KEY:", err)
+	}
+	var signers []string
+	if v := os.Getenv("MINICHAIN_POA_SIGNERS"); v != "" {
+		signers = strings.Split(v, ",")
+	}
+	consensus, err := blockchain.NewConsensus(os.Getenv("MINICHAIN_CONSENSUS"), signers, signerKey)
+	if err != nil {
+		log.Fatal("Invalid consensus config:", err)
+	}
+	bc.SetConsensus(consensus)
 
 	// 2️⃣ 启动libp2p节点，P2P端口通过命令行传值
-	node, err := p2p.NewNode(ctx, p2pPort)
+	// 监听的传输协议从环境变量读取（如 "tcp,quic"），未设置时只监听TCP
+	transports, err := p2p.ParseTransports(os.Getenv("MINICHAIN_TRANSPORTS"))
+	if err != nil {
+		log.Fatal("Invalid MINICHAIN_TRANSPORTS:", err)
+	}
+	// NAT后的节点通过 MINICHAIN_ANNOUNCE_ADDRS 指定对外通告的公网地址（逗号分隔的multiaddr）
+	announce, err := p2p.ParseAnnounceAddrs(os.Getenv("MINICHAIN_ANNOUNCE_ADDRS"))
+	if err != nil {
+		log.Fatal("Invalid MINICHAIN_ANNOUNCE_ADDRS:", err)
+	}
+	// 私有网络通过 MINICHAIN_PEER_ALLOWLIST 指定允许连接的节点ID（逗号分隔），未设置时不限制
+	allowlist, err := p2p.ParseAllowlist(os.Getenv("MINICHAIN_PEER_ALLOWLIST"))
+	if err != nil {
+		log.Fatal("Invalid MINICHAIN_PEER_ALLOWLIST:", err)
+	}
+	// MINICHAIN_SWARM_KEY 为私有网络预共享密钥文件（swarm.key格式），只有持有相同密钥的节点能互相连接
+	var psk pnet.PSK
+	if path := os.Getenv("MINICHAIN_SWARM_KEY"); path != "" {
+		if psk, err = p2p.LoadSwarmKey(path); err != nil {
+			log.Fatal("Invalid MINICHAIN_SWARM_KEY:", err)
+		}
+	}
+	node, err := p2p.NewNodeWithConfig(ctx, p2p.NodeConfig{
+		ListenPort:    p2pPort,
+		Transports:    transports,
+		AnnounceAddrs: announce,
+		Allowlist:     allowlist,
+		PSK:           psk,
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer node.Close()
+
+	// 对外提供链同步服务（区块头和区块体）
+	node.ServeSync(bc)
+
+	// 挖矿门槛：MINICHAIN_MIN_PEERS 为开始挖矿前需连接的最少peer数量，
+	// MINICHAIN_MINE_AFTER_SYNC=1 时还要求先从某个peer完成初始同步
+	minPeers := 0
+	if v := os.Getenv("MINICHAIN_MIN_PEERS"); v != "" {
+		if minPeers, err = strconv.Atoi(v); err != nil {
+			log.Fatal("Invalid MINICHAIN_MIN_PEERS:", err)
+		}
+	}
+	miningGate := p2p.NewMiningGate(node, minPeers, os.Getenv("MINICHAIN_MINE_AFTER_SYNC") == "1")
 
-	// 连接到引导节点（如果提供了的话）
+	// 连接到引导节点（如果提供了的话），连接成功后先同步区块头再下载区块体
 	for _, addr := range bootstrapPeers {
 		if err := node.ConnectPeer(addr); err != nil {
 			log.Printf("Failed to connect to bootstrap peer %s: %v", addr, err)
 		} else {
 			log.Printf("Connected to bootstrap peer: %s", addr)
+			if pi, err := peer.AddrInfoFromString(addr); err == nil && syncFromPeer(bc, node, pi.ID) {
+				miningGate.MarkSynced()
+			}
 		}
 	}
+	// 没有从引导节点完成同步时，等其他途径（如mDNS）连上peer后再同步
+	if miningGate.RequireSync && !miningGate.State().Synced {
+		go syncWhenConnected(ctx, bc, node, miningGate)
+	}
 
 	// 3️⃣ 启动REST + WebSocket API，API端口通过命令行传值
 	apiSrv := api.NewAPI(bc, node)
+	// 管理端点令牌从环境变量读取，未设置时管理端点禁用
+	apiSrv.AuthToken = os.Getenv("MINICHAIN_API_TOKEN")
+	// 测试端点仅在显式启用测试模式时开放
+	apiSrv.TestMode = os.Getenv("MINICHAIN_TEST_MODE") == "1"
+	// 默认为每个请求记录访问日志，MINICHAIN_ACCESS_LOG=0 时关闭
+	if os.Getenv("MINICHAIN_ACCESS_LOG") == "0" {
+		apiSrv.AccessLog = nil
+	}
+	apiSrv.MiningGate = miningGate
+	// 挖矿停滞超时由 MINICHAIN_STALL_TIMEOUT 指定（如 "5m"，默认10分钟）
+	watchdog := blockchain.NewMiningWatchdog(bc)
+	if v := os.Getenv("MINICHAIN_STALL_TIMEOUT"); v != "" {
+		if watchdog.Timeout, err = time.ParseDuration(v); err != nil {
+			log.Fatal("Invalid MINICHAIN_STALL_TIMEOUT:", err)
+		}
+	}
+	apiSrv.Watchdog = watchdog
+	// 设置 MINICHAIN_AUDIT_LOG 时将每笔被接受的交易（无论来自API还是gossip）追加写入该审计文件
+	if path := os.Getenv("MINICHAIN_AUDIT_LOG"); path != "" {
+		audit, err := blockchain.OpenAuditLog(path)
+		if err != nil {
+			log.Fatal("Failed to open audit log:", err)
+		}
+		defer audit.Close()
+		bc.OnTx(audit.LogEvent)
+	}
 	go apiSrv.Run(fmt.Sprintf(":%d", apiPort))
 
+	// 设置 MINICHAIN_SNAPSHOT_FILE 时定期把节点状态追加写入该文件，
+	// 间隔由 MINICHAIN_SNAPSHOT_INTERVAL 指定（如 "30s"，默认1分钟）
+	if path := os.Getenv("MINICHAIN_SNAPSHOT_FILE"); path != "" {
+		snapshotter := api.NewSnapshotter(apiSrv, path)
+		if v := os.Getenv("MINICHAIN_SNAPSHOT_INTERVAL"); v != "" {
+			if snapshotter.Interval, err = time.ParseDuration(v); err != nil {
+				log.Fatal("Invalid MINICHAIN_SNAPSHOT_INTERVAL:", err)
+			}
+		}
+		go snapshotter.Run(ctx)
+	}
+
 	// 打印节点信息
 	fmt.Printf("Node ID: %s\n", node.Host.ID().String())
-	for _, addr := range node.Host.Addrs() {
-		fmt.Printf("Node address: %s/p2p/%s\n", addr.String(), node.Host.ID().String())
+	for _, addr := range node.ListenAddrs() {
+		fmt.Printf("Node address: %s\n", addr)
 	}
 
-	// 4️⃣ 启动挖矿协程，使用固定地址作为矿工地址，奖励设为10
-	go mineRoutine(bc, node, "miner_address", 10)
+	// 定期重新广播仍未打包的内存池交易
+	rebroadcaster := blockchain.NewRebroadcaster(func(txid string, tx blockchain.UTXOTx) {
+		node.Broadcast(&p2p.Message{Type: p2p.MsgTx, Data: mustMarshal(tx)})
+	})
+	// 设置 MINICHAIN_TX_FILTER_FP（布隆过滤器误判率，如 "0.01"）时启用过滤器交易公告：
+	// 定期把本地内存池的过滤器发给peer，重新广播改为只向过滤器未命中的peer公告交易ID
+	if v := os.Getenv("MINICHAIN_TX_FILTER_FP"); v != "" {
+		fpRate, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(fpRate > 0 && fpRate < 1) {
+			log.Fatal("Invalid MINICHAIN_TX_FILTER_FP: must be between 0 and 1")
+		}
+		rebroadcaster.Announce = func(txid string, tx blockchain.UTXOTx) {
+			node.AnnounceTxs(ctx, []string{txid})
+		}
+		go sendTxFilters(ctx, node, fpRate)
+	}
+	go rebroadcaster.Run(ctx)
 
-	// 阻塞主线程
-	select {}
+	// 4️⃣ 启动挖矿协程，使用固定地址作为矿工地址，奖励按共识参数计算
+	// PoA模式下只有配置了签名私钥的授权签名者出块
+	mining := consensus.Name() != blockchain.ConsensusPoA || signerKey != nil
+	if mining {
+		go mineRoutine(ctx, bc, node, miningGate, "miner_address")
+	}
+
+	// 挖矿停滞检测：挖矿中且内存池非空却长时间没有新区块时 /readyz 返回503
+	watchdog.MiningEnabled = func() bool { return mining && miningGate.Ready() && !miningGate.Paused() }
+	go watchdog.Run(ctx)
+
+	// 阻塞主线程直到收到退出信号
+	<-ctx.Done()
+	log.Println("Shutting down")
 }
 
-// mineRoutine 挖矿例程，持续挖掘新区块
+// nodeChain 节点主程序依赖的区块链操作：core.Chain定义的通用链操作，
+// 加上打包内存池出块和先同步区块头的链同步，由blockchain.Blockchain实现
+type nodeChain interface {
+	core.Chain[blockchain.Block, blockchain.UTXOTx]
+	MinePending(ctx context.Context, minerAddress string) (blockchain.Block, error)
+	SyncHeadersFirst(src blockchain.SyncSource) error
+}
+
+var _ nodeChain = (*blockchain.Blockchain)(nil)
+
+// syncFromPeer 从指定peer执行先同步区块头的链同步
+// 同步成功或对方没有更好的链时返回true，表示本地链已与该peer一致
 // bc: 区块链实例
 // node: P2P节点实例
-// minerAddress: 矿工地址
-// reward: 挖矿奖励
-func mineRoutine(bc *blockchain.Blockchain, node *p2p.Node, minerAddress string, reward int) {
+// pid: peer ID
+func syncFromPeer(bc nodeChain, node *p2p.Node, pid peer.ID) bool {
+	switch err := bc.SyncHeadersFirst(node.PeerSource(pid)); err {
+	case nil:
+		log.Printf("Synced chain from %s, height %d", pid, bc.Height())
+	case blockchain.ErrChainNotBetter:
+		log.Printf("Peer %s has no better chain", pid)
+	default:
+		log.Printf("Failed to sync from %s: %v", pid, err)
+		return false
+	}
+	return true
+}
+
+// syncWhenConnected 定期尝试从已连接的peer同步，直到某次同步成功后标记门槛已同步
+func syncWhenConnected(ctx context.Context, bc nodeChain, node *p2p.Node, gate *p2p.MiningGate) {
+	ticker := time.NewTicker(p2p.DefaultMiningGatePoll)
+	defer ticker.Stop()
+	for {
+		for _, pid := range node.Host.Network().Peers() {
+			if syncFromPeer(bc, node, pid) {
+				gate.MarkSynced()
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// txFilterInterval 向peer重新发送内存池过滤器的间隔
+const txFilterInterval = time.Minute
+
+// sendTxFilters 定期把本地内存池交易ID的布隆过滤器发给每个已连接的peer，直到ctx被取消
+func sendTxFilters(ctx context.Context, node *p2p.Node, fpRate float64) {
+	ticker := time.NewTicker(txFilterInterval)
+	defer ticker.Stop()
 	for {
-		// 尝试挖取包含内存池交易的新区块，并给予矿工奖励
-		newBlock, err := bc.MinePending(minerAddress, reward)
+		txids := blockchain.ListMempool()
+		for _, pid := range node.Host.Network().Peers() {
+			if err := node.SendTxFilter(ctx, pid, txids, fpRate); err != nil {
+				log.Printf("Failed to send tx filter to %s: %v", pid, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// mineIdleInterval 内存池为空时矿工再次尝试出块前的等待时间
+const mineIdleInterval = 500 * time.Millisecond
+
+// mineRoutine 挖矿例程，持续挖掘新区块直到ctx被取消
+// bc: 区块链实例
+// node: P2P节点实例
+// gate: 挖矿门槛，打开之前不挖矿
+// minerAddress: 矿工地址，每个区块的奖励按共识参数计算
+func mineRoutine(ctx context.Context, bc nodeChain, node *p2p.Node, gate *p2p.MiningGate, minerAddress string) {
+	if err := gate.Wait(ctx); err != nil {
+		return
+	}
+	log.Printf("Mining gate open, starting miner")
+	for ctx.Err() == nil {
+		// 运维暂停挖矿时等待恢复
+		if gate.Paused() {
+			log.Printf("Mining paused")
+			if err := gate.WaitResumed(ctx); err != nil {
+				return
+			}
+			log.Printf("Mining resumed")
+		}
+		// 尝试挖取包含内存池交易的新区块，矿工获得共识参数规定的下一个高度的区块奖励
+		newBlock, err := bc.MinePending(ctx, minerAddress)
 		if err != nil {
-			// 如果没有交易可挖，等待一段时间再试
+			// 链顶无效时不延伸它，等待同步或重组替换链顶
+			if errors.Is(err, blockchain.ErrInvalidTip) {
+				log.Printf("ERROR: refusing to mine on top of invalid tip: %v", err)
+			}
+			// 如果没有交易可挖或链顶无效，等待一段时间再试
+			select {
+			case <-ctx.Done():
+			case <-time.After(mineIdleInterval):
+			}
 			continue
 		}
 
-		// 验证并应用新区块
-		if err := bc.ValidateAndApplyBlock(newBlock); err != nil {
-			log.Printf("Failed to validate and apply block: %v", err)
+		// 验证并应用新区块，被拒绝的原因记录在链的拒绝统计中
+		if !bc.AddBlock(newBlock) {
+			log.Printf("Failed to validate and apply block %s", newBlock.Hash)
 			continue
 		}
 
@@ -109,6 +402,22 @@ func mineRoutine(bc *blockchain.Blockchain, node *p2p.Node, minerAddress string,
 	}
 }
 
+// openBlockStore 打开数据目录dir下的区块存储，目录不存在时创建
+func openBlockStore(dir string) (*blockchain.BoltBlockStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return blockchain.OpenBoltBlockStore(filepath.Join(dir, "chain.db"))
+}
+
+// loadSignerKey 解析十六进制编码的secp256k1私钥，空字符串返回nil
+func loadSignerKey(hexKey string) (*ecdsa.PrivateKey, error) {
+	if hexKey == "" {
+		return nil, nil
+	}
+	return crypto.HexToECDSA(hexKey)
+}
+
 // mustMarshal 简化的序列化函数
 // 在实际实现中应该处理错误
 // v: 待序列化的对象
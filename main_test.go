@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mini_chain/gossip/core"
+	"mini_chain/internal/api"
+	"mini_chain/internal/blockchain"
+	"mini_chain/internal/p2p"
+	"mini_chain/internal/wallet"
+)
+
+// submitFundedTx 为新账户注入一笔UTXO，并把由它签名的转账放入内存池
+func submitFundedTx(t *testing.T, to string) {
+	t.Helper()
+	acc, err := wallet.NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := "fund-" + acc.Address[2:18]
+	blockchain.PutUTXO(prev, 0, blockchain.UTXOEntry{Address: acc.Address, Amount: 100})
+	wtx, err := wallet.BuildTransaction(acc, to, 40, 1, []wallet.UTXO{{Txid: prev, Vout: 0, Amount: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid, err := blockchain.PutTransaction(blockchain.TxFromWallet(wtx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockchain.AddToMempool(txid)
+}
+
+// waitFor 轮询直到cond成立或超时
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return cond()
+}
+
+// TestNodeLifecycle 启动节点并挖出若干区块，然后关闭，断言所有后台协程都已退出
+func TestNodeLifecycle(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	dataDir := t.TempDir()
+	store, err := openBlockStore(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	bc := blockchain.NewBlockchain(1, store)
+	node, err := p2p.NewNode(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.ServeSync(bc)
+	gate := p2p.NewMiningGate(node, 0, false)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		mineRoutine(ctx, bc, node, gate, "lifecycle-miner")
+	}()
+
+	recipient, err := wallet.NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const blocks = 3
+	for i := 1; i <= blocks; i++ {
+		submitFundedTx(t, recipient.Address)
+		if !waitFor(t, 10*time.Second, func() bool { return bc.Height() >= i }) {
+			t.Fatalf("height %d not reached, chain at %d", i, bc.Height())
+		}
+	}
+	var balance int
+	for _, u := range blockchain.FindUTXOsForAddress(recipient.Address) {
+		balance += u.Amount
+	}
+	if balance != blocks*40 {
+		t.Errorf("recipient balance = %d, want %d", balance, blocks*40)
+	}
+
+	// 关闭：取消ctx后矿工必须退出，节点关闭后不应遗留协程
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("mineRoutine did not return after cancel")
+	}
+	if err := node.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(t, 5*time.Second, func() bool { return runtime.NumGoroutine() <= baseline }) {
+		buf := make([]byte, 1<<16)
+		t.Fatalf("goroutines leaked: %d before start, %d after shutdown\n%s",
+			baseline, runtime.NumGoroutine(), buf[:runtime.Stack(buf, true)])
+	}
+
+	// 重启：从同一数据目录恢复的链高度和最新区块哈希与关闭前一致
+	tip := bc.GetLatest()
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, err = openBlockStore(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	restarted := blockchain.NewBlockchain(1, store)
+	if restarted.Height() != tip.Index || restarted.GetLatest().Hash != tip.Hash {
+		t.Errorf("after restart at height %d tip %s, want height %d tip %s",
+			restarted.Height(), restarted.GetLatest().Hash, tip.Index, tip.Hash)
+	}
+}
+
+// TestMiningPauseResume 通过管理端点暂停挖矿，确认暂停期间不出块，恢复后继续出块
+func TestMiningPauseResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bc := blockchain.NewBlockchain(1, nil)
+	node, err := p2p.NewNode(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	gate := p2p.NewMiningGate(node, 0, false)
+
+	apiSrv := api.NewAPI(bc, node)
+	apiSrv.AuthToken = "pause-token"
+	apiSrv.MiningGate = gate
+	srv := httptest.NewServer(apiSrv.Router())
+	defer srv.Close()
+	post := func(path, token string) (int, p2p.MiningGateState) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var st p2p.MiningGateState
+		json.NewDecoder(resp.Body).Decode(&st)
+		return resp.StatusCode, st
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		mineRoutine(ctx, bc, node, gate, "pause-miner")
+	}()
+	defer func() { cancel(); <-done }()
+
+	if code, _ := post("/mining/pause", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("pause without valid token: expected 401, got %d", code)
+	}
+	if code, st := post("/mining/pause", "pause-token"); code != http.StatusOK || !st.Paused {
+		t.Fatalf("pause: status %d, state %+v", code, st)
+	}
+	if !apiSrv.Status().Mining.Paused {
+		t.Error("/status does not report mining as paused")
+	}
+	time.Sleep(mineIdleInterval + 100*time.Millisecond) // 等矿工结束当前一轮
+
+	recipient, err := wallet.NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	height := bc.Height()
+	submitFundedTx(t, recipient.Address)
+	if waitFor(t, time.Second, func() bool { return bc.Height() > height }) {
+		t.Fatal("block mined while mining was paused")
+	}
+
+	if code, st := post("/mining/resume", "pause-token"); code != http.StatusOK || st.Paused {
+		t.Fatalf("resume: status %d, state %+v", code, st)
+	}
+	if !waitFor(t, 10*time.Second, func() bool { return bc.Height() > height }) {
+		t.Fatal("mining did not restart after resume")
+	}
+}
+
+// TestDemo 执行demo命令，断言接收方余额等于转账金额
+func TestDemo(t *testing.T) {
+	var out bytes.Buffer
+	cmd, err := parseArgs([]string{"demo"}, &out)
+	if err != nil || cmd.Run == nil {
+		t.Fatalf("demo: parse err=%v", err)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("demo: %v", err)
+	}
+	if !strings.Contains(out.String(), fmt.Sprintf("Recipient balance: %d\n", demoAmount)) {
+		t.Fatalf("unexpected demo output:\n%s", out.String())
+	}
+
+	r, err := runDemo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecipientBalance != demoAmount {
+		t.Errorf("recipient balance = %d, want %d", r.RecipientBalance, demoAmount)
+	}
+	if want := demoFaucet - demoAmount - blockchain.DefaultMinRelayFee + r.Reward; r.SenderBalance != want {
+		t.Errorf("sender balance = %d, want %d", r.SenderBalance, want)
+	}
+	if r.Block.Index != 1 || r.Block.Transactions[1] != r.TxID {
+		t.Errorf("demo block %d txs %v, want block 1 with the transfer after the coinbase", r.Block.Index, r.Block.Transactions)
+	}
+}
+
+// TestParseArgs 断言子命令分发到正确的命令，错误输入返回说明用法的错误
+func TestParseArgs(t *testing.T) {
+	acc, err := wallet.NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeCases := []struct {
+		args []string
+		want nodeOptions
+	}{
+		{[]string{"3000"}, nodeOptions{P2PPort: 3000, APIPort: 8080}},
+		{[]string{"3000", "8081", "/ip4/a,/ip4/b"}, nodeOptions{P2PPort: 3000, APIPort: 8081, BootstrapPeers: []string{"/ip4/a", "/ip4/b"}}},
+		{[]string{"node", "--p2p-port", "3001", "--api-port", "9000"}, nodeOptions{P2PPort: 3001, APIPort: 9000}},
+		{[]string{"node", "-peers", "/ip4/a", "-p2p-port=3002"}, nodeOptions{P2PPort: 3002, APIPort: 8080, BootstrapPeers: []string{"/ip4/a"}}},
+		{[]string{"node", "3003", "8083"}, nodeOptions{P2PPort: 3003, APIPort: 8083}},
+	}
+	for _, c := range nodeCases {
+		cmd, err := parseArgs(c.args, io.Discard)
+		if err != nil {
+			t.Fatalf("%v: %v", c.args, err)
+		}
+		if cmd.Name != "node" || cmd.Node == nil || cmd.Run != nil {
+			t.Fatalf("%v: dispatched to %+v, want node", c.args, cmd)
+		}
+		if fmt.Sprint(*cmd.Node) != fmt.Sprint(c.want) {
+			t.Errorf("%v: options %+v, want %+v", c.args, *cmd.Node, c.want)
+		}
+	}
+
+	commands := map[string][]string{
+		"wallet new":     {"wallet", "new"},
+		"wallet balance": {"wallet", "balance", "--api", "http://localhost:9000/", acc.Address},
+		"tx send":        {"tx", "send", "--fee", "2", acc.Address, "10"},
+		"signmsg":        {"signmsg", "hello", "world"},
+		"verifymsg":      {"verifymsg", acc.Address, "hello", "00"},
+		"sync":           {"sync", "http://localhost:9000"},
+		"demo":           {"demo"},
+	}
+	for name, args := range commands {
+		cmd, err := parseArgs(args, io.Discard)
+		if err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		if cmd.Name != name || cmd.Run == nil || cmd.Node != nil {
+			t.Errorf("%v: dispatched to %q, want %q", args, cmd.Name, name)
+		}
+	}
+
+	for _, args := range [][]string{{"--help"}, {"help"}, {"node", "--help"}, {"wallet", "-h"}, {"tx", "send", "-help"}} {
+		var out bytes.Buffer
+		if _, err := parseArgs(args, &out); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("%v: err = %v, want flag.ErrHelp", args, err)
+		}
+		if !strings.Contains(out.String(), "Usage:") {
+			t.Errorf("%v: no usage in output %q", args, out.String())
+		}
+	}
+
+	bad := []struct {
+		args []string
+		want string // 错误信息中应包含的内容
+	}{
+		{nil, "no command"},
+		{[]string{"frobnicate"}, `unknown command "frobnicate"`},
+		{[]string{"node"}, "--p2p-port is required"},
+		{[]string{"node", "--p2p-port", "70000"}, "--p2p-port is required"},
+		{[]string{"node", "3000", "http"}, `invalid API port "http"`},
+		{[]string{"node", "--bogus"}, "flag provided but not defined"},
+		{[]string{"wallet"}, "usage: wallet"},
+		{[]string{"wallet", "delete"}, `unknown wallet command "delete"`},
+		{[]string{"wallet", "balance"}, "usage: wallet balance"},
+		{[]string{"wallet", "balance", "not-an-address"}, "invalid address"},
+		{[]string{"tx", "receive"}, `unknown tx command "receive"`},
+		{[]string{"tx", "send", acc.Address}, "usage: tx send"},
+		{[]string{"tx", "send", acc.Address, "-5"}, `invalid amount "-5"`},
+		{[]string{"tx", "send", acc.Address, "ten"}, `invalid amount "ten"`},
+		{[]string{"tx", "send", acc.Address, "0"}, "amount must be greater than zero"},
+		{[]string{"tx", "send", acc.Address, "1000000001"}, "amount must not exceed"},
+		{[]string{"tx", "send", acc.Address, "99999999999999999999"}, "amount must not exceed"},
+		{[]string{"tx", "send", "--fee", "-1", acc.Address, "10"}, "invalid fee"},
+		{[]string{"signmsg"}, "usage: signmsg"},
+		{[]string{"verifymsg", acc.Address}, "usage: verifymsg"},
+	}
+	for _, c := range bad {
+		_, err := parseArgs(c.args, io.Discard)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%v: err = %v, want it to mention %q", c.args, err, c.want)
+		}
+	}
+
+	// tx send的金额与core.ParseAmount使用相同的上下界
+	if _, err := parseArgs([]string{"tx", "send", acc.Address, strconv.Itoa(core.MaxAmount)}, io.Discard); err != nil {
+		t.Errorf("amount at core.MaxAmount rejected: %v", err)
+	}
+	if _, err := parseArgs([]string{"tx", "send", acc.Address, strconv.Itoa(core.MaxAmount + 1)}, io.Discard); !errors.Is(err, core.ErrAmountTooLarge) {
+		t.Errorf("amount above core.MaxAmount: err = %v, want %v", err, core.ErrAmountTooLarge)
+	}
+}
@@ -1,3 +1,7 @@
 module mini_chain/p2p
 
 go 1.25.2
+
+require mini_chain/gossip/core v0.0.0-00010101000000-000000000000
+
+replace mini_chain/gossip/core => ../gossip/core
@@ -8,21 +8,27 @@ package main
 
 import (
 	"bufio"         // 用于读取网络连接和标准输入的数据
+	"bytes"         // 字节切片处理
+	"crypto/ecdh"   // 校验公钥是曲线上的点
 	"crypto/ecdsa"  // 椭圆曲线数字签名算法，用于钱包密钥
 	"crypto/elliptic" // 椭圆曲线加密相关
 	"crypto/rand"   // 加密安全的随机数生成器
 	"crypto/sha256" // SHA256哈希函数
 	"encoding/hex"  // 十六进制编码解码
 	"encoding/json" // JSON序列化反序列化
+	"errors"        // 错误处理
 	"fmt"           // 格式化输入输出
 	"io"            // IO操作接口
 	"log"           // 日志记录
+	"math/big"      // 大整数，用于还原公钥坐标
 	"net"           // 网络编程相关
 	"os"            // 系统操作
 	"strconv"       // 字符串与数值转换
 	"strings"       // 字符串处理
 	"sync"          // 同步原语，如互斥锁
 	"time"          // 时间处理
+
+	"mini_chain/gossip/core" // 与gossip节点共享的链校验规则
 )
 
 // ===== 数据结构 =====
@@ -59,7 +65,7 @@ var (
 	txPoolMutex sync.Mutex          // 交易池访问互斥锁，保证并发安全
 	peers       []string            // 邻居节点地址列表
 	addr        string              // 本节点地址，格式如"localhost:3000"
-	difficulty  = 3                 // PoW挖矿难度：要求哈希前difficulty个0(十六进制字符串)
+	difficulty  = 3                 // PoW挖矿难度：目标值为2^(256-difficulty*4)，即哈希前difficulty个十六进制0
 )
 
 // ===== 钱包与签名工具 =====
@@ -80,6 +86,14 @@ func HashTransaction(tx Transaction) []byte {
 	return h[:]
 }
 
+// TxID 返回交易的唯一标识：签名内容和签名一起的SHA256（十六进制），规则与gossip/core.TxID一致
+// 交易池用它去重，收发方和金额相同的两笔转账因签名不同而得到不同的TxID
+func TxID(tx Transaction) string {
+	data := tx.From + "|" + tx.To + "|" + strconv.Itoa(tx.Amount) + "|" + tx.Signature
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
+
 // SignTransaction 使用私钥对交易进行签名
 func SignTransaction(priv *ecdsa.PrivateKey, tx Transaction) (string, error) {
 	h := HashTransaction(tx)
@@ -90,31 +104,46 @@ func SignTransaction(priv *ecdsa.PrivateKey, tx Transaction) (string, error) {
 	return hex.EncodeToString(sig), nil
 }
 
+// parsePublicKey 把未压缩P256公钥的十六进制编码解析为ECDSA公钥，规则与gossip/core.ParsePublicKey一致
+// 长度、前缀不符或不是曲线上的点时返回错误，任何输入都不会panic
+func parsePublicKey(s string) (*ecdsa.PublicKey, error) {
+	pubBytes, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(pubBytes) != 65 || pubBytes[0] != 0x04 {
+		return nil, errors.New("public key is not an uncompressed P256 point")
+	}
+	if _, err := ecdh.P256().NewPublicKey(pubBytes); err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(pubBytes[1:33]),
+		Y:     new(big.Int).SetBytes(pubBytes[33:]),
+	}, nil
+}
+
 // VerifyTransaction 验证交易签名的有效性
 func VerifyTransaction(tx Transaction) bool {
-	// 从tx.From恢复公钥（这里假设From是hex.Marshal(pub)）
-	pubBytes, err := hex.DecodeString(tx.From)
+	// 从tx.From恢复公钥（From是未压缩公钥的十六进制编码），格式错误时返回false
+	pub, err := parsePublicKey(tx.From)
 	if err != nil {
 		return false
 	}
-	x, y := elliptic.Unmarshal(elliptic.P256(), pubBytes)
-	if x == nil {
-		return false
-	}
-	pub := ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
 	sigBytes, err := hex.DecodeString(tx.Signature)
 	if err != nil {
 		return false
 	}
 	h := HashTransaction(tx)
-	return ecdsa.VerifyASN1(&pub, h, sigBytes)
+	return ecdsa.VerifyASN1(pub, h, sigBytes)
 }
 
 // ===== 区块与 PoW =====
 // CalculateHash 计算区块的哈希值
 func CalculateHash(b Block) string {
 	// 注意：不把Hash字段本身参与哈希
-	txBytes, _ := json.Marshal(b.Transactions)
+	txBytes := core.CanonicalTxBytes(b.Transactions)
 	record := strconv.Itoa(b.Index) + strconv.FormatInt(b.Timestamp, 10) + string(txBytes) + b.PrevHash + strconv.FormatInt(b.Nonce, 10)
 	h := sha256.Sum256([]byte(record))
 	return fmt.Sprintf("%x", h)
@@ -133,8 +162,8 @@ func MineBlock(transactions []Transaction, prev Block) Block {
 	// 不断尝试不同的Nonce值直到找到满足难度要求的哈希
 	for {
 		newBlock.Hash = CalculateHash(newBlock)
-		// 检查哈希是否满足难度要求（以指定数量的0开头）
-		if strings.HasPrefix(newBlock.Hash, strings.Repeat("0", difficulty)) {
+		// 检查哈希是否满足难度对应的数值目标
+		if core.HashMeetsTarget(newBlock.Hash, difficulty) {
 			break
 		}
 		newBlock.Nonce++ // 增加Nonce值继续尝试
@@ -144,10 +173,11 @@ func MineBlock(transactions []Transaction, prev Block) Block {
 
 // ===== 链操作 =====
 // InitGenesis 初始化创世区块
+// 创世区块使用固定时间戳，独立启动的节点得到相同的创世区块，才能接受彼此的链
 func InitGenesis() {
 	genesis := Block{
 		Index:        0,                     // 创世区块索引为0
-		Timestamp:    time.Now().Unix(),     // 设置当前时间戳
+		Timestamp:    core.GenesisTimestamp, // 固定时间戳
 		Transactions: []Transaction{},       // 创世区块不包含交易
 		PrevHash:     "0",                   // 前一区块哈希为"0"
 		Nonce:        0,                     // 随机数初始化为0
@@ -171,22 +201,116 @@ func AddBlock(b Block) bool {
 	if CalculateHash(b) != b.Hash {
 		return false
 	}
-	// 3. 区块哈希必须满足难度要求（简单验证PoW）
-	if !strings.HasPrefix(b.Hash, strings.Repeat("0", difficulty)) {
+	// 3. 区块哈希必须满足难度对应的数值目标（验证PoW）
+	if !core.HashMeetsTarget(b.Hash, difficulty) {
 		return false
 	}
 	blockchain = append(blockchain, b)      // 将新区块添加到区块链末尾
 	return true
 }
 
-// ReplaceChain 用更长的链替换当前链（共识机制的一部分）
-func ReplaceChain(newChain []Block) {
+var (
+	// ErrChainNotLonger 候选链不比本地链长
+	ErrChainNotLonger = core.ErrChainNotLonger
+	// ErrGenesisMismatch 候选链的创世区块与本地链不同
+	ErrGenesisMismatch = core.ErrGenesisMismatch
+)
+
+// ValidateChain 校验候选链，规则由gossip/core.ValidateLinks实现，与gossip/core.ReplaceChain一致：
+// 创世区块与本地链相同，之后每个区块的索引连续、PrevHash等于前一区块的Hash、
+// Hash与CalculateHash的结果一致且满足PoW难度
+func ValidateChain(chain []Block) error {
+	chainMutex.Lock()
+	genesis := blockchain[0]
+	chainMutex.Unlock()
+	return core.ValidateLinks(genesis.Hash, chain, difficulty, chainLink)
+}
+
+// chainLink 返回区块参与链接校验的字段
+func chainLink(b Block) core.ChainLink {
+	return core.ChainLink{Index: b.Index, PrevHash: b.PrevHash, Hash: b.Hash, Computed: CalculateHash(b)}
+}
+
+// ReplaceChain 用更长的有效链替换当前链（共识机制的一部分）
+// 只有当新链比当前链更长且通过ValidateChain的校验时才替换，否则记录日志并返回拒绝原因
+func ReplaceChain(newChain []Block) error {
 	chainMutex.Lock()                       // 加锁保护区块链数据
 	defer chainMutex.Unlock()               // 函数结束时解锁
-	// 只有当新链比当前链更长时才替换
-	if len(newChain) > len(blockchain) {
-		blockchain = newChain
+	if len(newChain) <= len(blockchain) {
+		return ErrChainNotLonger
+	}
+	if err := core.ValidateLinks(blockchain[0].Hash, newChain, difficulty, chainLink); err != nil {
+		log.Println("Rejected invalid chain from peer:", err)
+		return err
+	}
+	blockchain = newChain
+	log.Println("Replaced chain with longer chain length:", len(blockchain))
+	return nil
+}
+
+// ===== core.Chain 实现 =====
+// nodeChain 基于全局区块链和交易池实现core.Chain，消息处理和挖矿都通过localChain操作区块链，
+// 与gossip节点和internal/blockchain遵循同一套接口语义（可用chaintest一致性测试验证）
+type nodeChain struct{}
+
+var _ core.Chain[Block, Transaction] = nodeChain{}
+
+// localChain 本节点使用的区块链
+var localChain core.Chain[Block, Transaction] = nodeChain{}
+
+// AddBlock 校验并追加扩展链顶的区块，区块中的交易随之离开交易池
+func (nodeChain) AddBlock(b Block) bool {
+	if !AddBlock(b) {
+		return false
+	}
+	removeTxs(b.Transactions)               // 清除已打包的交易
+	return true
+}
+
+// Tip 返回当前链顶区块
+func (nodeChain) Tip() Block {
+	chainMutex.Lock()
+	defer chainMutex.Unlock()
+	return blockchain[len(blockchain)-1]
+}
+
+// Height 返回链顶区块的高度，只有创世区块时为0
+func (nodeChain) Height() int {
+	chainMutex.Lock()
+	defer chainMutex.Unlock()
+	return len(blockchain) - 1
+}
+
+// ReplaceChain 按ReplaceChain的规则用更长的有效链替换本地链
+func (nodeChain) ReplaceChain(newChain []Block) error {
+	return ReplaceChain(newChain)
+}
+
+// AddTransaction 校验交易签名并加入交易池，签名无效或已在池中时返回false
+func (nodeChain) AddTransaction(tx Transaction) bool {
+	if !VerifyTransaction(tx) {
+		log.Println("Invalid tx signature")
+		return false
+	}
+	txPoolMutex.Lock()                      // 加锁保护交易池
+	defer txPoolMutex.Unlock()              // 函数结束时解锁
+	// 按TxID去重
+	id := TxID(tx)
+	for _, t := range txPool {
+		if TxID(t) == id {
+			return false
+		}
 	}
+	txPool = append(txPool, tx)             // 将交易添加到交易池
+	log.Println("Accepted tx into pool. Pool size:", len(txPool))
+	return true
+}
+
+// Mempool 返回交易池的副本，按加入顺序排列
+func (nodeChain) Mempool() []Transaction {
+	txPoolMutex.Lock()
+	defer txPoolMutex.Unlock()
+	return append([]Transaction(nil), txPool...)
 }
 
 // ===== P2P 简单实现（基于 TCP） =====
@@ -208,57 +332,72 @@ func startServer(listenAddr string) {
 	}
 }
 
+// MaxMessageSize 单条消息（一行JSON）的最大字节数
+// 超长的行会使读取失败并断开连接，避免对端用不带换行的数据流无限占用内存
+const MaxMessageSize = 4 << 20
+
 // handleConn 处理网络连接上的消息
 func handleConn(conn net.Conn) {
 	defer conn.Close()                      // 函数结束时关闭连接
-	reader := bufio.NewReader(conn)
-	// 循环读取并处理消息
-	for {
-		raw, err := reader.ReadBytes('\n')   // 读取一行数据（以\n结尾）
-		if err != nil {
-			if err != io.EOF {
-				log.Println("read error:", err)
-			}
-			return
+	readMessages(conn, func(msg Message) {
+		dispatchMessage(conn, msg)
+	})
+}
+
+// readMessages 逐行读取JSON消息并交给handle处理，直到连接关闭
+// 对端在最后一条消息后没有换行就关闭连接时，该消息只要是完整的JSON仍会被处理；
+// 无法解析的行（包括连接关闭时残留的半条消息）被丢弃并记录警告
+func readMessages(r io.Reader, handle func(Message)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxMessageSize)
+	for scanner.Scan() {
+		raw := scanner.Bytes()
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
 		}
 		var msg Message
 		// 反序列化消息
 		if err := json.Unmarshal(raw, &msg); err != nil {
-			log.Println("invalid message:", err)
+			log.Printf("discarding invalid message (%d bytes): %v", len(raw), err)
 			continue
 		}
-		// 根据消息类型进行不同处理
-		switch msg.Type {
-		case "TX":
-			var tx Transaction
-			// 反序列化交易数据
-			if err := json.Unmarshal(msg.Data, &tx); err == nil {
-				handleTx(tx)                // 处理接收到的交易
-			}
-		case "BLOCK":
-			var b Block
-			// 反序列化区块数据
-			if err := json.Unmarshal(msg.Data, &b); err == nil {
-				// 尝试添加区块
-				if AddBlock(b) {
-					log.Println("Added block from peer:", b.Index)
-					// 清除已包含的交易
-					removeTxs(b.Transactions)
-				} else {
-					log.Println("Received invalid block")
-				}
-			}
-		case "GETCHAIN":
-			sendChain(conn)                 // 发送本地区块链数据
-		case "CHAIN":
-			var chain []Block
-			// 反序列化区块链数据
-			if err := json.Unmarshal(msg.Data, &chain); err == nil {
-				ReplaceChain(chain)         // 替换本地区块链（如果更长）
+		handle(msg)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println("read error:", err)
+	}
+}
+
+// dispatchMessage 根据消息类型进行不同处理，需要回复的消息写回conn
+func dispatchMessage(conn io.Writer, msg Message) {
+	switch msg.Type {
+	case "TX":
+		var tx Transaction
+		// 反序列化交易数据
+		if err := json.Unmarshal(msg.Data, &tx); err == nil {
+			handleTx(tx)                // 处理接收到的交易
+		}
+	case "BLOCK":
+		var b Block
+		// 反序列化区块数据
+		if err := json.Unmarshal(msg.Data, &b); err == nil {
+			// 尝试添加区块，已包含的交易随之离开交易池
+			if localChain.AddBlock(b) {
+				log.Println("Added block from peer:", b.Index)
+			} else {
+				log.Println("Received invalid block")
 			}
-		default:
-			// 忽略未知类型的消息
 		}
+	case "GETCHAIN":
+		sendChain(conn)                 // 发送本地区块链数据
+	case "CHAIN":
+		var chain []Block
+		// 反序列化区块链数据
+		if err := json.Unmarshal(msg.Data, &chain); err == nil {
+			localChain.ReplaceChain(chain) // 替换本地区块链（如果更长）
+		}
+	default:
+		// 忽略未知类型的消息
 	}
 }
 
@@ -296,21 +435,10 @@ func broadcastMessage(msg Message) {
 // ===== 交易处理 =====
 // handleTx 处理接收到的交易
 func handleTx(tx Transaction) {
-	// 首先验证交易签名的有效性
-	if !VerifyTransaction(tx) {
-		log.Println("Invalid tx signature")
+	// 签名无效或已在交易池中的交易不再广播
+	if !localChain.AddTransaction(tx) {
 		return
 	}
-	txPoolMutex.Lock()                      // 加锁保护交易池
-	defer txPoolMutex.Unlock()              // 函数结束时解锁
-	// 简单去重：用signature判断交易是否已在交易池中
-	for _, t := range txPool {
-		if t.Signature == tx.Signature {
-			return
-		}
-	}
-	txPool = append(txPool, tx)             // 将交易添加到交易池
-	log.Println("Accepted tx into pool. Pool size:", len(txPool))
 	// 广播给其他节点
 	data, _ := json.Marshal(tx)
 	broadcastMessage(Message{Type: "TX", Data: data})
@@ -327,7 +455,7 @@ func removeTxs(txs []Transaction) {
 		found := false
 		// 检查该交易是否在要移除的交易列表中
 		for _, t := range txs {
-			if p.Signature == t.Signature {
+			if TxID(p) == TxID(t) {
 				found = true
 				break
 			}
@@ -356,31 +484,22 @@ func printChain() {
 // mineRoutine 挖矿例程，持续挖掘新区块
 func mineRoutine(priv *ecdsa.PrivateKey) {
 	for {
-		// 取出当前交易池
-		txPoolMutex.Lock()                  // 加锁访问交易池
+		// 取出当前交易池的副本
+		txs := localChain.Mempool()
 		// 如果交易池为空则等待
-		if len(txPool) == 0 {
-			txPoolMutex.Unlock()
+		if len(txs) == 0 {
 			time.Sleep(2 * time.Second)     // 等待2秒后重试
 			continue
 		}
-		// 复制当前交易池中的所有交易
-		txs := make([]Transaction, len(txPool))
-		copy(txs, txPool)
-		txPoolMutex.Unlock()                // 解锁交易池
-
-		chainMutex.Lock()                   // 加锁访问区块链
-		last := blockchain[len(blockchain)-1] // 获取最新的区块
-		chainMutex.Unlock()                 // 解锁区块链
+		last := localChain.Tip()            // 获取最新的区块
 
 		log.Println("Start mining block with", len(txs), "txs...")
 		// 挖掘包含这些交易的新区块
 		newB := MineBlock(txs, last)
-		// 尝试将新区块添加到区块链
-		if AddBlock(newB) {
+		// 尝试将新区块添加到区块链，已打包的交易随之离开交易池
+		if localChain.AddBlock(newB) {
 			log.Println("Mined new block:", newB.Index, newB.Hash[:10])
-			// 从交易池中移除已打包的交易并广播新区块
-			removeTxs(txs)
+			// 广播新区块
 			data, _ := json.Marshal(newB)
 			broadcastMessage(Message{Type: "BLOCK", Data: data})
 		}
@@ -388,7 +507,11 @@ func mineRoutine(priv *ecdsa.PrivateKey) {
 }
 
 // ===== 启动 & 辅助 =====
+// chainRequestTimeout 等待邻居节点回复区块链数据的超时时间
+const chainRequestTimeout = 10 * time.Second
+
 // requestChainsFromPeers 向所有邻居节点请求区块链数据以同步
+// 对方在同一连接上回复CHAIN消息，读取回复后按ReplaceChain的规则处理
 func requestChainsFromPeers() {
 	// 遍历所有邻居节点
 	for _, p := range peers {
@@ -403,7 +526,17 @@ func requestChainsFromPeers() {
 			msg := Message{Type: "GETCHAIN", Data: nil}
 			out, _ := json.Marshal(msg)
 			out = append(out, '\n')         // 添加换行符作为分隔符
-			conn.Write(out)                 // 发送请求
+			if _, err := conn.Write(out); err != nil {
+				return
+			}
+			// 关闭写方向使对方读到EOF后结束连接，再读取对方写回的CHAIN消息
+			if tc, ok := conn.(*net.TCPConn); ok {
+				tc.CloseWrite()
+			}
+			conn.SetReadDeadline(time.Now().Add(chainRequestTimeout))
+			readMessages(conn, func(msg Message) {
+				dispatchMessage(conn, msg)
+			})
 		}(p)
 	}
 }
@@ -458,7 +591,11 @@ func main() {
 				continue
 			}
 			to := parts[1]                  // 接收方地址
-			amt, _ := strconv.Atoi(parts[2]) // 转账金额
+			amt, err := core.ParseAmount(parts[2]) // 转账金额
+			if err != nil {
+				fmt.Println("invalid amount:", err)
+				continue
+			}
 			// 创建交易
 			tx := Transaction{From: pubAddr, To: to, Amount: amt}
 			// 对交易签名
@@ -474,12 +611,11 @@ func main() {
 			printChain()                    // 打印区块链信息
 		case "pool":
 			// 打印交易池信息
-			txPoolMutex.Lock()
-			fmt.Println("Pending txs:", len(txPool))
-			for i, t := range txPool {
+			pool := localChain.Mempool()
+			fmt.Println("Pending txs:", len(pool))
+			for i, t := range pool {
 				fmt.Printf("%d: %s -> %s : %d sig:%s\n", i, t.From[:10], t.To[:10], t.Amount, t.Signature[:10])
 			}
-			txPoolMutex.Unlock()
 		case "peers":
 			fmt.Println("Peers:", peers)    // 打印邻居节点列表
 		case "addpeer":
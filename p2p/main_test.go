@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mini_chain/gossip/core"
+	"mini_chain/gossip/core/chaintest"
+)
+
+func TestReadMessagesUnterminatedFinalMessage(t *testing.T) {
+	// 最后一条完整消息没有换行，随后是EOF
+	in := `{"type":"TX","data":{"from":"a"}}` + "\n" + `{"type":"GETCHAIN"}`
+	var got []string
+	readMessages(strings.NewReader(in), func(m Message) { got = append(got, m.Type) })
+	if len(got) != 2 || got[0] != "TX" || got[1] != "GETCHAIN" {
+		t.Fatalf("got messages %v, want [TX GETCHAIN]", got)
+	}
+}
+
+func TestReadMessagesDiscardsGarbage(t *testing.T) {
+	// 中间的无效行和连接关闭时残留的半条消息都被丢弃，不影响其他消息
+	in := "not json\n\n" + `{"type":"BLOCK"}` + "\r\n" + `{"type":"TX","da`
+	var got []string
+	readMessages(strings.NewReader(in), func(m Message) { got = append(got, m.Type) })
+	if len(got) != 1 || got[0] != "BLOCK" {
+		t.Fatalf("got messages %v, want [BLOCK]", got)
+	}
+}
+
+func TestReadMessagesOversizedLine(t *testing.T) {
+	in := `{"type":"TX"}` + "\n" + strings.Repeat("x", MaxMessageSize+1) + "\n" + `{"type":"BLOCK"}` + "\n"
+	var got []string
+	readMessages(strings.NewReader(in), func(m Message) { got = append(got, m.Type) })
+	if len(got) != 1 || got[0] != "TX" {
+		t.Fatalf("got messages %v, want only the message before the oversized line", got)
+	}
+}
+
+func TestHandleConnAnswersUnterminatedRequest(t *testing.T) {
+	InitGenesis()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			handleConn(conn)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	// 发送不带换行的GETCHAIN后关闭写方向
+	if _, err := io.WriteString(conn, `{"type":"GETCHAIN"}`); err != nil {
+		t.Fatal(err)
+	}
+	conn.(*net.TCPConn).CloseWrite()
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp Message
+	if err := json.Unmarshal(line, &resp); err != nil || resp.Type != "CHAIN" {
+		t.Fatalf("response %q (%v), want CHAIN", line, err)
+	}
+	var chain []Block
+	if err := json.Unmarshal(resp.Data, &chain); err != nil || len(chain) != 1 {
+		t.Fatalf("chain %v (%v), want the genesis block", chain, err)
+	}
+}
+
+// buildChain 在当前创世区块之后挖出n个区块，返回完整的候选链
+func buildChain(t *testing.T, n int) []Block {
+	t.Helper()
+	old := difficulty
+	difficulty = 1
+	t.Cleanup(func() { difficulty = old })
+	InitGenesis()
+	chain := []Block{blockchain[0]}
+	for i := 0; i < n; i++ {
+		chain = append(chain, MineBlock([]Transaction{{From: "a", To: "b", Amount: i + 1}}, chain[len(chain)-1]))
+	}
+	return chain
+}
+
+func TestReplaceChainValidLongerChain(t *testing.T) {
+	chain := buildChain(t, 3)
+	if err := ValidateChain(chain); err != nil {
+		t.Fatalf("ValidateChain: %v", err)
+	}
+	if err := ReplaceChain(chain); err != nil {
+		t.Fatalf("ReplaceChain: %v", err)
+	}
+	if len(blockchain) != 4 {
+		t.Fatalf("chain length %d after replace, want 4", len(blockchain))
+	}
+	if err := ReplaceChain(chain[:2]); !errors.Is(err, ErrChainNotLonger) {
+		t.Fatalf("shorter chain: expected %v, got %v", ErrChainNotLonger, err)
+	}
+}
+
+func TestReplaceChainRejectsTamperedBlock(t *testing.T) {
+	chain := buildChain(t, 3)
+	chain[2].Transactions[0].Amount = 1000 // 篡改中间区块的交易，哈希不再匹配
+	if err := ValidateChain(chain); err == nil {
+		t.Fatal("tampered chain passed validation")
+	}
+	if err := ReplaceChain(chain); err == nil || len(blockchain) != 1 {
+		t.Fatalf("tampered chain replaced local chain (err %v, length %d)", err, len(blockchain))
+	}
+
+	// 重新计算哈希但不重新挖矿，PoW不再成立（或后续区块的链接断开）
+	chain[2].Hash = CalculateHash(chain[2])
+	if err := ReplaceChain(chain); err == nil || len(blockchain) != 1 {
+		t.Fatalf("rehashed chain replaced local chain (err %v, length %d)", err, len(blockchain))
+	}
+}
+
+func TestReplaceChainRejectsBrokenLink(t *testing.T) {
+	chain := buildChain(t, 3)
+	// 第2个区块自身有效，但不指向第1个区块
+	chain[2] = MineBlock(chain[2].Transactions, Block{Index: 1, Hash: "deadbeef"})
+	if err := ReplaceChain(chain); err == nil || len(blockchain) != 1 {
+		t.Fatalf("chain with broken link replaced local chain (err %v, length %d)", err, len(blockchain))
+	}
+
+	other := buildChain(t, 3)
+	// 本地换成另一个创世区块
+	InitGenesis()
+	blockchain[0].Timestamp--
+	blockchain[0].Hash = CalculateHash(blockchain[0])
+	if err := ReplaceChain(other); !errors.Is(err, ErrGenesisMismatch) {
+		t.Fatalf("foreign genesis: expected %v, got %v", ErrGenesisMismatch, err)
+	}
+}
+
+func TestChainConformance(t *testing.T) {
+	old := difficulty
+	difficulty = 1
+	t.Cleanup(func() { difficulty = old })
+	chaintest.Run(t, chaintest.Harness[Block, Transaction]{
+		New: func(t *testing.T) core.Chain[Block, Transaction] {
+			InitGenesis()
+			txPool = nil
+			return localChain
+		},
+		Mine: func(t *testing.T, prev Block, txs []Transaction) Block {
+			return MineBlock(txs, prev)
+		},
+		NewTx: func(t *testing.T) Transaction {
+			priv, addr := NewKeyPair()
+			_, to := NewKeyPair()
+			tx := Transaction{From: addr, To: to, Amount: 1}
+			sig, err := SignTransaction(priv, tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx.Signature = sig
+			return tx
+		},
+		Blocks: func(c core.Chain[Block, Transaction]) []Block {
+			chainMutex.Lock()
+			defer chainMutex.Unlock()
+			return append([]Block(nil), blockchain...)
+		},
+		Hash:   func(b Block) string { return b.Hash },
+		Tamper: func(b Block) Block { b.Nonce++; return b },
+	})
+}
+
+// helperNodeEnv 设置时TestHelperNode作为独立进程中的远端节点运行，值为本地节点创建创世区块时的Unix秒数
+const helperNodeEnv = "MINICHAIN_TEST_HELPER_NODE"
+
+// TestHelperNode 不是独立的测试：由TestSyncIndependentNodes在子进程中启动，
+// 在晚于本地节点的另一秒创建自己的创世区块，挖出3个区块后在随机端口上提供链数据
+func TestHelperNode(t *testing.T) {
+	v := os.Getenv(helperNodeEnv)
+	if v == "" {
+		t.Skip("only runs as a helper process")
+	}
+	since, _ := strconv.ParseInt(v, 10, 64)
+	for time.Now().Unix() <= since {
+		time.Sleep(20 * time.Millisecond)
+	}
+	difficulty = 1
+	InitGenesis()
+	for i := 0; i < 3; i++ {
+		AddBlock(MineBlock([]Transaction{{From: "a", To: "b", Amount: i + 1}}, blockchain[len(blockchain)-1]))
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("addr:", ln.Addr())
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go handleConn(conn)
+	}
+}
+
+func TestSyncIndependentNodes(t *testing.T) {
+	old := difficulty
+	difficulty = 1
+	oldPeers := peers
+	t.Cleanup(func() { difficulty, peers = old, oldPeers })
+	InitGenesis()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperNode$")
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", helperNodeEnv, time.Now().Unix()))
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill(); cmd.Wait() })
+	var addr string
+	for scanner := bufio.NewScanner(out); scanner.Scan(); {
+		if a, ok := strings.CutPrefix(scanner.Text(), "addr: "); ok {
+			addr = a
+			break
+		}
+	}
+	if addr == "" {
+		t.Fatal("helper node did not report its address")
+	}
+
+	peers = []string{addr}
+	requestChainsFromPeers()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		chainMutex.Lock()
+		n := len(blockchain)
+		chainMutex.Unlock()
+		if n == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("local chain length %d after sync, want 4", n)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}